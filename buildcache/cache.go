@@ -0,0 +1,377 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package buildcache stores the artifacts of unikernel builds, i.e. their
+// kernels, in a local content-addressed cache such that a build whose inputs
+// have not changed can be restored instead of being repeated.
+//
+// The cache is a directory with the following layout:
+//
+//	blobs/sha256/<digest>  the contents of each distinct artifact
+//	entries/<key>.json     the artifacts of the build with the given Key
+//
+// Since neither blobs nor entries are ever modified once written, caches can be
+// shared by copying them, see Import and Export.
+package buildcache
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"kraftkit.sh/internal/fsutil"
+)
+
+// Entry records the artifacts of a cached build.
+type Entry struct {
+	// Key identifies the inputs of the build.
+	Key string `json:"key"`
+
+	// Created is the time at which the build was stored.
+	Created time.Time `json:"created"`
+
+	// Artifacts maps the names of the artifacts, e.g. `kernel`, to the
+	// digests of their contents.
+	Artifacts map[string]string `json:"artifacts"`
+}
+
+// Cache is a content-addressed cache of build artifacts in a directory.
+type Cache struct {
+	dir string
+}
+
+// NewCache returns the cache in the provided directory, which is created when
+// the first build is stored.
+func NewCache(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Dir returns the directory of the cache.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+func (c *Cache) entryPath(key string) string {
+	return filepath.Join(c.dir, "entries", key+".json")
+}
+
+func (c *Cache) blobPath(digest string) string {
+	return filepath.Join(c.dir, "blobs", "sha256", digest)
+}
+
+// validDigest returns whether the digest is a hex-encoded SHA-256 digest.  Keys
+// and digests are read from entries, which may come from a shared cache, and
+// must be validated before they are used as file names.
+func validDigest(digest string) bool {
+	if len(digest) != 2*sha256.Size {
+		return false
+	}
+
+	for _, c := range digest {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// verifyBlob returns an error if the contents of the blob with the provided
+// digest do not match it, e.g. because it was tampered with.
+func (c *Cache) verifyBlob(digest string) error {
+	sum, err := fsutil.Checksum(c.blobPath(digest))
+	if err != nil {
+		return err
+	}
+
+	if sum != digest {
+		return fmt.Errorf("blob %s has unexpected digest %s", digest, sum)
+	}
+
+	return nil
+}
+
+// Lookup returns the entry of the build with the provided key, if it is cached
+// and all of its artifacts are present.
+func (c *Cache) Lookup(key string) (*Entry, bool) {
+	if !validDigest(key) {
+		return nil, false
+	}
+
+	data, err := os.ReadFile(c.entryPath(key))
+	if err != nil {
+		return nil, false
+	}
+
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		return nil, false
+	}
+
+	for _, digest := range entry.Artifacts {
+		if !validDigest(digest) {
+			return nil, false
+		}
+
+		if _, err := os.Stat(c.blobPath(digest)); err != nil {
+			return nil, false
+		}
+	}
+
+	return &entry, true
+}
+
+// Restore copies the artifacts of the build with the provided key to their
+// destinations, a map of artifact names to paths, and returns whether the
+// build was cached.  Artifacts which the build did not produce are skipped.
+// Where the contents of an artifact do not match its digest, e.g. because the
+// blob was only partially written, the blob and the entry are removed such that
+// the build is no longer cached and can be stored anew.
+func (c *Cache) Restore(key string, artifacts map[string]string) (bool, error) {
+	entry, ok := c.Lookup(key)
+	if !ok {
+		return false, nil
+	}
+
+	for name, dest := range artifacts {
+		digest, ok := entry.Artifacts[name]
+		if !ok {
+			continue
+		}
+
+		if err := c.verifyBlob(digest); err != nil {
+			os.Remove(c.blobPath(digest))
+			os.Remove(c.entryPath(key))
+			return false, fmt.Errorf("could not restore %s: %v", name, err)
+		}
+
+		if err := fsutil.CopyFile(c.blobPath(digest), dest); err != nil {
+			return false, fmt.Errorf("could not restore %s: %v", name, err)
+		}
+
+		// Blobs from a shared cache need not be executable
+		if err := os.Chmod(dest, 0o755); err != nil {
+			return false, fmt.Errorf("could not restore %s: %v", name, err)
+		}
+	}
+
+	return true, nil
+}
+
+// Store adds the artifacts of the build with the provided key, a map of
+// artifact names to paths, to the cache.  An existing entry is replaced.
+func (c *Cache) Store(key string, artifacts map[string]string) error {
+	if !validDigest(key) {
+		return fmt.Errorf("invalid key: %s", key)
+	}
+
+	entry := Entry{
+		Key:       key,
+		Created:   time.Now().UTC(),
+		Artifacts: map[string]string{},
+	}
+
+	for name, src := range artifacts {
+		digest, err := c.storeBlob(src)
+		if err != nil {
+			return fmt.Errorf("could not store %s: %v", name, err)
+		}
+
+		entry.Artifacts[name] = digest
+	}
+
+	data, err := json.MarshalIndent(entry, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFile(c.entryPath(key), data)
+}
+
+// storeBlob adds the contents of the file to the blobs and returns their
+// digest.  The contents are copied to a temporary file in the blobs directory
+// and digested from there, such that a blob only ever appears under its final
+// path once complete: since existing blobs are never rewritten, an interrupted
+// copy would otherwise leave a truncated blob behind for good.
+func (c *Cache) storeBlob(src string) (string, error) {
+	dir := filepath.Join(c.dir, "blobs")
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".blob-*")
+	if err != nil {
+		return "", err
+	}
+
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err := fsutil.CopyFile(src, tmp.Name()); err != nil {
+		return "", err
+	}
+
+	digest, err := fsutil.Checksum(tmp.Name())
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := os.Stat(c.blobPath(digest)); err == nil {
+		return digest, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.blobPath(digest)), 0o755); err != nil {
+		return "", err
+	}
+
+	return digest, os.Rename(tmp.Name(), c.blobPath(digest))
+}
+
+// Keys returns the keys of all builds in the cache.
+func (c *Cache) Keys() ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(c.dir, "entries"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	var keys []string
+	for _, entry := range entries {
+		if key, ok := strings.CutSuffix(entry.Name(), ".json"); ok && !entry.IsDir() && validDigest(key) {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys, nil
+}
+
+// Export copies the builds with the provided keys, or all builds if none are
+// provided, to the cache in the provided directory.  Builds which are not
+// cached are skipped.  It returns the number of builds which were copied.
+func (c *Cache) Export(dir string, keys ...string) (int, error) {
+	return copyCache(c, NewCache(dir), keys)
+}
+
+// Import copies all builds of the cache in the provided directory which are
+// not yet cached to this cache.  It returns the number of builds which were
+// copied.
+func (c *Cache) Import(dir string) (int, error) {
+	from := NewCache(dir)
+
+	keys, err := from.Keys()
+	if err != nil {
+		return 0, err
+	}
+
+	var missing []string
+	for _, key := range keys {
+		if _, ok := c.Lookup(key); !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	if len(missing) == 0 {
+		return 0, nil
+	}
+
+	return copyCache(from, c, missing)
+}
+
+// copyCache copies the entries with the provided keys, or all entries, and
+// their blobs from one cache to another.  Blobs are verified once copied such
+// that a shared cache cannot inject artifacts which do not match its entries:
+// builds with such blobs are skipped and reported in the returned error.
+func copyCache(from, to *Cache, keys []string) (int, error) {
+	if len(keys) == 0 {
+		var err error
+		if keys, err = from.Keys(); err != nil {
+			return 0, err
+		}
+	}
+
+	copied := 0
+	var corrupt []error
+
+	for _, key := range keys {
+		entry, ok := from.Lookup(key)
+		if !ok {
+			continue
+		}
+
+		intact := true
+		for _, digest := range entry.Artifacts {
+			if _, err := os.Stat(to.blobPath(digest)); err == nil {
+				continue
+			}
+
+			if err := fsutil.CopyFile(from.blobPath(digest), to.blobPath(digest)); err != nil {
+				return copied, err
+			}
+
+			if err := to.verifyBlob(digest); err != nil {
+				os.Remove(to.blobPath(digest))
+				corrupt = append(corrupt, fmt.Errorf("skipped build %s: %v", key, err))
+				intact = false
+				break
+			}
+		}
+
+		if !intact {
+			continue
+		}
+
+		data, err := os.ReadFile(from.entryPath(key))
+		if err != nil {
+			return copied, err
+		}
+
+		if err := writeFile(to.entryPath(key), data); err != nil {
+			return copied, err
+		}
+
+		copied++
+	}
+
+	return copied, errors.Join(corrupt...)
+}
+
+// writeFile writes the data to the path such that the file is either complete
+// or left untouched.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,216 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package buildcache
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCache(t *testing.T) {
+	workdir := t.TempDir()
+	kernel := filepath.Join(workdir, "app_qemu-x86_64")
+	kernelDbg := kernel + ".dbg"
+
+	if err := os.WriteFile(kernel, []byte("kernel"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(kernelDbg, []byte("kernel with symbols"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cache := NewCache(filepath.Join(t.TempDir(), "builds"))
+
+	abc, def, missing := key("abc"), key("def"), key("missing")
+
+	if ok, err := cache.Restore(missing, map[string]string{"kernel": kernel}); err != nil || ok {
+		t.Fatalf("expected a miss, got %v, %v", ok, err)
+	}
+
+	artifacts := map[string]string{"kernel": kernel, "kernel.dbg": kernelDbg}
+	if err := cache.Store(abc, artifacts); err != nil {
+		t.Fatal(err)
+	}
+
+	// Identical artifacts of another build are stored only once
+	if err := cache.Store(def, map[string]string{"kernel": kernel}); err != nil {
+		t.Fatal(err)
+	}
+
+	blobs, err := os.ReadDir(filepath.Join(cache.Dir(), "blobs", "sha256"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(blobs) != 2 {
+		t.Errorf("expected 2 blobs, got %d", len(blobs))
+	}
+
+	// Blobs are staged next to the digested ones, which is all that remains
+	if staged, err := os.ReadDir(filepath.Join(cache.Dir(), "blobs")); err != nil || len(staged) != 1 {
+		t.Errorf("expected no staged blobs to remain, got %v, %v", staged, err)
+	}
+
+	// Artifacts which a build did not produce are left untouched
+	if err := os.Remove(kernelDbg); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := cache.Restore(def, artifacts); err != nil || !ok {
+		t.Errorf("expected a hit, got %v, %v", ok, err)
+	} else if _, err := os.Stat(kernelDbg); !os.IsNotExist(err) {
+		t.Errorf("expected %s not to be restored", kernelDbg)
+	}
+
+	shared := filepath.Join(t.TempDir(), "shared")
+	if n, err := cache.Export(shared, abc); err != nil || n != 1 {
+		t.Fatalf("expected to export 1 build, got %d, %v", n, err)
+	}
+
+	fresh := NewCache(filepath.Join(t.TempDir(), "builds"))
+	if n, err := fresh.Import(shared); err != nil || n != 1 {
+		t.Fatalf("expected to import 1 build, got %d, %v", n, err)
+	}
+
+	if err := os.Remove(kernel); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := fresh.Restore(abc, map[string]string{"kernel": kernel}); err != nil || !ok {
+		t.Fatalf("expected a hit, got %v, %v", ok, err)
+	}
+
+	if data, err := os.ReadFile(kernel); err != nil || string(data) != "kernel" {
+		t.Errorf("expected the kernel to be restored, got %q, %v", data, err)
+	}
+}
+
+func TestCacheUntrusted(t *testing.T) {
+	kernel := filepath.Join(t.TempDir(), "kernel")
+	if err := os.WriteFile(kernel, []byte("kernel"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	shared := NewCache(filepath.Join(t.TempDir(), "shared"))
+	good, tampered, escaping := key("good"), key("tampered"), key("escaping")
+
+	if err := shared.Store(good, map[string]string{"kernel": kernel}); err != nil {
+		t.Fatal(err)
+	}
+
+	entry, _ := shared.Lookup(good)
+
+	// A blob whose contents were replaced after it was stored
+	if err := os.WriteFile(kernel, []byte("malicious"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := shared.Store(tampered, map[string]string{"kernel": kernel}); err != nil {
+		t.Fatal(err)
+	}
+
+	other, _ := shared.Lookup(tampered)
+	if err := os.WriteFile(shared.blobPath(other.Artifacts["kernel"]), []byte("injected"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// An entry whose digest is a path outside of the cache
+	if err := writeFile(shared.entryPath(escaping), []byte(`{"key":"`+escaping+`","artifacts":{"kernel":"../../../kernel"}}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := shared.Lookup(escaping); ok {
+		t.Errorf("expected the entry with an invalid digest to be ignored")
+	}
+
+	if _, ok := shared.Lookup("../" + good); ok {
+		t.Errorf("expected an invalid key to be ignored")
+	}
+
+	cache := NewCache(filepath.Join(t.TempDir(), "builds"))
+	if _, err := cache.Import(shared.Dir()); err == nil {
+		t.Errorf("expected the import of the tampered blob to fail")
+	}
+
+	if _, ok := cache.Lookup(tampered); ok {
+		t.Errorf("expected the tampered build not to be imported")
+	}
+
+	if _, err := os.Stat(cache.blobPath(entry.Artifacts["kernel"])); err != nil {
+		t.Errorf("expected the intact blob to be imported: %v", err)
+	}
+
+	if ok, err := shared.Restore(tampered, map[string]string{"kernel": kernel}); err == nil || ok {
+		t.Errorf("expected the tampered blob not to be restored, got %v, %v", ok, err)
+	}
+
+	// The tampered build is evicted such that it can be built and stored anew
+	if _, ok := shared.Lookup(tampered); ok {
+		t.Errorf("expected the tampered build to be removed")
+	}
+
+	if err := shared.Store(tampered, map[string]string{"kernel": kernel}); err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := shared.Restore(tampered, map[string]string{"kernel": kernel}); err != nil || !ok {
+		t.Errorf("expected the stored build to be restored, got %v, %v", ok, err)
+	}
+}
+
+func key(name string) string {
+	k := NewKey()
+	k.AddString("name", name)
+
+	return k.String()
+}
+
+func TestKey(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "main.c"), []byte("int main() {}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "build"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	skip := func(rel string, _ fs.DirEntry) bool {
+		return rel == "build"
+	}
+
+	key := func() string {
+		k := NewKey()
+		k.AddString("toolchain", "gcc 12")
+		if err := k.AddDir("sources", dir, skip); err != nil {
+			t.Fatal(err)
+		}
+
+		return k.String()
+	}
+
+	before := key()
+
+	// Build artifacts do not change the key
+	if err := os.WriteFile(filepath.Join(dir, "build", "main.o"), []byte("obj"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if after := key(); after != before {
+		t.Errorf("expected skipped files not to change the key")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "main.c"), []byte("int main() { return 1; }"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if after := key(); after == before {
+		t.Errorf("expected a changed source to change the key")
+	}
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package buildcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io/fs"
+	"path/filepath"
+
+	"kraftkit.sh/internal/fsutil"
+)
+
+// Key accumulates the inputs of a build, e.g. the versions of its components,
+// its configuration and its sources, into a digest which identifies the
+// artifacts of the build.  Each input is named such that the same content
+// added as a different input results in a different key.
+type Key struct {
+	h hash.Hash
+}
+
+// NewKey returns an empty key.
+func NewKey() *Key {
+	return &Key{h: sha256.New()}
+}
+
+// AddString adds a named value to the key.
+func (k *Key) AddString(name, value string) {
+	fmt.Fprintf(k.h, "%s %d\n%s\n", name, len(value), value)
+}
+
+// AddFile adds the contents of the file at the provided path to the key.
+func (k *Key) AddFile(name, path string) error {
+	sum, err := fsutil.Checksum(path)
+	if err != nil {
+		return err
+	}
+
+	k.AddString(name, sum)
+
+	return nil
+}
+
+// AddDir adds the relative paths and contents of all regular files within the
+// provided directory to the key.  Files and directories for which skip returns
+// true, given their path relative to the directory, are ignored.
+func (k *Key) AddDir(name, dir string, skip func(rel string, d fs.DirEntry) bool) error {
+	h := sha256.New()
+
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		if rel != "." && skip != nil && skip(rel, d) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		sum, err := fsutil.Checksum(path)
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(h, "%s  %s\n", sum, filepath.ToSlash(rel))
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	k.AddString(name, hex.EncodeToString(h.Sum(nil)))
+
+	return nil
+}
+
+// String returns the hex-encoded digest of the inputs added so far.
+func (k *Key) String() string {
+	return hex.EncodeToString(k.h.Sum(nil))
+}
@@ -8,42 +8,63 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/MakeNowJust/heredoc"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"kraftkit.sh/buildcache"
 	"kraftkit.sh/cmdfactory"
 	"kraftkit.sh/config"
 	"kraftkit.sh/exec"
 	"kraftkit.sh/internal/cli"
 	"kraftkit.sh/pack"
+	"kraftkit.sh/sbom"
 	"kraftkit.sh/unikraft"
 
 	"kraftkit.sh/iostreams"
 	"kraftkit.sh/log"
 	"kraftkit.sh/make"
+	"kraftkit.sh/manifest"
 	"kraftkit.sh/packmanager"
 	"kraftkit.sh/tui/paraprogress"
 	"kraftkit.sh/unikraft/app"
+	"kraftkit.sh/unikraft/component"
 	"kraftkit.sh/unikraft/target"
 
 	"kraftkit.sh/tui/processtree"
 )
 
 type Build struct {
-	Architecture string `long:"arch" short:"m" usage:"Filter the creation of the build by architecture of known targets"`
-	DotConfig    string `long:"config" short:"c" usage:"Override the path to the KConfig .config file"`
-	Fast         bool   `long:"fast" usage:"Use maximum parallelization when performing the build"`
-	Jobs         int    `long:"jobs" short:"j" usage:"Allow N jobs at once"`
-	KernelDbg    bool   `long:"dbg" usage:"Build the debuggable (symbolic) kernel image instead of the stripped image"`
-	NoCache      bool   `long:"no-cache" short:"F" usage:"Force a rebuild even if existing intermediate artifacts already exist"`
-	NoConfigure  bool   `long:"no-configure" usage:"Do not run Unikraft's configure step before building"`
-	NoFetch      bool   `long:"no-fetch" usage:"Do not run Unikraft's fetch step before building"`
-	NoPrepare    bool   `long:"no-prepare" usage:"Do not run Unikraft's prepare step before building"`
-	Platform     string `long:"plat" short:"p" usage:"Filter the creation of the build by platform of known targets"`
-	SaveBuildLog string `long:"build-log" usage:"Use the specified file to save the output from the build"`
-	Target       string `long:"target" short:"t" usage:"Build a particular known target"`
+	Architecture  string `long:"arch" short:"m" usage:"Filter the creation of the build by architecture of known targets"`
+	CacheFrom     string `long:"cache-from" usage:"Import the cached builds in the given directory before building"`
+	CacheTo       string `long:"cache-to" usage:"Export the cached builds of the selected targets to the given directory"`
+	CompileDB     bool   `long:"compile-commands" usage:"Write a compile_commands.json of the selected targets into the project, e.g. for clangd"`
+	DotConfig     string `long:"config" short:"c" usage:"Override the path to the KConfig .config file"`
+	Diagnostics   string `long:"diagnostics-format" usage:"Print the diagnostics of the compiler and the linker in the given format (text, json, sarif)" default:"text"`
+	DiagnosticsTo string `long:"diagnostics-output" usage:"Write the diagnostics in the JSON or SARIF format to the given file instead of the standard output"`
+	Fast          bool   `long:"fast" usage:"Use maximum parallelization when performing the build"`
+	Frozen        bool   `long:"frozen-lockfile" usage:"Fail if the lockfile is missing or does not match the Kraftfile"`
+	Jobs          int    `long:"jobs" short:"j" usage:"Allow N jobs at once, shared between the targets which are built concurrently"`
+	KernelDbg     bool   `long:"dbg" usage:"Build the debuggable (symbolic) kernel image instead of the stripped image"`
+	NoCache       bool   `long:"no-cache" short:"F" usage:"Force a rebuild even if existing intermediate artifacts or a cached build already exist"`
+	NoConfigure   bool   `long:"no-configure" usage:"Do not run Unikraft's configure step before building"`
+	NoDeps        bool   `long:"no-deps" usage:"Do not look for libraries which the configuration depends on but are missing"`
+	NoFetch       bool   `long:"no-fetch" usage:"Do not run Unikraft's fetch step before building"`
+	NoPrepare     bool   `long:"no-prepare" usage:"Do not run Unikraft's prepare step before building"`
+	Platform      string `long:"plat" short:"p" usage:"Filter the creation of the build by platform of known targets"`
+	SaveBuildLog  string `long:"build-log" usage:"Use the specified file to save the output from the build"`
+	SBOM          string `long:"sbom" usage:"Generate a Software Bill of Materials next to the kernel in the given format (spdx, cyclonedx)"`
+	SizeCompare   string `long:"size-compare" usage:"Compare the size report with a previous report, given by its path, or with the one attached to a package"`
+	SizeFormat    string `long:"size-format" usage:"Print the size report in the given format (table, json)" default:"table"`
+	SizeReport    bool   `long:"size-report" usage:"Report the size of the kernel broken down by library and its largest symbols"`
+	SizeSymbols   int    `long:"size-symbols" usage:"Number of the largest symbols to report" default:"10"`
+	SizeThreshold int    `long:"size-threshold" usage:"Fail if the kernel or a library grew by more than the given percentage compared to --size-compare" default:"0"`
+	Target        string `long:"target" short:"t" usage:"Build a particular known target"`
+	UpdateLock    bool   `long:"update-lock" usage:"Resolve all components anew and update the lockfile"`
+	Yes           bool   `long:"yes" short:"y" usage:"Add and pull missing libraries without prompting"`
 }
 
 // The longest word is "configuring" (which is 11 characters long), plus
@@ -62,13 +83,70 @@ func New() *cobra.Command {
 
 			The default behaviour of %[1]skraft build%[1]s is to build a project.  Given no
 			arguments, you will be guided through interactive mode.
+
+			Built kernels are stored in a local cache under a key which is computed
+			from the resolved versions of the components, the .config of the target,
+			the version of the toolchain and the sources of the application.  When
+			none of these have changed, the kernels are restored from the cache
+			instead of being rebuilt, including in a fresh checkout of the project.
+			Use %[1]s--cache-from%[1]s and %[1]s--cache-to%[1]s to share the cache, e.g. in CI.
+
+			When more than one target is selected, each target is configured and
+			built in its own directory under %[1]s.targets%[1]s in the output directory and
+			its kernels are copied to their usual location once built.  The first
+			target fetches and prepares the sources of the libraries, with which
+			the remaining targets are then seeded, before up to %[1]s--jobs%[1]s targets
+			(by default, the number of CPUs) are built concurrently.  The result
+			and duration of each target are summarized at the end.
+
+			Errors and warnings of the compiler and the linker are collected while
+			building and summarized by the library they occur in once the build
+			completes.  Use %[1]s--diagnostics-format json%[1]s or %[1]s--diagnostics-format sarif%[1]s
+			to print all of them in a machine-readable format instead, e.g. for code
+			review tools.
+
+			With %[1]s--size-report%[1]s, the text, data and bss of each kernel is attributed
+			to the libraries whose objects in the build directory define its symbols
+			and the largest symbols are listed.  The report is saved next to the
+			kernel, from where %[1]skraft pkg%[1]s attaches it to the package.  Use
+			%[1]s--size-compare%[1]s with a previous report or a package to flag libraries
+			which grew by more than %[1]s--size-threshold%[1]s percent.
+
+			With %[1]s--compile-commands%[1]s, every invocation of the compiler for the
+			sources of the application, of Unikraft and of the libraries is written to
+			%[1]scompile_commands.json%[1]s in the project, such that editors using clangd
+			can follow the include paths and definitions of each target.  The sources
+			of all selected targets are merged into the one file, those shared by
+			several targets being compiled as by the first.
 		`, "`"),
 		Example: heredoc.Doc(`
 			# Build the current project (cwd)
 			$ kraft build
 
 			# Build path to a Unikraft project
-			$ kraft build path/to/app`),
+			$ kraft build path/to/app
+
+			# Build and generate a CycloneDX Software Bill of Materials
+			$ kraft build --sbom cyclonedx
+
+			# Build and add any libraries the configuration requires but which are
+			# missing from the Kraftfile without prompting
+			$ kraft build --yes
+
+			# Build using and updating a cache which is shared between CI jobs
+			$ kraft build --cache-from ci-cache/ --cache-to ci-cache/
+
+			# Build all targets of the project, at most 16 jobs at once
+			$ kraft build --jobs 16
+
+			# Build and save the diagnostics of the compiler for code review tools
+			$ kraft build --diagnostics-format sarif --diagnostics-output build.sarif
+
+			# Build and fail if the kernel grew by more than 5% compared to a package
+			$ kraft build --size-compare unikraft.org/helloworld:latest --size-threshold 5
+
+			# Build and generate a compilation database for clangd
+			$ kraft build --compile-commands`),
 		Annotations: map[string]string{
 			cmdfactory.AnnotationHelpGroup: "build",
 		},
@@ -109,6 +187,30 @@ func (opts *Build) Run(cmd *cobra.Command, args []string) error {
 		workdir = args[0]
 	}
 
+	switch opts.Diagnostics {
+	case diagnosticsText, diagnosticsJSON, diagnosticsSARIF:
+	default:
+		return fmt.Errorf("unsupported diagnostics format: %s", opts.Diagnostics)
+	}
+
+	switch opts.SizeFormat {
+	case sizeTable, sizeJSON:
+	default:
+		return fmt.Errorf("unsupported size report format: %s", opts.SizeFormat)
+	}
+
+	if len(opts.SizeCompare) > 0 {
+		opts.SizeReport = true
+	}
+
+	var sbomFormat sbom.Format
+	if len(opts.SBOM) > 0 {
+		sbomFormat, err = sbom.FormatFromString(opts.SBOM)
+		if err != nil {
+			return err
+		}
+	}
+
 	ctx := cmd.Context()
 
 	// Initialize at least the configuration options for a project
@@ -145,6 +247,7 @@ func (opts *Build) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	var missingPacks []pack.Package
+	var missingPacksMu sync.Mutex
 	var processes []*paraprogress.Process
 	var searches []*processtree.ProcessTreeItem
 
@@ -257,23 +360,75 @@ func (opts *Build) Run(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+
+	// Components which are linked to a local checkout are used as they are and
+	// are neither resolved nor pulled
+	var linked, unlinked []component.Component
+
+	var queries []packmanager.CatalogQuery
 	for _, component := range components {
+		if path, ok := project.Links().Lookup(component.Type(), component.Name()); ok {
+			component := component // loop closure
+			linked = append(linked, component)
+
+			searches = append(searches, processtree.NewProcessTreeItem(
+				fmt.Sprintf("linked %s -> %s",
+					unikraft.TypeNameVersion(component),
+					path,
+				), "",
+				func(ctx context.Context) error {
+					if f, err := os.Stat(path); err != nil || !f.IsDir() {
+						return fmt.Errorf("linked checkout of %s is not a directory: %s",
+							unikraft.TypeNameVersion(component),
+							path,
+						)
+					}
+
+					return nil
+				},
+			))
+
+			continue
+		}
+
+		unlinked = append(unlinked, component)
+		queries = append(queries, packmanager.CatalogQuery{
+			Name: component.Name(),
+			Types: []unikraft.ComponentType{
+				component.Type(),
+			},
+			Version: component.Version(),
+			Source:  component.Source(),
+			NoCache: opts.NoCache,
+		})
+	}
+
+	locker, err := manifest.NewLocker(workdir, opts.UpdateLock, opts.Frozen)
+	if err != nil {
+		return err
+	}
+
+	for _, component := range linked {
+		locker.Retain(component.Type(), component.Name(), component.Source())
+	}
+
+	// Honour the lockfile and resolve the version constraints of the remaining
+	// components together such that they are compatible with one another
+	queries, err = locker.Resolve(ctx, queries)
+	if err != nil {
+		return err
+	}
+
+	for i, component := range unlinked {
 		component := component // loop closure
+		query := queries[i]
 
 		searches = append(searches, processtree.NewProcessTreeItem(
 			fmt.Sprintf("finding %s",
 				unikraft.TypeNameVersion(component),
 			), "",
 			func(ctx context.Context) error {
-				p, err := packmanager.G(ctx).Catalog(ctx, packmanager.CatalogQuery{
-					Name: component.Name(),
-					Types: []unikraft.ComponentType{
-						component.Type(),
-					},
-					Version: component.Version(),
-					Source:  component.Source(),
-					NoCache: opts.NoCache,
-				})
+				p, err := packmanager.G(ctx).Catalog(ctx, query)
 				if err != nil {
 					return err
 				}
@@ -288,7 +443,15 @@ func (opts *Build) Run(cmd *cobra.Command, args []string) error {
 					)
 				}
 
-				missingPacks = append(missingPacks, p...)
+				pinned, err := locker.Lock(ctx, query, p[0])
+				if err != nil {
+					return err
+				}
+
+				missingPacksMu.Lock()
+				defer missingPacksMu.Unlock()
+
+				missingPacks = append(missingPacks, pinned)
 				return nil
 			},
 		))
@@ -349,6 +512,17 @@ func (opts *Build) Run(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if !opts.NoDeps {
+		project, err = opts.resolveDependencies(ctx, project, workdir, locker)
+		if err != nil {
+			return err
+		}
+	}
+
+	if err := locker.Save(ctx); err != nil {
+		return err
+	}
+
 	processes = []*paraprogress.Process{} // reset
 
 	// Filter project targets by any provided CLI options
@@ -363,71 +537,181 @@ func (opts *Build) Run(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("no targets selected to build")
 	}
 
+	matrix, err := newMatrix(ctx, project, selected)
+	if err != nil {
+		return err
+	}
+
+	concurrent, perTarget := matrixJobs(opts.Jobs, opts.Fast, len(matrix))
+
 	var mopts []make.MakeOption
-	if opts.Jobs > 0 {
+	if concurrent > 1 && perTarget > 0 {
+		mopts = append(mopts, make.WithJobs(perTarget))
+	} else if opts.Jobs > 0 {
 		mopts = append(mopts, make.WithJobs(opts.Jobs))
 	} else {
 		mopts = append(mopts, make.WithMaxJobs(opts.Fast))
 	}
 
-	for _, targ := range selected {
+	cache := buildcache.NewCache(config.G[config.KraftKit](ctx).Paths.Builds)
+
+	if len(opts.CacheFrom) > 0 {
+		// Builds which could not be imported, e.g. since their kernels do not
+		// match their digests, are simply built again
+		imported, err := cache.Import(opts.CacheFrom)
+		if err != nil {
+			log.G(ctx).Warnf("could not import all cached builds: %v", err)
+		}
+
+		log.G(ctx).WithField("from", opts.CacheFrom).Debugf("imported %d cached builds", imported)
+	}
+
+	// The cache keys of the selected targets, once computed
+	var keys []string
+	var keysMu sync.Mutex
+
+	sched := newScheduler(concurrent)
+
+	for i, mt := range matrix {
 		// See: https://github.com/golang/go/wiki/CommonMistakes#using-reference-to-loop-iterator-variable
-		targ := targ
-		if !opts.NoConfigure {
-			processes = append(processes, paraprogress.NewProcess(
-				fmt.Sprintf("configuring %s (%s)", targ.Name(), target.TargetPlatArchName(targ)),
-				func(ctx context.Context, w func(progress float64)) error {
-					return project.Configure(
+		i, mt := i, mt
+
+		processes = append(processes, paraprogress.NewProcess(
+			fmt.Sprintf("building %s (%s)", mt.targ.Name(), target.TargetPlatArchName(mt.targ)),
+			func(ctx context.Context, w func(progress float64)) (err error) {
+				// All but the first target wait for it to prepare the sources which
+				// they share before they take up a slot
+				seed := ""
+				if i > 0 {
+					if seed, err = sched.waitPrepared(ctx); err != nil {
+						mt.result = resultCanceled
+						return err
+					}
+				} else {
+					defer sched.donePreparing("")
+				}
+
+				if err := sched.acquire(ctx); err != nil {
+					mt.result = resultCanceled
+					return err
+				}
+
+				defer sched.release()
+
+				start := time.Now()
+				defer func() {
+					mt.duration = time.Since(start)
+					if err != nil {
+						mt.result = resultFailed
+						mt.err = err
+					}
+				}()
+
+				if !opts.NoConfigure {
+					if err := mt.project.Configure(
 						ctx,
-						targ, // Target-specific options
-						nil,  // No extra configuration options
-						make.WithProgressFunc(w),
+						mt.targ, // Target-specific options
+						nil,     // No extra configuration options
 						make.WithSilent(true),
 						make.WithExecOptions(
 							exec.WithStdin(iostreams.G(ctx).In),
 							exec.WithStdout(log.G(ctx).Writer()),
 							exec.WithStderr(log.G(ctx).WriterLevel(logrus.ErrorLevel)),
 						),
-					)
-				},
-			))
-		}
+					); err != nil {
+						return err
+					}
+				}
 
-		if !opts.NoPrepare {
-			processes = append(processes, paraprogress.NewProcess(
-				fmt.Sprintf("preparing %s (%s)", targ.Name(), target.TargetPlatArchName(targ)),
-				func(ctx context.Context, w func(progress float64)) error {
-					return project.Prepare(
+				key, err := buildKey(ctx, mt.project, mt.targ)
+				if err != nil {
+					log.G(ctx).Warnf("not caching %s: %v", mt.targ.Name(), err)
+					key = ""
+				} else {
+					keysMu.Lock()
+					keys = append(keys, key)
+					keysMu.Unlock()
+				}
+
+				if len(key) > 0 && !opts.NoCache {
+					// A build which cannot be restored is simply built anew
+					restored, err := cache.Restore(key, artifacts(mt.project, mt.targ))
+					if err != nil {
+						log.G(ctx).WithField("key", key).Debugf("could not restore %s from cache: %v", mt.targ.Name(), err)
+					}
+
+					if restored {
+						log.G(ctx).WithField("key", key).Infof("restored %s from cache", mt.targ.Name())
+						mt.result = resultCached
+
+						// Only configuring, preparing and building are skipped, the
+						// remaining steps apply to the restored kernel alike
+						return mt.writeSBOM(ctx, sbomFormat)
+					}
+				}
+
+				if !opts.NoPrepare {
+					if len(seed) > 0 && len(mt.stage) > 0 {
+						if err := seedOrigins(seed, mt.stage); err != nil {
+							log.G(ctx).Warnf("could not reuse the prepared sources of %s: %v", matrix[0].targ.Name(), err)
+						}
+					}
+
+					if err := mt.project.Prepare(
 						ctx,
-						targ, // Target-specific options
+						mt.targ, // Target-specific options
 						append(
 							mopts,
-							make.WithProgressFunc(w),
 							make.WithExecOptions(
 								exec.WithStdout(log.G(ctx).Writer()),
 								exec.WithStderr(log.G(ctx).WriterLevel(logrus.ErrorLevel)),
 							),
 						)...,
-					)
-				},
-			))
-		}
+					); err != nil {
+						return err
+					}
+
+					if i == 0 {
+						sched.donePreparing(mt.stage)
+					}
+				} else if i == 0 {
+					sched.donePreparing("")
+				}
 
-		processes = append(processes, paraprogress.NewProcess(
-			fmt.Sprintf("building %s (%s)", targ.Name(), target.TargetPlatArchName(targ)),
-			func(ctx context.Context, w func(progress float64)) error {
-				return project.Build(
+				if err := mt.project.Build(
 					ctx,
-					targ, // Target-specific options
+					mt.targ, // Target-specific options
 					app.WithBuildProgressFunc(w),
+					app.WithBuildNoPrepare(true),
 					app.WithBuildMakeOptions(append(mopts,
+						make.WithDiagnostics(mt.diagnostics),
 						make.WithExecOptions(
 							exec.WithStdout(log.G(ctx).Writer()),
 							exec.WithStderr(log.G(ctx).WriterLevel(logrus.ErrorLevel)),
 						),
 					)...),
 					app.WithBuildLogFile(opts.SaveBuildLog),
-				)
+				); err != nil {
+					return err
+				}
+
+				if err := mt.publish(); err != nil {
+					return err
+				}
+
+				if len(key) > 0 {
+					if err := cache.Store(key, builtArtifacts(mt.project, mt.targ)); err != nil {
+						log.G(ctx).Warnf("could not cache %s: %v", mt.targ.Name(), err)
+					}
+				}
+
+				if err := mt.writeSBOM(ctx, sbomFormat); err != nil {
+					return err
+				}
+
+				mt.result = resultBuilt
+
+				return nil
 			},
 		))
 	}
@@ -435,18 +719,63 @@ func (opts *Build) Run(cmd *cobra.Command, args []string) error {
 	paramodel, err := paraprogress.NewParaProgress(
 		ctx,
 		processes,
-		// Disable parallelization as:
-		//  - The first process may be pulling the container image, which is
-		//    necessary for the subsequent build steps;
-		//  - The Unikraft build system can re-use compiled files from previous
-		//    compilations (if the architecture does not change).
-		paraprogress.IsParallel(false),
+		// Each target is built in its own output directory and at most
+		// `concurrent` targets are built at once, see the scheduler.  Failing
+		// targets do not stop the remaining ones such that the summary covers
+		// the entire matrix.
+		paraprogress.IsParallel(concurrent > 1),
 		paraprogress.WithRenderer(norender),
-		paraprogress.WithFailFast(true),
+		paraprogress.WithFailFast(len(matrix) == 1),
 	)
 	if err != nil {
 		return err
 	}
 
-	return paramodel.Start()
+	err = paramodel.Start()
+
+	if len(opts.CacheTo) > 0 && len(keys) > 0 {
+		exported, err := cache.Export(opts.CacheTo, keys...)
+		if err != nil {
+			return fmt.Errorf("could not export cached builds: %v", err)
+		}
+
+		log.G(ctx).WithField("to", opts.CacheTo).Debugf("exported %d cached builds", exported)
+	}
+
+	if err := outputDiagnostics(ctx, opts.Diagnostics, opts.DiagnosticsTo, project, matrix); err != nil {
+		return err
+	}
+
+	if opts.CompileDB {
+		if err := compileCommands(ctx, project, matrix); err != nil {
+			return err
+		}
+	}
+
+	if err == nil && opts.SizeReport {
+		if err := reportSizes(ctx, opts, project, matrix); err != nil {
+			return err
+		}
+	}
+
+	if len(matrix) == 1 {
+		return err
+	}
+
+	if err := printSummary(ctx, matrix); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, mt := range matrix {
+		if mt.result == resultFailed {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("could not build %d of %d targets", failed, len(matrix))
+	}
+
+	return err
 }
@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package build
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+
+	"kraftkit.sh/buildcache"
+	"kraftkit.sh/kconfig"
+	"kraftkit.sh/manifest"
+	"kraftkit.sh/unikraft"
+	"kraftkit.sh/unikraft/app"
+	"kraftkit.sh/unikraft/component"
+	"kraftkit.sh/unikraft/target"
+)
+
+// buildKey computes the key under which the kernels of the target are cached
+// from the resolved versions of the components of the project, the target's
+// .config, the version of the toolchain and the sources of the application.
+// Paths which are specific to the host are left out such that the key is the
+// same in a fresh checkout.
+func buildKey(ctx context.Context, project app.Application, targ target.Target) (string, error) {
+	workdir := project.WorkingDir()
+	key := buildcache.NewKey()
+
+	key.AddString("target", target.TargetPlatArchName(targ))
+
+	components, err := project.Components(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	lockfile, err := manifest.NewLockfileFromFile(filepath.Join(workdir, manifest.LockfileName))
+	if err != nil {
+		return "", err
+	}
+
+	for _, component := range components {
+		name := unikraft.TypeNameVersion(component)
+
+		// Linked checkouts change without their version changing
+		if path, ok := project.Links().Lookup(component.Type(), component.Name()); ok {
+			if err := key.AddDir(name, path, skipVCS); err != nil {
+				return "", err
+			}
+
+			continue
+		}
+
+		if err := addRevision(key, name, lockfile, component); err != nil {
+			return "", err
+		}
+	}
+
+	dotconfig, err := kconfig.ParseConfig(filepath.Join(workdir, targ.ConfigFilename()))
+	if err != nil {
+		return "", fmt.Errorf("could not read the configuration of %s: %v", targ.Name(), err)
+	}
+
+	var values strings.Builder
+	for _, kv := range dotconfig.Slice {
+		value := strings.ReplaceAll(kv.Value, workdir, "$(APP)")
+		if uk := project.Unikraft(ctx).Path(); len(uk) > 0 {
+			value = strings.ReplaceAll(value, uk, "$(UK_BASE)")
+		}
+
+		fmt.Fprintf(&values, "%s=%s\n", kv.Key, value)
+	}
+
+	key.AddString("config", values.String())
+	key.AddString("toolchain", toolchain(dotconfig))
+
+	// The output directory of a target of a build matrix is staged within that
+	// of the project, all of which is left out
+	outdir := project.OutDir()
+	if filepath.Base(filepath.Dir(outdir)) == stagingDir {
+		outdir = filepath.Dir(filepath.Dir(outdir))
+	}
+
+	outdir, _ = filepath.Rel(workdir, outdir)
+
+	if err := key.AddDir("sources", workdir, func(rel string, d fs.DirEntry) bool {
+		name := d.Name()
+
+		return skipVCS(rel, d) ||
+			rel == unikraft.VendorDir ||
+			rel == outdir ||
+			rel == app.LinksFileName ||
+			strings.HasPrefix(name, kconfig.DotConfigFileName)
+	}); err != nil {
+		return "", err
+	}
+
+	return key.String(), nil
+}
+
+// addRevision adds the revision of the component to the key, since its version
+// in the Kraftfile may be a channel, e.g. `stable`, which moves.  The revision
+// is the commit or checksum pinned by the lockfile, otherwise the commit of its
+// checkout or, failing that, its contents.
+func addRevision(key *buildcache.Key, name string, lockfile *manifest.Lockfile, comp component.Component) error {
+	if entry, ok := lockfile.Lookup(comp.Type(), comp.Name(), comp.Source()); ok {
+		if len(entry.Commit) > 0 {
+			key.AddString(name, entry.Commit)
+			return nil
+		} else if len(entry.Sha256) > 0 {
+			key.AddString(name, entry.Version+"@"+entry.Sha256)
+			return nil
+		}
+	}
+
+	path := comp.Path()
+	if len(path) == 0 || !exists(path) {
+		return fmt.Errorf("could not resolve the revision of %s: it has not been pulled", name)
+	}
+
+	if repo, err := git.PlainOpen(path); err == nil {
+		if head, err := repo.Head(); err == nil {
+			key.AddString(name, head.Hash().String())
+			return nil
+		}
+	}
+
+	return key.AddDir(name, path, skipVCS)
+}
+
+// artifacts returns the kernels of the target which are cached by name.
+func artifacts(project app.Application, targ target.Target) map[string]string {
+	abs := func(path string) string {
+		if filepath.IsAbs(path) {
+			return path
+		}
+
+		return filepath.Join(project.WorkingDir(), path)
+	}
+
+	files := map[string]string{
+		"kernel": abs(targ.Kernel()),
+	}
+
+	if len(targ.KernelDbg()) > 0 {
+		files["kernel.dbg"] = abs(targ.KernelDbg())
+	}
+
+	return files
+}
+
+// builtArtifacts returns the kernels of the target which exist after it was
+// built.
+func builtArtifacts(project app.Application, targ target.Target) map[string]string {
+	files := artifacts(project, targ)
+	for name, path := range files {
+		if !exists(path) {
+			delete(files, name)
+		}
+	}
+
+	return files
+}
+
+// toolchain returns the version of the compiler which builds the target.
+func toolchain(dotconfig *kconfig.DotConfigFile) string {
+	cc := os.Getenv("CC")
+	if len(cc) == 0 {
+		cross := dotconfig.Value("CROSS_COMPILE")
+		if s, err := strconv.Unquote(cross); err == nil {
+			cross = s
+		} else if cross == kconfig.No {
+			cross = ""
+		}
+
+		cc = cross + "gcc"
+	}
+
+	out, err := exec.Command(cc, "--version").Output()
+	if err != nil {
+		return cc + " (unknown)"
+	}
+
+	version, _, _ := strings.Cut(string(out), "\n")
+
+	return version
+}
+
+func skipVCS(_ string, d fs.DirEntry) bool {
+	return d.IsDir() && d.Name() == ".git"
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,68 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package build
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/sirupsen/logrus"
+
+	"kraftkit.sh/compiledb"
+	"kraftkit.sh/exec"
+	"kraftkit.sh/log"
+	"kraftkit.sh/make"
+	"kraftkit.sh/unikraft/app"
+)
+
+// compileCommands writes the compilation database of the targets which were
+// built, restored from the cache or failed to build into the working directory
+// of the project.  The invocations of the compiler are printed by a dry run of
+// make which considers every file to be out of date, such that the database is
+// complete even if the kernel was restored or only partially rebuilt.  Sources
+// which several targets compile, e.g. those of the application, are recorded
+// as compiled by the first of them.
+func compileCommands(ctx context.Context, project app.Application, matrix []*matrixTarget) error {
+	db := compiledb.New()
+
+	for _, mt := range matrix {
+		if mt.result != resultBuilt && mt.result != resultCached && mt.result != resultFailed {
+			continue
+		}
+
+		var out bytes.Buffer
+
+		if err := mt.project.Make(
+			ctx,
+			mt.targ,
+			make.WithJustPrint(true),
+			make.WithAlwaysMake(true),
+			make.WithKeepGoing(true),
+			make.WithVar("V", "1"),
+			make.WithExecOptions(
+				exec.WithStdout(&out),
+				exec.WithStderr(log.G(ctx).WriterLevel(logrus.DebugLevel)),
+			),
+		); err != nil {
+			// Keeping going, make still prints the commands it could determine
+			log.G(ctx).Warnf("could not determine all compile commands of %s: %v", mt.targ.Name(), err)
+		}
+
+		added := db.Add(compiledb.Parse(mt.project.Unikraft(ctx).Path(), out.Bytes())...)
+
+		log.G(ctx).Debugf("recorded %d compile commands of %s", added, mt.targ.Name())
+	}
+
+	path := filepath.Join(project.WorkingDir(), compiledb.FileName)
+	if err := db.WriteFile(path); err != nil {
+		return fmt.Errorf("could not write compile commands: %v", err)
+	}
+
+	log.G(ctx).Infof("wrote %d compile commands to %s", len(db.Commands()), path)
+
+	return nil
+}
@@ -0,0 +1,304 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package build
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"kraftkit.sh/config"
+	"kraftkit.sh/internal/cli"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/log"
+	"kraftkit.sh/manifest"
+	"kraftkit.sh/pack"
+	"kraftkit.sh/packmanager"
+	"kraftkit.sh/tui/paraprogress"
+	"kraftkit.sh/unikraft"
+	"kraftkit.sh/unikraft/app"
+	"kraftkit.sh/unikraft/lib"
+)
+
+// resolveDependencies determines the libraries which the enabled configuration
+// of the selected targets depends on or selects but which are missing from the
+// project.  Those which can be found in the catalog are, once confirmed, added
+// to the Kraftfile, locked and pulled.  Since added libraries may themselves
+// depend on further libraries, this is repeated until nothing new is missing.
+// The returned project reflects the updated Kraftfile.
+func (opts *Build) resolveDependencies(ctx context.Context, project app.Application, workdir string, locker *manifest.Locker) (app.Application, error) {
+	found := map[string]pack.Package{}
+
+	missing := func(ctx context.Context) ([]string, error) {
+		var symbols []string
+
+		for _, targ := range cli.FilterTargets(
+			project.Targets(),
+			opts.Architecture,
+			opts.Platform,
+			opts.Target,
+		) {
+			missing, err := project.MissingLibraries(ctx, targ)
+			if err != nil {
+				return nil, err
+			}
+
+			symbols = append(symbols, missing...)
+		}
+
+		return symbols, nil
+	}
+
+	provide := func(ctx context.Context, symbol string) (string, bool) {
+		for _, name := range lib.NamesFromKConfigSymbol(symbol) {
+			packs, err := packmanager.G(ctx).Catalog(ctx, packmanager.CatalogQuery{
+				Name:    name,
+				Types:   []unikraft.ComponentType{unikraft.ComponentTypeLib},
+				NoCache: opts.NoCache,
+			})
+			if err != nil {
+				log.G(ctx).
+					WithField("symbol", symbol).
+					Debugf("could not query catalog for %s: %v", name, err)
+				continue
+			}
+
+			if len(packs) > 0 {
+				found[packs[0].Name()] = packs[0]
+				return packs[0].Name(), true
+			}
+		}
+
+		log.G(ctx).Warnf("could not find a library providing %s", symbol)
+
+		return "", false
+	}
+
+	add := func(ctx context.Context, providers map[string][]string) (bool, error) {
+		added, err := opts.addLibraries(ctx, project, workdir, locker, found, providers)
+		if err != nil || !added {
+			return false, err
+		}
+
+		if project, err = loadProject(ctx, workdir); err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	if err := closeDependencies(ctx, missing, provide, add); err != nil {
+		return nil, err
+	}
+
+	return project, nil
+}
+
+// closeDependencies repeatedly determines the missing symbols and adds the
+// libraries which provide them until no new symbols are missing, no new
+// libraries provide them or the libraries are not added.  Each symbol and each
+// library is only considered once such that a library which does not satisfy
+// the symbol it was added for, or dependencies which form a cycle, cannot
+// cause an endless loop.
+func closeDependencies(
+	ctx context.Context,
+	missing func(context.Context) ([]string, error),
+	provide func(context.Context, string) (string, bool),
+	add func(context.Context, map[string][]string) (bool, error),
+) error {
+	visited := map[string]bool{}
+	added := map[string]bool{}
+
+	for {
+		symbols, err := missing(ctx)
+		if err != nil {
+			return err
+		}
+
+		providers := map[string][]string{}
+
+		for _, symbol := range symbols {
+			if visited[symbol] {
+				continue
+			}
+
+			visited[symbol] = true
+
+			name, ok := provide(ctx, symbol)
+			if !ok || added[name] {
+				continue
+			}
+
+			providers[name] = append(providers[name], symbol)
+		}
+
+		if len(providers) == 0 {
+			return nil
+		}
+
+		ok, err := add(ctx, providers)
+		if err != nil || !ok {
+			return err
+		}
+
+		for name := range providers {
+			added[name] = true
+		}
+	}
+}
+
+// addLibraries adds the provided libraries, which provide the symbols they map
+// to, to the Kraftfile of the project once confirmed and locks and pulls them.
+// It returns whether the libraries were added.
+func (opts *Build) addLibraries(ctx context.Context, project app.Application, workdir string, locker *manifest.Locker, found map[string]pack.Package, providers map[string][]string) (bool, error) {
+	var names []string
+	for name := range providers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var described []string
+	for _, name := range names {
+		described = append(described, fmt.Sprintf("%s (%s) for %s",
+			name,
+			found[name].Version(),
+			strings.Join(providers[name], ", "),
+		))
+	}
+
+	if opts.Frozen {
+		return false, fmt.Errorf("missing libraries cannot be added with a frozen lockfile: %s", strings.Join(described, "; "))
+	}
+
+	if !opts.Yes {
+		if !iostreams.G(ctx).CanPrompt() {
+			log.G(ctx).Warnf("missing libraries, add them to the Kraftfile or use --yes: %s", strings.Join(described, "; "))
+			return false, nil
+		}
+
+		if !confirm(ctx, fmt.Sprintf("add missing libraries to the Kraftfile?\n  %s\n", strings.Join(described, "\n  "))) {
+			return false, nil
+		}
+	}
+
+	kraftfiles := project.Kraftfiles()
+	if len(kraftfiles) == 0 {
+		return false, fmt.Errorf("cannot add missing libraries without a Kraftfile")
+	}
+
+	libraries := map[string]string{}
+	var queries []packmanager.CatalogQuery
+	for _, name := range names {
+		libraries[name] = found[name].Version()
+		queries = append(queries, packmanager.CatalogQuery{
+			Name:    name,
+			Types:   []unikraft.ComponentType{unikraft.ComponentTypeLib},
+			Version: found[name].Version(),
+			NoCache: opts.NoCache,
+		})
+	}
+
+	if err := app.AddLibrariesToKraftfile(kraftfiles[0], libraries); err != nil {
+		return false, err
+	}
+
+	// Record the constraints of the added libraries such that they are locked
+	// in the same way as those previously listed in the Kraftfile.
+	queries, err := locker.Resolve(ctx, queries)
+	if err != nil {
+		return false, err
+	}
+
+	var processes []*paraprogress.Process
+	for i, name := range names {
+		p, err := locker.Lock(ctx, queries[i], found[name])
+		if err != nil {
+			return false, err
+		}
+
+		processes = append(processes, paraprogress.NewProcess(
+			fmt.Sprintf("pulling %s", unikraft.TypeNameVersion(p)),
+			func(ctx context.Context, w func(progress float64)) error {
+				return p.Pull(
+					ctx,
+					pack.WithPullProgressFunc(w),
+					pack.WithPullWorkdir(workdir),
+					pack.WithPullCache(!opts.NoCache),
+				)
+			},
+		))
+	}
+
+	paramodel, err := paraprogress.NewParaProgress(
+		ctx,
+		processes,
+		paraprogress.IsParallel(!config.G[config.KraftKit](ctx).NoParallel),
+		paraprogress.WithRenderer(log.LoggerTypeFromString(config.G[config.KraftKit](ctx).Log.Type) != log.FANCY),
+		paraprogress.WithFailFast(true),
+	)
+	if err != nil {
+		return false, err
+	}
+
+	if err := paramodel.Start(); err != nil {
+		return false, fmt.Errorf("could not pull missing libraries: %v", err)
+	}
+
+	return true, nil
+}
+
+// loadProject instantiates the project at the provided working directory,
+// merged over its template if it has one.
+func loadProject(ctx context.Context, workdir string) (app.Application, error) {
+	project, err := app.NewProjectFromOptions(
+		ctx,
+		app.WithProjectWorkdir(workdir),
+		app.WithProjectDefaultKraftfiles(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if project.Template().Name() == "" {
+		return project, nil
+	}
+
+	templateWorkdir, err := unikraft.PlaceComponent(workdir, project.Template().Type(), project.Template().Name())
+	if err != nil {
+		return nil, err
+	}
+
+	templateProject, err := app.NewProjectFromOptions(
+		ctx,
+		app.WithProjectWorkdir(templateWorkdir),
+		app.WithProjectDefaultKraftfiles(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return templateProject.MergeTemplate(ctx, project)
+}
+
+// confirm asks the user the provided question and returns whether it was
+// answered affirmatively, which is the default.
+func confirm(ctx context.Context, question string) bool {
+	fmt.Fprintf(iostreams.G(ctx).Out, "%s[Y/n]: ", question)
+
+	answer, err := bufio.NewReader(iostreams.G(ctx).In).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "", "y", "yes":
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,150 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package build
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// testDependencies simulates a project whose libraries select those of other
+// libraries, where each library `libfoo` provides the symbol `LIBFOO`.
+type testDependencies struct {
+	// requires maps each library to the symbols which it selects
+	requires map[string][]string
+
+	// libraries are those which are part of the project
+	libraries map[string]bool
+
+	// rounds are the libraries which were added in each round
+	rounds [][]string
+}
+
+func (td *testDependencies) missing(context.Context) ([]string, error) {
+	var symbols []string
+
+	for name := range td.libraries {
+		for _, symbol := range td.requires[name] {
+			if !td.libraries[strings.ToLower(symbol)] {
+				symbols = append(symbols, symbol)
+			}
+		}
+	}
+
+	sort.Strings(symbols)
+
+	return symbols, nil
+}
+
+func (td *testDependencies) provide(_ context.Context, symbol string) (string, bool) {
+	name := strings.ToLower(symbol)
+	if _, ok := td.requires[name]; !ok {
+		return "", false
+	}
+
+	return name, true
+}
+
+func (td *testDependencies) add(_ context.Context, providers map[string][]string) (bool, error) {
+	var names []string
+	for name := range providers {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	td.rounds = append(td.rounds, names)
+	for _, name := range names {
+		td.libraries[name] = true
+	}
+
+	return true, nil
+}
+
+func TestCloseDependencies(t *testing.T) {
+	td := &testDependencies{
+		requires: map[string][]string{
+			"app":       {"LIBNGINX"},
+			"libnginx":  {"LIBPOSIX", "LIBMUSL"},
+			"libmusl":   {"LIBPOSIX", "LIBLWIP"},
+			"libposix":  {"LIBMISSING"},
+			"liblwip":   {"LIBNETDEV"},
+			"libnetdev": {"LIBNGINX"},
+		},
+		libraries: map[string]bool{
+			"app": true,
+		},
+	}
+
+	if err := closeDependencies(context.Background(), td.missing, td.provide, td.add); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := [][]string{
+		{"libnginx"},
+		{"libmusl", "libposix"},
+		{"liblwip"},
+		{"libnetdev"},
+	}
+
+	if !reflect.DeepEqual(td.rounds, expected) {
+		t.Errorf("expected the rounds %v, got %v", expected, td.rounds)
+	}
+}
+
+func TestCloseDependenciesDeclined(t *testing.T) {
+	td := &testDependencies{
+		requires: map[string][]string{
+			"app":     {"LIBMUSL"},
+			"libmusl": {"LIBPOSIX"},
+		},
+		libraries: map[string]bool{
+			"app": true,
+		},
+	}
+
+	rounds := 0
+	add := func(context.Context, map[string][]string) (bool, error) {
+		rounds++
+		return false, nil
+	}
+
+	if err := closeDependencies(context.Background(), td.missing, td.provide, add); err != nil {
+		t.Fatal(err)
+	}
+
+	if rounds != 1 {
+		t.Errorf("expected to stop once libraries are not added, got %d rounds", rounds)
+	}
+}
+
+func TestCloseDependenciesUnsatisfied(t *testing.T) {
+	rounds := 0
+
+	// The added library does not provide the symbol it was added for
+	missing := func(context.Context) ([]string, error) {
+		return []string{"LIBMUSL"}, nil
+	}
+
+	provide := func(context.Context, string) (string, bool) {
+		return "libmusl", true
+	}
+
+	add := func(context.Context, map[string][]string) (bool, error) {
+		rounds++
+		return true, nil
+	}
+
+	if err := closeDependencies(context.Background(), missing, provide, add); err != nil {
+		t.Fatal(err)
+	}
+
+	if rounds != 1 {
+		t.Errorf("expected the library to be added once, got %d rounds", rounds)
+	}
+}
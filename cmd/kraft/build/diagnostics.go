@@ -0,0 +1,378 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/make"
+	"kraftkit.sh/unikraft/app"
+	"kraftkit.sh/unikraft/target"
+)
+
+// Formats in which the diagnostics of the build can be printed.
+const (
+	diagnosticsText  = "text"
+	diagnosticsJSON  = "json"
+	diagnosticsSARIF = "sarif"
+)
+
+// diagnosticSources maps the directories of the sources of the project to the
+// names of the libraries, of the Unikraft core and of the application, such
+// that diagnostics can be attributed to them.  This includes the directories
+// in the output directory in which Unikraft's build system prepares the
+// sources of libraries which are fetched from elsewhere.
+func diagnosticSources(ctx context.Context, project app.Application) map[string]string {
+	sources := map[string]string{
+		project.WorkingDir(): project.Name(),
+	}
+
+	if uk := project.Unikraft(ctx).Path(); len(uk) > 0 {
+		sources[uk] = "unikraft"
+	}
+
+	libraries, err := project.Libraries(ctx)
+	if err != nil {
+		return sources
+	}
+
+	for name, library := range libraries {
+		if len(library.Path()) > 0 {
+			sources[library.Path()] = name
+		}
+
+		sources[filepath.Join(project.OutDir(), "lib"+name)] = name
+	}
+
+	return sources
+}
+
+// printDiagnostics prints a summary of the errors and the number of warnings
+// of each target grouped by the library they are attributed to.
+func printDiagnostics(ctx context.Context, project app.Application, matrix []*matrixTarget) {
+	cs := iostreams.G(ctx).ColorScheme()
+	out := iostreams.G(ctx).Out
+
+	for _, mt := range matrix {
+		diagnostics := mt.diagnostics.List()
+
+		libraries := map[string][]make.Diagnostic{}
+		for _, d := range diagnostics {
+			if d.Severity == make.SeverityNote {
+				continue
+			}
+
+			library := d.Library
+			if len(library) == 0 {
+				library = "other"
+			}
+
+			libraries[library] = append(libraries[library], d)
+		}
+
+		if len(libraries) == 0 {
+			continue
+		}
+
+		var names []string
+		for name := range libraries {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		fmt.Fprintf(out, "%s\n", cs.Bold(fmt.Sprintf("diagnostics of %s (%s):",
+			mt.targ.Name(),
+			target.TargetPlatArchName(mt.targ),
+		)))
+
+		for _, name := range names {
+			errors, warnings := 0, 0
+			for _, d := range libraries[name] {
+				if d.Severity == make.SeverityError {
+					errors++
+				} else {
+					warnings++
+				}
+			}
+
+			fmt.Fprintf(out, "  %s: %s, %s\n",
+				name,
+				plural(errors, "error"),
+				plural(warnings, "warning"),
+			)
+
+			for _, d := range libraries[name] {
+				if d.Severity != make.SeverityError {
+					continue
+				}
+
+				fmt.Fprintf(out, "    %s %s\n",
+					cs.Gray(location(project, d)),
+					cs.Red(d.Message),
+				)
+			}
+		}
+	}
+}
+
+// writeDiagnostics writes the diagnostics of all targets in the provided
+// format, i.e. as JSON or SARIF.
+func writeDiagnostics(w io.Writer, format string, project app.Application, matrix []*matrixTarget) error {
+	var doc interface{}
+
+	switch format {
+	case diagnosticsJSON:
+		doc = diagnosticsDocument(matrix)
+	case diagnosticsSARIF:
+		doc = sarifDocument(project, matrix)
+	default:
+		return fmt.Errorf("unsupported diagnostics format: %s", format)
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(doc)
+}
+
+type targetDiagnostics struct {
+	Target      string            `json:"target"`
+	Platform    string            `json:"platform"`
+	Diagnostics []make.Diagnostic `json:"diagnostics"`
+}
+
+func diagnosticsDocument(matrix []*matrixTarget) []targetDiagnostics {
+	doc := []targetDiagnostics{}
+
+	for _, mt := range matrix {
+		diagnostics := mt.diagnostics.List()
+		if diagnostics == nil {
+			diagnostics = []make.Diagnostic{}
+		}
+
+		doc = append(doc, targetDiagnostics{
+			Target:      mt.targ.Name(),
+			Platform:    target.TargetPlatArchName(mt.targ),
+			Diagnostics: diagnostics,
+		})
+	}
+
+	return doc
+}
+
+// The subset of the Static Analysis Results Interchange Format (SARIF) 2.1.0
+// which is needed to describe the diagnostics of a build, see:
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                    `json:"tool"`
+	AutomationDetails  sarifAutomationDetails       `json:"automationDetails"`
+	OriginalURIBaseIDs map[string]sarifArtifactLink `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult                `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string `json:"name"`
+	InformationURI string `json:"informationUri"`
+}
+
+type sarifAutomationDetails struct {
+	ID string `json:"id"`
+}
+
+type sarifArtifactLink struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifResult struct {
+	Level      string            `json:"level"`
+	Message    sarifMessage      `json:"message"`
+	Locations  []sarifLocation   `json:"locations,omitempty"`
+	Properties map[string]string `json:"properties,omitempty"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLink `json:"artifactLocation"`
+	Region           *sarifRegion      `json:"region,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// sarifDocument returns a SARIF log with one run per target.  Files within the
+// project are relative to the `SRCROOT` base such that code review tools can
+// relate them to the repository.
+func sarifDocument(project app.Application, matrix []*matrixTarget) sarifLog {
+	srcroot := (&url.URL{Scheme: "file", Path: filepath.ToSlash(project.WorkingDir()) + "/"}).String()
+
+	doc := sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{},
+	}
+
+	for _, mt := range matrix {
+		run := sarifRun{
+			Tool: sarifTool{
+				Driver: sarifDriver{
+					Name:           "kraft",
+					InformationURI: "https://kraftkit.sh",
+				},
+			},
+			AutomationDetails: sarifAutomationDetails{
+				ID: fmt.Sprintf("%s/%s/", mt.targ.Name(), target.TargetPlatArchName(mt.targ)),
+			},
+			OriginalURIBaseIDs: map[string]sarifArtifactLink{
+				"SRCROOT": {URI: srcroot},
+			},
+			Results: []sarifResult{},
+		}
+
+		for _, d := range mt.diagnostics.List() {
+			result := sarifResult{
+				Level:   string(d.Severity),
+				Message: sarifMessage{Text: d.Message},
+			}
+
+			if len(d.Library) > 0 {
+				result.Properties = map[string]string{"library": d.Library}
+			}
+
+			if len(d.File) > 0 {
+				location := sarifPhysicalLocation{
+					ArtifactLocation: sarifArtifact(project, d.File),
+				}
+
+				if d.Line > 0 {
+					location.Region = &sarifRegion{
+						StartLine:   d.Line,
+						StartColumn: d.Column,
+					}
+				}
+
+				result.Locations = []sarifLocation{{PhysicalLocation: location}}
+			}
+
+			run.Results = append(run.Results, result)
+		}
+
+		doc.Runs = append(doc.Runs, run)
+	}
+
+	return doc
+}
+
+func sarifArtifact(project app.Application, file string) sarifArtifactLink {
+	if rel, ok := relative(project, file); ok {
+		return sarifArtifactLink{
+			URI:       (&url.URL{Path: filepath.ToSlash(rel)}).String(),
+			URIBaseID: "SRCROOT",
+		}
+	}
+
+	if filepath.IsAbs(file) {
+		return sarifArtifactLink{
+			URI: (&url.URL{Scheme: "file", Path: filepath.ToSlash(file)}).String(),
+		}
+	}
+
+	return sarifArtifactLink{URI: (&url.URL{Path: filepath.ToSlash(file)}).String()}
+}
+
+// relative returns the path of the file relative to the working directory of
+// the project, if it is within it.
+func relative(project app.Application, file string) (string, bool) {
+	if !filepath.IsAbs(file) {
+		return "", false
+	}
+
+	rel, err := filepath.Rel(project.WorkingDir(), file)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+
+	return rel, true
+}
+
+// location formats the location of the diagnostic like the compiler does.
+func location(project app.Application, d make.Diagnostic) string {
+	if len(d.File) == 0 {
+		return "ld:"
+	}
+
+	file := d.File
+	if rel, ok := relative(project, file); ok {
+		file = rel
+	}
+
+	switch {
+	case d.Line > 0 && d.Column > 0:
+		return fmt.Sprintf("%s:%d:%d:", file, d.Line, d.Column)
+	case d.Line > 0:
+		return fmt.Sprintf("%s:%d:", file, d.Line)
+	}
+
+	return file + ":"
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// outputDiagnostics prints the summary of the diagnostics or writes them in
+// the requested format to the provided file, or to the standard output.
+func outputDiagnostics(ctx context.Context, format, output string, project app.Application, matrix []*matrixTarget) error {
+	if format == diagnosticsText {
+		printDiagnostics(ctx, project, matrix)
+		return nil
+	}
+
+	if len(output) == 0 {
+		return writeDiagnostics(iostreams.G(ctx).Out, format, project, matrix)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("could not create diagnostics file: %v", err)
+	}
+
+	defer f.Close()
+
+	return writeDiagnostics(f, format, project, matrix)
+}
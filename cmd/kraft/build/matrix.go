@@ -0,0 +1,296 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package build
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"kraftkit.sh/internal/fsutil"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/make"
+	"kraftkit.sh/sbom"
+	"kraftkit.sh/unikraft/app"
+	"kraftkit.sh/unikraft/target"
+	"kraftkit.sh/utils"
+)
+
+// stagingDir is the directory within the output directory of the project under
+// which each target of a build matrix has its own output directory.
+const stagingDir = ".targets"
+
+// Possible results of building a target of the matrix.
+const (
+	resultBuilt    = "built"
+	resultCached   = "cached"
+	resultFailed   = "failed"
+	resultSkipped  = "skipped"
+	resultCanceled = "canceled"
+)
+
+// matrixTarget is a target of the build matrix together with the project whose
+// output directory is specific to the target and the outcome of its build.
+type matrixTarget struct {
+	targ     target.Target
+	project  app.Application
+	stage    string
+	result   string
+	duration time.Duration
+	err      error
+
+	// diagnostics are those of the compiler and the linker while building the
+	// target.
+	diagnostics *make.Diagnostics
+}
+
+// newMatrix returns the build matrix of the selected targets.  When more than
+// one target is built, each is staged in its own output directory such that
+// their configuration, prepared sources and objects do not conflict and they
+// can be built concurrently.
+func newMatrix(ctx context.Context, project app.Application, selected target.Targets) ([]*matrixTarget, error) {
+	var matrix []*matrixTarget
+
+	for _, targ := range selected {
+		mt := &matrixTarget{
+			targ:    targ,
+			project: project,
+			result:  resultSkipped,
+		}
+
+		if len(selected) > 1 {
+			mt.stage = filepath.Join(
+				project.OutDir(),
+				stagingDir,
+				fmt.Sprintf("%s_%s", targ.Name(), target.TargetPlatArchName(targ)),
+			)
+
+			var err error
+			mt.project, err = project.WithOutDir(mt.stage)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		mt.diagnostics = make.NewDiagnostics(diagnosticSources(ctx, mt.project))
+
+		matrix = append(matrix, mt)
+	}
+
+	return matrix, nil
+}
+
+// matrixJobs returns the number of targets which are built concurrently and
+// the number of jobs each of their invocations of make is allowed.  The
+// provided number of jobs, or the number of CPUs if none are provided, bounds
+// the number of concurrent targets and is shared between them.  A make
+// invocation is otherwise left at its default of a single job unless fast is
+// set.
+func matrixJobs(jobs int, fast bool, targets int) (int, int) {
+	total := jobs
+	if total <= 0 {
+		total = runtime.NumCPU()
+	}
+
+	concurrent := targets
+	if concurrent > total {
+		concurrent = total
+	}
+
+	if concurrent < 1 {
+		concurrent = 1
+	}
+
+	if jobs <= 0 && !fast {
+		return concurrent, 0
+	}
+
+	perTarget := total / concurrent
+	if perTarget < 1 {
+		perTarget = 1
+	}
+
+	return concurrent, perTarget
+}
+
+// seedOrigins copies the fetched and prepared sources of the libraries, i.e.
+// each library's `origin` directory and its stamp files, from the output
+// directory of one target to that of another, such that Unikraft's build
+// system finds them up-to-date and does not fetch and prepare them again.
+// Sources which already exist in the destination are left untouched.
+func seedOrigins(from, to string) error {
+	libs, err := os.ReadDir(from)
+	if err != nil {
+		return err
+	}
+
+	for _, lib := range libs {
+		if !lib.IsDir() || strings.HasPrefix(lib.Name(), ".") {
+			continue
+		}
+
+		origin := filepath.Join(from, lib.Name(), "origin")
+		if f, err := os.Stat(origin); err != nil || !f.IsDir() {
+			continue
+		}
+
+		dest := filepath.Join(to, lib.Name())
+		if _, err := os.Stat(filepath.Join(dest, "origin")); err == nil {
+			continue
+		}
+
+		if err := copyTree(origin, filepath.Join(dest, "origin")); err != nil {
+			return err
+		}
+
+		stamps, err := os.ReadDir(filepath.Join(from, lib.Name()))
+		if err != nil {
+			return err
+		}
+
+		for _, stamp := range stamps {
+			if !stamp.Type().IsRegular() || !strings.HasPrefix(stamp.Name(), ".") {
+				continue
+			}
+
+			if err := fsutil.CopyFile(
+				filepath.Join(from, lib.Name(), stamp.Name()),
+				filepath.Join(dest, stamp.Name()),
+			); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// publish copies the kernels which were built in the output directory of the
+// target to their location in the output directory of the project.
+func (mt *matrixTarget) publish() error {
+	if len(mt.stage) == 0 {
+		return nil
+	}
+
+	for _, dest := range artifacts(mt.project, mt.targ) {
+		src := filepath.Join(mt.stage, filepath.Base(dest))
+		if !exists(src) {
+			continue
+		}
+
+		if err := fsutil.CopyFile(src, dest); err != nil {
+			return fmt.Errorf("could not publish %s: %v", filepath.Base(dest), err)
+		}
+	}
+
+	return nil
+}
+
+// writeSBOM saves the Software Bill of Materials of the target in the provided
+// format next to its kernel, unless no format is provided.
+func (mt *matrixTarget) writeSBOM(ctx context.Context, format sbom.Format) error {
+	if len(format) == 0 {
+		return nil
+	}
+
+	bom, err := sbom.NewFromTarget(ctx, mt.project, mt.targ)
+	if err != nil {
+		return err
+	}
+
+	return bom.WriteFile(mt.targ.Kernel()+format.Extension(), format)
+}
+
+// printSummary prints the result and duration of the build of each target of
+// the matrix.
+func printSummary(ctx context.Context, matrix []*matrixTarget) error {
+	cs := iostreams.G(ctx).ColorScheme()
+	table := utils.NewTablePrinter(ctx)
+
+	table.AddField("TARGET", nil, cs.Bold)
+	table.AddField("PLAT", nil, cs.Bold)
+	table.AddField("RESULT", nil, cs.Bold)
+	table.AddField("DURATION", nil, cs.Bold)
+	table.AddField("KERNEL", nil, cs.Bold)
+	table.EndRow()
+
+	for _, mt := range matrix {
+		color := cs.Green
+		kernel := mt.targ.Kernel()
+
+		switch mt.result {
+		case resultFailed:
+			color = cs.Red
+			kernel = mt.err.Error()
+		case resultSkipped, resultCanceled:
+			color = cs.Gray
+			kernel = ""
+		}
+
+		table.AddField(mt.targ.Name(), nil, nil)
+		table.AddField(target.TargetPlatArchName(mt.targ), nil, nil)
+		table.AddField(mt.result, nil, color)
+		table.AddField(mt.duration.Round(time.Second).String(), nil, nil)
+		table.AddField(kernel, nil, nil)
+		table.EndRow()
+	}
+
+	return table.Render()
+}
+
+// copyTree copies a directory recursively, preserving the modification times of
+// its files and directories which make compares.
+func copyTree(src, dest string) error {
+	err := filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		to := filepath.Join(dest, rel)
+
+		switch {
+		case info.IsDir():
+			return os.MkdirAll(to, info.Mode().Perm()|0o700)
+		case info.Mode()&os.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+
+			return os.Symlink(link, to)
+		case info.Mode().IsRegular():
+			return fsutil.CopyFile(path, to)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// Restore the modification times of the directories last, since creating
+	// their entries updated them
+	return filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil || !info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+
+		return os.Chtimes(filepath.Join(dest, rel), info.ModTime(), info.ModTime())
+	})
+}
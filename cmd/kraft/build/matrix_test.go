@@ -0,0 +1,202 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package build
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"kraftkit.sh/unikraft/app"
+	"kraftkit.sh/unikraft/arch"
+	"kraftkit.sh/unikraft/plat"
+	"kraftkit.sh/unikraft/target"
+)
+
+func TestMatrixJobs(t *testing.T) {
+	cpus := runtime.NumCPU()
+
+	tests := []struct {
+		name       string
+		jobs       int
+		fast       bool
+		targets    int
+		concurrent int
+		perTarget  int
+	}{
+		{"single target", 4, false, 1, 1, 4},
+		{"jobs shared", 8, false, 2, 2, 4},
+		{"jobs rounded down", 7, false, 2, 2, 3},
+		{"targets bounded by jobs", 2, false, 4, 2, 1},
+		{"serial", 1, false, 3, 1, 1},
+		{"default jobs", 0, false, 1, 1, 0},
+		{"fast", 0, true, 1, 1, cpus},
+		{"no targets", 4, false, 0, 1, 4},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			concurrent, perTarget := matrixJobs(test.jobs, test.fast, test.targets)
+			if concurrent != test.concurrent || perTarget != test.perTarget {
+				t.Errorf("expected (%d, %d), got (%d, %d)",
+					test.concurrent, test.perTarget, concurrent, perTarget,
+				)
+			}
+		})
+	}
+
+	// Without jobs, targets are bounded by the number of CPUs
+	if concurrent, _ := matrixJobs(0, false, cpus+1); concurrent != cpus {
+		t.Errorf("expected %d concurrent targets, got %d", cpus, concurrent)
+	}
+}
+
+func newTestTarget(t *testing.T, name, platform, architecture string) *target.TargetConfig {
+	t.Helper()
+
+	p, err := plat.NewPlatformFromOptions(plat.WithName(platform))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	a, err := arch.NewArchitectureFromSchema(architecture)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	targ, err := target.NewTargetFromOptions(
+		target.WithName(name),
+		target.WithPlatform(*p.(*plat.PlatformConfig)),
+		target.WithArchitecture(a),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return targ.(*target.TargetConfig)
+}
+
+func TestNewMatrix(t *testing.T) {
+	ctx := context.Background()
+	workdir := t.TempDir()
+	outdir := filepath.Join(workdir, "build")
+
+	project, err := app.NewApplicationFromOptions(
+		app.WithWorkingDir(workdir),
+		app.WithOutDir(outdir),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	qemu := newTestTarget(t, "dev", "qemu", "x86_64")
+	fc := newTestTarget(t, "dev", "fc", "x86_64")
+
+	// A single target is built in the output directory of the project
+	matrix, err := newMatrix(ctx, project, target.Targets{qemu})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matrix[0].stage) > 0 || matrix[0].project.OutDir() != outdir {
+		t.Errorf("expected a single target not to be staged, got %s", matrix[0].stage)
+	}
+
+	// Targets of the same name are isolated by their platform and architecture
+	matrix, err = newMatrix(ctx, project, target.Targets{qemu, fc})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(matrix) != 2 {
+		t.Fatalf("expected 2 targets, got %d", len(matrix))
+	}
+
+	stages := map[string]bool{}
+	for _, mt := range matrix {
+		if filepath.Dir(mt.stage) != filepath.Join(outdir, stagingDir) {
+			t.Errorf("expected %s to be staged in %s", mt.stage, filepath.Join(outdir, stagingDir))
+		}
+
+		if mt.project.OutDir() != mt.stage {
+			t.Errorf("expected the output directory %s, got %s", mt.stage, mt.project.OutDir())
+		}
+
+		if mt.result != resultSkipped {
+			t.Errorf("expected the initial result %s, got %s", resultSkipped, mt.result)
+		}
+
+		stages[mt.stage] = true
+	}
+
+	if len(stages) != 2 {
+		t.Errorf("expected distinct output directories, got %v", stages)
+	}
+
+	if project.OutDir() != outdir {
+		t.Errorf("expected the output directory of the project to be unchanged, got %s", project.OutDir())
+	}
+}
+
+func TestSeedOrigins(t *testing.T) {
+	from := t.TempDir()
+	to := t.TempDir()
+	modified := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	write := func(path, contents string) {
+		t.Helper()
+
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(filepath.Join(from, "libmusl", "origin", "musl-1.2.3", "Makefile"), "musl")
+	write(filepath.Join(from, "libmusl", ".origin"), "")
+	write(filepath.Join(from, "libmusl", "musl.o"), "object")
+	write(filepath.Join(from, "liblwip", "lwip.o"), "object")
+	write(filepath.Join(from, "libnewlib", "origin", "Makefile"), "newlib")
+	write(filepath.Join(to, "libnewlib", "origin", "Makefile"), "prepared")
+
+	if err := seedOrigins(from, to); err != nil {
+		t.Fatal(err)
+	}
+
+	makefile := filepath.Join(to, "libmusl", "origin", "musl-1.2.3", "Makefile")
+	if info, err := os.Stat(makefile); err != nil {
+		t.Errorf("expected the origin of libmusl to be seeded: %v", err)
+	} else if !info.ModTime().Equal(modified) {
+		t.Errorf("expected the modification time %s to be preserved, got %s", modified, info.ModTime())
+	}
+
+	if _, err := os.Stat(filepath.Join(to, "libmusl", ".origin")); err != nil {
+		t.Errorf("expected the stamp of libmusl to be seeded: %v", err)
+	}
+
+	// Objects are specific to each target
+	if _, err := os.Stat(filepath.Join(to, "libmusl", "musl.o")); !os.IsNotExist(err) {
+		t.Error("expected the objects of libmusl not to be seeded")
+	}
+
+	if _, err := os.Stat(filepath.Join(to, "liblwip")); !os.IsNotExist(err) {
+		t.Error("expected liblwip without an origin not to be seeded")
+	}
+
+	// Sources which were already prepared are left untouched
+	if contents, _ := os.ReadFile(filepath.Join(to, "libnewlib", "origin", "Makefile")); string(contents) != "prepared" {
+		t.Errorf("expected the origin of libnewlib to be untouched, got %s", contents)
+	}
+}
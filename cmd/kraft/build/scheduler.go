@@ -0,0 +1,64 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package build
+
+import (
+	"context"
+	"sync"
+)
+
+// scheduler bounds the number of targets of the matrix which are built at once
+// and holds back all but the first target until the first has prepared the
+// sources of the libraries, which the others are then seeded with.
+type scheduler struct {
+	slots    chan struct{}
+	prepared chan struct{}
+	once     sync.Once
+
+	// seed is the output directory of the first target once its sources were
+	// prepared successfully.
+	seed string
+}
+
+func newScheduler(concurrent int) *scheduler {
+	return &scheduler{
+		slots:    make(chan struct{}, concurrent),
+		prepared: make(chan struct{}),
+	}
+}
+
+// acquire waits until fewer than the maximum number of targets are built.
+func (s *scheduler) acquire(ctx context.Context) error {
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *scheduler) release() {
+	<-s.slots
+}
+
+// donePreparing releases the remaining targets, which are seeded with the
+// sources in the provided output directory unless it is empty.
+func (s *scheduler) donePreparing(seed string) {
+	s.once.Do(func() {
+		s.seed = seed
+		close(s.prepared)
+	})
+}
+
+// waitPrepared waits until the first target has prepared its sources and
+// returns the output directory to seed from, if any.
+func (s *scheduler) waitPrepared(ctx context.Context) (string, error) {
+	select {
+	case <-s.prepared:
+		return s.seed, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
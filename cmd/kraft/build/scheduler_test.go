@@ -0,0 +1,106 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package build
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerBoundsTargets(t *testing.T) {
+	ctx := context.Background()
+	sched := newScheduler(2)
+
+	var running, peak int32
+	var wg sync.WaitGroup
+
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := sched.acquire(ctx); err != nil {
+				t.Error(err)
+				return
+			}
+
+			defer sched.release()
+
+			now := atomic.AddInt32(&running, 1)
+			for {
+				highest := atomic.LoadInt32(&peak)
+				if now <= highest || atomic.CompareAndSwapInt32(&peak, highest, now) {
+					break
+				}
+			}
+
+			time.Sleep(10 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		}()
+	}
+
+	wg.Wait()
+
+	if peak != 2 {
+		t.Errorf("expected at most 2 concurrent targets, got %d", peak)
+	}
+}
+
+func TestSchedulerOrdering(t *testing.T) {
+	ctx := context.Background()
+	sched := newScheduler(2)
+
+	seeds := make(chan string, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			seed, err := sched.waitPrepared(ctx)
+			if err != nil {
+				t.Error(err)
+			}
+
+			seeds <- seed
+		}()
+	}
+
+	// The remaining targets are held back until the first has prepared
+	select {
+	case seed := <-seeds:
+		t.Fatalf("expected targets to wait for the first, got seed %q", seed)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	sched.donePreparing("/build/.targets/first")
+
+	// Only the first call releases the remaining targets
+	sched.donePreparing("/build/.targets/second")
+
+	for i := 0; i < 2; i++ {
+		if seed := <-seeds; seed != "/build/.targets/first" {
+			t.Errorf("expected to be seeded from the first target, got %q", seed)
+		}
+	}
+}
+
+func TestSchedulerCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	sched := newScheduler(1)
+
+	if err := sched.acquire(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	cancel()
+
+	if err := sched.acquire(ctx); err == nil {
+		t.Error("expected acquiring a slot to fail once canceled")
+	}
+
+	if _, err := sched.waitPrepared(ctx); err == nil {
+		t.Error("expected waiting for the first target to fail once canceled")
+	}
+}
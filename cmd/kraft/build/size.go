@@ -0,0 +1,261 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package build
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/dustin/go-humanize"
+
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/log"
+	"kraftkit.sh/packmanager"
+	"kraftkit.sh/sizereport"
+	"kraftkit.sh/unikraft/app"
+	"kraftkit.sh/unikraft/target"
+	"kraftkit.sh/utils"
+)
+
+// Formats in which the size report can be printed.
+const (
+	sizeTable = "table"
+	sizeJSON  = "json"
+)
+
+// sizeReport returns the size report of the kernel of the target and saves it
+// next to the kernel such that it can be compared with, or attached to the
+// package of the kernel by `kraft pkg`.
+func sizeReport(project app.Application, mt *matrixTarget, symbols int) (*sizereport.Report, error) {
+	kernels := artifacts(project, mt.targ)
+
+	// Only the debuggable kernel has the symbols to attribute its size with
+	kernel, ok := kernels["kernel.dbg"]
+	if !ok || !exists(kernel) {
+		kernel = kernels["kernel"]
+	}
+
+	buildDir := mt.stage
+	if len(buildDir) == 0 {
+		buildDir = project.OutDir()
+	}
+
+	report, err := sizereport.New(kernel,
+		sizereport.WithBuildDir(buildDir),
+		sizereport.WithSymbols(symbols),
+		sizereport.WithTarget(mt.targ.Name(), target.TargetPlatArchName(mt.targ)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := report.WriteFile(kernels["kernel"] + sizereport.FileExtension); err != nil {
+		return nil, fmt.Errorf("could not save size report: %v", err)
+	}
+
+	return report, nil
+}
+
+// previousSizeReport loads the report to compare with from the provided path
+// or, if no such file exists, from the package with the provided name.
+func previousSizeReport(ctx context.Context, ref string) (*sizereport.Report, error) {
+	if _, err := os.Stat(ref); err == nil {
+		return sizereport.Load(ref)
+	}
+
+	packs, err := packmanager.G(ctx).Catalog(ctx, packmanager.CatalogQuery{
+		Name: ref,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var providers []sizereport.Provider
+	for _, p := range packs {
+		if provider, ok := p.(sizereport.Provider); ok {
+			providers = append(providers, provider)
+		}
+	}
+
+	if len(providers) == 0 {
+		return nil, fmt.Errorf("could not find size report or package: %s", ref)
+	} else if len(providers) > 1 {
+		return nil, fmt.Errorf("too many packages match: %s", ref)
+	}
+
+	data, err := providers[0].SizeReport(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return sizereport.Parse(data)
+}
+
+// reportSizes reports the sizes of the kernels of all targets which were built,
+// compared with the previous report if one is provided, and returns an error
+// if any regressed by more than the threshold.
+func reportSizes(ctx context.Context, opts *Build, project app.Application, matrix []*matrixTarget) error {
+	var previous *sizereport.Report
+	if len(opts.SizeCompare) > 0 {
+		var err error
+		if previous, err = previousSizeReport(ctx, opts.SizeCompare); err != nil {
+			return fmt.Errorf("could not load the size report to compare with: %v", err)
+		}
+	}
+
+	reports := []*sizereport.Report{}
+	regressed := 0
+
+	for _, mt := range matrix {
+		// Kernels restored from the build cache come without the objects which
+		// their sizes are attributed to libraries with
+		if mt.result == resultCached {
+			log.G(ctx).Warnf("not reporting the size of %s: its kernel was restored from the build cache, use --no-cache to rebuild it", mt.targ.Name())
+			continue
+		} else if mt.result != resultBuilt {
+			continue
+		}
+
+		report, err := sizeReport(project, mt, opts.SizeSymbols)
+		if err != nil {
+			log.G(ctx).Warnf("could not report the size of %s: %v", mt.targ.Name(), err)
+			continue
+		}
+
+		if previous != nil {
+			if len(previous.Platform) > 0 && previous.Platform != report.Platform {
+				log.G(ctx).Warnf("not comparing the size of %s with that of a %s kernel", mt.targ.Name(), previous.Platform)
+			} else if report.Compare(previous, float64(opts.SizeThreshold)); len(report.Regressions()) > 0 {
+				regressed++
+			}
+		}
+
+		reports = append(reports, report)
+	}
+
+	if opts.SizeFormat == sizeJSON {
+		encoder := json.NewEncoder(iostreams.G(ctx).Out)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(reports); err != nil {
+			return err
+		}
+	} else {
+		for _, report := range reports {
+			if err := printSizeReport(ctx, report); err != nil {
+				return err
+			}
+		}
+	}
+
+	if regressed > 0 {
+		return fmt.Errorf("the size of %d of %d kernels grew by more than %d%%", regressed, len(reports), opts.SizeThreshold)
+	}
+
+	return nil
+}
+
+// printSizeReport prints the sizes of the libraries of the kernel, how they
+// changed if the report was compared, and the largest symbols.
+func printSizeReport(ctx context.Context, report *sizereport.Report) error {
+	cs := iostreams.G(ctx).ColorScheme()
+	out := iostreams.G(ctx).Out
+
+	changes := map[string]sizereport.Change{}
+	for _, change := range report.Changes {
+		changes[change.Name] = change
+	}
+
+	formatChange := func(name string) (string, func(string) string) {
+		change, ok := changes[name]
+		if !ok {
+			return "", nil
+		}
+
+		text := fmt.Sprintf("%+d (%+.1f%%)", change.Delta(), change.Percent())
+		switch {
+		case change.Regression:
+			return text, cs.Red
+		case change.Delta() < 0:
+			return text, cs.Green
+		}
+
+		return text, cs.Yellow
+	}
+
+	fmt.Fprintf(out, "%s\n", cs.Bold(fmt.Sprintf("size of %s (%s): %s",
+		report.Target,
+		report.Platform,
+		humanize.IBytes(report.Total()),
+	)))
+
+	table := utils.NewTablePrinter(ctx)
+
+	table.AddField("LIBRARY", nil, cs.Bold)
+	table.AddField("TEXT", nil, cs.Bold)
+	table.AddField("DATA", nil, cs.Bold)
+	table.AddField("BSS", nil, cs.Bold)
+	table.AddField("TOTAL", nil, cs.Bold)
+	if report.Changes != nil {
+		table.AddField("CHANGE", nil, cs.Bold)
+	}
+	table.EndRow()
+
+	row := func(name string, sizes sizereport.Sizes) {
+		table.AddField(name, nil, nil)
+		table.AddField(fmt.Sprintf("%d", sizes.Text), nil, nil)
+		table.AddField(fmt.Sprintf("%d", sizes.Data), nil, nil)
+		table.AddField(fmt.Sprintf("%d", sizes.BSS), nil, nil)
+		table.AddField(fmt.Sprintf("%d", sizes.Total()), nil, nil)
+		if report.Changes != nil {
+			text, color := formatChange(name)
+			table.AddField(text, nil, color)
+		}
+		table.EndRow()
+	}
+
+	for _, lib := range report.Libraries {
+		row(lib.Name, lib.Sizes)
+	}
+
+	row(sizereport.TotalName, report.Sizes)
+
+	// Libraries which are no longer part of the kernel
+	for _, change := range report.Changes {
+		if _, ok := report.Library(change.Name); !ok && change.Name != sizereport.TotalName {
+			row(change.Name, sizereport.Sizes{})
+		}
+	}
+
+	if err := table.Render(); err != nil {
+		return err
+	}
+
+	if len(report.Symbols) == 0 {
+		return nil
+	}
+
+	fmt.Fprintln(out)
+
+	table = utils.NewTablePrinter(ctx)
+
+	table.AddField("SYMBOL", nil, cs.Bold)
+	table.AddField("LIBRARY", nil, cs.Bold)
+	table.AddField("SECTION", nil, cs.Bold)
+	table.AddField("SIZE", nil, cs.Bold)
+	table.EndRow()
+
+	for _, sym := range report.Symbols {
+		table.AddField(sym.Name, nil, nil)
+		table.AddField(sym.Library, nil, cs.Gray)
+		table.AddField(string(sym.Section), nil, nil)
+		table.AddField(fmt.Sprintf("%d", sym.Size), nil, nil)
+		table.EndRow()
+	}
+
+	return table.Render()
+}
@@ -0,0 +1,255 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package check implements the `kraft config check` command
+package check
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/internal/cli"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/kconfig"
+	"kraftkit.sh/log"
+	"kraftkit.sh/packmanager"
+	"kraftkit.sh/unikraft/app"
+	"kraftkit.sh/unikraft/target"
+	"kraftkit.sh/utils"
+)
+
+type Check struct {
+	Architecture string `long:"arch" short:"m" usage:"Filter the targets to check by architecture"`
+	Platform     string `long:"plat" short:"p" usage:"Filter the targets to check by platform"`
+	Target       string `long:"target" short:"t" usage:"Only check a specific target"`
+}
+
+func New() *cobra.Command {
+	cmd, err := cmdfactory.New(&Check{}, cobra.Command{
+		Short: "Check the KConfig options of a project for mistakes",
+		Use:   "check [FLAGS] [DIR]",
+		Args:  cmdfactory.MaxDirArgs(1),
+		Long: heredoc.Docf(`
+			Check the KConfig options of a project for mistakes.
+
+			The options which are set in the %[1]skconfig%[1]s sections of the Kraftfile
+			are evaluated for each target, applying the %[1]sdepends on%[1]s, %[1]sselect%[1]s
+			and %[1]sdefault%[1]s rules of the KConfig trees of the Unikraft core, its
+			libraries and the application.  The following problems are reported:
+
+			  unknown        the option is not defined by any component, e.g. due to a
+			                 typo or a missing library;
+			  invalid        the value does not match the type of the option;
+			  no-effect      the option is silently ignored, e.g. because its
+			                 dependencies are not met;
+			  contradiction  the option is overruled by another, e.g. it is disabled
+			                 but selected, or selected despite its dependencies.
+
+			Components must have been pulled for their options to be known.  The
+			command fails if any problem is found.
+		`, "`"),
+		Example: heredoc.Doc(`
+			# Check the configuration of the project in the cwd
+			$ kraft config check
+
+			# Check the configuration of a single target of a project at a path
+			$ kraft config check --target qemu-x86_64 path/to/app`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "build",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (*Check) Pre(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+	pm, err := packmanager.NewUmbrellaManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	cmd.SetContext(packmanager.WithPackageManager(ctx, pm))
+
+	return nil
+}
+
+func (opts *Check) Run(cmd *cobra.Command, args []string) error {
+	var err error
+
+	ctx := cmd.Context()
+	workdir := ""
+
+	if len(args) == 0 {
+		workdir, err = os.Getwd()
+		if err != nil {
+			return err
+		}
+	} else {
+		workdir = args[0]
+	}
+
+	project, err := app.NewProjectFromOptions(
+		ctx,
+		app.WithProjectWorkdir(workdir),
+		app.WithProjectDefaultKraftfiles(),
+	)
+	if err != nil {
+		return err
+	}
+
+	targets := cli.FilterTargets(
+		project.Targets(),
+		opts.Architecture,
+		opts.Platform,
+		opts.Target,
+	)
+	if len(targets) == 0 {
+		return fmt.Errorf("no matching targets")
+	}
+
+	warnMissing(ctx, project)
+
+	tree := kconfig.Merge("", project.KConfigTrees(ctx)...)
+
+	// The same problem is usually found for every target, so each is only
+	// reported once along with the targets it was found for.
+	type finding struct {
+		problem kconfig.Problem
+		origin  string
+		targets []string
+	}
+
+	var findings []*finding
+	found := map[string]*finding{}
+
+	for _, t := range targets {
+		values := kconfig.KeyValueMap{}
+		values.OverrideBy(project.KConfig())
+		values.OverrideBy(t.KConfig())
+
+		for _, problem := range tree.Check(values) {
+			key := problem.String() + "=" + problem.Value
+			if f, ok := found[key]; ok {
+				f.targets = append(f.targets, t.Name())
+				continue
+			}
+
+			f := &finding{
+				problem: problem,
+				origin:  origin(ctx, project, t, problem.Symbol),
+				targets: []string{t.Name()},
+			}
+
+			found[key] = f
+			findings = append(findings, f)
+		}
+	}
+
+	if len(findings) == 0 {
+		log.G(ctx).Info("no problems found")
+		return nil
+	}
+
+	cs := iostreams.G(ctx).ColorScheme()
+	table := utils.NewTablePrinter(ctx)
+
+	table.AddField("PROBLEM", nil, cs.Bold)
+	table.AddField("OPTION", nil, cs.Bold)
+	table.AddField("ORIGIN", nil, cs.Bold)
+	table.AddField("TARGETS", nil, cs.Bold)
+	table.AddField("DESCRIPTION", nil, cs.Bold)
+	table.EndRow()
+
+	for _, f := range findings {
+		option := kconfig.Prefix + f.problem.Symbol
+		if len(f.problem.Value) > 0 {
+			option += "=" + f.problem.Value
+		}
+
+		names := strings.Join(f.targets, ", ")
+		if len(targets) > 1 && len(f.targets) == len(targets) {
+			names = "all"
+		}
+
+		table.AddField(string(f.problem.Kind), nil, cs.Yellow)
+		table.AddField(option, nil, nil)
+		table.AddField(f.origin, nil, cs.Gray)
+		table.AddField(names, nil, nil)
+		table.AddField(f.problem.Message, nil, nil)
+		table.EndRow()
+	}
+
+	if err := table.Render(); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("found %d problems in the configuration", len(findings))
+}
+
+// origin returns the section of the Kraftfile which sets the option, if it is
+// set at all.
+func origin(ctx context.Context, project app.Application, t target.Target, symbol string) string {
+	has := func(values kconfig.KeyValueMap) bool {
+		_, ok := values[kconfig.Prefix+symbol]
+		if !ok {
+			_, ok = values[symbol]
+		}
+		return ok
+	}
+
+	if has(t.KConfig()) {
+		return "targets." + t.Name()
+	}
+
+	if libraries, err := project.Libraries(ctx); err == nil {
+		for name, library := range libraries {
+			if !library.IsInternal() && has(library.KConfig()) {
+				return "libraries." + name
+			}
+		}
+	}
+
+	if has(project.Unikraft(ctx).KConfig()) {
+		return "unikraft"
+	}
+
+	if has(project.KConfig()) {
+		return "kconfig"
+	}
+
+	return ""
+}
+
+// warnMissing warns about components which have not been pulled since the
+// options they define are unknown.
+func warnMissing(ctx context.Context, project app.Application) {
+	if uk := project.Unikraft(ctx); uk != nil {
+		if fi, err := os.Stat(uk.Path()); err != nil || !fi.IsDir() {
+			log.G(ctx).Warnf("%s has not been pulled, its options are unknown", uk.Name())
+		}
+	}
+
+	libraries, err := project.Libraries(ctx)
+	if err != nil {
+		log.G(ctx).Warnf("could not list libraries: %v", err)
+		return
+	}
+
+	for name, library := range libraries {
+		if !library.IsUnpacked() {
+			log.G(ctx).Warnf("library %s has not been pulled, its options are unknown", name)
+		}
+	}
+}
@@ -0,0 +1,55 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package config implements the `kraft config` command
+package config
+
+import (
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+
+	"kraftkit.sh/cmd/kraft/config/check"
+	"kraftkit.sh/cmd/kraft/config/diff"
+)
+
+type Config struct{}
+
+func New() *cobra.Command {
+	cmd, err := cmdfactory.New(&Config{}, cobra.Command{
+		Short: "Inspect the configuration of a Unikraft project",
+		Use:   "config SUBCOMMAND",
+		Long: heredoc.Docf(`
+			Inspect the KConfig options of a Unikraft project.
+
+			The options which are set in the %[1]skconfig%[1]s sections of a Kraftfile are
+			evaluated against the KConfig trees of the Unikraft core, its libraries
+			and the application itself, as Unikraft's build system would.  Use %[1]skraft
+			menu%[1]s to edit the configuration interactively.
+		`, "`"),
+		Example: heredoc.Doc(`
+			# Check the configuration of the project in the cwd
+			$ kraft config check
+
+			# Compare the configuration of two targets
+			$ kraft config diff qemu-x86_64 fc-x86_64`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "build",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	cmd.AddCommand(check.New())
+	cmd.AddCommand(diff.New())
+
+	return cmd
+}
+
+func (*Config) Run(cmd *cobra.Command, _ []string) error {
+	return cmd.Help()
+}
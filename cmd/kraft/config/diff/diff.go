@@ -0,0 +1,233 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package diff implements the `kraft config diff` command
+package diff
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/kconfig"
+	"kraftkit.sh/log"
+	"kraftkit.sh/packmanager"
+	"kraftkit.sh/unikraft/app"
+	"kraftkit.sh/unikraft/target"
+)
+
+type Diff struct {
+	Workdir string `long:"workdir" short:"w" usage:"Resolve target names in the project at a path"`
+}
+
+func New() *cobra.Command {
+	cmd, err := cmdfactory.New(&Diff{}, cobra.Command{
+		Short: "Compare the KConfig options of targets, builds and packages",
+		Use:   "diff [FLAGS] A B",
+		Args:  cobra.ExactArgs(2),
+		Long: heredoc.Docf(`
+			Compare the KConfig options of targets, builds and packages.
+
+			Each side of the comparison is one of:
+
+			  - the path to a .config file;
+			  - the name of a target of the project, whose .config is used if it has
+			    been configured, otherwise the options of its Kraftfile as they are
+			    resolved against the KConfig trees of the project;
+			  - the reference of a package, whose options are read from the
+			    %[1]sorg.unikraft.kernel.kconfig.*%[1]s annotations of its manifest.
+
+			Options which are added, removed or changed from A to B are shown grouped
+			by the menus they appear in along with their prompts, as far as the
+			components of the project have been pulled.
+		`, "`"),
+		Example: heredoc.Doc(`
+			# Compare two targets of the project in the cwd
+			$ kraft config diff qemu-x86_64 fc-x86_64
+
+			# Compare a local build with a published package
+			$ kraft config diff .config.helloworld_qemu-x86_64 unikraft.org/helloworld:latest`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "build",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (*Diff) Pre(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+	pm, err := packmanager.NewUmbrellaManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	cmd.SetContext(packmanager.WithPackageManager(ctx, pm))
+
+	return nil
+}
+
+func (opts *Diff) Run(cmd *cobra.Command, args []string) error {
+	var err error
+
+	ctx := cmd.Context()
+	workdir := opts.Workdir
+
+	if len(workdir) == 0 {
+		workdir, err = os.Getwd()
+		if err != nil {
+			return err
+		}
+	}
+
+	// The project is optional as long as neither side refers to a target.
+	project, err := app.NewProjectFromOptions(
+		ctx,
+		app.WithProjectWorkdir(workdir),
+		app.WithProjectDefaultKraftfiles(),
+	)
+	if err != nil {
+		log.G(ctx).Debugf("could not load project: %v", err)
+		project = nil
+	}
+
+	var tree *kconfig.KConfigFile
+	if project != nil {
+		if trees := project.KConfigTrees(ctx); len(trees) > 0 {
+			tree = kconfig.Merge("", trees...)
+		}
+	}
+
+	from, err := resolve(ctx, project, tree, args[0])
+	if err != nil {
+		return err
+	}
+
+	to, err := resolve(ctx, project, tree, args[1])
+	if err != nil {
+		return err
+	}
+
+	diffs := tree.Diff(from, to)
+	if len(diffs) == 0 {
+		log.G(ctx).Info("no differences")
+		return nil
+	}
+
+	out := iostreams.G(ctx).Out
+	cs := iostreams.G(ctx).ColorScheme()
+
+	fmt.Fprintf(out, "%s\n%s\n", cs.Red("--- "+args[0]), cs.Green("+++ "+args[1]))
+
+	group := ""
+	for i, d := range diffs {
+		menu := strings.Join(d.Menus, " > ")
+		if len(d.Prompt) == 0 && tree != nil {
+			menu = "Not defined by any component"
+		}
+
+		if i == 0 || menu != group {
+			group = menu
+			if len(group) > 0 {
+				fmt.Fprintf(out, "\n%s\n", cs.Bold(group))
+			} else {
+				fmt.Fprintln(out)
+			}
+		}
+
+		option := kconfig.Prefix + d.Symbol
+
+		var line string
+		switch d.Kind {
+		case kconfig.DiffAdded:
+			line = cs.Green(fmt.Sprintf("+ %s=%s", option, d.New))
+		case kconfig.DiffRemoved:
+			line = cs.Red(fmt.Sprintf("- %s=%s", option, d.Old))
+		case kconfig.DiffChanged:
+			line = cs.Yellow(fmt.Sprintf("~ %s=%s -> %s", option, d.Old, d.New))
+		}
+
+		if len(d.Prompt) > 0 {
+			line += " " + cs.Gray("("+d.Prompt+")")
+		}
+
+		fmt.Fprintf(out, "  %s\n", line)
+	}
+
+	return nil
+}
+
+// resolve returns the KConfig options of a .config file, a target of the
+// project or a package.
+func resolve(ctx context.Context, project app.Application, tree *kconfig.KConfigFile, arg string) (kconfig.KeyValueMap, error) {
+	if fi, err := os.Stat(arg); err == nil && !fi.IsDir() {
+		dotconfig, err := kconfig.ParseConfig(arg)
+		if err != nil {
+			return nil, err
+		}
+
+		return dotconfig.Map, nil
+	}
+
+	if project != nil {
+		for _, t := range project.Targets() {
+			if t.Name() != arg {
+				continue
+			}
+
+			if project.IsConfigured(t) {
+				dotconfig, err := kconfig.ParseConfig(filepath.Join(project.WorkingDir(), t.ConfigFilename()))
+				if err != nil {
+					return nil, err
+				}
+
+				return dotconfig.Map, nil
+			}
+
+			values := kconfig.KeyValueMap{}
+			values.OverrideBy(project.KConfig())
+			values.OverrideBy(t.KConfig())
+
+			if tree == nil {
+				log.G(ctx).Warnf("target %s has not been configured, comparing the options of its Kraftfile", arg)
+				return values, nil
+			}
+
+			return tree.Solve(values), nil
+		}
+	}
+
+	packs, err := packmanager.G(ctx).Catalog(ctx, packmanager.CatalogQuery{
+		Name: arg,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var targets []target.Target
+	for _, p := range packs {
+		if t, ok := p.(target.Target); ok {
+			targets = append(targets, t)
+		}
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("%s is neither a .config file, a target nor a package", arg)
+	} else if len(targets) > 1 {
+		return nil, fmt.Errorf("too many packages match: %s", arg)
+	}
+
+	return targets[0].KConfig(), nil
+}
@@ -22,6 +22,7 @@ import (
 
 	"kraftkit.sh/cmd/kraft/build"
 	"kraftkit.sh/cmd/kraft/clean"
+	configcmd "kraftkit.sh/cmd/kraft/config"
 	"kraftkit.sh/cmd/kraft/events"
 	"kraftkit.sh/cmd/kraft/fetch"
 	"kraftkit.sh/cmd/kraft/login"
@@ -35,6 +36,7 @@ import (
 	"kraftkit.sh/cmd/kraft/run"
 	"kraftkit.sh/cmd/kraft/set"
 	"kraftkit.sh/cmd/kraft/stop"
+	"kraftkit.sh/cmd/kraft/syscalls"
 	"kraftkit.sh/cmd/kraft/unset"
 	"kraftkit.sh/cmd/kraft/version"
 
@@ -68,11 +70,13 @@ func New() *cobra.Command {
 	cmd.AddGroup(&cobra.Group{ID: "build", Title: "BUILD COMMANDS"})
 	cmd.AddCommand(build.New())
 	cmd.AddCommand(clean.New())
+	cmd.AddCommand(configcmd.New())
 	cmd.AddCommand(fetch.New())
 	cmd.AddCommand(menu.New())
 	cmd.AddCommand(prepare.New())
 	cmd.AddCommand(properclean.New())
 	cmd.AddCommand(set.New())
+	cmd.AddCommand(syscalls.New())
 	cmd.AddCommand(unset.New())
 
 	cmd.AddGroup(&cobra.Group{ID: "pkg", Title: "PACKAGING COMMANDS"})
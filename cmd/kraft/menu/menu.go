@@ -7,6 +7,7 @@ package menu
 import (
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"github.com/MakeNowJust/heredoc"
 	"github.com/spf13/cobra"
@@ -14,15 +15,21 @@ import (
 	"kraftkit.sh/cmdfactory"
 	"kraftkit.sh/config"
 	"kraftkit.sh/internal/cli"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/kconfig"
+	"kraftkit.sh/log"
 	"kraftkit.sh/make"
 	"kraftkit.sh/packmanager"
+	"kraftkit.sh/tui/menuconfig"
 	"kraftkit.sh/unikraft/app"
 	"kraftkit.sh/unikraft/target"
 )
 
 type Menu struct {
 	Architecture string `long:"arch" short:"m" usage:"Filter prepare based on a target's architecture"`
+	Ncurses      bool   `long:"ncurses" usage:"Use Unikraft's own ncurses-based editor, which requires a native toolchain"`
 	Platform     string `long:"plat" short:"p" usage:"Filter prepare based on a target's platform"`
+	Search       string `long:"search" short:"s" usage:"Start by searching for configuration options matching the query"`
 	Target       string `long:"target" short:"t" usage:"Filter prepare based on a specific target"`
 }
 
@@ -33,13 +40,25 @@ func New() *cobra.Command {
 		Aliases: []string{"m", "menuconfig"},
 		Args:    cmdfactory.MaxDirArgs(1),
 		Long: heredoc.Doc(`
-			Open Unikraft's configuration editor TUI`),
+			Open Unikraft's configuration editor TUI
+
+			The editor shows the configuration options of the Unikraft core, of all
+			libraries and of the application itself.  The visibility of each option
+			and its dependencies are evaluated as options change, and the result is
+			saved to the target's .config file.  Unikraft's own ncurses-based editor
+			can be used instead with --ncurses.`),
 		Example: heredoc.Doc(`
 			# Open configuration editor in the cwd project
 			$ kraft menu
 			
 			# Open configuration editor for a project at a path
-			$ kraft menu path/to/app`),
+			$ kraft menu path/to/app
+
+			# Open configuration editor searching for options related to the stack
+			$ kraft menu --search stack
+
+			# Open Unikraft's ncurses-based configuration editor
+			$ kraft menu --ncurses`),
 		Annotations: map[string]string{
 			cmdfactory.AnnotationHelpGroup: "build",
 		},
@@ -112,9 +131,63 @@ func (opts *Menu) Run(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	return project.Make(
-		ctx,
-		t,
-		make.WithTarget("menuconfig"),
+	if opts.Ncurses {
+		return project.Make(
+			ctx,
+			t,
+			make.WithTarget("menuconfig"),
+		)
+	}
+
+	if !iostreams.G(ctx).IsStdoutTTY() {
+		return fmt.Errorf("cannot open the configuration editor without a terminal")
+	}
+
+	trees := project.KConfigTrees(ctx)
+	if len(trees) == 0 {
+		return fmt.Errorf("could not parse the configuration options of the project, try --ncurses")
+	}
+
+	tree := kconfig.Merge(
+		fmt.Sprintf("%s (%s)", project.Name(), target.TargetPlatArchName(t)),
+		trees...,
+	)
+
+	path := filepath.Join(project.WorkingDir(), t.ConfigFilename())
+
+	var dotconfig *kconfig.DotConfigFile
+	if project.IsConfigured(t) {
+		dotconfig, err = kconfig.ParseConfig(path)
+		if err != nil {
+			return err
+		}
+	} else {
+		// Start from the options of the Kraftfile, which the target's own take
+		// precedence over.
+		values := kconfig.KeyValueMap{}
+		values.OverrideBy(project.KConfig())
+		values.OverrideBy(t.KConfig())
+
+		dotconfig = tree.NewDotConfigFile(values)
+	}
+
+	editor, err := menuconfig.NewMenuConfig(
+		tree,
+		dotconfig,
+		path,
+		menuconfig.WithSearch(opts.Search),
 	)
+	if err != nil {
+		return err
+	}
+
+	if err := editor.Start(); err != nil {
+		return err
+	}
+
+	if editor.Saved() {
+		log.G(ctx).WithField("path", path).Info("saved configuration")
+	}
+
+	return nil
 }
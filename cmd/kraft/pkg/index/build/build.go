@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package build implements the `kraft pkg index build` command
+package build
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/manifest"
+)
+
+type Build struct {
+	BaseURL string `long:"base-url" usage:"URL from which the output directory is served, making the resources of the index absolute"`
+	Name    string `long:"name" short:"n" usage:"Name of the index"`
+	Output  string `long:"output" short:"o" usage:"Directory in which the index and the archives of the components are saved" default:"index"`
+}
+
+func New() *cobra.Command {
+	cmd, err := cmdfactory.New(&Build{}, cobra.Command{
+		Short: "Generate a manifest index from component repositories and tarballs",
+		Use:   "build [FLAGS] DIR...",
+		Args:  cobra.MinimumNArgs(1),
+		Long: heredoc.Docf(`
+			Generate a manifest index from component repositories and tarballs.
+
+			The provided directories are scanned for Git repositories and tarballs of
+			Unikraft components.  The branches of a repository become the channels
+			of the component, with the checked out branch as the default, and its
+			semantic version tags become its versions.  Tarballs which are named
+			after a component and a version, e.g. %[1]slib-musl-1.2.3.tar.gz%[1]s, are
+			added to the versions of the component.  The type of a component is
+			determined by its name, e.g. %[1]slib-musl%[1]s, by the name of the
+			directory containing it, e.g. %[1]slibs/musl%[1]s, or by its contents.
+
+			Each channel and version is packaged as an archive within the output
+			directory alongside the resulting %[1]sindex.yaml%[1]s, which references the
+			archives with their SHA-256 checksums.
+		`, "`"),
+		Example: heredoc.Doc(`
+			# Generate an index of the repositories in ./components within ./index
+			$ kraft pkg index build ./components
+
+			# Generate an index whose resources are served from a known location
+			$ kraft pkg index build --output ./catalog --base-url https://catalog.example.com ./libs ./apps`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "pkg",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *Build) Run(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	builder, err := manifest.NewIndexBuilder(
+		manifest.WithIndexName(opts.Name),
+		manifest.WithIndexOutputDir(opts.Output),
+		manifest.WithIndexBaseURL(opts.BaseURL),
+	)
+	if err != nil {
+		return err
+	}
+
+	index, err := builder.Build(ctx, args...)
+	if err != nil {
+		return err
+	}
+
+	if len(index.Manifests) == 0 {
+		return fmt.Errorf("no components found in %v", args)
+	}
+
+	path := filepath.Join(opts.Output, manifest.DefaultIndexFileName)
+	if err := index.WriteToFile(path); err != nil {
+		return fmt.Errorf("could not save index: %v", err)
+	}
+
+	fmt.Fprintf(iostreams.G(ctx).Out, "indexed %d components in %s\n", len(index.Manifests), path)
+
+	return nil
+}
@@ -0,0 +1,54 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package index implements the `kraft pkg index` command
+package index
+
+import (
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+
+	"kraftkit.sh/cmd/kraft/pkg/index/build"
+	"kraftkit.sh/cmd/kraft/pkg/index/serve"
+)
+
+type Index struct{}
+
+func New() *cobra.Command {
+	cmd, err := cmdfactory.New(&Index{}, cobra.Command{
+		Short: "Generate and serve manifest indexes of Unikraft components",
+		Use:   "index [FLAGS] SUBCOMMAND",
+		Args:  cobra.NoArgs,
+		Long: heredoc.Docf(`
+			Generate and serve manifest indexes of Unikraft components.
+
+			A manifest index lists components together with their channels, versions
+			and the archives from which they can be retrieved.  Once served, the
+			index can be used as a catalog by adding its URL to the
+			%[1]sunikraft.manifests%[1]s list of the KraftKit configuration.
+		`, "`"),
+		Example: heredoc.Doc(`
+			# Generate an index from the repositories in a directory and serve it
+			$ kraft pkg index build --output ./catalog ./components
+			$ kraft pkg index serve ./catalog`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "pkg",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	cmd.AddCommand(build.New())
+	cmd.AddCommand(serve.New())
+
+	return cmd
+}
+
+func (*Index) Run(cmd *cobra.Command, _ []string) error {
+	return cmd.Help()
+}
@@ -0,0 +1,109 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package serve implements the `kraft pkg index serve` command
+package serve
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/log"
+	"kraftkit.sh/manifest"
+)
+
+type Serve struct {
+	Listen string `long:"listen" short:"l" usage:"Address on which the index is served" default:":8080"`
+}
+
+func New() *cobra.Command {
+	cmd, err := cmdfactory.New(&Serve{}, cobra.Command{
+		Short: "Serve a manifest index and its archives over HTTP",
+		Use:   "serve [FLAGS] [DIR]",
+		Args:  cobra.MaximumNArgs(1),
+		Long: heredoc.Docf(`
+			Serve a manifest index and its archives over HTTP.
+
+			The directory generated by %[1]skraft pkg index build%[1]s, which defaults
+			to %[1]sindex%[1]s, is served such that the index is available at
+			%[1]s/index.yaml%[1]s.  Resources of the index which are relative are
+			resolved against the location of the index, allowing it to be served
+			from any address.
+		`, "`"),
+		Example: heredoc.Doc(`
+			# Serve the index in ./index on port 8080
+			$ kraft pkg index serve
+
+			# Serve the index in ./catalog on a specific address
+			$ kraft pkg index serve --listen 0.0.0.0:9000 ./catalog
+
+			# Use the served index as a catalog
+			$ kraft pkg source http://localhost:8080/index.yaml`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "pkg",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *Serve) Run(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	dir := "index"
+	if len(args) > 0 {
+		dir = args[0]
+	}
+
+	index := filepath.Join(dir, manifest.DefaultIndexFileName)
+	if _, err := manifest.NewManifestIndexFromFile(index); err != nil {
+		return fmt.Errorf("could not read index: %v", err)
+	}
+
+	files := http.FileServer(http.Dir(dir))
+
+	server := &http.Server{
+		Addr:              opts.Listen,
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			log.G(ctx).WithFields(logrus.Fields{
+				"method": r.Method,
+				"path":   r.URL.Path,
+				"remote": r.RemoteAddr,
+			}).Debug("http")
+
+			files.ServeHTTP(w, r)
+		}),
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		_ = server.Shutdown(shutdown)
+	}()
+
+	log.G(ctx).Infof("serving %s on %s", index, opts.Listen)
+
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+
+	return nil
+}
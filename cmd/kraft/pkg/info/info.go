@@ -0,0 +1,311 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package info implements the `kraft pkg info` command
+package info
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/kconfig"
+	"kraftkit.sh/log"
+	"kraftkit.sh/manifest"
+	"kraftkit.sh/pack"
+	"kraftkit.sh/packmanager"
+	"kraftkit.sh/unikraft"
+	"kraftkit.sh/unikraft/lib"
+	"kraftkit.sh/utils"
+)
+
+type Info struct {
+	NoSource bool `long:"no-source" usage:"Do not retrieve the source of the component to inspect its syscalls and KConfig options"`
+	Update   bool `long:"update" short:"u" usage:"Get latest information about components before showing results"`
+}
+
+func New() *cobra.Command {
+	cmd, err := cmdfactory.New(&Info{}, cobra.Command{
+		Short: "Show detailed information about a Unikraft component",
+		Use:   "info [FLAGS] NAME",
+		Args:  cobra.ExactArgs(1),
+		Long: heredoc.Docf(`
+			Show detailed information about a Unikraft component.
+
+			All channels and versions of the component are listed together with its
+			origin and the versions of Unikraft it supports.  For libraries, the
+			source of the default channel, or of the version provided as part of the
+			name, e.g. %[1]slib/musl:1.2.3%[1]s, is retrieved to additionally list the
+			syscalls which the library provides and the KConfig options it exports.
+		`, "`"),
+		Example: heredoc.Doc(`
+			# Show information about the musl library
+			$ kraft pkg info lib/musl
+
+			# Inspect the syscalls and options of a specific version
+			$ kraft pkg info lib/musl:stable
+
+			# Only show the channels and versions from the catalog
+			$ kraft pkg info --no-source musl`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "pkg",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *Info) Run(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	manifests, err := manifest.Manifests(ctx, packmanager.CatalogQuery{
+		Name:    args[0],
+		NoCache: opts.Update,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(manifests) == 0 {
+		return fmt.Errorf("could not find component: %s", args[0])
+	}
+
+	version := ""
+	if _, _, v, err := unikraft.GuessTypeNameVersion(args[0]); err == nil {
+		version = v
+	}
+
+	err = iostreams.G(ctx).StartPager()
+	if err != nil {
+		log.G(ctx).Errorf("error starting pager: %v", err)
+	}
+
+	defer iostreams.G(ctx).StopPager()
+
+	for i, m := range manifests {
+		if i > 0 {
+			fmt.Fprintln(iostreams.G(ctx).Out)
+		}
+
+		if err := opts.printManifest(ctx, m, version); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// printManifest prints the details of the provided manifest and, unless
+// disabled, of the source of the provided version of the component.
+func (opts *Info) printManifest(ctx context.Context, m *manifest.Manifest, version string) error {
+	out := iostreams.G(ctx).Out
+	cs := iostreams.G(ctx).ColorScheme()
+
+	fmt.Fprintf(out, "%s\n", cs.Bold(string(m.Type)+"-"+m.Name))
+
+	fields := [][2]string{
+		{"description", strings.TrimSpace(m.Description)},
+		{"origin", m.Origin},
+		{"unikraft", strings.Join(supportedUnikraft(m), ", ")},
+	}
+	if m.Provider != nil {
+		fields = append(fields, [2]string{"provider", m.Provider.String()})
+	}
+
+	for _, field := range fields {
+		if len(field[1]) > 0 {
+			fmt.Fprintf(out, "  %-12s %s\n", field[0]+":", field[1])
+		}
+	}
+
+	if len(m.Channels) > 0 {
+		fmt.Fprintf(out, "\n%s\n", cs.Bold("CHANNELS"))
+
+		table := utils.NewTablePrinter(ctx)
+		table.AddField("NAME", nil, cs.Bold)
+		table.AddField("DEFAULT", nil, cs.Bold)
+		table.AddField("LATEST", nil, cs.Bold)
+		table.AddField("RESOURCE", nil, cs.Bold)
+		table.EndRow()
+
+		for _, channel := range m.Channels {
+			def := ""
+			if channel.Default {
+				def = "yes"
+			}
+
+			table.AddField(channel.Name, nil, nil)
+			table.AddField(def, nil, nil)
+			table.AddField(channel.Latest, nil, nil)
+			table.AddField(channel.Resource, nil, nil)
+			table.EndRow()
+		}
+
+		if err := table.Render(); err != nil {
+			return err
+		}
+	}
+
+	if len(m.Versions) > 0 {
+		fmt.Fprintf(out, "\n%s\n", cs.Bold("VERSIONS"))
+
+		table := utils.NewTablePrinter(ctx)
+		table.AddField("VERSION", nil, cs.Bold)
+		table.AddField("TYPE", nil, cs.Bold)
+		table.AddField("UNIKRAFT", nil, cs.Bold)
+		table.AddField("RESOURCE", nil, cs.Bold)
+		table.EndRow()
+
+		for _, ver := range m.Versions {
+			table.AddField(ver.Version, nil, nil)
+			table.AddField(string(ver.Type), nil, nil)
+			table.AddField(ver.Unikraft, nil, nil)
+			table.AddField(ver.Resource, nil, nil)
+			table.EndRow()
+		}
+
+		if err := table.Render(); err != nil {
+			return err
+		}
+	}
+
+	// Only libraries provide syscalls and export options which are relevant to
+	// the user of the component
+	if opts.NoSource || m.Type != unikraft.ComponentTypeLib {
+		return nil
+	}
+
+	return printSource(ctx, m, version)
+}
+
+// printSource retrieves the source of the provided version of the library, or
+// of its default channel, and prints the syscalls which it provides and the
+// KConfig options which it exports.
+func printSource(ctx context.Context, m *manifest.Manifest, version string) error {
+	out := iostreams.G(ctx).Out
+	cs := iostreams.G(ctx).ColorScheme()
+
+	if len(version) == 0 {
+		channel, err := m.DefaultChannel()
+		if err != nil {
+			log.G(ctx).Debugf("not inspecting source: %v", err)
+			return nil
+		}
+
+		version = channel.Name
+	} else if resolved, err := m.ResolveVersion(version); err == nil {
+		version = resolved
+	}
+
+	// The package is derived from a copy since the manifest is reduced to the
+	// requested version
+	reduced := *m
+	p, err := manifest.NewPackageFromManifestWithVersion(&reduced, version)
+	if err != nil {
+		return err
+	}
+
+	workdir, err := os.MkdirTemp("", "kraft-pkg-info-")
+	if err != nil {
+		return err
+	}
+
+	defer os.RemoveAll(workdir)
+
+	if err := p.Pull(ctx, pack.WithPullWorkdir(workdir)); err != nil {
+		log.G(ctx).Warnf("could not retrieve source of %s:%s: %v", m.Name, version, err)
+		return nil
+	}
+
+	dir, err := unikraft.PlaceComponent(workdir, m.Type, m.Name)
+	if err != nil {
+		return err
+	}
+
+	var syscalls []string
+
+	libs, err := lib.NewFromDir(ctx, dir)
+	if err != nil {
+		log.G(ctx).Warnf("could not parse library: %v", err)
+	}
+
+	for _, l := range libs {
+		for _, syscall := range l.Syscalls() {
+			syscalls = append(syscalls, fmt.Sprintf("%s/%d", syscall.Name, syscall.Nargs))
+		}
+	}
+
+	if len(syscalls) > 0 {
+		sort.Strings(syscalls)
+
+		fmt.Fprintf(out, "\n%s (%s)\n", cs.Bold("SYSCALLS"), version)
+		fmt.Fprintf(out, "%s\n", strings.Join(syscalls, " "))
+	}
+
+	tree, err := kconfig.Parse(filepath.Join(dir, unikraft.Config_uk))
+	if err != nil {
+		log.G(ctx).Warnf("could not parse %s: %v", unikraft.Config_uk, err)
+		return nil
+	}
+
+	var names []string
+	for name, config := range tree.Configs {
+		if config.Kind == kconfig.MenuConfig {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	sort.Strings(names)
+
+	fmt.Fprintf(out, "\n%s (%s)\n", cs.Bold("KCONFIG"), version)
+
+	table := utils.NewTablePrinter(ctx)
+	table.AddField("OPTION", nil, cs.Bold)
+	table.AddField("TYPE", nil, cs.Bold)
+	table.AddField("PROMPT", nil, cs.Bold)
+	table.EndRow()
+
+	for _, name := range names {
+		table.AddField(kconfig.Prefix+name, nil, nil)
+		table.AddField(tree.Configs[name].Type.String(), nil, nil)
+		table.AddField(tree.Configs[name].Prompt(), nil, nil)
+		table.EndRow()
+	}
+
+	return table.Render()
+}
+
+// supportedUnikraft returns the distinct versions of Unikraft which are
+// supported by the versions of the component.
+func supportedUnikraft(m *manifest.Manifest) []string {
+	seen := map[string]bool{}
+	var supported []string
+
+	for _, ver := range m.Versions {
+		if len(ver.Unikraft) == 0 || seen[ver.Unikraft] {
+			continue
+		}
+
+		seen[ver.Unikraft] = true
+		supported = append(supported, ver.Unikraft)
+	}
+
+	return supported
+}
@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package link
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/log"
+	"kraftkit.sh/unikraft"
+	"kraftkit.sh/unikraft/app"
+)
+
+type Link struct {
+	Workdir string `long:"workdir" short:"w" usage:"Set a path to the working directory of the project"`
+}
+
+func New() *cobra.Command {
+	cmd, err := cmdfactory.New(&Link{}, cobra.Command{
+		Short: "Use a local checkout in place of a component of a project",
+		Use:   "link [FLAGS] NAME PATH",
+		Args:  cmdfactory.ExactArgs(2, "must specify component and path"),
+		Long: heredoc.Docf(`
+			Use a local checkout in place of a component of a project.
+
+			The link is recorded in the %[1]s%[2]s%[1]s file alongside the Kraftfile
+			such that the Kraftfile does not need to be modified.  Linked components
+			are not pulled and their checkouts are used as they are.  Use %[1]skraft pkg
+			unlink%[1]s to restore the source which is specified in the Kraftfile.`, "`", app.LinksFileName),
+		Example: heredoc.Doc(`
+			# Use a fork of the musl library
+			$ kraft pkg link lib/musl ~/src/lib-musl
+
+			# Use a local checkout of the Unikraft core
+			$ kraft pkg link unikraft ~/src/unikraft`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "pkg",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *Link) Run(cmd *cobra.Command, args []string) error {
+	var err error
+
+	ctx := cmd.Context()
+	workdir := opts.Workdir
+
+	if len(workdir) == 0 {
+		workdir, err = os.Getwd()
+		if err != nil {
+			return err
+		}
+	}
+
+	if !app.IsWorkdirInitialized(workdir) {
+		return fmt.Errorf("no Kraftfile found in %s", workdir)
+	}
+
+	project, err := app.NewProjectFromOptions(
+		ctx,
+		app.WithProjectWorkdir(workdir),
+		app.WithProjectDefaultKraftfiles(),
+	)
+	if err != nil {
+		return err
+	}
+
+	t, n, _, err := unikraft.GuessTypeNameVersion(args[0])
+	if err != nil {
+		return err
+	}
+
+	components, err := project.Components(ctx)
+	if err != nil {
+		return err
+	}
+
+	var found unikraft.Nameable
+	for _, component := range components {
+		if component.Name() == n && (t == unikraft.ComponentTypeUnknown || component.Type() == t) {
+			found = component
+			break
+		}
+	}
+
+	if found == nil {
+		return fmt.Errorf("project does not use the component %s", args[0])
+	} else if found.Type() != unikraft.ComponentTypeCore && found.Type() != unikraft.ComponentTypeLib {
+		return fmt.Errorf("only the Unikraft core and libraries can be linked")
+	}
+
+	path, err := filepath.Abs(args[1])
+	if err != nil {
+		return err
+	}
+
+	if f, err := os.Stat(path); err != nil {
+		return fmt.Errorf("could not access %s: %v", path, err)
+	} else if !f.IsDir() {
+		return fmt.Errorf("%s is not a directory", path)
+	}
+
+	if _, err := os.Stat(filepath.Join(path, unikraft.Config_uk)); err != nil {
+		log.G(ctx).Warnf("%s does not contain a %s file", path, unikraft.Config_uk)
+	}
+
+	links := project.Links()
+	links.Link(found.Type(), found.Name(), path)
+
+	if err := links.WriteToFile(filepath.Join(workdir, app.LinksFileName)); err != nil {
+		return err
+	}
+
+	log.G(ctx).Infof("linked %s/%s to %s", found.Type(), found.Name(), path)
+
+	return nil
+}
@@ -8,13 +8,17 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sync"
 
 	"github.com/MakeNowJust/heredoc"
 	"github.com/mattn/go-shellwords"
 	"github.com/spf13/cobra"
 
 	"kraftkit.sh/config"
+	"kraftkit.sh/initrd"
 	"kraftkit.sh/pack"
+	kraftsbom "kraftkit.sh/sbom"
+	"kraftkit.sh/sizereport"
 	"kraftkit.sh/unikraft"
 
 	"kraftkit.sh/cmdfactory"
@@ -23,9 +27,17 @@ import (
 	"kraftkit.sh/tui/processtree"
 	"kraftkit.sh/unikraft/app"
 
+	"kraftkit.sh/cmd/kraft/pkg/index"
+	"kraftkit.sh/cmd/kraft/pkg/info"
+	"kraftkit.sh/cmd/kraft/pkg/link"
 	"kraftkit.sh/cmd/kraft/pkg/list"
+	"kraftkit.sh/cmd/kraft/pkg/prune"
 	"kraftkit.sh/cmd/kraft/pkg/pull"
+	"kraftkit.sh/cmd/kraft/pkg/rm"
+	"kraftkit.sh/cmd/kraft/pkg/sbom"
+	"kraftkit.sh/cmd/kraft/pkg/search"
 	"kraftkit.sh/cmd/kraft/pkg/source"
+	"kraftkit.sh/cmd/kraft/pkg/unlink"
 	"kraftkit.sh/cmd/kraft/pkg/unsource"
 	"kraftkit.sh/cmd/kraft/pkg/update"
 )
@@ -36,11 +48,13 @@ type Pkg struct {
 	Dbg          bool     `local:"true" long:"dbg" usage:"Package the debuggable (symbolic) kernel image instead of the stripped image"`
 	Force        bool     `local:"true" long:"force-format" usage:"Force the use of a packaging handler format"`
 	Format       string   `local:"true" long:"as" short:"M" usage:"Force the packaging despite possible conflicts" default:"auto"`
-	Initrd       string   `local:"true" long:"initrd" short:"i" usage:"Path to init ramdisk to bundle within the package (passing a path will automatically generate a CPIO image)"`
+	Initrd       string   `local:"true" long:"initrd" short:"i" usage:"Path to init ramdisk to bundle within the package (passing a directory, Dockerfile or image reference will automatically generate a CPIO image)"`
 	Kernel       string   `local:"true" long:"kernel" short:"k" usage:"Override the path to the unikernel image"`
 	Name         string   `local:"true" long:"name" short:"n" usage:"Specify the name of the package"`
 	Output       string   `local:"true" long:"output" short:"o" usage:"Save the package at the following output"`
 	Platform     string   `local:"true" long:"plat" short:"p" usage:"Filter the creation of the package by platform of known targets"`
+	SBOM         string   `local:"true" long:"sbom" usage:"Generate and attach a Software Bill of Materials in the given format (spdx, cyclonedx)"`
+	SizeReport   bool     `local:"true" long:"size-report" usage:"Attach the size report of the kernel saved by kraft build --size-report"`
 	Target       string   `local:"true" long:"target" short:"t" usage:"Package a particular known target"`
 	Volumes      []string `local:"true" long:"volume" short:"v" usage:"Additional volumes to bundle within the package"`
 	WithKConfig  bool     `local:"true" long:"with-kconfig" usage:"Include the target .config"`
@@ -65,7 +79,9 @@ func New() *cobra.Command {
 			For initram and disk images, passing in a directory as the argument will
 			result automatically packaging that directory into the requested format.
 			Separating the input with a %[1]s:%[1]s delimiter allows you to set the
-			output that of the artifact.
+			output that of the artifact.  A Dockerfile is built and a container image
+			reference is pulled for the architecture of each target, after which the
+			layers of the image are flattened into the initram.
 		`, "`"),
 		Example: heredoc.Doc(`
 			# Package the current Unikraft project (cwd)
@@ -78,7 +94,22 @@ func New() *cobra.Command {
 			$ kraft pkg --initrd ./root-fs .
 
 			# Same as above but also save the resulting CPIO artifact locally
-			$ kraft pkg --initrd ./root-fs:./root-fs.cpio .`),
+			$ kraft pkg --initrd ./root-fs:./root-fs.cpio .
+
+			# Same as above but as a zstd-compressed tarball (ustar) instead
+			$ kraft pkg --initrd ./root-fs:./root-fs.tar.zst .
+
+			# Package with an initramfs built from a Dockerfile
+			$ kraft pkg --initrd ./Dockerfile .
+
+			# Package with an initramfs from the filesystem of a container image
+			$ kraft pkg --initrd alpine:3.18 .
+
+			# Package and attach an SPDX Software Bill of Materials
+			$ kraft pkg --sbom spdx .
+
+			# Package and attach the size report of the kernel
+			$ kraft build --size-report && kraft pkg --size-report .`),
 		Annotations: map[string]string{
 			cmdfactory.AnnotationHelpGroup: "pkg",
 		},
@@ -87,9 +118,17 @@ func New() *cobra.Command {
 		panic(err)
 	}
 
+	cmd.AddCommand(index.New())
+	cmd.AddCommand(info.New())
+	cmd.AddCommand(link.New())
 	cmd.AddCommand(list.New())
+	cmd.AddCommand(prune.New())
 	cmd.AddCommand(pull.New())
+	cmd.AddCommand(rm.New())
+	cmd.AddCommand(sbom.New())
+	cmd.AddCommand(search.New())
 	cmd.AddCommand(source.New())
+	cmd.AddCommand(unlink.New())
 	cmd.AddCommand(unsource.New())
 	cmd.AddCommand(update.New())
 
@@ -125,6 +164,14 @@ func (opts *Pkg) Run(cmd *cobra.Command, args []string) error {
 		workdir = args[0]
 	}
 
+	var sbomFormat kraftsbom.Format
+	if len(opts.SBOM) > 0 {
+		sbomFormat, err = kraftsbom.FormatFromString(opts.SBOM)
+		if err != nil {
+			return err
+		}
+	}
+
 	ctx := cmd.Context()
 
 	// Interpret the project directory
@@ -137,6 +184,28 @@ func (opts *Pkg) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	// The initrd from the command-line is shared by all targets and is therefore
+	// only built once per architecture, as container images may differ.
+	var initrdMu sync.Mutex
+	initrdPaths := map[string]string{}
+	cliInitrd := func(ctx context.Context, arch string) (string, error) {
+		initrdMu.Lock()
+		defer initrdMu.Unlock()
+
+		if path, ok := initrdPaths[arch]; ok {
+			return path, nil
+		}
+
+		path, err := buildInitrd(ctx, opts.Initrd, initrd.WithArchitecture(arch))
+		if err != nil {
+			return "", err
+		}
+
+		initrdPaths[arch] = path
+
+		return path, nil
+	}
+
 	var tree []*processtree.ProcessTreeItem
 
 	parallel := !config.G[config.KraftKit](ctx).NoParallel
@@ -209,7 +278,27 @@ func (opts *Pkg) Run(cmd *cobra.Command, args []string) error {
 						packmanager.PackArgs(cmdShellArgs...),
 						packmanager.PackKConfig(opts.WithKConfig),
 						packmanager.PackOutput(opts.Output),
-						packmanager.PackInitrd(opts.Initrd),
+					}
+
+					// Prefer the initrd from the command-line, otherwise fall back to
+					// the initrd described in the Kraftfile
+					var ramfs string
+					if len(opts.Initrd) > 0 {
+						ramfs, err = cliInitrd(ctx, targ.Architecture().Name())
+						if err != nil {
+							return err
+						}
+					} else if targ.Initrd() != nil && len(targ.Initrd().Input) > 0 {
+						ramfs, err = targ.Initrd().Build(ctx,
+							initrd.WithArchitecture(targ.Architecture().Name()),
+						)
+						if err != nil {
+							return fmt.Errorf("could not build initrd: %v", err)
+						}
+					}
+
+					if len(ramfs) > 0 {
+						popts = append(popts, packmanager.PackInitrd(ramfs))
 					}
 
 					if ukversion, ok := targ.KConfig().Get(unikraft.UK_FULLVERSION); ok {
@@ -218,6 +307,32 @@ func (opts *Pkg) Run(cmd *cobra.Command, args []string) error {
 						)
 					}
 
+					if len(sbomFormat) > 0 {
+						bom, err := kraftsbom.NewFromTarget(ctx, project, targ)
+						if err != nil {
+							return fmt.Errorf("could not generate sbom: %v", err)
+						}
+
+						path := targ.Kernel() + sbomFormat.Extension()
+						if err := bom.WriteFile(path, sbomFormat); err != nil {
+							return fmt.Errorf("could not save sbom: %v", err)
+						}
+
+						popts = append(popts, packmanager.PackSBOM(path))
+					}
+
+					// Attach the size report of the kernel, which is saved by
+					// `kraft build --size-report`, such that later builds can be
+					// compared with the package
+					if opts.SizeReport {
+						path := targ.Kernel() + sizereport.FileExtension
+						if !isNewer(path, targ.Kernel()) {
+							return fmt.Errorf("no size report of the current kernel at %s: run kraft build --size-report first", path)
+						}
+
+						popts = append(popts, packmanager.PackSizeReport(path))
+					}
+
 					if _, err := pm.Pack(ctx, targ, popts...); err != nil {
 						return err
 					}
@@ -245,3 +360,45 @@ func (opts *Pkg) Run(cmd *cobra.Command, args []string) error {
 
 	return model.Start()
 }
+
+// buildInitrd returns the path to the initrd described by the provided value.
+// An existing archive is used as-is, whereas a directory, Dockerfile or image
+// reference is archived according to the `SRC[:OUTPUT]` short syntax.
+func buildInitrd(ctx context.Context, value string, bopts ...initrd.BuildOption) (string, error) {
+	if fi, err := os.Stat(value); err == nil && !fi.IsDir() && !initrd.IsDockerfile(value) {
+		return value, nil
+	}
+
+	cwd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+
+	ramfs, err := initrd.ParseInitrdConfig(cwd, value)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := ramfs.Build(ctx, bopts...)
+	if err != nil {
+		return "", fmt.Errorf("could not build initrd: %v", err)
+	}
+
+	return path, nil
+}
+
+// isNewer returns whether the file at the provided path exists and was
+// modified no earlier than the reference file.
+func isNewer(path, reference string) bool {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	ref, err := os.Stat(reference)
+	if err != nil {
+		return false
+	}
+
+	return !fi.ModTime().Before(ref.ModTime())
+}
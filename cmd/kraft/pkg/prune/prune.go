@@ -0,0 +1,215 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package prune implements the `kraft pkg prune` command
+package prune
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/dustin/go-humanize"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/config"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/log"
+	"kraftkit.sh/machine"
+	"kraftkit.sh/packmanager"
+	"kraftkit.sh/unikraft"
+	"kraftkit.sh/unikraft/app"
+	"kraftkit.sh/utils"
+)
+
+type Prune struct {
+	All       bool          `long:"all" short:"a" usage:"Remove all unreferenced packages and not only partial downloads"`
+	DryRun    bool          `long:"dry-run" usage:"Only print what would be removed"`
+	OlderThan time.Duration `long:"older-than" usage:"Only remove packages which have not been modified within the given duration (e.g. 720h)"`
+}
+
+func New() *cobra.Command {
+	cmd, err := cmdfactory.New(&Prune{}, cobra.Command{
+		Short: "Remove unused packages and reclaim disk space",
+		Use:   "prune [FLAGS] [DIR...]",
+		Args:  cobra.ArbitraryArgs,
+		Long: heredoc.Docf(`
+			Remove unused packages and reclaim disk space.
+
+			By default, only partial downloads are removed.  With %[1]s--all%[1]s,
+			cached archives which are no longer part of the local index, stale
+			manifests and all packages are removed except those which are referenced
+			by the project in the current working directory (or the projects at the
+			provided paths) and by machines known to KraftKit.
+		`, "`"),
+		Example: heredoc.Doc(`
+			# Remove partial downloads
+			$ kraft pkg prune
+
+			# Remove all packages which are not used by the project in the cwd or by
+			# an existing machine
+			$ kraft pkg prune --all
+
+			# Remove all unused packages which have not been modified for 30 days
+			$ kraft pkg prune --all --older-than 720h
+
+			# Show what would be removed whilst keeping packages used by two projects
+			$ kraft pkg prune --all --dry-run path/to/app1 path/to/app2`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "pkg",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (*Prune) Pre(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+	pm, err := packmanager.NewUmbrellaManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	cmd.SetContext(packmanager.WithPackageManager(ctx, pm))
+
+	return nil
+}
+
+func (opts *Prune) Run(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	if len(args) == 0 {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+
+		args = []string{cwd}
+	}
+
+	refs, err := References(ctx, args...)
+	if err != nil {
+		return err
+	}
+
+	reclaimed, err := packmanager.G(ctx).Prune(ctx,
+		packmanager.PruneAll(opts.All),
+		packmanager.PruneDryRun(opts.DryRun),
+		packmanager.PruneOlderThan(opts.OlderThan),
+		packmanager.PruneKeep(refs...),
+	)
+	if err != nil {
+		return err
+	}
+
+	return PrintReclaimed(ctx, reclaimed, opts.DryRun)
+}
+
+// References returns the list of packages which are in use, either by the
+// projects located at the provided directories or by machines which are known
+// to the machine store.  Directories which do not contain a project are
+// ignored.
+func References(ctx context.Context, dirs ...string) ([]string, error) {
+	var refs []string
+
+	for _, dir := range dirs {
+		if !app.IsWorkdirInitialized(dir) {
+			continue
+		}
+
+		project, err := app.NewProjectFromOptions(
+			ctx,
+			app.WithProjectWorkdir(dir),
+			app.WithProjectDefaultKraftfiles(),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("could not read project %s: %v", dir, err)
+		}
+
+		components, err := project.Components(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, component := range components {
+			refs = append(refs, unikraft.TypeNameVersion(component))
+		}
+	}
+
+	store, err := machine.NewMachineStoreFromPath(config.G[config.KraftKit](ctx).RuntimeDir)
+	if err != nil {
+		return nil, err
+	}
+
+	mcfgs, err := store.ListAllMachineConfigs()
+	if err != nil {
+		return nil, err
+	}
+
+	for mid, mcfg := range mcfgs {
+		scheme, entity, ok := strings.Cut(mcfg.Source, "://")
+		if !ok {
+			continue
+		}
+
+		// Machines derived from a project or a kernel image do not reference a
+		// package directly
+		switch scheme {
+		case "project", "kernel":
+			continue
+		}
+
+		log.G(ctx).WithField("machine", mid).Debugf("keeping %s", entity)
+
+		refs = append(refs, entity)
+	}
+
+	return refs, nil
+}
+
+// PrintReclaimed prints the list of removed entries followed by a summary of
+// the reclaimed disk space.
+func PrintReclaimed(ctx context.Context, reclaimed []packmanager.Reclaimed, dryRun bool) error {
+	var total int64
+
+	if len(reclaimed) > 0 {
+		cs := iostreams.G(ctx).ColorScheme()
+		table := utils.NewTablePrinter(ctx)
+
+		table.AddField("FORMAT", nil, cs.Bold)
+		table.AddField("REMOVED", nil, cs.Bold)
+		table.AddField("SIZE", nil, cs.Bold)
+		table.EndRow()
+
+		for _, entry := range reclaimed {
+			table.AddField(entry.Format.String(), nil, nil)
+			table.AddField(entry.Name, nil, nil)
+			table.AddField(humanize.Bytes(uint64(entry.Size)), nil, nil)
+			table.EndRow()
+
+			total += entry.Size
+		}
+
+		if err := table.Render(); err != nil {
+			return err
+		}
+	}
+
+	verb := "reclaimed"
+	if dryRun {
+		verb = "would reclaim"
+	}
+
+	fmt.Fprintf(iostreams.G(ctx).Out, "%s %s\n", verb, humanize.Bytes(uint64(total)))
+
+	return nil
+}
@@ -16,22 +16,26 @@ import (
 	"kraftkit.sh/config"
 	"kraftkit.sh/iostreams"
 	"kraftkit.sh/log"
+	"kraftkit.sh/manifest"
 	"kraftkit.sh/pack"
 	"kraftkit.sh/packmanager"
 	"kraftkit.sh/tui/paraprogress"
 	"kraftkit.sh/tui/processtree"
 	"kraftkit.sh/unikraft"
 	"kraftkit.sh/unikraft/app"
+	"kraftkit.sh/unikraft/component"
 )
 
 type Pull struct {
 	AllVersions  bool   `long:"all-versions" short:"A" usage:"Pull all versions"`
 	Architecture string `long:"arch" short:"m" usage:"Specify the desired architecture"`
 	ForceCache   bool   `long:"force-cache" short:"Z" usage:"Force using cache and pull directly from source"`
+	Frozen       bool   `long:"frozen-lockfile" usage:"Fail if the lockfile is missing or does not match the Kraftfile"`
 	Manager      string `long:"manager" short:"M" usage:"Force the handler type (Omittion will attempt auto-detect)" default:"auto"`
 	NoChecksum   bool   `long:"no-checksum" short:"C" usage:"Do not verify package checksum (if available)"`
 	NoDeps       bool   `long:"no-deps" short:"D" usage:"Do not pull dependencies"`
 	Platform     string `long:"plat" short:"p" usage:"Specify the desired platform"`
+	UpdateLock   bool   `long:"update-lock" usage:"Resolve all components anew and update the lockfile"`
 	WithDeps     bool   `long:"with-deps" short:"d" usage:"Pull dependencies"`
 	Workdir      string `long:"workdir" short:"w" usage:"Set a path to working directory to pull components to"`
 }
@@ -116,9 +120,11 @@ func (opts *Pull) Run(cmd *cobra.Command, args []string) error {
 	type pmQuery struct {
 		pm    packmanager.PackageManager
 		query packmanager.CatalogQuery
+		lock  bool
 	}
 
 	var queries []pmQuery
+	var locker *manifest.Locker
 
 	// Are we pulling an application directory?  If so, interpret the application
 	// so we can get a list of components
@@ -234,16 +240,47 @@ func (opts *Pull) Run(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return err
 		}
+
+		var linked []component.Component
+		var componentQueries []packmanager.CatalogQuery
 		for _, c := range components {
+			// Components which are linked to a local checkout are not pulled
+			if path, ok := project.Links().Lookup(c.Type(), c.Name()); ok {
+				log.G(ctx).Infof("using linked %s from %s", unikraft.TypeNameVersion(c), path)
+				linked = append(linked, c)
+				continue
+			}
+
+			componentQueries = append(componentQueries, packmanager.CatalogQuery{
+				Name:    c.Name(),
+				Version: c.Version(),
+				Source:  c.Source(),
+				Types:   []unikraft.ComponentType{c.Type()},
+				NoCache: !opts.ForceCache,
+			})
+		}
+
+		locker, err = manifest.NewLocker(workdir, opts.UpdateLock, opts.Frozen)
+		if err != nil {
+			return err
+		}
+
+		for _, c := range linked {
+			locker.Retain(c.Type(), c.Name(), c.Source())
+		}
+
+		// Honour the lockfile and resolve the version constraints of the remaining
+		// components together such that they are compatible with one another
+		componentQueries, err = locker.Resolve(ctx, componentQueries)
+		if err != nil {
+			return err
+		}
+
+		for _, query := range componentQueries {
 			queries = append(queries, pmQuery{
-				pm: pm,
-				query: packmanager.CatalogQuery{
-					Name:    c.Name(),
-					Version: c.Version(),
-					Source:  c.Source(),
-					Types:   []unikraft.ComponentType{c.Type()},
-					NoCache: !opts.ForceCache,
-				},
+				pm:    pm,
+				query: query,
+				lock:  true,
 			})
 		}
 
@@ -282,6 +319,13 @@ func (opts *Pull) Run(cmd *cobra.Command, args []string) error {
 
 		for _, p := range next {
 			p := p
+			if c.lock {
+				p, err = locker.Lock(ctx, c.query, p)
+				if err != nil {
+					return err
+				}
+			}
+
 			processes = append(processes, paraprogress.NewProcess(
 				fmt.Sprintf("pulling %s",
 					c.query.String(),
@@ -314,6 +358,12 @@ func (opts *Pull) Run(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
+	if locker != nil {
+		if err := locker.Save(ctx); err != nil {
+			return err
+		}
+	}
+
 	if project != nil {
 		fmt.Fprint(iostreams.G(ctx).Out, project.PrintInfo(ctx))
 	}
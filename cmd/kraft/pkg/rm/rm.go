@@ -0,0 +1,113 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package rm implements the `kraft pkg rm` command
+package rm
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/packmanager"
+
+	"kraftkit.sh/cmd/kraft/pkg/prune"
+)
+
+type Rm struct {
+	DryRun bool `long:"dry-run" usage:"Only print what would be removed"`
+	Force  bool `long:"force" short:"f" usage:"Remove packages even if they are referenced by a project or machine"`
+}
+
+func New() *cobra.Command {
+	cmd, err := cmdfactory.New(&Rm{}, cobra.Command{
+		Short:   "Remove locally available packages",
+		Use:     "rm [FLAGS] NAME[:VERSION] [NAME[:VERSION]...]",
+		Aliases: []string{"remove"},
+		Args:    cmdfactory.MinimumArgs(1, "must specify at least one package"),
+		Long: heredoc.Docf(`
+			Remove locally available packages.
+
+			Packages which are referenced by the project in the current working
+			directory or by a machine known to KraftKit are kept unless %[1]s--force%[1]s
+			is provided.  Omitting the version removes all versions of the package.
+		`, "`"),
+		Example: heredoc.Doc(`
+			# Remove all versions of the musl library
+			$ kraft pkg rm lib/musl
+
+			# Remove a specific version of the Unikraft core
+			$ kraft pkg rm unikraft:stable
+
+			# Remove an OCI package even if a machine uses it
+			$ kraft pkg rm --force unikraft.org/helloworld:latest`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "pkg",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (*Rm) Pre(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+	pm, err := packmanager.NewUmbrellaManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	cmd.SetContext(packmanager.WithPackageManager(ctx, pm))
+
+	return nil
+}
+
+func (opts *Rm) Run(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	var refs []string
+	if !opts.Force {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+
+		refs, err = prune.References(ctx, cwd)
+		if err != nil {
+			return err
+		}
+	}
+
+	var reclaimed []packmanager.Reclaimed
+
+	for _, arg := range args {
+		removed, err := packmanager.G(ctx).Prune(ctx,
+			packmanager.PruneAll(true),
+			packmanager.PruneDryRun(opts.DryRun),
+			packmanager.PruneQuery(packmanager.CatalogQuery{Name: arg}),
+			packmanager.PruneKeep(refs...),
+		)
+		if err != nil {
+			return err
+		}
+
+		if len(removed) == 0 {
+			if !opts.Force {
+				return fmt.Errorf("could not remove %s: package not found or still in use (use --force to remove anyway)", arg)
+			}
+
+			return fmt.Errorf("could not remove %s: package not found", arg)
+		}
+
+		reclaimed = append(reclaimed, removed...)
+	}
+
+	return prune.PrintReclaimed(ctx, reclaimed, opts.DryRun)
+}
@@ -0,0 +1,101 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package sbom implements the `kraft pkg sbom` command
+package sbom
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/packmanager"
+	"kraftkit.sh/sbom"
+)
+
+type Sbom struct {
+	Output string `long:"output" short:"o" usage:"Save the SBOM to the provided path instead of printing it"`
+}
+
+func New() *cobra.Command {
+	cmd, err := cmdfactory.New(&Sbom{}, cobra.Command{
+		Short: "Print the Software Bill of Materials of a package",
+		Use:   "sbom [FLAGS] REF",
+		Args:  cmdfactory.ExactArgs(1, "must specify package reference"),
+		Long: heredoc.Docf(`
+			Print the Software Bill of Materials (SBOM) attached to a package.
+
+			SBOMs are attached to packages when they are created with the %[1]s--sbom%[1]s
+			flag of %[1]skraft pkg%[1]s.
+		`, "`"),
+		Example: heredoc.Doc(`
+			# Print the SBOM of a locally available package
+			$ kraft pkg sbom unikraft.org/helloworld:latest
+
+			# Save the SBOM to a file
+			$ kraft pkg sbom -o helloworld.spdx.json unikraft.org/helloworld:latest`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "pkg",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (*Sbom) Pre(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+	pm, err := packmanager.NewUmbrellaManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	cmd.SetContext(packmanager.WithPackageManager(ctx, pm))
+
+	return nil
+}
+
+func (opts *Sbom) Run(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	packs, err := packmanager.G(ctx).Catalog(ctx, packmanager.CatalogQuery{
+		Name: args[0],
+	})
+	if err != nil {
+		return err
+	}
+
+	var providers []sbom.Provider
+	for _, p := range packs {
+		if provider, ok := p.(sbom.Provider); ok {
+			providers = append(providers, provider)
+		}
+	}
+
+	if len(providers) == 0 {
+		return fmt.Errorf("could not find package supporting sbom: %s", args[0])
+	} else if len(providers) > 1 {
+		return fmt.Errorf("too many packages match: %s", args[0])
+	}
+
+	data, err := providers[0].SBOM(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(opts.Output) > 0 {
+		return os.WriteFile(opts.Output, data, 0o644)
+	}
+
+	fmt.Fprintln(iostreams.G(ctx).Out, string(data))
+
+	return nil
+}
@@ -0,0 +1,218 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package search implements the `kraft pkg search` command
+package search
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/sahilm/fuzzy"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/log"
+	"kraftkit.sh/manifest"
+	"kraftkit.sh/pack"
+	"kraftkit.sh/packmanager"
+	"kraftkit.sh/unikraft"
+	"kraftkit.sh/utils"
+)
+
+type Search struct {
+	Limit     int  `long:"limit" short:"l" usage:"Set the maximum number of results" default:"20"`
+	NoLimit   bool `long:"no-limit" usage:"Do not limit the number of items to print"`
+	ShowApps  bool `long:"apps" usage:"Only search applications"`
+	ShowArchs bool `long:"archs" short:"M" usage:"Only search architectures"`
+	ShowCore  bool `long:"core" short:"C" usage:"Only search Unikraft core versions"`
+	ShowLibs  bool `long:"libs" short:"L" usage:"Only search libraries"`
+	ShowPlats bool `long:"plats" short:"P" usage:"Only search platforms"`
+	Update    bool `long:"update" short:"u" usage:"Get latest information about components before searching"`
+}
+
+func New() *cobra.Command {
+	cmd, err := cmdfactory.New(&Search{}, cobra.Command{
+		Short: "Search the catalog of Unikraft components and packages",
+		Use:   "search [FLAGS] QUERY",
+		Args:  cobra.ExactArgs(1),
+		Long: heredoc.Docf(`
+			Search the catalog of Unikraft components and packages.
+
+			The query is fuzzily matched against the names and descriptions of the
+			packages of every source known to KraftKit, such that %[1]sposix%[1]s finds
+			%[1]slib-posix-socket%[1]s and %[1]stls%[1]s finds a library which mentions TLS
+			in its description.  Matches on the name are ranked above matches on the
+			description.  Use %[1]skraft pkg info%[1]s to show the details of a result.
+		`, "`"),
+		Example: heredoc.Doc(`
+			# Search for libraries and applications related to networking
+			$ kraft pkg search net
+
+			# Only search libraries, refreshing the catalog first
+			$ kraft pkg search --libs --update tls`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "pkg",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (*Search) Pre(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+	pm, err := packmanager.NewUmbrellaManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	cmd.SetContext(packmanager.WithPackageManager(ctx, pm))
+
+	return nil
+}
+
+func (opts *Search) Run(cmd *cobra.Command, args []string) error {
+	ctx := cmd.Context()
+
+	query := packmanager.CatalogQuery{
+		NoCache: opts.Update,
+	}
+	if opts.ShowCore {
+		query.Types = append(query.Types, unikraft.ComponentTypeCore)
+	}
+	if opts.ShowArchs {
+		query.Types = append(query.Types, unikraft.ComponentTypeArch)
+	}
+	if opts.ShowPlats {
+		query.Types = append(query.Types, unikraft.ComponentTypePlat)
+	}
+	if opts.ShowLibs {
+		query.Types = append(query.Types, unikraft.ComponentTypeLib)
+	}
+	if opts.ShowApps {
+		query.Types = append(query.Types, unikraft.ComponentTypeApp)
+	}
+
+	packages, err := packmanager.G(ctx).Catalog(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	packages = rank(args[0], packages)
+
+	if !opts.NoLimit && opts.Limit > 0 && len(packages) > opts.Limit {
+		packages = packages[:opts.Limit]
+	}
+
+	err = iostreams.G(ctx).StartPager()
+	if err != nil {
+		log.G(ctx).Errorf("error starting pager: %v", err)
+	}
+
+	defer iostreams.G(ctx).StopPager()
+
+	cs := iostreams.G(ctx).ColorScheme()
+	table := utils.NewTablePrinter(ctx)
+
+	// Header row
+	table.AddField("TYPE", nil, cs.Bold)
+	table.AddField("PACKAGE", nil, cs.Bold)
+	table.AddField("LATEST", nil, cs.Bold)
+	table.AddField("FORMAT", nil, cs.Bold)
+	table.AddField("DESCRIPTION", nil, cs.Bold)
+	table.EndRow()
+
+	for _, pack := range packages {
+		table.AddField(string(pack.Type()), nil, nil)
+		table.AddField(pack.Name(), nil, nil)
+		table.AddField(pack.Version(), nil, nil)
+		table.AddField(pack.Format().String(), nil, nil)
+		table.AddField(description(pack), nil, cs.Gray)
+		table.EndRow()
+	}
+
+	return table.Render()
+}
+
+// description returns the description of the package, if it is known.
+func description(p pack.Package) string {
+	if m, ok := p.Metadata().(*manifest.Manifest); ok && m != nil {
+		return strings.TrimSpace(m.Description)
+	}
+
+	return ""
+}
+
+// rank returns the packages which fuzzily match the query, ordered by their
+// relevance: matches on the type and name are ranked above matches on the
+// description, and each by their score.
+func rank(query string, packages []pack.Package) []pack.Package {
+	names := make([]string, len(packages))
+	descriptions := make([]string, len(packages))
+
+	for i, p := range packages {
+		names[i] = string(p.Type()) + "-" + p.Name()
+		descriptions[i] = description(p)
+	}
+
+	type result struct {
+		pack   pack.Package
+		name   string
+		inName bool
+		score  int
+	}
+
+	results := map[int]*result{}
+
+	for _, match := range fuzzy.Find(query, names) {
+		results[match.Index] = &result{
+			pack:   packages[match.Index],
+			name:   names[match.Index],
+			inName: true,
+			score:  match.Score,
+		}
+	}
+
+	for _, match := range fuzzy.Find(query, descriptions) {
+		if _, ok := results[match.Index]; ok {
+			continue
+		}
+
+		results[match.Index] = &result{
+			pack:  packages[match.Index],
+			name:  names[match.Index],
+			score: match.Score,
+		}
+	}
+
+	ranked := make([]*result, 0, len(results))
+	for _, r := range results {
+		ranked = append(ranked, r)
+	}
+
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].inName != ranked[j].inName {
+			return ranked[i].inName
+		}
+
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+
+		return ranked[i].name < ranked[j].name
+	})
+
+	found := make([]pack.Package, len(ranked))
+	for i, r := range ranked {
+		found[i] = r.pack
+	}
+
+	return found
+}
@@ -0,0 +1,89 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package unlink
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/log"
+	"kraftkit.sh/unikraft"
+	"kraftkit.sh/unikraft/app"
+)
+
+type Unlink struct {
+	Workdir string `long:"workdir" short:"w" usage:"Set a path to the working directory of the project"`
+}
+
+func New() *cobra.Command {
+	cmd, err := cmdfactory.New(&Unlink{}, cobra.Command{
+		Short: "Restore the source of a linked component of a project",
+		Use:   "unlink [FLAGS] NAME",
+		Args:  cmdfactory.ExactArgs(1, "must specify component"),
+		Long: heredoc.Docf(`
+			Restore the source of a component of a project which was previously linked
+			to a local checkout with %[1]skraft pkg link%[1]s.`, "`"),
+		Example: heredoc.Doc(`
+			# Use the musl library as it is specified in the Kraftfile again
+			$ kraft pkg unlink lib/musl`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "pkg",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (opts *Unlink) Run(cmd *cobra.Command, args []string) error {
+	var err error
+
+	ctx := cmd.Context()
+	workdir := opts.Workdir
+
+	if len(workdir) == 0 {
+		workdir, err = os.Getwd()
+		if err != nil {
+			return err
+		}
+	}
+
+	path := filepath.Join(workdir, app.LinksFileName)
+
+	links, err := app.NewLinksFromFile(path)
+	if err != nil {
+		return err
+	}
+
+	t, n, _, err := unikraft.GuessTypeNameVersion(args[0])
+	if err != nil {
+		return err
+	}
+
+	for _, linked := range links.Components {
+		if linked.Name != n || (t != unikraft.ComponentTypeUnknown && linked.Type != t) {
+			continue
+		}
+
+		links.Unlink(linked.Type, linked.Name)
+
+		if err := links.WriteToFile(path); err != nil {
+			return err
+		}
+
+		log.G(ctx).Infof("unlinked %s/%s from %s", linked.Type, linked.Name, linked.Path)
+
+		return nil
+	}
+
+	return fmt.Errorf("component %s is not linked", args[0])
+}
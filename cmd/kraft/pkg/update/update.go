@@ -19,7 +19,9 @@ import (
 )
 
 type Update struct {
+	Force   bool   `long:"force" short:"f" usage:"Retrieve sources even if they are fresh or have not changed"`
 	Manager string `long:"manager" short:"m" usage:"Force the handler type" default:"manifest" local:"true"`
+	Source  string `long:"source" short:"s" usage:"Only retrieve the provided source"`
 }
 
 func New() *cobra.Command {
@@ -27,10 +29,21 @@ func New() *cobra.Command {
 		Short: "Retrieve new lists of Unikraft components, libraries and packages",
 		Use:   "update [FLAGS]",
 		Long: heredoc.Doc(`
-			Retrieve new lists of Unikraft components, libraries and packages.`),
+			Retrieve new lists of Unikraft components, libraries and packages.
+
+			Sources which were updated within the configured TTL, or which have
+			not changed upstream, are not retrieved again.  Only the manifests of
+			sources which have changed are rewritten.`),
 		Aliases: []string{"u"},
 		Example: heredoc.Doc(`
+			# Update all sources which are no longer fresh
 			$ kraft pkg update
+
+			# Only update a single source
+			$ kraft pkg update --source https://manifests.kraftkit.sh/index.yaml
+
+			# Retrieve all sources regardless of whether they have changed
+			$ kraft pkg update --force
 		`),
 		Annotations: map[string]string{
 			cmdfactory.AnnotationHelpGroup: "pkg",
@@ -84,7 +97,11 @@ func (opts *Update) Run(cmd *cobra.Command, args []string) error {
 				"Updating...",
 				"",
 				func(ctx context.Context) error {
-					return pm.Update(ctx)
+					return pm.Update(
+						ctx,
+						packmanager.UpdateSource(opts.Source),
+						packmanager.UpdateForce(opts.Force),
+					)
 				},
 			),
 		}...,
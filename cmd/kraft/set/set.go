@@ -40,12 +40,19 @@ import (
 	"github.com/spf13/cobra"
 
 	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/internal/cli"
+	"kraftkit.sh/kconfig"
 	"kraftkit.sh/packmanager"
 	"kraftkit.sh/unikraft/app"
 )
 
 type Set struct {
-	Workdir string `long:"workdir" short:"w" usage:"Work on a unikernel at a path"`
+	Architecture string `long:"arch" short:"m" usage:"Filter the targets to check the options against by architecture"`
+	NoDeps       bool   `long:"no-deps" usage:"Do not enable the dependencies of the options"`
+	Platform     string `long:"plat" short:"p" usage:"Filter the targets to check the options against by platform"`
+	Target       string `long:"target" short:"t" usage:"Check the options against a specific target"`
+	To           string `long:"to" usage:"Write the options to the Kraftfile or to the .config of a target (kraftfile|dotconfig)" default:"kraftfile"`
+	Workdir      string `long:"workdir" short:"w" usage:"Work on a unikernel at a path"`
 }
 
 func New() *cobra.Command {
@@ -54,14 +61,26 @@ func New() *cobra.Command {
 		Hidden:  true,
 		Use:     "set [OPTIONS] [param=value ...]",
 		Aliases: []string{"s"},
-		Long: heredoc.Doc(`
-			set a variable for a Unikraft project`),
+		Long: heredoc.Docf(`
+			Set a KConfig option for a Unikraft project.
+
+			Each option is looked up in the KConfig trees of the Unikraft core, its
+			libraries and the application, and its value is checked against the type
+			and range of the option.  Options which the option depends on are enabled
+			as well, unless %[1]s--no-deps%[1]s is set.
+
+			By default, the options are written to the %[1]skconfig%[1]s section of the
+			Kraftfile, preserving its comments.  Use %[1]s--to=dotconfig%[1]s to write them
+			to the .config of a target which has already been configured instead.`, "`"),
 		Example: heredoc.Doc(`
 			# Set variables in the cwd project
 			$ kraft set LIBDEVFS_DEV_STDOUT=/dev/null LWIP_TCP_SND_BUF=4096
 
 			# Set variables in a project at a path
-			$ kraft set -w path/to/app LIBDEVFS_DEV_STDOUT=/dev/null LWIP_TCP_SND_BUF=4096`),
+			$ kraft set -w path/to/app LIBDEVFS_DEV_STDOUT=/dev/null LWIP_TCP_SND_BUF=4096
+
+			# Set a variable in the .config of a specific target
+			$ kraft set --to dotconfig --target qemu-x86_64 CONFIG_LIBUKDEBUG_PRINTK_INFO=y`),
 		Annotations: map[string]string{
 			cmdfactory.AnnotationHelpGroup: "build",
 		},
@@ -91,7 +110,7 @@ func (opts *Set) Run(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 
 	workdir := ""
-	confOpts := []string{}
+	changes := []*kconfig.KeyValue{}
 
 	// Skip if nothing can be set
 	if len(args) == 0 {
@@ -110,32 +129,32 @@ func (opts *Set) Run(cmd *cobra.Command, args []string) error {
 
 	// Set the configuration options, skip the first one if needed
 	for _, arg := range args {
-		if !strings.ContainsRune(arg, '=') || strings.HasSuffix(arg, "=") {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok || len(key) == 0 || len(value) == 0 {
 			return fmt.Errorf("invalid or malformed argument: %s", arg)
 		}
 
-		confOpts = append(confOpts, arg)
-	}
-
-	// Check if dotconfig exists in workdir
-	dotconfig := fmt.Sprintf("%s/.config", workdir)
-
-	// Check if the file exists
-	// TODO: offer option to start in interactive mode
-	if _, err := os.Stat(dotconfig); os.IsNotExist(err) {
-		return fmt.Errorf("dotconfig file does not exist: %s", dotconfig)
+		changes = append(changes, &kconfig.KeyValue{Key: key, Value: value})
 	}
 
-	// Initialize at least the configuration options for a project
 	project, err := app.NewProjectFromOptions(
 		ctx,
 		app.WithProjectWorkdir(workdir),
 		app.WithProjectDefaultKraftfiles(),
-		app.WithProjectConfig(confOpts),
 	)
 	if err != nil {
 		return err
 	}
 
-	return project.Set(ctx, nil)
+	targets, err := cli.KConfigTargets(ctx, cli.FilterTargets(
+		project.Targets(),
+		opts.Architecture,
+		opts.Platform,
+		opts.Target,
+	), opts.To)
+	if err != nil {
+		return err
+	}
+
+	return cli.UpdateKConfig(ctx, project, targets, opts.To, opts.NoDeps, changes...)
 }
@@ -0,0 +1,326 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package check implements the `kraft syscalls check` command
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/config"
+	"kraftkit.sh/internal/cli"
+	"kraftkit.sh/iostreams"
+	"kraftkit.sh/kconfig"
+	"kraftkit.sh/log"
+	"kraftkit.sh/packmanager"
+	"kraftkit.sh/syscalls"
+	"kraftkit.sh/unikraft/app"
+	"kraftkit.sh/unikraft/lib"
+	"kraftkit.sh/unikraft/target"
+	"kraftkit.sh/utils"
+)
+
+// Formats in which the result of the check can be printed.
+const (
+	formatTable = "table"
+	formatJSON  = "json"
+)
+
+type Check struct {
+	Architecture string `long:"arch" short:"m" usage:"Filter the target by architecture"`
+	Format       string `long:"format" short:"f" usage:"Print the result in the given format (table, json)" default:"table"`
+	Platform     string `long:"plat" short:"p" usage:"Filter the target by platform"`
+	Target       string `long:"target" short:"t" usage:"Check against a particular known target"`
+	Workdir      string `long:"workdir" short:"w" usage:"Work on a unikernel at a path"`
+}
+
+func New() *cobra.Command {
+	cmd, err := cmdfactory.New(&Check{}, cobra.Command{
+		Short: "Check whether a unikernel provides the syscalls of an application",
+		Use:   "check [FLAGS] [ELF...]",
+		Args:  cobra.ArbitraryArgs,
+		Long: heredoc.Docf(`
+			Statically determine the Linux syscalls which an application uses and check
+			whether the libraries of a Unikraft project provide them.
+
+			The syscalls are determined from the libc wrappers which the application
+			calls, e.g. %[1]sopen%[1]s or %[1]spthread_create%[1]s, and from syscall
+			instructions whose number is loaded immediately before.  The ELF
+			executables, shared objects or relocatable objects to scan can be provided
+			as arguments.  Otherwise, the objects of the application built within the
+			project are scanned.
+
+			Each syscall is reported as available, stubbed if the library which
+			provides it only implements it as a stub, or missing.  Only libraries which
+			are enabled in the configuration of the target are considered, once it has
+			been configured.  The command fails if any syscall is missing.
+		`, "`"),
+		Example: heredoc.Doc(`
+			# Check the application built within the project in the cwd
+			$ kraft syscalls check
+
+			# Check a Linux executable against the syscalls of a project at a path
+			$ kraft syscalls check -w path/to/app ./nginx
+
+			# Print the result as JSON
+			$ kraft syscalls check --format json`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "build",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	return cmd
+}
+
+func (*Check) Pre(cmd *cobra.Command, _ []string) error {
+	ctx := cmd.Context()
+	pm, err := packmanager.NewUmbrellaManager(ctx)
+	if err != nil {
+		return err
+	}
+
+	cmd.SetContext(packmanager.WithPackageManager(ctx, pm))
+
+	return nil
+}
+
+func (opts *Check) Run(cmd *cobra.Command, args []string) error {
+	var err error
+
+	ctx := cmd.Context()
+
+	if opts.Format != formatTable && opts.Format != formatJSON {
+		return fmt.Errorf("unsupported format: %s", opts.Format)
+	}
+
+	workdir := opts.Workdir
+	if len(workdir) == 0 {
+		workdir, err = os.Getwd()
+		if err != nil {
+			return err
+		}
+	}
+
+	// Initialize at least the configuration options for a project
+	project, err := app.NewProjectFromOptions(
+		ctx,
+		app.WithProjectWorkdir(workdir),
+		app.WithProjectDefaultKraftfiles(),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Filter project targets by any provided CLI options
+	targets := cli.FilterTargets(
+		project.Targets(),
+		opts.Architecture,
+		opts.Platform,
+		opts.Target,
+	)
+
+	var t target.Target
+
+	switch {
+	case len(targets) == 1:
+		t = targets[0]
+
+	case config.G[config.KraftKit](ctx).NoPrompt:
+		return fmt.Errorf("could not determine which target to check")
+
+	default:
+		t, err = cli.SelectTarget(targets)
+		if err != nil {
+			return err
+		}
+	}
+
+	paths := args
+	if len(paths) == 0 {
+		paths, err = applicationObjects(project.OutDir())
+		if err != nil {
+			return err
+		}
+
+		if len(paths) == 0 {
+			return fmt.Errorf("could not find the objects of the application in %s: build the project or provide an ELF", project.OutDir())
+		}
+	}
+
+	usage, err := syscalls.Scan(paths...)
+	if err != nil {
+		return err
+	}
+
+	if arch := t.Architecture().Name(); len(arch) > 0 && arch != usage.Arch {
+		log.G(ctx).Warnf("checking a %s application against a %s target", usage.Arch, arch)
+	}
+
+	libraries, err := projectLibraries(ctx, project)
+	if err != nil {
+		return err
+	}
+
+	var values kconfig.KeyValueMap
+	if project.IsConfigured(t) {
+		values, err = kconfig.NewKeyValueMapFromFile(filepath.Join(project.WorkingDir(), t.ConfigFilename()))
+		if err != nil {
+			return err
+		}
+	} else {
+		log.G(ctx).Warnf("%s is not configured: considering the syscalls of all libraries", t.Name())
+	}
+
+	results := syscalls.Check(usage, libraries, values)
+
+	if opts.Format == formatJSON {
+		encoder := json.NewEncoder(iostreams.G(ctx).Out)
+		encoder.SetIndent("", "  ")
+
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else if err := printResults(ctx, results); err != nil {
+		return err
+	}
+
+	missing := 0
+	for _, result := range results {
+		if result.Status == syscalls.StatusMissing {
+			missing++
+		}
+	}
+
+	if missing > 0 {
+		return fmt.Errorf("%d of %d syscalls are missing", missing, len(results))
+	}
+
+	return nil
+}
+
+// applicationObjects returns the objects of the application within the build
+// directory, i.e. `<build>/app<name>/*.o`.
+func applicationObjects(buildDir string) ([]string, error) {
+	dirs, err := os.ReadDir(buildDir)
+	if err != nil {
+		return nil, fmt.Errorf("could not read build directory: %v", err)
+	}
+
+	var objects []string
+	for _, dir := range dirs {
+		if !dir.IsDir() || !strings.HasPrefix(dir.Name(), "app") {
+			continue
+		}
+
+		err := filepath.WalkDir(filepath.Join(buildDir, dir.Name()), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if !d.IsDir() && filepath.Ext(path) == ".o" {
+				objects = append(objects, path)
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return objects, nil
+}
+
+// projectLibraries returns the libraries of the project.  Libraries which are
+// declared in the Kraftfile only become aware of the syscalls they provide
+// once their Makefile.uk is parsed, which is done if they have been fetched.
+func projectLibraries(ctx context.Context, project app.Application) (lib.Libraries, error) {
+	libraries, err := project.Libraries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := lib.Libraries{}
+	for name, library := range libraries {
+		ret[name] = library
+
+		if len(library.KConfigName()) > 0 || len(library.Path()) == 0 {
+			continue
+		}
+
+		parsed, err := lib.NewFromDir(ctx, library.Path())
+		if err != nil {
+			log.G(ctx).Debugf("could not parse library %s: %v", name, err)
+			continue
+		}
+
+		for name, library := range parsed {
+			ret[name] = library
+		}
+	}
+
+	return ret, nil
+}
+
+// printResults prints the status of each syscall followed by a summary.
+func printResults(ctx context.Context, results []syscalls.Result) error {
+	cs := iostreams.G(ctx).ColorScheme()
+
+	table := utils.NewTablePrinter(ctx)
+
+	table.AddField("SYSCALL", nil, cs.Bold)
+	table.AddField("STATUS", nil, cs.Bold)
+	table.AddField("LIBRARY", nil, cs.Bold)
+	table.AddField("SOURCES", nil, cs.Bold)
+	table.EndRow()
+
+	counts := map[syscalls.Status]int{}
+
+	for _, result := range results {
+		counts[result.Status]++
+
+		var color func(string) string
+		switch result.Status {
+		case syscalls.StatusAvailable:
+			color = cs.Green
+		case syscalls.StatusStubbed:
+			color = cs.Yellow
+		case syscalls.StatusMissing:
+			color = cs.Red
+		}
+
+		table.AddField(result.Syscall, nil, nil)
+		table.AddField(string(result.Status), nil, color)
+		table.AddField(result.Library, nil, nil)
+		table.AddField(strings.Join(result.Sources, ", "), nil, cs.Gray)
+		table.EndRow()
+	}
+
+	if err := table.Render(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(iostreams.G(ctx).Out, "%d syscalls: %d available, %d stubbed, %d missing\n",
+		len(results),
+		counts[syscalls.StatusAvailable],
+		counts[syscalls.StatusStubbed],
+		counts[syscalls.StatusMissing],
+	)
+
+	return nil
+}
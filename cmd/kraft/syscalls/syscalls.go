@@ -0,0 +1,46 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package syscalls implements the `kraft syscalls` command
+package syscalls
+
+import (
+	"github.com/MakeNowJust/heredoc"
+	"github.com/spf13/cobra"
+
+	"kraftkit.sh/cmdfactory"
+
+	"kraftkit.sh/cmd/kraft/syscalls/check"
+)
+
+type Syscalls struct{}
+
+func New() *cobra.Command {
+	cmd, err := cmdfactory.New(&Syscalls{}, cobra.Command{
+		Short: "Inspect the syscalls used by an application",
+		Use:   "syscalls [FLAGS] SUBCOMMAND",
+		Args:  cobra.NoArgs,
+		Long: heredoc.Doc(`
+			Inspect the Linux syscalls used by an application and whether the
+			libraries of a unikernel provide them.`),
+		Example: heredoc.Doc(`
+			# Check whether the project provides the syscalls its application uses
+			$ kraft syscalls check`),
+		Annotations: map[string]string{
+			cmdfactory.AnnotationHelpGroup: "build",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	cmd.AddCommand(check.New())
+
+	return cmd
+}
+
+func (*Syscalls) Run(cmd *cobra.Command, _ []string) error {
+	return cmd.Help()
+}
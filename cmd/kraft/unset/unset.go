@@ -39,12 +39,18 @@ import (
 	"github.com/spf13/cobra"
 
 	"kraftkit.sh/cmdfactory"
+	"kraftkit.sh/internal/cli"
+	"kraftkit.sh/kconfig"
 	"kraftkit.sh/packmanager"
 	"kraftkit.sh/unikraft/app"
 )
 
 type Unset struct {
-	Workdir string `long:"workdir" short:"w" usage:"Work on a unikernel at a path"`
+	Architecture string `long:"arch" short:"m" usage:"Filter the targets to check the options against by architecture"`
+	Platform     string `long:"plat" short:"p" usage:"Filter the targets to check the options against by platform"`
+	Target       string `long:"target" short:"t" usage:"Check the options against a specific target"`
+	To           string `long:"to" usage:"Write the options to the Kraftfile or to the .config of a target (kraftfile|dotconfig)" default:"kraftfile"`
+	Workdir      string `long:"workdir" short:"w" usage:"Work on a unikernel at a path"`
 }
 
 func New() *cobra.Command {
@@ -53,8 +59,16 @@ func New() *cobra.Command {
 		Hidden:  true,
 		Use:     "unset [OPTIONS] [param ...]",
 		Aliases: []string{"u"},
-		Long: heredoc.Doc(`
-			unset a variable for a Unikraft project`),
+		Long: heredoc.Docf(`
+			Unset a KConfig option for a Unikraft project.
+
+			Bool and tristate options are disabled, which fails if another option
+			selects them.  Options of other types are removed such that their default
+			applies again.
+
+			By default, the options are written to the %[1]skconfig%[1]s section of the
+			Kraftfile, preserving its comments.  Use %[1]s--to=dotconfig%[1]s to write them
+			to the .config of a target which has already been configured instead.`, "`"),
 		Example: heredoc.Doc(`
 			# Unset variables in the cwd project
 			$ kraft unset LIBDEVFS_DEV_STDOUT LWIP_TCP_SND_BUF
@@ -90,7 +104,7 @@ func (opts *Unset) Run(cmd *cobra.Command, args []string) error {
 	ctx := cmd.Context()
 
 	workdir := ""
-	confOpts := []string{}
+	changes := []*kconfig.KeyValue{}
 
 	// Skip if nothing can be unset
 	if len(args) == 0 {
@@ -108,28 +122,27 @@ func (opts *Unset) Run(cmd *cobra.Command, args []string) error {
 	}
 
 	for _, arg := range args {
-		confOpts = append(confOpts, arg+"=n")
-	}
-
-	// Check if dotconfig exists in workdir
-	dotconfig := fmt.Sprintf("%s/.config", workdir)
-
-	// Check if the file exists
-	// TODO: offer option to start in interactive mode
-	if _, err := os.Stat(dotconfig); os.IsNotExist(err) {
-		return fmt.Errorf("dotconfig file does not exist: %s", dotconfig)
+		changes = append(changes, &kconfig.KeyValue{Key: arg, Value: kconfig.No})
 	}
 
-	// Initialize at least the configuration options for a project
 	project, err := app.NewProjectFromOptions(
 		ctx,
 		app.WithProjectWorkdir(workdir),
-		// app.WithProjectDefaultConfigPath(),
-		app.WithProjectConfig(confOpts),
+		app.WithProjectDefaultKraftfiles(),
 	)
 	if err != nil {
 		return err
 	}
 
-	return project.Unset(ctx, nil)
+	targets, err := cli.KConfigTargets(ctx, cli.FilterTargets(
+		project.Targets(),
+		opts.Architecture,
+		opts.Platform,
+		opts.Target,
+	), opts.To)
+	if err != nil {
+		return err
+	}
+
+	return cli.UpdateKConfig(ctx, project, targets, opts.To, false, changes...)
 }
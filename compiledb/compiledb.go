@@ -0,0 +1,276 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package compiledb builds a JSON compilation database, i.e. the
+// `compile_commands.json` file which tools such as clangd use to understand
+// how each source of a project is compiled, from the commands printed by make.
+package compiledb
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// FileName is the name under which tools look for the database in the root of
+// a project.
+const FileName = "compile_commands.json"
+
+// Command is the invocation of the compiler for a single source, see:
+// https://clang.llvm.org/docs/JSONCompilationDatabase.html
+type Command struct {
+	Directory string   `json:"directory"`
+	File      string   `json:"file"`
+	Arguments []string `json:"arguments"`
+	Output    string   `json:"output,omitempty"`
+}
+
+// Database is a compilation database which holds a single command per source.
+type Database struct {
+	commands []Command
+	files    map[string]struct{}
+}
+
+// New returns an empty database.
+func New() *Database {
+	return &Database{
+		commands: []Command{},
+		files:    map[string]struct{}{},
+	}
+}
+
+// Add adds the commands of sources which are not yet in the database and
+// returns how many were added.  When the commands of several targets are added,
+// the sources which they share are compiled as by the first.
+func (db *Database) Add(commands ...Command) int {
+	added := 0
+	for _, command := range commands {
+		if _, ok := db.files[command.File]; ok {
+			continue
+		}
+
+		db.files[command.File] = struct{}{}
+		db.commands = append(db.commands, command)
+		added++
+	}
+
+	return added
+}
+
+// Commands returns the commands in the order in which they were added.
+func (db *Database) Commands() []Command {
+	return append([]Command{}, db.commands...)
+}
+
+// WriteFile saves the database as JSON to the provided path.
+func (db *Database) WriteFile(path string) error {
+	data, err := json.MarshalIndent(db.commands, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+var (
+	// compiler matches the names of C and C++ compilers, including those of
+	// cross toolchains and versioned ones, e.g. `x86_64-linux-gnu-gcc-12`.
+	compiler = regexp.MustCompile(`^(?:[\w.+-]*-)?(?:gcc|g\+\+|cc|c\+\+|clang|clang\+\+)(?:-[\d.]+)?$`)
+
+	// launchers are commands which run the compiler given as their arguments.
+	launchers = map[string]bool{
+		"ccache":  true,
+		"distcc":  true,
+		"sccache": true,
+	}
+
+	// sourceExtensions are the extensions of the sources which are compiled.
+	sourceExtensions = map[string]bool{
+		".c":   true,
+		".cc":  true,
+		".cpp": true,
+		".cxx": true,
+		".c++": true,
+		".C":   true,
+		".S":   true,
+		".s":   true,
+		".m":   true,
+	}
+
+	// valueOptions are options of the compiler whose value is the following
+	// argument, which must not be mistaken for a source.
+	valueOptions = map[string]bool{
+		"-D":             true,
+		"-I":             true,
+		"-MF":            true,
+		"-MQ":            true,
+		"-MT":            true,
+		"-T":             true,
+		"-U":             true,
+		"-Xassembler":    true,
+		"-Xlinker":       true,
+		"-Xpreprocessor": true,
+		"-idirafter":     true,
+		"-imacros":       true,
+		"-include":       true,
+		"-iquote":        true,
+		"-isystem":       true,
+		"-o":             true,
+		"-x":             true,
+	}
+)
+
+// Parse returns the commands which compile a source among the commands printed
+// by make, e.g. with `make -n` or `make V=1`, which were run in the provided
+// directory.  Commands which only preprocess or link are ignored.
+func Parse(dir string, output []byte) []Command {
+	var ret []Command
+
+	// Recipes which span several lines are printed with continuations
+	output = bytes.ReplaceAll(output, []byte("\\\n"), []byte(" "))
+
+	for _, line := range strings.Split(string(output), "\n") {
+		cwd := dir
+
+		for _, args := range split(line) {
+			if args[0] == "cd" && len(args) == 2 {
+				cwd = resolve(cwd, args[1])
+				continue
+			}
+
+			if command, ok := parseCommand(cwd, args); ok {
+				ret = append(ret, command)
+			}
+		}
+	}
+
+	return ret
+}
+
+func parseCommand(dir string, args []string) (Command, bool) {
+	for len(args) > 0 && launchers[filepath.Base(args[0])] {
+		args = args[1:]
+	}
+
+	if len(args) == 0 || !compiler.MatchString(filepath.Base(args[0])) {
+		return Command{}, false
+	}
+
+	compile := false
+	file, output := "", ""
+
+	for i := 1; i < len(args); i++ {
+		arg := args[i]
+
+		switch {
+		case arg == "-c":
+			compile = true
+		case arg == "-o" && i+1 < len(args):
+			output = args[i+1]
+			i++
+		case valueOptions[arg]:
+			i++
+		case strings.HasPrefix(arg, "-"):
+		case sourceExtensions[filepath.Ext(arg)]:
+			file = arg
+		}
+	}
+
+	if !compile || len(file) == 0 {
+		return Command{}, false
+	}
+
+	return Command{
+		Directory: dir,
+		File:      resolve(dir, file),
+		Arguments: args,
+		Output:    output,
+	}, true
+}
+
+func resolve(dir, path string) string {
+	if filepath.IsAbs(path) || len(dir) == 0 {
+		return filepath.Clean(path)
+	}
+
+	return filepath.Join(dir, path)
+}
+
+// split splits a line of shell into its commands, e.g. those joined by `&&`,
+// and each command into its words with quotes and escapes removed.
+func split(line string) [][]string {
+	var (
+		ret    [][]string
+		args   []string
+		word   strings.Builder
+		inWord bool
+	)
+
+	endWord := func() {
+		if inWord {
+			args = append(args, word.String())
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	endCommand := func() {
+		endWord()
+		if len(args) > 0 {
+			ret = append(ret, args)
+			args = nil
+		}
+	}
+
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+
+		switch {
+		case c == '\'':
+			inWord = true
+			end := strings.IndexByte(line[i+1:], '\'')
+			if end < 0 {
+				end = len(line) - i - 1
+			}
+
+			word.WriteString(line[i+1 : i+1+end])
+			i += end + 1
+
+		case c == '"':
+			inWord = true
+			for i++; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) && strings.IndexByte("\"\\$`", line[i+1]) >= 0 {
+					i++
+				}
+
+				word.WriteByte(line[i])
+			}
+
+		case c == '\\':
+			if i+1 < len(line) {
+				inWord = true
+				i++
+				word.WriteByte(line[i])
+			}
+
+		case c == ' ' || c == '\t':
+			endWord()
+
+		case strings.IndexByte(";&|<>()", c) >= 0:
+			endCommand()
+
+		default:
+			inWord = true
+			word.WriteByte(c)
+		}
+	}
+
+	endCommand()
+
+	return ret
+}
@@ -0,0 +1,73 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package compiledb
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	output := []byte(`mkdir -p /app/build/libukdebug
+gcc -nostdlib -U __linux__ -I/uk/include -I/uk/lib/ukdebug/include -D__Unikraft__ -DUK_CODENAME="Kiviuq" -D__LIBNAME__=libukdebug -D__BASENAME__=print.c -c /uk/lib/ukdebug/print.c -o /app/build/libukdebug/print.o -Wp,-MD,/app/build/libukdebug/.print.o.d
+ccache x86_64-linux-gnu-g++-12 -I /app/include -x c++ \
+	-c main.cc -o /app/build/apphello/main.o
+gcc -E -P -x assembler-with-cpp /uk/plat/kvm/x86/link64.lds.S -o /app/build/link64.lds
+gcc -nostdlib -Wl,-r -o /app/build/libukdebug.ld.o /app/build/libukdebug/print.o
+cd /app/src && gcc '-DNAME="hello world"' -c util.c -o util.o && echo done
+`)
+
+	expected := []Command{
+		{
+			Directory: "/uk",
+			File:      "/uk/lib/ukdebug/print.c",
+			Arguments: []string{"gcc", "-nostdlib", "-U", "__linux__", "-I/uk/include", "-I/uk/lib/ukdebug/include", "-D__Unikraft__", "-DUK_CODENAME=Kiviuq", "-D__LIBNAME__=libukdebug", "-D__BASENAME__=print.c", "-c", "/uk/lib/ukdebug/print.c", "-o", "/app/build/libukdebug/print.o", "-Wp,-MD,/app/build/libukdebug/.print.o.d"},
+			Output:    "/app/build/libukdebug/print.o",
+		},
+		{
+			Directory: "/uk",
+			File:      "/uk/main.cc",
+			Arguments: []string{"x86_64-linux-gnu-g++-12", "-I", "/app/include", "-x", "c++", "-c", "main.cc", "-o", "/app/build/apphello/main.o"},
+			Output:    "/app/build/apphello/main.o",
+		},
+		{
+			Directory: "/app/src",
+			File:      "/app/src/util.c",
+			Arguments: []string{"gcc", "-DNAME=\"hello world\"", "-c", "util.c", "-o", "util.o"},
+			Output:    "util.o",
+		},
+	}
+
+	if got := Parse("/uk", output); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %#v, got %#v", expected, got)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	db := New()
+
+	first := []Command{
+		{Directory: "/uk", File: "/app/main.c", Arguments: []string{"gcc", "-DTARGET=1", "-c", "/app/main.c"}},
+		{Directory: "/uk", File: "/uk/plat/kvm/setup.c", Arguments: []string{"gcc", "-c", "/uk/plat/kvm/setup.c"}},
+	}
+
+	second := []Command{
+		{Directory: "/uk", File: "/app/main.c", Arguments: []string{"gcc", "-DTARGET=2", "-c", "/app/main.c"}},
+		{Directory: "/uk", File: "/uk/plat/xen/setup.c", Arguments: []string{"gcc", "-c", "/uk/plat/xen/setup.c"}},
+	}
+
+	if added := db.Add(first...); added != 2 {
+		t.Errorf("expected 2 commands to be added, got %d", added)
+	}
+
+	if added := db.Add(second...); added != 1 {
+		t.Errorf("expected 1 command to be added, got %d", added)
+	}
+
+	expected := []Command{first[0], first[1], second[1]}
+	if got := db.Commands(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
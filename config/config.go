@@ -19,6 +19,7 @@ type KraftKit struct {
 	NoParallel     bool   `yaml:"no_parallel" env:"KRAFTKIT_NO_PARALLEL" long:"no-parallel" usage:"Do not run internal tasks in parallel" default:"true"`
 	NoEmojis       bool   `yaml:"no_emojis" env:"KRAFTKIT_NO_EMOJIS" long:"no-emojis" usage:"Do not use emojis in any console output" default:"true"`
 	NoCheckUpdates bool   `yaml:"no_check_updates" env:"KRAFTKIT_NO_CHECK_UPDATES" long:"no-check-updates" usage:"Do not check for updates" default:"false"`
+	Offline        bool   `yaml:"offline" env:"KRAFTKIT_OFFLINE" long:"offline" usage:"Do not access the network and only use locally cached components and packages" default:"false"`
 	Editor         string `yaml:"editor" env:"KRAFTKIT_EDITOR" long:"editor" usage:"Set the text editor to open when prompt to edit a file"`
 	GitProtocol    string `yaml:"git_protocol" env:"KRAFTKIT_GIT_PROTOCOL" long:"git-protocol" usage:"Preferred Git protocol to use" default:"https"`
 	Pager          string `yaml:"pager,omitempty" env:"KRAFTKIT_PAGER" long:"pager" usage:"System pager to pipe output to"`
@@ -34,6 +35,7 @@ type KraftKit struct {
 		Config    string `yaml:"config,omitempty" env:"KRAFTKIT_PATHS_CONFIG" long:"config-dir" usage:"Path to KraftKit config directory"`
 		Manifests string `yaml:"manifests,omitempty" env:"KRAFTKIT_PATHS_MANIFESTS" long:"manifests-dir" usage:"Path to Unikraft manifest cache"`
 		Sources   string `yaml:"sources,omitempty" env:"KRAFTKIT_PATHS_SOURCES" long:"sources-dir" usage:"Path to Unikraft component cache"`
+		Builds    string `yaml:"builds,omitempty" env:"KRAFTKIT_PATHS_BUILDS" long:"builds-dir" usage:"Path to the cache of built unikernels"`
 	} `yaml:"paths,omitempty"`
 
 	Log struct {
@@ -43,10 +45,16 @@ type KraftKit struct {
 	} `yaml:"log"`
 
 	Unikraft struct {
-		Mirrors   []string `yaml:"mirrors" env:"KRAFTKIT_UNIKRAFT_MIRRORS" long:"with-mirror" usage:"Paths to mirrors of Unikraft component artifacts"`
-		Manifests []string `yaml:"manifests" env:"KRAFTKIT_UNIKRAFT_MANIFESTS" long:"with-manifest" usage:"Paths to package or component manifests"`
+		Mirrors      []string `yaml:"mirrors" env:"KRAFTKIT_UNIKRAFT_MIRRORS" long:"with-mirror" usage:"Paths to mirrors of Unikraft component artifacts"`
+		Manifests    []string `yaml:"manifests" env:"KRAFTKIT_UNIKRAFT_MANIFESTS" long:"with-manifest" usage:"Paths to package or component manifests"`
+		ManifestsTTL string   `yaml:"manifests_ttl" env:"KRAFTKIT_UNIKRAFT_MANIFESTS_TTL" long:"manifests-ttl" usage:"Duration for which an updated manifest source is not checked for changes" default:"5m"`
 	} `yaml:"unikraft"`
 
+	Fetch struct {
+		Workers int `yaml:"workers" env:"KRAFTKIT_FETCH_WORKERS" long:"fetch-workers" usage:"Maximum number of components fetched at once (default is the number of CPUs)"`
+		PerHost int `yaml:"per_host" env:"KRAFTKIT_FETCH_PER_HOST" long:"fetch-per-host" usage:"Maximum number of components fetched at once from the same host" default:"4"`
+	} `yaml:"fetch"`
+
 	Auth map[string]AuthConfig `yaml:"auth,omitempty" noattribute:"true"`
 
 	Aliases map[string]map[string]string `yaml:"aliases" noattribute:"true"`
@@ -64,6 +72,10 @@ var configDetails = []ConfigDetail{
 		Key:         "no_prompt",
 		Description: "toggle interactive prompting in the terminal",
 	},
+	{
+		Key:         "offline",
+		Description: "only use locally cached components and packages without accessing the network",
+	},
 	{
 		Key:         "editor",
 		Description: "the text editor program to use for authoring text",
@@ -84,6 +96,10 @@ var configDetails = []ConfigDetail{
 		Key:         "pager",
 		Description: "the terminal pager program to send standard output to",
 	},
+	{
+		Key:         "unikraft.manifests_ttl",
+		Description: "the duration for which an updated manifest source is not checked for changes",
+	},
 	{
 		Key:         "log.level",
 		Description: "Set the logging verbosity",
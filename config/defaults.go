@@ -39,11 +39,16 @@ func NewDefaultKraftKitConfig() (*KraftKit, error) {
 		c.Paths.Manifests = filepath.Join(DataDir(), "manifests")
 	}
 
-	// ..and for cached source files
+	// ..for cached source files..
 	if len(c.Paths.Sources) == 0 {
 		c.Paths.Sources = filepath.Join(DataDir(), "sources")
 	}
 
+	// ..and for cached builds
+	if len(c.Paths.Builds) == 0 {
+		c.Paths.Builds = filepath.Join(DataDir(), "builds")
+	}
+
 	if len(c.Unikraft.Manifests) == 0 {
 		c.Unikraft.Manifests = append(c.Unikraft.Manifests, defaultManifestIndex)
 	}
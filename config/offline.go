@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrOffline is returned when the network is accessed in offline mode.
+var ErrOffline = errors.New("network access is disabled in offline mode")
+
+// offlineContextKey is used to retrieve whether offline mode is set from the
+// context.
+type offlineContextKey struct{}
+
+// WithOffline returns a new context in which offline mode is set as provided,
+// regardless of the KraftKit configuration.
+func WithOffline(ctx context.Context, offline bool) context.Context {
+	return context.WithValue(ctx, offlineContextKey{}, offline)
+}
+
+// Offline returns whether offline mode is set in the context, either
+// explicitly via WithOffline or via the KraftKit configuration in the context.
+// In offline mode, components and packages are only served from local caches.
+func Offline(ctx context.Context) bool {
+	if offline, ok := ctx.Value(offlineContextKey{}).(bool); ok {
+		return offline
+	}
+
+	return G[KraftKit](ctx).Offline
+}
+
+// OfflineError returns the error of attempting to retrieve the provided
+// artifact, e.g. a URL, from the network in offline mode.  The error wraps
+// ErrOffline.
+func OfflineError(artifact string) error {
+	return fmt.Errorf("cannot retrieve %s: %w (unset --offline or KRAFTKIT_OFFLINE, or populate the cache while online)", artifact, ErrOffline)
+}
@@ -0,0 +1,141 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package initrd
+
+import (
+	"archive/tar"
+	"io"
+	"strings"
+
+	"github.com/cavaliergopher/cpio"
+)
+
+// Writer writes the entries of an initrd archive regardless of its format.
+// Entries are described with a cpio.Header; the target of symbolic links is
+// provided via its Linkname and not as contents.
+type Writer interface {
+	WriteHeader(*cpio.Header) error
+	io.Writer
+	io.Closer
+}
+
+// Reader reads the entries of an initrd archive regardless of its format.  The
+// Reader must be closed to release its decompressor, whereas the underlying
+// reader is not closed.
+type Reader interface {
+	Next() (*cpio.Header, error)
+	io.Reader
+	io.Closer
+}
+
+// archiveReader reads the entries of an uncompressed archive.
+type archiveReader interface {
+	Next() (*cpio.Header, error)
+	io.Reader
+}
+
+// newcWriter adapts cpio.Writer, which expects the target of symbolic links to
+// be written as contents, to the Writer interface.
+type newcWriter struct {
+	*cpio.Writer
+}
+
+// WriteHeader implements Writer.
+func (w newcWriter) WriteHeader(hdr *cpio.Header) error {
+	if hdr.Mode&cpio.ModeType != cpio.TypeSymlink {
+		return w.Writer.WriteHeader(hdr)
+	}
+
+	link := *hdr
+	link.Size = int64(len(hdr.Linkname))
+	if err := w.Writer.WriteHeader(&link); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w.Writer, hdr.Linkname)
+	return err
+}
+
+// ustarWriter adapts tar.Writer to the Writer interface.
+type ustarWriter struct {
+	*tar.Writer
+}
+
+// WriteHeader implements Writer.
+func (w ustarWriter) WriteHeader(hdr *cpio.Header) error {
+	th := &tar.Header{
+		Name:     hdr.Name,
+		Linkname: hdr.Linkname,
+		Size:     hdr.Size,
+		Mode:     int64(hdr.Mode & (cpio.ModePerm | cpio.ModeSetuid | cpio.ModeSetgid | cpio.ModeSticky)),
+		Uid:      hdr.Uid,
+		Gid:      hdr.Guid,
+		ModTime:  hdr.ModTime,
+		Format:   tar.FormatUSTAR,
+	}
+
+	switch hdr.Mode & cpio.ModeType {
+	case cpio.TypeDir:
+		th.Typeflag = tar.TypeDir
+		th.Size = 0
+		if !strings.HasSuffix(th.Name, "/") {
+			th.Name += "/"
+		}
+	case cpio.TypeSymlink:
+		th.Typeflag = tar.TypeSymlink
+		th.Size = 0
+	case cpio.TypeChar:
+		th.Typeflag = tar.TypeChar
+	case cpio.TypeBlock:
+		th.Typeflag = tar.TypeBlock
+	case cpio.TypeFifo:
+		th.Typeflag = tar.TypeFifo
+	default:
+		th.Typeflag = tar.TypeReg
+	}
+
+	return w.Writer.WriteHeader(th)
+}
+
+// ustarReader adapts tar.Reader to the Reader interface.
+type ustarReader struct {
+	*tar.Reader
+}
+
+// Next implements Reader.
+func (r ustarReader) Next() (*cpio.Header, error) {
+	th, err := r.Reader.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	hdr := &cpio.Header{
+		Name:     strings.TrimSuffix(th.Name, "/"),
+		Linkname: th.Linkname,
+		Size:     th.Size,
+		Mode:     cpio.FileMode(th.Mode),
+		Uid:      th.Uid,
+		Guid:     th.Gid,
+		ModTime:  th.ModTime,
+		Links:    1,
+	}
+
+	switch th.Typeflag {
+	case tar.TypeDir:
+		hdr.Mode |= cpio.TypeDir
+	case tar.TypeSymlink:
+		hdr.Mode |= cpio.TypeSymlink
+	case tar.TypeChar:
+		hdr.Mode |= cpio.TypeChar
+	case tar.TypeBlock:
+		hdr.Mode |= cpio.TypeBlock
+	case tar.TypeFifo:
+		hdr.Mode |= cpio.TypeFifo
+	default:
+		hdr.Mode |= cpio.TypeReg
+	}
+
+	return hdr, nil
+}
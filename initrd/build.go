@@ -0,0 +1,307 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package initrd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cavaliergopher/cpio"
+	"github.com/google/go-containerregistry/pkg/name"
+
+	"kraftkit.sh/log"
+)
+
+// entry is a file which is to be placed in the archive.
+type entry struct {
+	src  string
+	link string
+	mode fs.FileMode
+}
+
+// BuildOption is an option function which is used to modify the build of an
+// initrd.
+type BuildOption func(*buildOptions)
+
+type buildOptions struct {
+	arch string
+}
+
+// WithArchitecture sets the architecture, using Unikraft's naming, of the
+// container images used as input.  It defaults to the host's architecture.
+func WithArchitecture(arch string) BuildOption {
+	return func(bopts *buildOptions) {
+		bopts.arch = arch
+	}
+}
+
+// archivePath returns the cleaned path of a file within the archive, without
+// a leading separator.
+func archivePath(elem ...string) string {
+	return strings.TrimPrefix(path.Clean("/"+path.Join(elem...)), "/")
+}
+
+// splitInput separates an input of the form `SRC[:DEST]` into its source and
+// destination.  A destination which is absolute is always recognised, which
+// disambiguates it from the tag of an image reference, e.g.
+// `alpine:3.18:/rootfs`.  Otherwise, the input is only split if its source
+// exists on the host.
+func (i *InitrdConfig) splitInput(input string) (string, string) {
+	if idx := strings.LastIndex(input, InputDelimeter); idx >= 0 && strings.HasPrefix(input[idx+1:], "/") {
+		return input[:idx], input[idx+1:]
+	}
+
+	if src, dest, ok := strings.Cut(input, InputDelimeter); ok {
+		if _, err := os.Lstat(i.RelativePath(src)); err == nil {
+			return src, dest
+		}
+	}
+
+	return input, ""
+}
+
+// entries returns the files of all inputs indexed by their path within the
+// archive.  Each input is of the form `SRC[:DEST]` where DEST is the path
+// within the archive at which SRC is placed.  SRC is either a path on the host,
+// relative to the working directory, a Dockerfile which is built or the
+// reference of a container image which is pulled.  Later inputs take
+// precedence over earlier ones.  Intermediate files are stored in the scratch
+// directory.
+func (i *InitrdConfig) entries(ctx context.Context, scratch string, bopts *buildOptions) (map[string]entry, error) {
+	entries := map[string]entry{}
+
+	for _, input := range i.Input {
+		src, dest := i.splitInput(input)
+		file := i.RelativePath(src)
+
+		fi, err := os.Lstat(file)
+		if err != nil {
+			if _, perr := name.ParseReference(src); perr != nil {
+				return nil, fmt.Errorf("could not read initrd input: %v", err)
+			}
+
+			img, err := imageFromReference(ctx, src, bopts.arch)
+			if err != nil {
+				return nil, fmt.Errorf("could not read initrd input %s as path or image: %v", src, err)
+			}
+
+			if err := imageEntries(ctx, img, dest, scratch, entries); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if IsDockerfile(file) {
+			img, err := imageFromDockerfile(ctx, file, bopts.arch, scratch)
+			if err != nil {
+				return nil, err
+			}
+
+			if err := imageEntries(ctx, img, dest, scratch, entries); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
+		if !fi.IsDir() && len(dest) == 0 {
+			dest = filepath.Base(file)
+		}
+
+		err = filepath.WalkDir(file, func(walked string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			rel, err := filepath.Rel(file, walked)
+			if err != nil {
+				return err
+			}
+
+			name := archivePath(dest, filepath.ToSlash(rel))
+			if name == "" || name == "." {
+				return nil
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case info.Mode().IsRegular(), info.IsDir(), info.Mode()&fs.ModeSymlink != 0:
+			default:
+				log.G(ctx).Warnf("skipping unsupported file in initrd: %s", walked)
+				return nil
+			}
+
+			entries[name] = entry{
+				src:  walked,
+				mode: info.Mode(),
+			}
+
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Create any missing parent directories
+	for name := range entries {
+		for parent := path.Dir(name); parent != "." && parent != "/"; parent = path.Dir(parent) {
+			if _, ok := entries[parent]; !ok {
+				entries[parent] = entry{mode: fs.ModeDir | 0o755}
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+// Build creates the archive from the configured inputs and returns its path.
+// If no output is configured, the archive is created in the output directory,
+// or a temporary one.  The archive is reproducible: entries are sorted by name
+// and their ownership, modification time and inode numbers are normalized.
+func (i *InitrdConfig) Build(ctx context.Context, opts ...BuildOption) (string, error) {
+	if len(i.Input) == 0 {
+		return "", fmt.Errorf("no initrd inputs provided")
+	}
+
+	bopts := &buildOptions{
+		arch: runtime.GOARCH,
+	}
+	for _, opt := range opts {
+		opt(bopts)
+	}
+
+	scratch, err := os.MkdirTemp("", "kraftkit-initrd-*")
+	if err != nil {
+		return "", err
+	}
+
+	defer os.RemoveAll(scratch)
+
+	entries, err := i.entries(ctx, scratch, bopts)
+	if err != nil {
+		return "", err
+	}
+
+	var f *os.File
+	if len(i.Output) > 0 {
+		if err := os.MkdirAll(filepath.Dir(i.Output), 0o755); err != nil {
+			return "", err
+		}
+
+		f, err = os.Create(i.Output)
+	} else {
+		dir := i.OutDir
+		if len(dir) > 0 {
+			if err := os.MkdirAll(dir, 0o755); err != nil {
+				return "", err
+			}
+		}
+
+		f, err = os.CreateTemp(dir, "initramfs-*"+i.Extension())
+	}
+	if err != nil {
+		return "", fmt.Errorf("could not create initrd: %v", err)
+	}
+
+	defer f.Close()
+
+	writer, err := i.NewWriter(f)
+	if err != nil {
+		return "", err
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+
+	// Parent directories are always a prefix of their children and are
+	// therefore written first.
+	sort.Strings(names)
+
+	for _, name := range names {
+		e := entries[name]
+		hdr := &cpio.Header{
+			Name:    name,
+			Mode:    cpio.FileMode(e.mode.Perm()),
+			ModTime: time.Unix(0, 0),
+			Links:   1,
+		}
+
+		if e.mode&fs.ModeSetuid != 0 {
+			hdr.Mode |= cpio.ModeSetuid
+		}
+		if e.mode&fs.ModeSetgid != 0 {
+			hdr.Mode |= cpio.ModeSetgid
+		}
+		if e.mode&fs.ModeSticky != 0 {
+			hdr.Mode |= cpio.ModeSticky
+		}
+
+		switch {
+		case e.mode.IsDir():
+			hdr.Mode |= cpio.TypeDir
+			hdr.Links = 2
+
+		case e.mode&fs.ModeSymlink != 0:
+			hdr.Mode |= cpio.TypeSymlink
+			hdr.Linkname = e.link
+			if len(hdr.Linkname) == 0 {
+				hdr.Linkname, err = os.Readlink(e.src)
+				if err != nil {
+					return "", err
+				}
+			}
+
+		default:
+			hdr.Mode |= cpio.TypeReg
+			fi, err := os.Stat(e.src)
+			if err != nil {
+				return "", err
+			}
+			hdr.Size = fi.Size()
+		}
+
+		if err := writer.WriteHeader(hdr); err != nil {
+			return "", fmt.Errorf("could not write %s to initrd: %v", name, err)
+		}
+
+		if hdr.Mode&cpio.ModeType != cpio.TypeReg {
+			continue
+		}
+
+		src, err := os.Open(e.src)
+		if err != nil {
+			return "", err
+		}
+
+		_, err = io.CopyN(writer, src, hdr.Size)
+		src.Close()
+		if err != nil {
+			return "", fmt.Errorf("could not write %s to initrd: %v", name, err)
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	return f.Name(), nil
+}
@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package initrd
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pierrec/lz4/v4"
+)
+
+// Compression is the algorithm used to compress an initrd archive.
+type Compression string
+
+const (
+	CompressionNone Compression = ""
+	CompressionGzip Compression = "gzip"
+	CompressionLZ4  Compression = "lz4"
+	CompressionZstd Compression = "zstd"
+)
+
+// String implements fmt.Stringer
+func (c Compression) String() string {
+	if c == CompressionNone {
+		return "none"
+	}
+
+	return string(c)
+}
+
+// Extension returns the conventional file extension of the compression.
+func (c Compression) Extension() string {
+	switch c {
+	case CompressionGzip:
+		return ".gz"
+	case CompressionLZ4:
+		return ".lz4"
+	case CompressionZstd:
+		return ".zst"
+	}
+
+	return ""
+}
+
+// NameToCompression maps the accepted names of compression algorithms.
+var NameToCompression = map[string]Compression{
+	"":      CompressionNone,
+	"none":  CompressionNone,
+	"false": CompressionNone,
+	"gzip":  CompressionGzip,
+	"gz":    CompressionGzip,
+	"true":  CompressionGzip,
+	"lz4":   CompressionLZ4,
+	"zstd":  CompressionZstd,
+	"zst":   CompressionZstd,
+}
+
+// CompressionFromString returns the Compression matching the provided name.
+func CompressionFromString(name string) (Compression, error) {
+	if c, ok := NameToCompression[strings.ToLower(name)]; ok {
+		return c, nil
+	}
+
+	return CompressionNone, fmt.Errorf("unknown initrd compression: %s", name)
+}
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	lz4Magic  = []byte{0x04, 0x22, 0x4d, 0x18}
+	lz4Legacy = []byte{0x02, 0x21, 0x4c, 0x18}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// DetectCompression determines the compression of a stream from its leading
+// bytes.
+func DetectCompression(header []byte) Compression {
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return CompressionGzip
+	case bytes.HasPrefix(header, lz4Magic), bytes.HasPrefix(header, lz4Legacy):
+		return CompressionLZ4
+	case bytes.HasPrefix(header, zstdMagic):
+		return CompressionZstd
+	}
+
+	return CompressionNone
+}
+
+// nopWriteCloser wraps an io.Writer which does not need to be closed.
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// compressWriter wraps the provided writer such that everything written to it
+// is compressed with the provided algorithm.  The returned writer must be
+// closed to flush the compressed stream; the underlying writer is not closed.
+func compressWriter(w io.Writer, c Compression) (io.WriteCloser, error) {
+	switch c {
+	case CompressionNone:
+		return nopWriteCloser{w}, nil
+
+	case CompressionGzip:
+		// The header of the stream intentionally omits the modification time
+		// and name such that the output is reproducible.
+		return gzip.NewWriterLevel(w, gzip.BestCompression)
+
+	case CompressionLZ4:
+		// The legacy frame format is the one understood by kernels when
+		// decompressing an initramfs.
+		zw := lz4.NewWriter(w)
+		if err := zw.Apply(lz4.LegacyOption(true)); err != nil {
+			return nil, err
+		}
+		return zw, nil
+
+	case CompressionZstd:
+		// A single encoder goroutine guarantees identical output for identical
+		// input.
+		return zstd.NewWriter(w, zstd.WithEncoderConcurrency(1))
+	}
+
+	return nil, fmt.Errorf("unknown initrd compression: %s", c)
+}
+
+// decompressReader detects the compression of the provided stream and returns
+// a reader of its decompressed contents.  The returned reader must be closed to
+// release the decompressor, e.g. the goroutines of a zstd decoder, whereas the
+// provided stream is not closed.
+func decompressReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+
+	header, err := br.Peek(4)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch DetectCompression(header) {
+	case CompressionGzip:
+		return gzip.NewReader(br)
+	case CompressionLZ4:
+		return io.NopCloser(lz4.NewReader(br)), nil
+	case CompressionZstd:
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+
+		return zr.IOReadCloser(), nil
+	}
+
+	return io.NopCloser(br), nil
+}
@@ -0,0 +1,228 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package initrd
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/sirupsen/logrus"
+
+	"kraftkit.sh/config"
+	"kraftkit.sh/exec"
+	"kraftkit.sh/log"
+)
+
+// DockerBin is the binary used to build Dockerfiles.
+var DockerBin = "docker"
+
+// IsDockerfile returns whether the provided path is a Dockerfile, following
+// the conventional naming of `Dockerfile`, `Dockerfile.<name>` and
+// `<name>.Dockerfile`.
+func IsDockerfile(file string) bool {
+	fi, err := os.Stat(file)
+	if err != nil || fi.IsDir() {
+		return false
+	}
+
+	base := strings.ToLower(filepath.Base(file))
+
+	return base == "dockerfile" ||
+		strings.HasPrefix(base, "dockerfile.") ||
+		strings.HasSuffix(base, ".dockerfile")
+}
+
+// ociArchitecture converts the name of a Unikraft architecture to its OCI
+// equivalent.
+func ociArchitecture(arch string) string {
+	switch arch {
+	case "x86_64", "amd64":
+		return "amd64"
+	case "arm", "arm32":
+		return "arm"
+	}
+
+	return arch
+}
+
+// imageFromReference retrieves the image with the provided reference from its
+// registry, which is not contacted in offline mode.
+func imageFromReference(ctx context.Context, ref, arch string) (v1.Image, error) {
+	parsed, err := name.ParseReference(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Offline(ctx) {
+		return nil, config.OfflineError(parsed.String())
+	}
+
+	log.G(ctx).WithFields(logrus.Fields{
+		"ref":  parsed.String(),
+		"arch": arch,
+	}).Debug("pulling initrd image")
+
+	return remote.Image(parsed,
+		remote.WithContext(ctx),
+		remote.WithAuthFromKeychain(authn.DefaultKeychain),
+		remote.WithPlatform(v1.Platform{
+			OS:           "linux",
+			Architecture: ociArchitecture(arch),
+		}),
+	)
+}
+
+// imageFromDockerfile builds the provided Dockerfile, using its parent
+// directory as the build context, and returns the resulting image.  The image
+// is exported to the provided scratch directory.
+func imageFromDockerfile(ctx context.Context, dockerfile, arch, scratch string) (v1.Image, error) {
+	iidfile := filepath.Join(scratch, "iid")
+	imagetar := filepath.Join(scratch, "image.tar")
+	stdout := log.G(ctx).WriterLevel(logrus.DebugLevel)
+	defer stdout.Close()
+
+	build, err := exec.NewProcess(DockerBin, []string{
+		"build",
+		"--platform", "linux/" + ociArchitecture(arch),
+		"--iidfile", iidfile,
+		"--file", dockerfile,
+		filepath.Dir(dockerfile),
+	},
+		exec.WithStdout(stdout),
+		exec.WithStderr(stdout),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	log.G(ctx).WithFields(logrus.Fields{
+		"cmd": build.Cmdline(),
+	}).Debug("building initrd image")
+
+	if err := build.StartAndWait(ctx); err != nil {
+		return nil, fmt.Errorf("could not build %s: %v", dockerfile, err)
+	}
+
+	iid, err := os.ReadFile(iidfile)
+	if err != nil {
+		return nil, fmt.Errorf("could not determine built image: %v", err)
+	}
+
+	save, err := exec.NewProcess(DockerBin, []string{
+		"save",
+		"--output", imagetar,
+		strings.TrimSpace(string(iid)),
+	},
+		exec.WithStdout(stdout),
+		exec.WithStderr(stdout),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := save.StartAndWait(ctx); err != nil {
+		return nil, fmt.Errorf("could not export image built from %s: %v", dockerfile, err)
+	}
+
+	return tarball.ImageFromPath(imagetar, nil)
+}
+
+// imageEntries flattens the layers of the image, applying whiteouts, and adds
+// the resulting files to entries at the provided destination.  The contents of
+// regular files are extracted to the scratch directory.
+//
+// The layers are read from the top, such that the first occurrence of a path
+// is the one which is kept, and hard links, whose target may be found in a
+// lower layer, are only resolved once all files have been read.
+func imageEntries(ctx context.Context, img v1.Image, dest, scratch string, entries map[string]entry) error {
+	rc := mutate.Extract(img)
+	defer rc.Close()
+
+	dir, err := os.MkdirTemp(scratch, "rootfs-*")
+	if err != nil {
+		return err
+	}
+
+	tr := tar.NewReader(rc)
+
+	seen := map[string]bool{}
+	links := map[string]string{}
+
+	for n := 0; ; n++ {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("could not read image: %v", err)
+		}
+
+		name := archivePath(dest, hdr.Name)
+		if name == "" || name == "." || seen[name] {
+			continue
+		}
+
+		seen[name] = true
+
+		mode := hdr.FileInfo().Mode()
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			entries[name] = entry{mode: mode}
+
+		case tar.TypeSymlink:
+			entries[name] = entry{mode: mode, link: hdr.Linkname}
+
+		case tar.TypeLink:
+			links[name] = archivePath(dest, hdr.Linkname)
+
+		case tar.TypeReg:
+			// Files are extracted under a flat, generated name and readable by the
+			// current user regardless of their mode, which is preserved separately.
+			src := filepath.Join(dir, fmt.Sprintf("%d", n))
+
+			f, err := os.OpenFile(src, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+			if err != nil {
+				return err
+			}
+
+			_, err = io.Copy(f, tr)
+			f.Close()
+			if err != nil {
+				return fmt.Errorf("could not extract %s: %v", hdr.Name, err)
+			}
+
+			entries[name] = entry{src: src, mode: mode}
+
+		default:
+			log.G(ctx).Warnf("skipping unsupported file in initrd: %s", path.Clean(hdr.Name))
+		}
+	}
+
+	for name, target := range links {
+		// Links only refer to the files of the same image, not to those of
+		// previous inputs which the image may have replaced
+		linked, ok := entries[target]
+		if !ok || !seen[target] || linked.mode.IsDir() {
+			log.G(ctx).Warnf("skipping hard link to unknown file in initrd: %s", name)
+			continue
+		}
+
+		entries[name] = linked
+	}
+
+	return nil
+}
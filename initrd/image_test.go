@@ -0,0 +1,186 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package initrd
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/empty"
+	"github.com/google/go-containerregistry/pkg/v1/mutate"
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+
+	"kraftkit.sh/config"
+)
+
+func testLayer(t *testing.T, hdrs ...*tar.Header) v1.Layer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+
+	for _, hdr := range hdrs {
+		data := hdr.Linkname
+		if hdr.Typeflag == tar.TypeReg {
+			hdr.Size = int64(len(data))
+			hdr.Linkname = ""
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatal(err)
+		}
+
+		if hdr.Typeflag == tar.TypeReg {
+			if _, err := io.WriteString(tw, data); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	layer, err := tarball.LayerFromOpener(func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return layer
+}
+
+func TestImageEntries(t *testing.T) {
+	// Regular files carry their contents in Linkname for brevity
+	img, err := mutate.AppendLayers(empty.Image,
+		testLayer(t,
+			&tar.Header{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0o755},
+			&tar.Header{Name: "etc/hostname", Typeflag: tar.TypeReg, Mode: 0o644, Linkname: "alpine\n"},
+			&tar.Header{Name: "etc/passwd", Typeflag: tar.TypeReg, Mode: 0o644, Linkname: "root:x:0:0\n"},
+			&tar.Header{Name: "bin/busybox", Typeflag: tar.TypeReg, Mode: 0o755, Linkname: "busybox"},
+			&tar.Header{Name: "bin/sh", Typeflag: tar.TypeSymlink, Mode: 0o777, Linkname: "/bin/busybox"},
+		),
+		testLayer(t,
+			&tar.Header{Name: "etc/.wh.passwd", Typeflag: tar.TypeReg, Mode: 0o644},
+			&tar.Header{Name: "etc/hostname", Typeflag: tar.TypeReg, Mode: 0o600, Linkname: "unikraft\n"},
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := map[string]entry{}
+	if err := imageEntries(context.Background(), img, "/rootfs", t.TempDir(), entries); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := entries["rootfs/etc/passwd"]; ok {
+		t.Errorf("expected whited out file to be removed")
+	}
+
+	hostname, ok := entries["rootfs/etc/hostname"]
+	if !ok {
+		t.Fatalf("expected rootfs/etc/hostname in %v", entries)
+	}
+
+	if hostname.mode.Perm() != 0o600 {
+		t.Errorf("expected mode of upper layer but got %v", hostname.mode)
+	}
+
+	data, err := os.ReadFile(hostname.src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(data) != "unikraft\n" {
+		t.Errorf("expected contents of upper layer but got %q", data)
+	}
+
+	if sh := entries["rootfs/bin/sh"]; sh.link != "/bin/busybox" {
+		t.Errorf("unexpected target of bin/sh: %q", sh.link)
+	}
+}
+
+func TestImageEntriesHardLink(t *testing.T) {
+	// The layers are read from the top, such that the upper link precedes the
+	// file of the lower layer which it refers to
+	img, err := mutate.AppendLayers(empty.Image,
+		testLayer(t,
+			&tar.Header{Name: "bin/busybox", Typeflag: tar.TypeReg, Mode: 0o755, Linkname: "busybox"},
+		),
+		testLayer(t,
+			&tar.Header{Name: "bin/ls", Typeflag: tar.TypeLink, Linkname: "bin/busybox"},
+			&tar.Header{Name: "bin/cat", Typeflag: tar.TypeLink, Linkname: "bin/missing"},
+		),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Files of previous inputs must not be linked to
+	entries := map[string]entry{
+		"bin/missing": {src: "previous", mode: 0o644},
+	}
+
+	if err := imageEntries(context.Background(), img, "", t.TempDir(), entries); err != nil {
+		t.Fatal(err)
+	}
+
+	ls, ok := entries["bin/ls"]
+	if !ok {
+		t.Fatalf("expected bin/ls in %v", entries)
+	}
+
+	if busybox := entries["bin/busybox"]; ls != busybox {
+		t.Errorf("expected bin/ls to be %v but got %v", busybox, ls)
+	}
+
+	if _, ok := entries["bin/cat"]; ok {
+		t.Errorf("expected hard link to unknown file to be skipped")
+	}
+}
+
+func TestImageFromReferenceOffline(t *testing.T) {
+	ctx := config.WithOffline(context.Background(), true)
+
+	if _, err := imageFromReference(ctx, "unikraft.org/base:latest", "x86_64"); !errors.Is(err, config.ErrOffline) {
+		t.Errorf("expected offline error but got %v", err)
+	}
+}
+
+func TestSplitInput(t *testing.T) {
+	workdir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(workdir, "rootfs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	config := &InitrdConfig{WorkingDir: workdir}
+
+	for _, tc := range []struct {
+		input string
+		src   string
+		dest  string
+	}{
+		{"rootfs", "rootfs", ""},
+		{"rootfs:etc", "rootfs", "etc"},
+		{"rootfs:/etc", "rootfs", "/etc"},
+		{"alpine:3.18", "alpine:3.18", ""},
+		{"alpine:3.18:/rootfs", "alpine:3.18", "/rootfs"},
+		{"localhost:5000/app:latest", "localhost:5000/app:latest", ""},
+	} {
+		src, dest := config.splitInput(tc.input)
+		if src != tc.src || dest != tc.dest {
+			t.Errorf("%s: expected %q, %q but got %q, %q", tc.input, tc.src, tc.dest, src, dest)
+		}
+	}
+}
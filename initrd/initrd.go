@@ -32,6 +32,7 @@
 package initrd
 
 import (
+	"archive/tar"
 	"fmt"
 	"io"
 	"os"
@@ -69,35 +70,90 @@ type InitrdConfig struct {
 	Output     string       `yaml:",omitempty" json:"output,omitempty"`
 	Input      []string     `yaml:",omitempty" json:"input,omitempty"`
 	Format     InitrdFormat `yaml:",omitempty" json:"format,omitempty"`
-	Compress   bool         `yaml:",omitempty" json:"compress,omitempty"`
+	Compress   Compression  `yaml:",omitempty" json:"compress,omitempty"`
 	WorkingDir string
 	OutDir     string
 }
 
-func (i *InitrdConfig) NewWriter(w io.Writer) (*cpio.Writer, error) {
+// compressedWriter closes the archive before flushing the compressed stream.
+type compressedWriter struct {
+	Writer
+	compressor io.WriteCloser
+}
+
+func (cw compressedWriter) Close() error {
+	if err := cw.Writer.Close(); err != nil {
+		return err
+	}
+
+	return cw.compressor.Close()
+}
+
+// NewWriter returns a Writer which writes an archive in the configured format
+// and compression to w.  The returned Writer must be closed to write the
+// archive trailer and flush the compressed stream.
+func (i *InitrdConfig) NewWriter(w io.Writer) (Writer, error) {
+	compressor, err := compressWriter(w, i.Compress)
+	if err != nil {
+		return nil, err
+	}
+
+	var writer Writer
+
 	switch i.Format {
 	case ODC:
-		return nil, fmt.Errorf("unsupported CPIO format: odc")
+		writer = newODCWriter(compressor)
 	case NEWC:
-		return cpio.NewWriter(w), nil
+		writer = newcWriter{cpio.NewWriter(compressor)}
 	case USTAR:
-		return nil, fmt.Errorf("unsupported CPIO format: ustar")
+		writer = ustarWriter{tar.NewWriter(compressor)}
 	default:
 		return nil, fmt.Errorf("unknown CPIO format")
 	}
+
+	return compressedWriter{
+		Writer:     writer,
+		compressor: compressor,
+	}, nil
+}
+
+// decompressedReader releases the decompressor of the archive once closed.
+type decompressedReader struct {
+	archiveReader
+	decompressor io.Closer
+}
+
+func (dr decompressedReader) Close() error {
+	return dr.decompressor.Close()
 }
 
-func (i *InitrdConfig) NewReader(w io.Reader) (*cpio.Reader, error) {
+// NewReader returns a Reader of an archive in the configured format from r.
+// The compression of the archive, if any, is detected automatically.  The
+// returned Reader must be closed to release the decompressor.
+func (i *InitrdConfig) NewReader(r io.Reader) (Reader, error) {
+	decompressor, err := decompressReader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var reader archiveReader
+
 	switch i.Format {
 	case ODC:
-		return nil, fmt.Errorf("unsupported CPIO format: odc")
+		reader = newODCReader(decompressor)
 	case NEWC:
-		return cpio.NewReader(w), nil
+		reader = cpio.NewReader(decompressor)
 	case USTAR:
-		return nil, fmt.Errorf("unsupported CPIO format: ustar")
+		reader = ustarReader{tar.NewReader(decompressor)}
 	default:
+		decompressor.Close()
 		return nil, fmt.Errorf("unknown CPIO format")
 	}
+
+	return decompressedReader{
+		archiveReader: reader,
+		decompressor:  decompressor,
+	}, nil
 }
 
 // RelativePath resolve a relative path based the working directory
@@ -114,10 +170,48 @@ func (i *InitrdConfig) RelativePath(path string) string {
 	return filepath.Join(i.WorkingDir, path)
 }
 
-// ParseInitrdConfig parse short syntax for architecture configuration
+// Extension returns the conventional file extension of an archive in the
+// configured format and compression.
+func (i *InitrdConfig) Extension() string {
+	ext := ".cpio"
+	if i.Format == USTAR {
+		ext = ".tar"
+	}
+
+	return ext + i.Compress.Extension()
+}
+
+// formatFromPath determines the format and compression of an archive from the
+// extension of its path, e.g. `initramfs.odc.zst`.
+func formatFromPath(path string) (InitrdFormat, Compression, bool) {
+	compress := CompressionNone
+	ext := strings.ToLower(filepath.Ext(path))
+
+	if c, err := CompressionFromString(strings.TrimPrefix(ext, ".")); err == nil && len(ext) > 0 && c != CompressionNone {
+		compress = c
+		path = strings.TrimSuffix(path, filepath.Ext(path))
+		ext = strings.ToLower(filepath.Ext(path))
+	}
+
+	switch ext {
+	case ".cpio", ".newc":
+		return NEWC, compress, true
+	case ".odc":
+		return ODC, compress, true
+	case ".tar", ".ustar":
+		return USTAR, compress, true
+	}
+
+	return NEWC, compress, false
+}
+
+// ParseInitrdConfig parse short syntax for initrd configuration.  The format
+// and compression of the archive are derived from the extension of the output,
+// if provided.
 func ParseInitrdConfig(workdir string, value string) (*InitrdConfig, error) {
 	initrd := &InitrdConfig{
-		Format: NEWC,
+		Format:     NEWC,
+		WorkingDir: workdir,
 	}
 
 	if len(value) == 0 {
@@ -127,21 +221,26 @@ func ParseInitrdConfig(workdir string, value string) (*InitrdConfig, error) {
 	// Possible formats:
 	//
 	// ./path/on/disk (all files, we'll create a temp file)
-	// ./path/on/disk:./filename.format (if format=cpio, select default)
-	// ./path/on/diisk:./filename.formatz (-z suffix means compress)
+	// ./path/on/disk:./filename.cpio (format derived from the extension)
+	// ./path/on/disk:./filename.odc.gz (compression derived from the extension)
+	// ./Dockerfile:./filename.cpio (built with Docker)
+	// alpine:3.18 (pulled from its registry)
+	// alpine:3.18:./filename.cpio
+	//
+	// As image references may themselves contain the delimiter, the value is
+	// only split if the source exists on disk or the output is an archive.
 
-	split := strings.Split(value, ":")
-	if len(split) == 1 {
-		initrd.Input = []string{
-			fmt.Sprintf("%s:%s", workdir, split[0]),
-		}
-	} else if len(split) == 2 {
-		initrd.Input = []string{
-			fmt.Sprintf("%s:%s", workdir, split[0]),
+	initrd.Input = []string{value}
+
+	if idx := strings.LastIndex(value, InputDelimeter); idx >= 0 {
+		src, output := value[:idx], value[idx+1:]
+		_, _, isArchive := formatFromPath(output)
+
+		if _, err := os.Stat(initrd.RelativePath(src)); err == nil || isArchive {
+			initrd.Input = []string{src}
+			initrd.Output = initrd.RelativePath(output)
+			initrd.Format, initrd.Compress, _ = formatFromPath(initrd.Output)
 		}
-		initrd.Output = initrd.RelativePath(split[1])
-	} else if len(split) > 2 {
-		return initrd, fmt.Errorf("unexpected initrd format, expected path:file expression")
 	}
 
 	return initrd, nil
@@ -0,0 +1,203 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package initrd
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cavaliergopher/cpio"
+)
+
+func testRootfs(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "etc"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "etc", "hostname"), []byte("unikraft\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "init"), []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("init", filepath.Join(dir, "sbin-init")); err != nil {
+		t.Fatal(err)
+	}
+
+	return dir
+}
+
+func TestBuildRoundTrip(t *testing.T) {
+	rootfs := testRootfs(t)
+
+	for _, format := range []InitrdFormat{NEWC, ODC, USTAR} {
+		for _, compress := range []Compression{CompressionNone, CompressionGzip, CompressionLZ4, CompressionZstd} {
+			t.Run(format.String()+"/"+compress.String(), func(t *testing.T) {
+				config := &InitrdConfig{
+					Input:    []string{rootfs},
+					Format:   format,
+					Compress: compress,
+					Output:   filepath.Join(t.TempDir(), "initramfs"+compress.Extension()),
+				}
+
+				path, err := config.Build(context.Background())
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				f, err := os.Open(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer f.Close()
+
+				reader, err := config.NewReader(f)
+				if err != nil {
+					t.Fatal(err)
+				}
+				defer reader.Close()
+
+				var names []string
+				contents := map[string]string{}
+				links := map[string]string{}
+
+				for {
+					hdr, err := reader.Next()
+					if err == io.EOF {
+						break
+					} else if err != nil {
+						t.Fatal(err)
+					}
+
+					names = append(names, hdr.Name)
+
+					if hdr.Uid != 0 || hdr.Guid != 0 || !hdr.ModTime.Equal(time.Unix(0, 0)) {
+						t.Errorf("%s: expected normalized ownership and mtime", hdr.Name)
+					}
+
+					switch hdr.Mode & cpio.ModeType {
+					case cpio.TypeReg:
+						data, err := io.ReadAll(reader)
+						if err != nil {
+							t.Fatal(err)
+						}
+						contents[hdr.Name] = string(data)
+					case cpio.TypeSymlink:
+						links[hdr.Name] = hdr.Linkname
+					}
+				}
+
+				expected := []string{"etc", "etc/hostname", "init", "sbin-init"}
+				if len(names) != len(expected) {
+					t.Fatalf("expected entries %v but got %v", expected, names)
+				}
+				for i := range expected {
+					if names[i] != expected[i] {
+						t.Fatalf("expected entries %v but got %v", expected, names)
+					}
+				}
+
+				if contents["etc/hostname"] != "unikraft\n" {
+					t.Errorf("unexpected contents of etc/hostname: %q", contents["etc/hostname"])
+				}
+
+				if links["sbin-init"] != "init" {
+					t.Errorf("unexpected target of sbin-init: %q", links["sbin-init"])
+				}
+			})
+		}
+	}
+}
+
+func TestBuildDeterministic(t *testing.T) {
+	rootfs := testRootfs(t)
+
+	var previous []byte
+
+	for i := 0; i < 2; i++ {
+		// Alter the modification times between builds
+		now := time.Now().Add(time.Duration(i) * time.Hour)
+		if err := os.Chtimes(filepath.Join(rootfs, "init"), now, now); err != nil {
+			t.Fatal(err)
+		}
+
+		config := &InitrdConfig{
+			Input:    []string{rootfs + ":/rootfs"},
+			Format:   NEWC,
+			Compress: CompressionZstd,
+			OutDir:   t.TempDir(),
+		}
+
+		path, err := config.Build(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if previous != nil && !bytes.Equal(previous, data) {
+			t.Errorf("expected identical archives across builds")
+		}
+
+		previous = data
+	}
+}
+
+func TestParseInitrdConfig(t *testing.T) {
+	config, err := ParseInitrdConfig("/work", "./rootfs:./out/initramfs.odc.lz4")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Format != ODC || config.Compress != CompressionLZ4 {
+		t.Errorf("expected odc/lz4 but got %s/%s", config.Format, config.Compress)
+	}
+
+	if config.Output != filepath.Join("/work", "out", "initramfs.odc.lz4") {
+		t.Errorf("unexpected output: %s", config.Output)
+	}
+}
+
+func TestODCSymlinkSize(t *testing.T) {
+	for _, test := range []struct {
+		size int
+		ok   bool
+	}{
+		{odcMaxLinkSize, true},
+		{odcMaxLinkSize + 1, false},
+	} {
+		var buf bytes.Buffer
+
+		w := newODCWriter(&buf)
+		if err := w.WriteHeader(&cpio.Header{
+			Name:     "link",
+			Mode:     cpio.TypeSymlink | 0o777,
+			Linkname: string(bytes.Repeat([]byte("a"), test.size)),
+		}); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		hdr, err := newODCReader(&buf).Next()
+		if test.ok && (err != nil || len(hdr.Linkname) != test.size) {
+			t.Errorf("expected a link of %d bytes to be read: %v", test.size, err)
+		} else if !test.ok && err == nil {
+			t.Errorf("expected a link of %d bytes to be rejected", test.size)
+		}
+	}
+}
@@ -0,0 +1,294 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package initrd
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/cavaliergopher/cpio"
+)
+
+// The odc format, also known as the POSIX.1 portable format, uses a fixed size
+// header of octal-encoded ASCII fields followed by the NUL-terminated name of
+// the entry and its contents, without any padding.
+const (
+	odcMagic      = "070707"
+	odcHeaderSize = 76
+	odcTrailer    = "TRAILER!!!"
+
+	odcMaxInode    = 0o777777
+	odcMaxNameSize = 0o777777
+
+	// odcMaxLinkSize is the maximum size of the target of a symbolic link,
+	// i.e. PATH_MAX, which is read in full when reading its header.
+	odcMaxLinkSize = 4096
+)
+
+// odcWriter writes an archive in the odc format.
+type odcWriter struct {
+	w      io.Writer
+	inode  int64
+	remain int64
+	closed bool
+}
+
+func newODCWriter(w io.Writer) *odcWriter {
+	return &odcWriter{w: w}
+}
+
+// writeOctal writes i as a zero-padded octal number filling b.
+func writeOctal(b []byte, i int64) error {
+	s := strconv.FormatInt(i, 8)
+	if len(s) > len(b) {
+		return fmt.Errorf("value %d too large for odc header field", i)
+	}
+
+	for j := range b {
+		b[j] = '0'
+	}
+
+	copy(b[len(b)-len(s):], s)
+
+	return nil
+}
+
+// WriteHeader implements Writer.
+func (w *odcWriter) WriteHeader(hdr *cpio.Header) error {
+	if w.closed {
+		return cpio.ErrWriteAfterClose
+	}
+
+	if w.remain > 0 {
+		return fmt.Errorf("missing %d bytes of previous odc entry", w.remain)
+	}
+
+	mode := hdr.Mode
+	if mode&^cpio.ModePerm == 0 {
+		mode |= cpio.TypeReg
+	}
+
+	// The target of a symbolic link is stored as the contents of the entry
+	size := hdr.Size
+	var data []byte
+	if mode&cpio.ModeType == cpio.TypeSymlink {
+		data = []byte(hdr.Linkname)
+		size = int64(len(data))
+	} else if mode&cpio.ModeType != cpio.TypeReg {
+		size = 0
+	}
+
+	inode := hdr.Inode
+	if hdr.Name != odcTrailer && inode == 0 {
+		w.inode++
+		inode = w.inode
+	}
+
+	links := int64(hdr.Links)
+	if links < 1 {
+		links = 1
+	}
+
+	if hdr.Name == odcTrailer {
+		links = 1
+		inode = 0
+	}
+
+	var mtime int64
+	if !hdr.ModTime.IsZero() {
+		mtime = hdr.ModTime.Unix()
+	}
+
+	var buf [odcHeaderSize]byte
+	copy(buf[0:6], odcMagic)
+
+	for _, field := range []struct {
+		b []byte
+		v int64
+	}{
+		{buf[6:12], int64(hdr.DeviceID)},
+		{buf[12:18], inode & odcMaxInode},
+		{buf[18:24], int64(mode)},
+		{buf[24:30], int64(hdr.Uid)},
+		{buf[30:36], int64(hdr.Guid)},
+		{buf[36:42], links},
+		{buf[42:48], 0}, // rdev
+		{buf[48:59], mtime},
+		{buf[59:65], int64(len(hdr.Name) + 1)},
+		{buf[65:76], size},
+	} {
+		if err := writeOctal(field.b, field.v); err != nil {
+			return fmt.Errorf("could not write header of %s: %v", hdr.Name, err)
+		}
+	}
+
+	if _, err := w.w.Write(buf[:]); err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w.w, hdr.Name+"\x00"); err != nil {
+		return err
+	}
+
+	if data != nil {
+		_, err := w.w.Write(data)
+		return err
+	}
+
+	w.remain = size
+
+	return nil
+}
+
+// Write implements Writer.
+func (w *odcWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, cpio.ErrWriteAfterClose
+	}
+
+	if int64(len(p)) > w.remain {
+		n, err := w.w.Write(p[:w.remain])
+		w.remain -= int64(n)
+		if err != nil {
+			return n, err
+		}
+
+		return n, cpio.ErrWriteTooLong
+	}
+
+	n, err := w.w.Write(p)
+	w.remain -= int64(n)
+
+	return n, err
+}
+
+// Close implements Writer.
+func (w *odcWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+
+	if err := w.WriteHeader(&cpio.Header{
+		Name: odcTrailer,
+		Mode: cpio.TypeReg,
+	}); err != nil {
+		return err
+	}
+
+	w.closed = true
+
+	return nil
+}
+
+// odcReader reads an archive in the odc format.
+type odcReader struct {
+	r      io.Reader
+	remain int64
+}
+
+func newODCReader(r io.Reader) *odcReader {
+	return &odcReader{r: r}
+}
+
+// readOctal parses the octal number in b.
+func readOctal(b []byte) (int64, error) {
+	return strconv.ParseInt(string(b), 8, 64)
+}
+
+// Next implements Reader.
+func (r *odcReader) Next() (*cpio.Header, error) {
+	// Skip the unread contents of the previous entry
+	if r.remain > 0 {
+		if _, err := io.CopyN(io.Discard, r.r, r.remain); err != nil {
+			return nil, err
+		}
+
+		r.remain = 0
+	}
+
+	var buf [odcHeaderSize]byte
+	if _, err := io.ReadFull(r.r, buf[:]); err != nil {
+		return nil, err
+	}
+
+	if string(buf[0:6]) != odcMagic {
+		return nil, cpio.ErrHeader
+	}
+
+	var fields [10]int64
+	for i, field := range [][]byte{
+		buf[6:12], buf[12:18], buf[18:24], buf[24:30], buf[30:36],
+		buf[36:42], buf[42:48], buf[48:59], buf[59:65], buf[65:76],
+	} {
+		v, err := readOctal(field)
+		if err != nil {
+			return nil, cpio.ErrHeader
+		}
+
+		fields[i] = v
+	}
+
+	nameSize := fields[8]
+	if nameSize < 1 || nameSize > odcMaxNameSize {
+		return nil, cpio.ErrHeader
+	}
+
+	name := make([]byte, nameSize)
+	if _, err := io.ReadFull(r.r, name); err != nil {
+		return nil, err
+	}
+
+	hdr := &cpio.Header{
+		DeviceID: int(fields[0]),
+		Inode:    fields[1],
+		Mode:     cpio.FileMode(fields[2]),
+		Uid:      int(fields[3]),
+		Guid:     int(fields[4]),
+		Links:    int(fields[5]),
+		ModTime:  time.Unix(fields[7], 0),
+		Name:     string(name[:nameSize-1]),
+		Size:     fields[9],
+	}
+
+	if hdr.Name == odcTrailer {
+		return nil, io.EOF
+	}
+
+	if hdr.Mode&cpio.ModeType == cpio.TypeSymlink {
+		if hdr.Size < 0 || hdr.Size > odcMaxLinkSize {
+			return nil, fmt.Errorf("target of symbolic link %s exceeds %d bytes", hdr.Name, odcMaxLinkSize)
+		}
+
+		link := make([]byte, hdr.Size)
+		if _, err := io.ReadFull(r.r, link); err != nil {
+			return nil, err
+		}
+
+		hdr.Linkname = string(link)
+		hdr.Size = 0
+	}
+
+	r.remain = hdr.Size
+
+	return hdr, nil
+}
+
+// Read implements Reader.
+func (r *odcReader) Read(p []byte) (int, error) {
+	if r.remain <= 0 {
+		return 0, io.EOF
+	}
+
+	if int64(len(p)) > r.remain {
+		p = p[:r.remain]
+	}
+
+	n, err := r.r.Read(p)
+	r.remain -= int64(n)
+
+	return n, err
+}
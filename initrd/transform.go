@@ -0,0 +1,129 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package initrd
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"kraftkit.sh/unikraft"
+)
+
+// TransformFromSchema parses an input schema and returns an instantiated
+// InitrdConfig
+func TransformFromSchema(ctx context.Context, data interface{}) (interface{}, error) {
+	uk := unikraft.FromContext(ctx)
+	workdir := ""
+	if uk != nil {
+		workdir = uk.UK_BASE
+	}
+
+	switch value := data.(type) {
+	case string:
+		initrd, err := ParseInitrdConfig(workdir, value)
+		if err != nil {
+			return nil, err
+		}
+
+		if uk != nil {
+			initrd.OutDir = uk.BUILD_DIR
+		}
+
+		return *initrd, nil
+
+	case InitrdConfig:
+		return value, nil
+
+	case map[string]interface{}:
+		initrd := InitrdConfig{
+			Format:     NEWC,
+			WorkingDir: workdir,
+		}
+
+		if uk != nil {
+			initrd.OutDir = uk.BUILD_DIR
+		}
+
+		explicitFormat := false
+		explicitCompress := false
+
+		for key, prop := range value {
+			switch key {
+			case "output":
+				initrd.Output = initrd.RelativePath(fmt.Sprintf("%v", prop))
+
+			case "format":
+				switch format := prop.(type) {
+				case InitrdFormat:
+					initrd.Format = format
+				case string:
+					typ, ok := NameToType[format]
+					if !ok {
+						return nil, fmt.Errorf("invalid option for initrd type: %s", format)
+					}
+					initrd.Format = typ
+				default:
+					return nil, fmt.Errorf("invalid type %T for initrd format", prop)
+				}
+
+				explicitFormat = true
+
+			case "compress":
+				compress, err := CompressionFromString(fmt.Sprintf("%v", prop))
+				if err != nil {
+					return nil, err
+				}
+
+				initrd.Compress = compress
+				explicitCompress = true
+
+			case "input":
+				switch input := prop.(type) {
+				case string:
+					initrd.Input = []string{input}
+				case []string:
+					initrd.Input = input
+				case []interface{}:
+					for _, i := range input {
+						initrd.Input = append(initrd.Input, fmt.Sprintf("%v", i))
+					}
+				case map[string]interface{}:
+					// Sort the sources as later inputs take precedence
+					srcs := make([]string, 0, len(input))
+					for src := range input {
+						srcs = append(srcs, src)
+					}
+					sort.Strings(srcs)
+
+					for _, src := range srcs {
+						if input[src] == nil {
+							initrd.Input = append(initrd.Input, src)
+						} else {
+							initrd.Input = append(initrd.Input, fmt.Sprintf("%s%s%v", src, InputDelimeter, input[src]))
+						}
+					}
+				default:
+					return nil, fmt.Errorf("invalid type %T for initrd input", prop)
+				}
+			}
+		}
+
+		// Derive unspecified attributes from the extension of the output
+		if len(initrd.Output) > 0 {
+			format, compress, ok := formatFromPath(initrd.Output)
+			if !explicitFormat && ok {
+				initrd.Format = format
+			}
+			if !explicitCompress {
+				initrd.Compress = compress
+			}
+		}
+
+		return initrd, nil
+	}
+
+	return nil, fmt.Errorf("invalid type %T for initrd", data)
+}
@@ -0,0 +1,258 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"kraftkit.sh/config"
+	"kraftkit.sh/kconfig"
+	"kraftkit.sh/log"
+	"kraftkit.sh/unikraft/app"
+	"kraftkit.sh/unikraft/target"
+)
+
+const (
+	// KConfigToKraftfile writes KConfig options to the top-level `kconfig`
+	// section of the project's Kraftfile.
+	KConfigToKraftfile = "kraftfile"
+
+	// KConfigToDotConfig writes KConfig options to the `.config` file of a
+	// target which has already been configured.
+	KConfigToDotConfig = "dotconfig"
+)
+
+// UpdateKConfig type-checks the provided KConfig options against the merged
+// KConfig tree of the project and writes them, along with the options which
+// are minimally needed to meet their dependencies, to the provided destination.
+// Options are evaluated for every provided target, and a change whose value is
+// kconfig.No unsets the option.  When noDeps is set, options whose
+// dependencies are not yet met are rejected instead.
+func UpdateKConfig(ctx context.Context, project app.Application, targets []target.Target, to string, noDeps bool, changes ...*kconfig.KeyValue) error {
+	trees := project.KConfigTrees(ctx)
+	if len(trees) == 0 {
+		return fmt.Errorf("could not parse the configuration options of the project, have its components been pulled?")
+	}
+
+	tree := kconfig.Merge("", trees...)
+
+	var path string
+	var dotconfig *kconfig.DotConfigFile
+
+	// The values which are already set for each target
+	values := map[string]kconfig.KeyValueMap{}
+
+	switch to {
+	case KConfigToKraftfile:
+		if len(project.Kraftfiles()) == 0 {
+			return fmt.Errorf("project does not have a Kraftfile")
+		}
+
+		path = project.Kraftfiles()[0]
+
+		for _, t := range targets {
+			values[t.Name()] = prefixed(project.KConfig(), t.KConfig())
+		}
+
+	case KConfigToDotConfig:
+		if len(targets) != 1 {
+			return fmt.Errorf("the .config of a single target can be updated at a time")
+		}
+
+		t := targets[0]
+		if !project.IsConfigured(t) {
+			return fmt.Errorf("target %s has not been configured yet", t.Name())
+		}
+
+		var err error
+
+		path = filepath.Join(project.WorkingDir(), t.ConfigFilename())
+		dotconfig, err = kconfig.ParseConfig(path)
+		if err != nil {
+			return err
+		}
+
+		current := kconfig.KeyValueMap{}
+		for _, kv := range dotconfig.Slice {
+			value := kv.Value
+			if value == kconfig.No {
+				value = "n"
+			}
+
+			current.Set(kconfig.Prefix+kv.Key, value)
+		}
+
+		values[t.Name()] = current
+
+	default:
+		return fmt.Errorf("unknown destination %s, expected %s or %s", to, KConfigToKraftfile, KConfigToDotConfig)
+	}
+
+	set := kconfig.KeyValueMap{}
+	removed := map[string]bool{}
+
+	for _, change := range changes {
+		m, err := tree.Lookup(change.Key)
+		if err != nil {
+			return err
+		}
+
+		key := kconfig.Prefix + m.Name
+
+		// Options which are not bool or tristate are unset by removing them, such
+		// that their default applies.
+		if change.Value == kconfig.No && m.Type != kconfig.TypeBool && m.Type != kconfig.TypeTristate {
+			for _, t := range targets {
+				values[t.Name()].Unset(key)
+			}
+
+			set.Unset(key)
+			removed[key] = true
+
+			log.G(ctx).Infof("unset %s", key)
+			continue
+		}
+
+		value := change.Value
+		if value == kconfig.No {
+			value = "n"
+		}
+
+		deps := kconfig.KeyValueMap{}
+
+		for _, t := range targets {
+			assigned, err := tree.Assign(values[t.Name()], key, value)
+			if err != nil && len(targets) > 1 {
+				return fmt.Errorf("%s: %w", t.Name(), err)
+			} else if err != nil {
+				return err
+			}
+
+			if noDeps && len(assigned) > 1 {
+				var required []string
+				for _, name := range sortedKeys(assigned) {
+					if name != key {
+						required = append(required, assigned[name].String())
+					}
+				}
+
+				return fmt.Errorf("%s also requires setting %s", key, strings.Join(required, ", "))
+			}
+
+			values[t.Name()].OverrideBy(assigned)
+			set.OverrideBy(assigned)
+			deps.OverrideBy(assigned)
+		}
+
+		for name := range deps {
+			delete(removed, name)
+		}
+
+		log.G(ctx).Infof("set %s=%s", key, set[key].Value)
+
+		for _, name := range sortedKeys(deps) {
+			if name != key {
+				log.G(ctx).WithField("required by", key).Infof("set %s=%s", name, deps[name].Value)
+			}
+		}
+	}
+
+	switch to {
+	case KConfigToKraftfile:
+		// The options of a target take precedence over those at the top-level.
+		for _, t := range targets {
+			own := prefixed(t.KConfig())
+			for _, name := range sortedKeys(set) {
+				if kv, ok := own[name]; ok && kv.Value != set[name].Value {
+					log.G(ctx).Warnf("%s is also set to %s by target %s, which takes precedence", name, kv.Value, t.Name())
+				}
+			}
+		}
+
+		if err := app.UpdateKConfigInKraftfile(path, set, sortedKeys(removed)); err != nil {
+			return err
+		}
+
+	case KConfigToDotConfig:
+		for _, name := range sortedKeys(set) {
+			value := set[name].Value
+			if value == "n" {
+				value = kconfig.No
+			}
+
+			dotconfig.Set(strings.TrimPrefix(name, kconfig.Prefix), value)
+		}
+
+		for name := range removed {
+			dotconfig.Unset(strings.TrimPrefix(name, kconfig.Prefix))
+		}
+
+		if err := os.WriteFile(path, dotconfig.Serialize(), 0o644); err != nil {
+			return err
+		}
+	}
+
+	log.G(ctx).WithField("path", path).Info("updated configuration")
+
+	return nil
+}
+
+// KConfigTargets returns the targets which KConfig options written to the
+// provided destination are checked against.  A .config belongs to a single
+// target, which the user is prompted for if needed.
+func KConfigTargets(ctx context.Context, targets target.Targets, to string) ([]target.Target, error) {
+	switch {
+	case len(targets) == 0:
+		return nil, fmt.Errorf("no matching targets")
+
+	case to != KConfigToDotConfig || len(targets) == 1:
+		selected := make([]target.Target, len(targets))
+		for i, t := range targets {
+			selected[i] = t
+		}
+
+		return selected, nil
+
+	case config.G[config.KraftKit](ctx).NoPrompt:
+		return nil, fmt.Errorf("could not determine which target to update")
+	}
+
+	t, err := SelectTarget(targets)
+	if err != nil {
+		return nil, err
+	}
+
+	return []target.Target{t}, nil
+}
+
+// prefixed merges the values such that all keys include the CONFIG_ prefix.
+func prefixed(all ...kconfig.KeyValueMap) kconfig.KeyValueMap {
+	values := kconfig.KeyValueMap{}
+
+	for _, kvm := range all {
+		for key, kv := range kvm {
+			if kv != nil {
+				values.Set(kconfig.Prefix+strings.TrimPrefix(key, kconfig.Prefix), kv.Value)
+			}
+		}
+	}
+
+	return values
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,82 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package fsutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestChecksum(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(path, []byte("unikraft"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	sum, err := Checksum(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// echo -n unikraft | sha256sum
+	expected := "df5c1978aa5530d8edf411f5091c904386858b8cd93ee5d3bc388f450ce12997"
+	if sum != expected {
+		t.Errorf("expected %s, got %s", expected, sum)
+	}
+}
+
+func TestCopyFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "kernel")
+	dest := filepath.Join(dir, "out", "kernel")
+	modified := time.Now().Add(-time.Hour).Truncate(time.Second)
+
+	if err := os.WriteFile(src, []byte("unikraft"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.Chtimes(src, modified, modified); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := CopyFile(src, dest); err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(contents) != "unikraft" {
+		t.Errorf("expected the contents to be copied, got %s", contents)
+	}
+
+	info, err := os.Stat(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if info.Mode().Perm() != 0o755 {
+		t.Errorf("expected the mode 0755 to be preserved, got %o", info.Mode().Perm())
+	}
+
+	if !info.ModTime().Equal(modified) {
+		t.Errorf("expected the modification time %s to be preserved, got %s", modified, info.ModTime())
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(dest))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 1 {
+		t.Errorf("expected only the copy, got %d entries", len(entries))
+	}
+}
@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"strings"
 
+	"kraftkit.sh/config"
 	"kraftkit.sh/internal/version"
 	"kraftkit.sh/iostreams"
 
@@ -27,6 +28,10 @@ func Check(ctx context.Context) error {
 		return nil
 	}
 
+	if config.Offline(ctx) {
+		return config.OfflineError(KraftKitLatestPath)
+	}
+
 	client := &http.Client{}
 
 	get, err := http.NewRequest("GET", KraftKitLatestPath, nil)
@@ -0,0 +1,356 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2022 Unikraft GmbH. All rights reserved.
+
+package kconfig
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Lookup returns the config with the provided name, with or without the
+// CONFIG_ prefix.  If it is not defined, the error suggests similarly named
+// configs.
+func (kconf *KConfigFile) Lookup(symbol string) (*KConfigMenu, error) {
+	name := strings.TrimPrefix(symbol, Prefix)
+	if m, ok := kconf.Configs[name]; ok && len(name) > 0 && m.Type > 0 {
+		return m, nil
+	}
+
+	var suggestions []string
+	for _, candidate := range sortedNames(kconf.Configs) {
+		if len(candidate) > 0 && distance(name, candidate) <= 2 {
+			suggestions = append(suggestions, Prefix+candidate)
+		}
+	}
+
+	if len(suggestions) > 0 {
+		return nil, fmt.Errorf("%s%s is not defined by any component, did you mean %s?", Prefix, name, strings.Join(suggestions, " or "))
+	}
+
+	return nil, fmt.Errorf("%s%s is not defined by any component", Prefix, name)
+}
+
+// Range returns the bounds of an int or hex config whose condition is met, if
+// it has any.
+func (ev *Evaluator) Range(m *KConfigMenu) (string, string, bool) {
+	for _, r := range m.ranges {
+		if r.cond != nil && ev.eval(r.cond) == TristateNo {
+			continue
+		}
+
+		return ev.symbol(r.min), ev.symbol(r.max), true
+	}
+
+	return "", "", false
+}
+
+// Assign checks the value of the config with the provided name against its
+// type and range given the values which are already set, e.g. those of a
+// Kraftfile, and returns what must additionally be set for the value to take
+// effect: the value itself, as it is written in a .config, and the minimal set
+// of values which meet the dependencies of the config.  Keys of the values and
+// of the result include the CONFIG_ prefix.  Bool and tristate configs also
+// accept `true`, `yes`, `false` and `no`.
+func (kconf *KConfigFile) Assign(values KeyValueMap, symbol, value string) (KeyValueMap, error) {
+	m, err := kconf.Lookup(symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &assigner{
+		kconf:    kconf,
+		values:   KeyValueMap{},
+		assigned: KeyValueMap{},
+		visiting: map[string]bool{},
+	}
+
+	for key, kv := range values {
+		if kv != nil {
+			a.values.Set(Prefix+strings.TrimPrefix(key, Prefix), kv.Value)
+		}
+	}
+
+	if err := a.assign(m, value); err != nil {
+		return nil, err
+	}
+
+	return a.assigned, nil
+}
+
+// assigner accumulates the values which are needed for an assignment to take
+// effect.
+type assigner struct {
+	kconf    *KConfigFile
+	values   KeyValueMap
+	assigned KeyValueMap
+	ev       *Evaluator
+	visiting map[string]bool
+}
+
+func (a *assigner) eval() *Evaluator {
+	if a.ev == nil {
+		a.ev = a.kconf.NewEvaluator(a.kconf.NewDotConfigFile(a.values))
+	}
+
+	return a.ev
+}
+
+func (a *assigner) set(name, value string) {
+	a.values.Set(Prefix+name, value)
+	a.assigned.Set(Prefix+name, value)
+	a.ev = nil
+}
+
+// assign sets the config to the value after meeting its dependencies.
+func (a *assigner) assign(m *KConfigMenu, value string) error {
+	value, err := normalize(m.Type, value)
+	if err != nil {
+		return fmt.Errorf("%s%s: %v", Prefix, m.Name, err)
+	}
+
+	switch m.Type {
+	case TypeBool, TypeTristate:
+		if tristateOf(value) == TristateNo {
+			err = a.disable(m)
+		} else {
+			err = a.enable(m, value)
+		}
+
+	default:
+		if err = a.settable(m); err == nil {
+			a.set(m.Name, value)
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	ev := a.eval()
+
+	if lo, hi, ok := ev.Range(m); ok && (m.Type == TypeInt || m.Type == TypeHex) && (compare(value, lo, opLt) || compare(value, hi, opGt)) {
+		return fmt.Errorf("%s%s: %s is not within the range of %s to %s", Prefix, m.Name, value, lo, hi)
+	}
+
+	if effective := ev.Value(m.Name); !equal(m.Type, value, effective) {
+		return fmt.Errorf("%s%s: cannot be set to %s as it is %s", Prefix, m.Name, value, effective)
+	}
+
+	return nil
+}
+
+// enable sets a bool or tristate config to `y`, or `m`, after meeting its
+// dependencies.
+func (a *assigner) enable(m *KConfigMenu, value string) error {
+	ev := a.eval()
+	if ev.Tristate(m.Name) == tristateOf(value) {
+		return nil
+	}
+
+	if a.visiting[m.Name] {
+		return fmt.Errorf("%s%s depends on itself", Prefix, m.Name)
+	}
+
+	a.visiting[m.Name] = true
+	defer delete(a.visiting, m.Name)
+
+	if err := a.settable(m); err != nil {
+		return err
+	}
+
+	if isChoiceMember(m) {
+		for _, elem := range m.Parent.Elems {
+			if elem.Kind == MenuConfig && elem != m && a.eval().Tristate(elem.Name) > TristateNo {
+				a.set(elem.Name, "n")
+			}
+		}
+	}
+
+	a.set(m.Name, value)
+
+	return nil
+}
+
+// disable sets a bool or tristate config to `n`, which is impossible if it is
+// selected.
+func (a *assigner) disable(m *KConfigMenu) error {
+	if by := a.eval().SelectedBy(m.Name); len(by) > 0 {
+		return fmt.Errorf("%s%s cannot be disabled as it is selected by %s", Prefix, m.Name, strings.Join(by, ", "))
+	}
+
+	if isChoiceMember(m) && a.eval().Choice(m.Parent) == m {
+		return fmt.Errorf("%s%s cannot be disabled, select another option of its choice instead", Prefix, m.Name)
+	}
+
+	a.set(m.Name, "n")
+
+	return nil
+}
+
+// settable meets the dependencies of the config and the condition of its
+// prompt such that the user is able to set it.
+func (a *assigner) settable(m *KConfigMenu) error {
+	if err := a.satisfy(exprAnd(m.dependsOn, m.visibleIf)); err != nil {
+		return fmt.Errorf("%s%s depends on %s: %v", Prefix, m.Name, a.eval().condition(m), err)
+	}
+
+	if _, ok := a.eval().prompt(m); ok {
+		return nil
+	}
+
+	if len(m.prompts) > 0 {
+		return a.satisfy(m.prompts[0].cond)
+	}
+
+	msg := fmt.Sprintf("%s%s cannot be set directly", Prefix, m.Name)
+	if selectors := a.eval().selectedBy[m.Name]; len(selectors) > 0 {
+		var names []string
+		for _, s := range selectors {
+			names = append(names, s.Name)
+		}
+
+		msg += ", it is selected by " + strings.Join(names, ", ")
+	}
+
+	return errors.New(msg)
+}
+
+// satisfy sets the configs which the expression refers to such that it holds.
+// Of alternatives, the first which can be met is chosen.
+func (a *assigner) satisfy(ex expr) error {
+	if ex == nil || a.eval().eval(ex) > TristateNo {
+		return nil
+	}
+
+	switch ex := ex.(type) {
+	case *exprIdent:
+		m, err := a.kconf.Lookup(ex.name)
+		if err != nil {
+			return err
+		}
+
+		if m.Type != TypeBool && m.Type != TypeTristate {
+			break
+		}
+
+		return a.enable(m, Yes)
+
+	case *exprNot:
+		if ident, ok := ex.ex.(*exprIdent); ok {
+			m, err := a.kconf.Lookup(ident.name)
+			if err != nil {
+				// A config which is not defined is always disabled.
+				return nil
+			}
+
+			return a.disable(m)
+		}
+
+	case *exprBin:
+		switch ex.op {
+		case opAnd:
+			if err := a.satisfy(ex.lex); err != nil {
+				return err
+			}
+
+			return a.satisfy(ex.rex)
+
+		case opOr:
+			values := KeyValueMap{}.OverrideBy(a.values)
+			assigned := KeyValueMap{}.OverrideBy(a.assigned)
+
+			lerr := a.satisfy(ex.lex)
+			if lerr == nil {
+				return nil
+			}
+
+			a.values, a.assigned, a.ev = values, assigned, nil
+
+			if err := a.satisfy(ex.rex); err != nil {
+				return fmt.Errorf("%v, nor %v", lerr, err)
+			}
+
+			return nil
+
+		case opEq:
+			ident, ok := ex.lex.(*exprIdent)
+			if !ok {
+				break
+			}
+
+			m, err := a.kconf.Lookup(ident.name)
+			if err != nil {
+				return err
+			}
+
+			return a.assign(m, a.eval().symbol(ex.rex))
+		}
+	}
+
+	return fmt.Errorf("cannot meet %s", strings.TrimSuffix(strings.TrimPrefix(ex.String(), "("), ")"))
+}
+
+// normalize returns the value as it is written in a .config, or an error if it
+// does not match the type.
+func normalize(t ConfigType, value string) (string, error) {
+	switch t {
+	case TypeBool, TypeTristate:
+		switch strings.ToLower(value) {
+		case "true", "yes", "on":
+			value = Yes
+		case "false", "no", "off", "":
+			value = "n"
+		}
+
+	case TypeHex:
+		if len(value) > 0 && !strings.HasPrefix(strings.ToLower(value), "0x") {
+			value = "0x" + value
+		}
+
+	case TypeString:
+		if !isQuoted(value) {
+			value = strconv.Quote(value)
+		}
+	}
+
+	if err := validate(t, value); err != nil {
+		return "", err
+	}
+
+	return value, nil
+}
+
+// distance returns the Levenshtein distance between two strings.
+func distance(a, b string) int {
+	prev := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		cur := make([]int, len(b)+1)
+		cur[0] = i
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			cur[j] = prev[j-1] + cost
+			if prev[j]+1 < cur[j] {
+				cur[j] = prev[j] + 1
+			}
+			if cur[j-1]+1 < cur[j] {
+				cur[j] = cur[j-1] + 1
+			}
+		}
+
+		prev = cur
+	}
+
+	return prev[len(b)]
+}
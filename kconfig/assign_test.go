@@ -0,0 +1,131 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2022 Unikraft GmbH. All rights reserved.
+
+package kconfig
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAssign(t *testing.T) {
+	kconf, err := ParseData([]byte(`
+config LIBPOSIX_SOCKET
+	bool "socket"
+
+config LIBUKNETDEV
+	bool "netdev"
+
+config LIBLWIP
+	bool "lwip"
+	depends on LIBPOSIX_SOCKET && (LIBUKNETDEV || LIBUKMMAP)
+
+if LIBLWIP
+config LWIP_TCP_SND_BUF
+	int "TCP send buffer"
+	range 1024 65535
+	default 4096
+
+config LWIP_HOSTNAME
+	string "Hostname"
+endif
+
+config LIBUKALLOC
+	bool "ukalloc"
+
+config LIBNGINX
+	bool "nginx"
+	select LIBUKALLOC
+
+choice
+	prompt "Allocator"
+
+config LIBUKALLOC_BUDDY
+	bool "buddy"
+
+config LIBUKALLOC_TLSF
+	bool "tlsf"
+endchoice
+`), "Config.uk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, test := range []struct {
+		values   []interface{}
+		symbol   string
+		value    string
+		expected map[string]string
+		err      string
+	}{
+		{
+			symbol: "CONFIG_LWIP_TCP_SND_BUF",
+			value:  "8192",
+			expected: map[string]string{
+				"CONFIG_LIBPOSIX_SOCKET":  "y",
+				"CONFIG_LIBUKNETDEV":      "y",
+				"CONFIG_LIBLWIP":          "y",
+				"CONFIG_LWIP_TCP_SND_BUF": "8192",
+			},
+		},
+		{
+			values: []interface{}{"CONFIG_LIBPOSIX_SOCKET=y", "CONFIG_LIBUKNETDEV=y"},
+			symbol: "LWIP_HOSTNAME",
+			value:  "unikraft",
+			expected: map[string]string{
+				"CONFIG_LIBLWIP":       "y",
+				"CONFIG_LWIP_HOSTNAME": `"unikraft"`,
+			},
+		},
+		{
+			symbol: "LIBUKALLOC_TLSF",
+			value:  "true",
+			expected: map[string]string{
+				"CONFIG_LIBUKALLOC_BUDDY": "n",
+				"CONFIG_LIBUKALLOC_TLSF":  "y",
+			},
+		},
+		{
+			symbol: "LWIP_TCP_SND_BUF",
+			value:  "100",
+			err:    "not within the range of 1024 to 65535",
+		},
+		{
+			symbol: "LIBLWIP",
+			value:  "maybe",
+			err:    "expected y or n for bool",
+		},
+		{
+			values: []interface{}{"CONFIG_LIBNGINX=y"},
+			symbol: "LIBUKALLOC",
+			value:  "n",
+			err:    "selected by LIBNGINX",
+		},
+		{
+			symbol: "CONFIG_LIBLWP",
+			value:  "y",
+			err:    "did you mean CONFIG_LIBLWIP?",
+		},
+	} {
+		assigned, err := kconf.Assign(NewKeyValueMapFromSlice(test.values...), test.symbol, test.value)
+		if len(test.err) > 0 {
+			if err == nil || !strings.Contains(err.Error(), test.err) {
+				t.Errorf("%s=%s: expected error containing %q, got %v", test.symbol, test.value, test.err, err)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s=%s: %v", test.symbol, test.value, err)
+			continue
+		}
+
+		if len(assigned) != len(test.expected) {
+			t.Errorf("%s=%s: expected %d values, got %s", test.symbol, test.value, len(test.expected), assigned)
+		}
+
+		for name, expected := range test.expected {
+			if kv, ok := assigned[name]; !ok || kv.Value != expected {
+				t.Errorf("%s=%s: expected %s=%s, got %v", test.symbol, test.value, name, expected, kv)
+			}
+		}
+	}
+}
@@ -0,0 +1,311 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2022 Unikraft GmbH. All rights reserved.
+
+package kconfig
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ProblemKind classifies a Problem of a configuration.
+type ProblemKind string
+
+const (
+	// ProblemUnknown is a value of a config which is not defined by the tree.
+	ProblemUnknown = ProblemKind("unknown")
+
+	// ProblemInvalid is a value which does not match the type of its config.
+	ProblemInvalid = ProblemKind("invalid")
+
+	// ProblemNoEffect is a value which is ignored, e.g. because the dependencies
+	// of its config are not met.
+	ProblemNoEffect = ProblemKind("no-effect")
+
+	// ProblemContradiction is a value which is overruled by another, e.g. a
+	// config which is disabled but selected by another, or a config which is
+	// selected despite its dependencies not being met.
+	ProblemContradiction = ProblemKind("contradiction")
+)
+
+// Problem is an issue of a configuration which is found by Check.
+type Problem struct {
+	Kind    ProblemKind
+	Symbol  string // name without CONFIG_
+	Value   string // value as it is set, if any
+	Message string
+}
+
+// String implements fmt.Stringer
+func (p Problem) String() string {
+	return fmt.Sprintf("%s%s: %s", Prefix, p.Symbol, p.Message)
+}
+
+// NewDotConfigFile converts the values, e.g. those of a Kraftfile, into a
+// .config for the tree.  Keys may include the CONFIG_ prefix, `n` disables a
+// config and the values of string configs are quoted if they are not yet.
+func (kconf *KConfigFile) NewDotConfigFile(values KeyValueMap) *DotConfigFile {
+	cf, _ := ParseConfigData(nil)
+
+	var names []string
+	for key, kv := range values {
+		if kv != nil {
+			names = append(names, key)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, key := range names {
+		name := strings.TrimPrefix(key, Prefix)
+		value := values[key].Value
+
+		switch {
+		case value == "n":
+			value = No
+		case kconf.typeOf(name) == TypeString && !isQuoted(value):
+			value = strconv.Quote(value)
+		}
+
+		cf.Set(name, value)
+	}
+
+	return cf
+}
+
+// Solve applies the semantics of `select`, `default` and `depends on` to the
+// values and returns the effective value of every config whose dependencies
+// are met.  Keys of the result include the CONFIG_ prefix and disabled bool and
+// tristate configs are `n`.
+func (kconf *KConfigFile) Solve(values KeyValueMap) KeyValueMap {
+	ev := kconf.NewEvaluator(kconf.NewDotConfigFile(values))
+	solved := KeyValueMap{}
+
+	for name, m := range kconf.Configs {
+		if len(name) == 0 || m.Type == 0 {
+			continue
+		}
+
+		if value := ev.Value(name); len(value) > 0 {
+			solved.Set(Prefix+name, value)
+		}
+	}
+
+	return solved
+}
+
+// Check returns the problems of the values, e.g. those of a Kraftfile, for the
+// tree: values of configs which are not defined or do not match their type,
+// values which have no effect because the dependencies of their config are not
+// met or because it cannot be set, and contradictions between selections and
+// the values or dependencies of the selected configs.  Keys of the values may
+// include the CONFIG_ prefix.
+func (kconf *KConfigFile) Check(values KeyValueMap) []Problem {
+	cf := kconf.NewDotConfigFile(values)
+	ev := kconf.NewEvaluator(cf)
+
+	var problems []Problem
+
+	for _, kv := range cf.Slice {
+		name, value := kv.Key, kv.Value
+		if value == No {
+			value = "n"
+		}
+
+		m, ok := kconf.Configs[name]
+		if !ok || m.Type == 0 {
+			problems = append(problems, Problem{
+				Kind:    ProblemUnknown,
+				Symbol:  name,
+				Value:   value,
+				Message: "is not defined by any component",
+			})
+			continue
+		}
+
+		if err := validate(m.Type, value); err != nil {
+			problems = append(problems, Problem{
+				Kind:    ProblemInvalid,
+				Symbol:  name,
+				Value:   value,
+				Message: err.Error(),
+			})
+			continue
+		}
+
+		if p, ok := ev.explain(m, value); ok {
+			problems = append(problems, p)
+		}
+	}
+
+	// Selecting a config forces it on regardless of its own dependencies, which
+	// usually results in a broken build.
+	for _, name := range sortedNames(kconf.Configs) {
+		m := kconf.Configs[name]
+		if len(m.Name) == 0 || ev.dependencies(m) > TristateNo {
+			continue
+		}
+
+		if by := ev.SelectedBy(name); len(by) > 0 {
+			problems = append(problems, Problem{
+				Kind:   ProblemContradiction,
+				Symbol: name,
+				Message: fmt.Sprintf("is selected by %s but depends on %s %s",
+					strings.Join(by, ", "), ev.condition(m), ev.unmet(m),
+				),
+			})
+		}
+	}
+
+	return problems
+}
+
+// explain returns why the value which is set for the config is not its
+// effective value, if it is not.
+func (ev *Evaluator) explain(m *KConfigMenu, value string) (Problem, bool) {
+	effective := ev.Value(m.Name)
+	if equal(m.Type, value, effective) {
+		return Problem{}, false
+	}
+
+	p := Problem{
+		Kind:   ProblemNoEffect,
+		Symbol: m.Name,
+		Value:  value,
+	}
+
+	switch {
+	case ev.dependencies(m) == TristateNo:
+		p.Message = fmt.Sprintf("has no effect because it depends on %s %s", ev.condition(m), ev.unmet(m))
+
+	case !ev.isSettable(m):
+		p.Message = fmt.Sprintf("has no effect because it cannot be set and is always %s", effective)
+
+	case len(ev.SelectedBy(m.Name)) > 0 && tristateOf(effective) > tristateOf(value):
+		p.Kind = ProblemContradiction
+		p.Message = fmt.Sprintf("is disabled but selected by %s", strings.Join(ev.SelectedBy(m.Name), ", "))
+
+	case isChoiceMember(m):
+		p.Kind = ProblemContradiction
+		if selected := ev.Choice(m.Parent); selected != nil {
+			p.Message = fmt.Sprintf("conflicts with %s%s which is selected in the same choice", Prefix, selected.Name)
+		} else {
+			p.Message = "is not an option of its choice"
+		}
+
+	default:
+		p.Message = fmt.Sprintf("has no effect and is %s", effective)
+	}
+
+	return p, true
+}
+
+// condition returns the dependencies of the config as they are written in a
+// KConfig file.
+func (ev *Evaluator) condition(m *KConfigMenu) string {
+	cond := exprAnd(m.dependsOn, m.visibleIf)
+	if cond == nil {
+		return ""
+	}
+
+	return strings.TrimSuffix(strings.TrimPrefix(cond.String(), "("), ")")
+}
+
+// unmet lists the configs referenced by the dependencies of the config which
+// are disabled.
+func (ev *Evaluator) unmet(m *KConfigMenu) string {
+	deps := map[string]bool{}
+	if cond := exprAnd(m.dependsOn, m.visibleIf); cond != nil {
+		cond.collectDeps(deps)
+	}
+
+	var disabled []string
+	for _, name := range sortedNames(deps) {
+		if dep, ok := ev.kconf.Configs[name]; !ok {
+			disabled = append(disabled, name+" is not defined")
+		} else if (dep.Type == TypeBool || dep.Type == TypeTristate) && ev.Tristate(name) == TristateNo {
+			disabled = append(disabled, name+" is n")
+		}
+	}
+
+	if len(disabled) == 0 {
+		return ""
+	}
+
+	return "(" + strings.Join(disabled, ", ") + ")"
+}
+
+// isChoiceMember returns whether the config is one of the options of a choice.
+func isChoiceMember(m *KConfigMenu) bool {
+	return m.Parent != nil && m.Parent.Kind == MenuChoice
+}
+
+// typeOf returns the type of the named config, or zero if it is not defined.
+func (kconf *KConfigFile) typeOf(name string) ConfigType {
+	if m, ok := kconf.Configs[name]; ok {
+		return m.Type
+	}
+
+	return 0
+}
+
+// validate returns an error if the value does not match the type.
+func validate(t ConfigType, value string) error {
+	switch t {
+	case TypeBool:
+		if value != Yes && value != "n" {
+			return fmt.Errorf("expected y or n for bool but got %s", value)
+		}
+
+	case TypeTristate:
+		if value != Yes && value != Mod && value != "n" {
+			return fmt.Errorf("expected y, m or n for tristate but got %s", value)
+		}
+
+	case TypeInt:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("expected a decimal number for int but got %s", value)
+		}
+
+	case TypeHex:
+		if !strings.HasPrefix(strings.ToLower(value), "0x") {
+			return fmt.Errorf("expected a number prefixed with 0x for hex but got %s", value)
+		}
+		if _, err := strconv.ParseUint(value[2:], 16, 64); err != nil {
+			return fmt.Errorf("expected a number prefixed with 0x for hex but got %s", value)
+		}
+	}
+
+	return nil
+}
+
+// equal returns whether two values of the type are the same, e.g. `0x10` and
+// `0x010` for hex configs.
+func equal(t ConfigType, a, b string) bool {
+	switch t {
+	case TypeBool, TypeTristate:
+		return tristateOf(a) == tristateOf(b)
+	case TypeInt, TypeHex:
+		return compare(a, b, opEq)
+	case TypeString:
+		return unquote(a) == unquote(b)
+	}
+
+	return a == b
+}
+
+func isQuoted(value string) bool {
+	return len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`)
+}
+
+func sortedNames[T any](m map[string]T) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2022 Unikraft GmbH. All rights reserved.
+
+package kconfig
+
+import (
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	kconf, err := ParseData([]byte(`
+config LIBPOSIX_SOCKET
+	bool "socket"
+
+config LIBLWIP
+	bool "lwip"
+	depends on LIBPOSIX_SOCKET
+
+if LIBLWIP
+config LWIP_TCP_SND_BUF
+	int "TCP send buffer"
+	default 4096
+endif
+
+config LIBNGINX
+	bool "nginx"
+	select LIBLWIP
+
+config LIBUKALLOC
+	bool "ukalloc"
+	default y
+
+config LIBUKBOOT
+	bool "ukboot"
+	select LIBUKALLOC
+
+choice
+	prompt "Allocator"
+
+config LIBUKALLOC_BUDDY
+	bool "buddy"
+
+config LIBUKALLOC_TLSF
+	bool "tlsf"
+endchoice
+`), "Config.uk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := NewKeyValueMapFromMap(map[string]interface{}{
+		"CONFIG_LWIP_TCP_SND_BUF":  "8192",
+		"CONFIG_LIBNGINX":          true,
+		"CONFIG_LIBUKBOOT":         true,
+		"CONFIG_LIBUKALLOC":        false,
+		"CONFIG_LIBUKALLOC_BUDDY":  true,
+		"CONFIG_LIBUKALLOC_TLSF":   true,
+		"CONFIG_LIBPOSIX_SOCKET":   "yes",
+		"CONFIG_LIBDOES_NOT_EXIST": true,
+	})
+
+	problems := map[string]ProblemKind{}
+	for _, p := range kconf.Check(values) {
+		if _, ok := problems[p.Symbol]; ok {
+			t.Errorf("unexpected second problem %s", p)
+		}
+
+		problems[p.Symbol] = p.Kind
+	}
+
+	for symbol, kind := range map[string]ProblemKind{
+		// The socket is not enabled due to its invalid value, which is why lwip
+		// is selected despite its dependencies
+		"LIBPOSIX_SOCKET":   ProblemInvalid,
+		"LIBLWIP":           ProblemContradiction,
+		"LIBUKALLOC":        ProblemContradiction,
+		"LIBUKALLOC_TLSF":   ProblemContradiction,
+		"LIBDOES_NOT_EXIST": ProblemUnknown,
+	} {
+		if problems[symbol] != kind {
+			t.Errorf("expected %s to be %s, got %q", symbol, kind, problems[symbol])
+		}
+
+		delete(problems, symbol)
+	}
+
+	for symbol, kind := range problems {
+		t.Errorf("unexpected %s problem of %s", kind, symbol)
+	}
+
+	solved := kconf.Solve(values)
+	for name, expected := range map[string]string{
+		"CONFIG_LIBLWIP":          "y",
+		"CONFIG_LWIP_TCP_SND_BUF": "8192",
+		"CONFIG_LIBUKALLOC":       "y",
+		"CONFIG_LIBUKALLOC_BUDDY": "y",
+		"CONFIG_LIBUKALLOC_TLSF":  "n",
+	} {
+		if kv, ok := solved[name]; !ok || kv.Value != expected {
+			t.Errorf("expected %s=%s, got %v", name, expected, kv)
+		}
+	}
+}
+
+func TestCheckNoEffect(t *testing.T) {
+	kconf, err := ParseData([]byte(`
+config LIBFOO
+	bool "foo"
+
+config LIBFOO_DEBUG
+	bool "debug"
+	depends on LIBFOO
+
+config LIBFOO_VERSION
+	string
+	default "1.0"
+`), "Config.uk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	problems := kconf.Check(NewKeyValueMapFromSlice(
+		"LIBFOO_DEBUG=y",
+		"LIBFOO_VERSION=2.0",
+	))
+
+	if len(problems) != 2 {
+		t.Fatalf("expected 2 problems, got %v", problems)
+	}
+
+	for _, p := range problems {
+		if p.Kind != ProblemNoEffect {
+			t.Errorf("expected %s to have no effect, got %s", p.Symbol, p.Kind)
+		}
+	}
+
+	if expected := "CONFIG_LIBFOO_DEBUG: has no effect because it depends on LIBFOO (LIBFOO is n)"; problems[0].String() != expected {
+		t.Errorf("expected %q, got %q", expected, problems[0])
+	}
+}
@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2022 Unikraft GmbH. All rights reserved.
+
+package kconfig
+
+// MissingSymbols returns the names of the configs which are required by the
+// enabled configs of the provided trees but which are not defined by any of
+// them.  A config is required if it is selected by an enabled config, or if the
+// dependencies of an enabled config cannot be met without it.  Where a config
+// depends on either of several configs, e.g. `depends on LIBFOO || LIBBAR`, the
+// first which meets the dependency is required, which is none if it is
+// defined.  Configs which are explicitly enabled by the values are never
+// missing.
+//
+// Whether a config is enabled is determined by an Evaluator of the values,
+// which are the user's, in which missing configs are assumed to be enabled once
+// they are provided.  Configs which the values enable are also considered
+// enabled when their dependencies are not met.  Keys of the values may include
+// the CONFIG_ prefix.
+func MissingSymbols(values KeyValueMap, trees ...*KConfigFile) []string {
+	kconf := &KConfigFile{Configs: map[string]*KConfigMenu{}}
+	for _, tree := range trees {
+		if tree == nil {
+			continue
+		}
+
+		for name, menu := range tree.Configs {
+			if _, ok := kconf.Configs[name]; !ok {
+				kconf.Configs[name] = menu
+			}
+		}
+	}
+
+	config := kconf.NewDotConfigFile(values)
+
+	isDefined := func(name string) bool {
+		_, ok := kconf.Configs[name]
+		return ok || tristateOf(config.Value(name)) > TristateNo
+	}
+
+	missing := map[string]bool{}
+
+	// Providing a config may in turn enable further configs, e.g. those which
+	// it selects or which default to `y` once their dependencies are met.
+	for {
+		ev := kconf.NewEvaluator(withEnabled(config, sortedNames(missing)...))
+		found := false
+
+		require := func(name string) {
+			if !isDefined(name) && !missing[name] {
+				missing[name] = true
+				found = true
+			}
+		}
+
+		for _, name := range sortedNames(kconf.Configs) {
+			menu := kconf.Configs[name]
+			if ev.Tristate(name) == TristateNo && tristateOf(config.Value(name)) == TristateNo {
+				continue
+			}
+
+			for _, sel := range menu.selects {
+				if ev.selects(menu, sel.name) > TristateNo {
+					require(sel.name)
+				}
+			}
+
+			if ev.dependencies(menu) == TristateNo {
+				for _, dep := range ev.required(exprAnd(menu.dependsOn, menu.visibleIf)) {
+					require(dep)
+				}
+			}
+		}
+
+		if !found {
+			break
+		}
+	}
+
+	return sortedNames(missing)
+}
+
+// required returns the configs which must additionally be enabled for the
+// condition to be met.  The configs of the condition are enabled in order until
+// it is met, after which those without which it is met nonetheless are dropped
+// again.  Configs which are negated by the condition are never enabled.
+func (ev *Evaluator) required(cond expr) []string {
+	met := func(names []string) bool {
+		return ev.kconf.NewEvaluator(withEnabled(ev.config, names...)).eval(cond) > TristateNo
+	}
+
+	var enabled []string
+	for _, name := range identsOf(cond) {
+		if met(enabled) {
+			break
+		}
+
+		enabled = append(enabled, name)
+	}
+
+	if !met(enabled) {
+		return nil
+	}
+
+	for i := len(enabled) - 1; i >= 0; i-- {
+		without := append(append([]string{}, enabled[:i]...), enabled[i+1:]...)
+		if met(without) {
+			enabled = without
+		}
+	}
+
+	return enabled
+}
+
+// identsOf returns the configs which the expression refers to, in order and
+// except for those which it negates.
+func identsOf(ex expr) []string {
+	switch ex := ex.(type) {
+	case *exprIdent:
+		switch ex.name {
+		case Yes, Mod, "n":
+			return nil
+		}
+
+		return []string{ex.name}
+
+	case *exprBin:
+		var names []string
+		for _, name := range append(identsOf(ex.lex), identsOf(ex.rex)...) {
+			if !contains(names, name) {
+				names = append(names, name)
+			}
+		}
+
+		return names
+	}
+
+	return nil
+}
+
+// withEnabled returns a copy of the .config in which the named configs are set
+// to `y`.
+func withEnabled(config *DotConfigFile, names ...string) *DotConfigFile {
+	ret := config.Clone()
+	for _, name := range names {
+		ret.Set(name, Yes)
+	}
+
+	return ret
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+
+	return false
+}
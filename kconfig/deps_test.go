@@ -0,0 +1,121 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2022 Unikraft GmbH. All rights reserved.
+
+package kconfig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMissingSymbols(t *testing.T) {
+	app, err := ParseData([]byte(`
+config APPNGINX
+	bool "nginx"
+	default y
+	select LIBNGINX
+	select LIBPOSIX_SOCKET
+
+config APPNGINX_TLS
+	bool "TLS"
+	default n
+	select LIBMBEDTLS
+`), "Config.uk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nginx, err := ParseData([]byte(`
+menuconfig LIBNGINX
+	bool "nginx"
+	depends on LIBMUSL || LIBNEWLIB
+	depends on LIBLWIP && !LIBNOLIBC
+	select LIBCOMPILER_RT if LIBMUSL
+
+if LIBNGINX
+config LIBNGINX_HTTP
+	bool "HTTP module"
+	default y
+	select LIBPCRE
+endif
+`), "Config.uk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	posix, err := ParseData([]byte(`
+config LIBPOSIX_SOCKET
+	bool "POSIX sockets"
+`), "Config.uk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	values := NewKeyValueMapFromMap(map[string]interface{}{
+		"CONFIG_LIBNOLIBC": "n",
+	})
+
+	// LIBMUSL is the first alternative C library and, once provided, in turn
+	// selects the compiler runtime
+	expected := []string{"LIBCOMPILER_RT", "LIBLWIP", "LIBMUSL", "LIBPCRE"}
+	if missing := MissingSymbols(values, app, nginx, posix); !reflect.DeepEqual(missing, expected) {
+		t.Errorf("expected %v, got %v", expected, missing)
+	}
+
+	values.Set("APPNGINX_TLS", Yes)
+	values.Set("LIBNEWLIB", Yes)
+
+	// The explicitly enabled LIBNEWLIB satisfies the alternative to LIBMUSL even
+	// though it is not defined
+	expected = []string{"LIBLWIP", "LIBMBEDTLS", "LIBPCRE"}
+	if missing := MissingSymbols(values, app, nginx, posix); !reflect.DeepEqual(missing, expected) {
+		t.Errorf("expected %v, got %v", expected, missing)
+	}
+}
+
+func TestMissingSymbolsExpressions(t *testing.T) {
+	kconf, err := ParseData([]byte(`
+config LIBFOO
+	tristate "foo"
+	default m
+	select LIBMODULE if LIBFOO != y
+	select LIBBUILTIN if LIBFOO = y
+
+config LIBFOO_STACK
+	int "Stack size"
+	default 8
+
+config LIBFOO_BIG
+	bool "big"
+	default y
+	select LIBHUGE if LIBFOO_STACK > 4
+	select LIBTINY if LIBFOO_STACK < 4
+
+config LIBFOO_TLS
+	bool "TLS"
+	depends on !LIBNOTLS && LIBFOO_STACK >= 8
+	depends on LIBMBEDTLS || LIBFOO
+	default y
+	select LIBCRYPTO
+`), "Config.uk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Comparisons and modules are evaluated as by the Evaluator, and the
+	// defined LIBFOO meets the dependency of LIBFOO_TLS instead of LIBMBEDTLS
+	expected := []string{"LIBCRYPTO", "LIBHUGE", "LIBMODULE"}
+	if missing := MissingSymbols(KeyValueMap{}, kconf); !reflect.DeepEqual(missing, expected) {
+		t.Errorf("expected %v, got %v", expected, missing)
+	}
+
+	values := NewKeyValueMapFromMap(map[string]interface{}{
+		"CONFIG_LIBFOO":       "y",
+		"CONFIG_LIBFOO_STACK": "2",
+	})
+
+	expected = []string{"LIBBUILTIN", "LIBTINY"}
+	if missing := MissingSymbols(values, kconf); !reflect.DeepEqual(missing, expected) {
+		t.Errorf("expected %v, got %v", expected, missing)
+	}
+}
@@ -0,0 +1,173 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2022 Unikraft GmbH. All rights reserved.
+
+package kconfig
+
+import (
+	"sort"
+	"strings"
+)
+
+// DiffKind classifies a Difference between two configurations.
+type DiffKind string
+
+const (
+	DiffAdded   = DiffKind("added")
+	DiffRemoved = DiffKind("removed")
+	DiffChanged = DiffKind("changed")
+)
+
+// Difference is a config whose value differs between two configurations.
+type Difference struct {
+	Kind   DiffKind
+	Symbol string   // name without CONFIG_
+	Old    string   // value in the first configuration, if any
+	New    string   // value in the second configuration, if any
+	Prompt string   // prompt of the config, if it is known
+	Menus  []string // prompts of the menus containing the config, outermost first
+}
+
+// Diff returns the configs which are added, removed or changed from one set of
+// values to another, e.g. those of two .config files.  Keys may include the
+// CONFIG_ prefix, quotes of strings are removed and bool and tristate configs
+// which are `n` are the same as those which are not set.  If the tree is not
+// nil, the differences include the prompts of the configs and of their menus
+// and are ordered as the configs appear in the tree, followed by those which
+// the tree does not define.
+func (kconf *KConfigFile) Diff(from, to KeyValueMap) []Difference {
+	old, cur := normalized(from), normalized(to)
+
+	names := map[string]bool{}
+	for name := range old {
+		names[name] = true
+	}
+	for name := range cur {
+		names[name] = true
+	}
+
+	var diffs []Difference
+
+	for name := range names {
+		o, inOld := old[name]
+		n, inCur := cur[name]
+
+		switch {
+		case (!inOld || o == "n") && (!inCur || n == "n"):
+			continue
+		case inOld && inCur && kconf.equal(name, o, n):
+			continue
+		}
+
+		d := Difference{
+			Kind:   DiffChanged,
+			Symbol: name,
+			Old:    o,
+			New:    n,
+		}
+
+		if !inOld {
+			d.Kind = DiffAdded
+		} else if !inCur {
+			d.Kind = DiffRemoved
+		}
+
+		if m, ok := kconf.lookup(name); ok {
+			d.Prompt = m.Prompt()
+			d.Menus = menus(m)
+		}
+
+		diffs = append(diffs, d)
+	}
+
+	order := map[string]int{}
+	if kconf != nil && kconf.Root != nil {
+		kconf.order(kconf.Root, order)
+	}
+
+	sort.Slice(diffs, func(i, j int) bool {
+		oi, iok := order[diffs[i].Symbol]
+		oj, jok := order[diffs[j].Symbol]
+
+		switch {
+		case iok && jok:
+			return oi < oj
+		case iok != jok:
+			return iok
+		}
+
+		return diffs[i].Symbol < diffs[j].Symbol
+	})
+
+	return diffs
+}
+
+// lookup returns the config with the provided name, if the tree is not nil and
+// defines it.
+func (kconf *KConfigFile) lookup(name string) (*KConfigMenu, bool) {
+	if kconf == nil {
+		return nil, false
+	}
+
+	m, ok := kconf.Configs[name]
+	return m, ok && m.Type > 0
+}
+
+// equal returns whether two values of the named config are the same.
+func (kconf *KConfigFile) equal(name, a, b string) bool {
+	if m, ok := kconf.lookup(name); ok {
+		return equal(m.Type, a, b)
+	}
+
+	return a == b
+}
+
+// order numbers the configs in the order in which they appear in the tree.
+func (kconf *KConfigFile) order(m *KConfigMenu, order map[string]int) {
+	if m.Kind == MenuConfig && len(m.Name) > 0 {
+		if _, ok := order[m.Name]; !ok {
+			order[m.Name] = len(order)
+		}
+	}
+
+	for _, elem := range m.Elems {
+		kconf.order(elem, order)
+	}
+}
+
+// menus returns the prompts of the menus, choices and menuconfigs containing
+// the config, outermost first.
+func menus(m *KConfigMenu) []string {
+	var prompts []string
+
+	for parent := m.Parent; parent != nil; parent = parent.Parent {
+		// Skip `if` blocks and the `mainmenu`
+		if len(parent.Prompt()) == 0 || (parent.Kind == MenuConfig && len(parent.Name) == 0) {
+			continue
+		}
+
+		prompts = append([]string{parent.Prompt()}, prompts...)
+	}
+
+	return prompts
+}
+
+// normalized returns the values without the CONFIG_ prefix of their keys and
+// the quotes of strings.
+func normalized(values KeyValueMap) map[string]string {
+	result := map[string]string{}
+
+	for key, kv := range values {
+		if kv == nil {
+			continue
+		}
+
+		value := kv.Value
+		if value == No {
+			value = "n"
+		}
+
+		result[strings.TrimPrefix(key, Prefix)] = unquote(value)
+	}
+
+	return result
+}
@@ -0,0 +1,94 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2022 Unikraft GmbH. All rights reserved.
+
+package kconfig
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	kconf, err := ParseData([]byte(`
+mainmenu "Unikraft Configuration"
+
+menu "Library Configuration"
+
+menuconfig LIBLWIP
+	bool "lwip - Lightweight TCP/IP stack"
+
+if LIBLWIP
+config LWIP_TCP_SND_BUF
+	int "TCP send buffer"
+	default 4096
+
+config LWIP_HOSTNAME
+	string "Hostname"
+endif
+
+endmenu
+
+config LIBUKDEBUG
+	bool "ukdebug"
+`), "Config.uk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from, err := ParseConfigData([]byte(`CONFIG_LIBLWIP=y
+CONFIG_LWIP_TCP_SND_BUF=4096
+CONFIG_LWIP_HOSTNAME="staging"
+# CONFIG_LIBUKDEBUG is not set
+# CONFIG_LIBPOSIX_SOCKET is not set
+CONFIG_LIBUKNOTDEFINED=y
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	to := NewKeyValueMapFromSlice(
+		"CONFIG_LIBLWIP=y",
+		"CONFIG_LWIP_TCP_SND_BUF=8192",
+		"CONFIG_LWIP_HOSTNAME=production",
+		"CONFIG_LIBUKDEBUG=y",
+	)
+
+	expected := []Difference{
+		{
+			Kind:   DiffChanged,
+			Symbol: "LWIP_TCP_SND_BUF",
+			Old:    "4096",
+			New:    "8192",
+			Prompt: "TCP send buffer",
+			Menus:  []string{"Library Configuration"},
+		},
+		{
+			Kind:   DiffChanged,
+			Symbol: "LWIP_HOSTNAME",
+			Old:    "staging",
+			New:    "production",
+			Prompt: "Hostname",
+			Menus:  []string{"Library Configuration"},
+		},
+		{
+			Kind:   DiffChanged,
+			Symbol: "LIBUKDEBUG",
+			Old:    "n",
+			New:    "y",
+			Prompt: "ukdebug",
+		},
+		{
+			Kind:   DiffRemoved,
+			Symbol: "LIBUKNOTDEFINED",
+			Old:    "y",
+		},
+	}
+
+	if diffs := kconf.Diff(from.Map, to); !reflect.DeepEqual(diffs, expected) {
+		t.Errorf("expected %+v, got %+v", expected, diffs)
+	}
+
+	if diffs := (*KConfigFile)(nil).Diff(from.Map, from.Map); len(diffs) != 0 {
+		t.Errorf("expected no differences, got %+v", diffs)
+	}
+}
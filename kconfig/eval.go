@@ -0,0 +1,434 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2022 Unikraft GmbH. All rights reserved.
+
+package kconfig
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Tristate is the value of a bool or tristate config or of an expression.
+type Tristate int
+
+const (
+	TristateNo Tristate = iota
+	TristateMod
+	TristateYes
+)
+
+// String returns the value as it is written in a .config file.
+func (t Tristate) String() string {
+	switch t {
+	case TristateYes:
+		return Yes
+	case TristateMod:
+		return Mod
+	}
+
+	return "n"
+}
+
+// tristateOf parses the value of a config as a tristate.  Any value which is
+// not `y` or `m` is considered to be `n`.
+func tristateOf(value string) Tristate {
+	switch value {
+	case Yes:
+		return TristateYes
+	case Mod:
+		return TristateMod
+	}
+
+	return TristateNo
+}
+
+// Evaluator computes the effective values of the configs of a KConfig tree and
+// the visibility of its menus given the values of a .config file, taking into
+// account defaults, dependencies, reverse dependencies via `select` and
+// choices.  Results are cached, so a new Evaluator must be created whenever the
+// .config changes.
+type Evaluator struct {
+	kconf      *KConfigFile
+	config     *DotConfigFile
+	selectedBy map[string][]*KConfigMenu
+	values     map[string]string
+	pending    map[string]bool
+}
+
+// NewEvaluator returns an Evaluator of the tree for the provided .config.
+func (kconf *KConfigFile) NewEvaluator(config *DotConfigFile) *Evaluator {
+	ev := &Evaluator{
+		kconf:      kconf,
+		config:     config,
+		selectedBy: map[string][]*KConfigMenu{},
+		values:     map[string]string{},
+		pending:    map[string]bool{},
+	}
+
+	for _, m := range kconf.Configs {
+		for _, sel := range m.selects {
+			ev.selectedBy[sel.name] = append(ev.selectedBy[sel.name], m)
+		}
+	}
+
+	return ev
+}
+
+// Value returns the effective value of the named config as it would be written
+// to a .config file, e.g. `y`, `n`, `42` or `"foo"`.  Bool and tristate configs
+// which are disabled are `n` and other configs whose dependencies are not met
+// are empty.
+func (ev *Evaluator) Value(name string) string {
+	if value, ok := ev.values[name]; ok {
+		return value
+	}
+
+	m, ok := ev.kconf.Configs[name]
+	if !ok {
+		if value := ev.config.Value(name); value != No {
+			return value
+		}
+
+		return ""
+	}
+
+	// Break cycles between configs which depend on each other by considering
+	// the config to be unset while it is being evaluated.
+	if ev.pending[name] {
+		return ev.zero(m)
+	}
+
+	ev.pending[name] = true
+	value := ev.value(m)
+	delete(ev.pending, name)
+
+	ev.values[name] = value
+	return value
+}
+
+// Tristate returns the effective value of the named config as a tristate.
+func (ev *Evaluator) Tristate(name string) Tristate {
+	return tristateOf(ev.Value(name))
+}
+
+// IsVisible returns whether the menu would be shown to the user, i.e. whether
+// its dependencies are met and, for configs and choices, whether it has a
+// prompt whose condition is met.
+func (ev *Evaluator) IsVisible(m *KConfigMenu) bool {
+	if ev.dependencies(m) == TristateNo {
+		return false
+	}
+
+	switch m.Kind {
+	case MenuConfig, MenuChoice:
+		_, ok := ev.prompt(m)
+		return ok
+	}
+
+	return true
+}
+
+// Prompt returns the text of the first prompt of the menu whose condition is
+// met.
+func (ev *Evaluator) Prompt(m *KConfigMenu) string {
+	text, _ := ev.prompt(m)
+	return text
+}
+
+// SelectedBy returns the names of the enabled configs which select the named
+// config.  A config which is selected cannot be disabled.
+func (ev *Evaluator) SelectedBy(name string) []string {
+	var names []string
+	for _, m := range ev.selectedBy[name] {
+		if ev.selects(m, name) > TristateNo {
+			names = append(names, m.Name)
+		}
+	}
+
+	return names
+}
+
+// Choice returns the config which is selected by the choice, or nil if none of
+// its configs are visible.
+func (ev *Evaluator) Choice(choice *KConfigMenu) *KConfigMenu {
+	var visible []*KConfigMenu
+	for _, elem := range choice.Elems {
+		if elem.Kind == MenuConfig && ev.IsVisible(elem) {
+			visible = append(visible, elem)
+		}
+	}
+
+	// The user's choice takes precedence over the default of the choice.
+	for _, elem := range visible {
+		if ev.config.Value(elem.Name) == Yes {
+			return elem
+		}
+	}
+
+	for _, def := range choice.defaults {
+		ident, ok := def.val.(*exprIdent)
+		if !ok || (def.cond != nil && ev.eval(def.cond) == TristateNo) {
+			continue
+		}
+
+		for _, elem := range visible {
+			if elem.Name == ident.name {
+				return elem
+			}
+		}
+	}
+
+	if len(visible) > 0 {
+		return visible[0]
+	}
+
+	return nil
+}
+
+func (ev *Evaluator) value(m *KConfigMenu) string {
+	deps := ev.dependencies(m)
+
+	switch m.Type {
+	case TypeBool, TypeTristate:
+		value := TristateNo
+
+		if deps > TristateNo {
+			if m.Parent != nil && m.Parent.Kind == MenuChoice {
+				if ev.Choice(m.Parent) == m {
+					value = TristateYes
+				}
+			} else if user, ok := ev.user(m.Name); ok && ev.isSettable(m) {
+				value = tristateOf(user)
+			} else {
+				value = tristateOf(ev.defaultValue(m))
+			}
+
+			value = tristateMin(value, deps)
+		}
+
+		// Selecting a config overrides its own dependencies.
+		for _, s := range ev.selectedBy[m.Name] {
+			value = tristateMax(value, ev.selects(s, m.Name))
+		}
+
+		if m.Type == TypeBool && value == TristateMod {
+			value = TristateYes
+		}
+
+		return value.String()
+
+	default:
+		if deps == TristateNo {
+			return ""
+		}
+
+		if user, ok := ev.user(m.Name); ok && user != No && ev.isSettable(m) {
+			return user
+		}
+
+		return ev.defaultValue(m)
+	}
+}
+
+// zero returns the value of a config which is not set.
+func (ev *Evaluator) zero(m *KConfigMenu) string {
+	switch m.Type {
+	case TypeBool, TypeTristate:
+		return TristateNo.String()
+	}
+
+	return ""
+}
+
+// user returns the value of the config in the .config, if it is present, be it
+// only as `is not set`.
+func (ev *Evaluator) user(name string) (string, bool) {
+	if kv, ok := ev.config.Map[name]; ok && kv != nil {
+		return kv.Value, true
+	}
+
+	return "", false
+}
+
+// isSettable returns whether the value of the config is taken from the .config
+// which is only the case if the user would be able to change it.
+func (ev *Evaluator) isSettable(m *KConfigMenu) bool {
+	_, ok := ev.prompt(m)
+	return ok
+}
+
+// dependencies evaluates the `depends on` and `if` conditions of the menu.
+func (ev *Evaluator) dependencies(m *KConfigMenu) Tristate {
+	cond := exprAnd(m.dependsOn, m.visibleIf)
+	if cond == nil {
+		return TristateYes
+	}
+
+	return ev.eval(cond)
+}
+
+// selects returns the value which the config forces onto the named config by
+// selecting it.
+func (ev *Evaluator) selects(m *KConfigMenu, name string) Tristate {
+	for _, sel := range m.selects {
+		if sel.name != name {
+			continue
+		}
+
+		value := ev.Tristate(m.Name)
+		if sel.cond != nil {
+			value = tristateMin(value, ev.eval(sel.cond))
+		}
+
+		if value > TristateNo {
+			return value
+		}
+	}
+
+	return TristateNo
+}
+
+func (ev *Evaluator) prompt(m *KConfigMenu) (string, bool) {
+	for _, p := range m.prompts {
+		if p.cond == nil || ev.eval(p.cond) > TristateNo {
+			return p.text, true
+		}
+	}
+
+	return "", false
+}
+
+// defaultValue returns the first default of the config whose condition is met.
+func (ev *Evaluator) defaultValue(m *KConfigMenu) string {
+	for _, def := range m.defaults {
+		if def.cond != nil && ev.eval(def.cond) == TristateNo {
+			continue
+		}
+
+		switch m.Type {
+		case TypeBool, TypeTristate:
+			return ev.eval(def.val).String()
+
+		case TypeString:
+			return strconv.Quote(ev.symbol(def.val))
+		}
+
+		return ev.symbol(def.val)
+	}
+
+	return ev.zero(m)
+}
+
+// symbol returns the value of a term of an expression: the value of a config,
+// or the term itself for constants such as `y`, `42` or `"foo"`.  The quotes
+// of strings are removed.
+func (ev *Evaluator) symbol(ex expr) string {
+	switch ex := ex.(type) {
+	case *exprString:
+		return ex.val
+
+	case *exprIdent:
+		if _, ok := ev.kconf.Configs[ex.name]; ok {
+			return unquote(ev.Value(ex.name))
+		}
+
+		return ex.name
+	}
+
+	return ev.eval(ex).String()
+}
+
+// eval evaluates the expression as a tristate.  Configs which are not defined
+// and shell invocations are `n`.
+func (ev *Evaluator) eval(ex expr) Tristate {
+	switch ex := ex.(type) {
+	case *exprIdent:
+		switch ex.name {
+		case Yes:
+			return TristateYes
+		case Mod:
+			return TristateMod
+		case "n":
+			return TristateNo
+		}
+
+		return ev.Tristate(ex.name)
+
+	case *exprNot:
+		return TristateYes - ev.eval(ex.ex)
+
+	case *exprBin:
+		switch ex.op {
+		case opAnd:
+			return tristateMin(ev.eval(ex.lex), ev.eval(ex.rex))
+
+		case opOr:
+			return tristateMax(ev.eval(ex.lex), ev.eval(ex.rex))
+		}
+
+		if compare(ev.symbol(ex.lex), ev.symbol(ex.rex), ex.op) {
+			return TristateYes
+		}
+	}
+
+	return TristateNo
+}
+
+// compare the values of two terms, numerically if both are numbers.
+func compare(lhs, rhs string, op binOp) bool {
+	cmp := strings.Compare(lhs, rhs)
+
+	l, lerr := strconv.ParseInt(lhs, 0, 64)
+	r, rerr := strconv.ParseInt(rhs, 0, 64)
+	if lerr == nil && rerr == nil {
+		switch {
+		case l < r:
+			cmp = -1
+		case l > r:
+			cmp = 1
+		default:
+			cmp = 0
+		}
+	}
+
+	switch op {
+	case opEq:
+		return cmp == 0
+	case opNe:
+		return cmp != 0
+	case opLt:
+		return cmp < 0
+	case opLe:
+		return cmp <= 0
+	case opGt:
+		return cmp > 0
+	case opGe:
+		return cmp >= 0
+	}
+
+	return false
+}
+
+func unquote(value string) string {
+	if s, err := strconv.Unquote(value); err == nil {
+		return s
+	}
+
+	return value
+}
+
+func tristateMin(a, b Tristate) Tristate {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func tristateMax(a, b Tristate) Tristate {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: Apache-2.0
+// Copyright 2022 Unikraft GmbH. All rights reserved.
+
+package kconfig
+
+import (
+	"testing"
+)
+
+func TestEvaluator(t *testing.T) {
+	kconf, err := ParseData([]byte(`
+mainmenu "Test"
+
+config LIBFOO
+	bool "foo"
+	default y
+	help
+	  Enable foo.
+
+	  It is great.
+
+if LIBFOO
+config LIBFOO_STACK
+	int "Stack size"
+	default 4096 if LIBFOO_BIG
+	default 1024
+
+config LIBFOO_BIG
+	bool "big"
+	select LIBBAR
+
+config LIBFOO_NAME
+	string "Name" if LIBFOO_BIG
+	default "foo"
+endif
+
+config LIBBAR
+	bool "bar"
+	depends on LIBBAZ
+
+config LIBBAZ
+	bool "baz"
+
+choice
+	prompt "Allocator"
+	default LIBFOO_TLSF
+
+config LIBFOO_BUDDY
+	bool "buddy"
+
+config LIBFOO_TLSF
+	bool "tlsf"
+endchoice
+`), "Config.uk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if help := kconf.Configs["LIBFOO"].Help(); help != "Enable foo.\nIt is great." {
+		t.Errorf("unexpected help %q", help)
+	}
+
+	config, _ := ParseConfigData([]byte(`
+CONFIG_LIBFOO_BIG=y
+CONFIG_LIBFOO_NAME="bar"
+`))
+
+	ev := kconf.NewEvaluator(config)
+
+	for name, expected := range map[string]string{
+		"LIBFOO":       "y",
+		"LIBFOO_STACK": "4096",
+		"LIBFOO_BIG":   "y",
+		"LIBFOO_NAME":  `"bar"`,
+		"LIBBAR":       "y",
+		"LIBBAZ":       "n",
+		"LIBFOO_BUDDY": "n",
+		"LIBFOO_TLSF":  "y",
+	} {
+		if value := ev.Value(name); value != expected {
+			t.Errorf("expected %s=%s, got %s", name, expected, value)
+		}
+	}
+
+	if by := ev.SelectedBy("LIBBAR"); len(by) != 1 || by[0] != "LIBFOO_BIG" {
+		t.Errorf("expected LIBBAR to be selected by LIBFOO_BIG, got %v", by)
+	}
+
+	// Disabling LIBFOO hides and disables everything within `if LIBFOO`, and
+	// the value of a config without a visible prompt is its default.
+	config.Set("LIBFOO", No)
+	config.Set("LIBFOO_BUDDY", Yes)
+	ev = kconf.NewEvaluator(config)
+
+	for name, expected := range map[string]string{
+		"LIBFOO":       "n",
+		"LIBFOO_STACK": "",
+		"LIBFOO_BIG":   "n",
+		"LIBBAR":       "n",
+		"LIBFOO_BUDDY": "y",
+		"LIBFOO_TLSF":  "n",
+	} {
+		if value := ev.Value(name); value != expected {
+			t.Errorf("expected %s=%s, got %s", name, expected, value)
+		}
+	}
+
+	if ev.IsVisible(kconf.Configs["LIBFOO_STACK"]) {
+		t.Error("expected LIBFOO_STACK to be hidden")
+	}
+
+	if !ev.IsVisible(kconf.Configs["LIBFOO"]) {
+		t.Error("expected LIBFOO to be visible")
+	}
+}
+
+func TestParseSourceVariables(t *testing.T) {
+	kconf, err := ParseData([]byte(`
+mainmenu "Test"
+
+source "$(KCONFIG_LIB_IN)"
+
+config LIBFOO
+	bool "foo"
+`), "Config.uk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := kconf.Configs["LIBFOO"]; !ok {
+		t.Error("expected LIBFOO to be parsed")
+	}
+}
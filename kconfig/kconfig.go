@@ -13,6 +13,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"sync"
 )
 
@@ -33,8 +35,11 @@ type KConfigMenu struct {
 	kconfigFile *KConfigFile // back-link to the owning KConfig
 	prompts     []prompt
 	defaults    []defaultVal
+	selects     []selectVal
+	ranges      []rangeVal
 	dependsOn   expr
 	visibleIf   expr
+	help        []string
 	deps        map[string]bool
 	depsOnce    sync.Once
 }
@@ -49,6 +54,17 @@ type defaultVal struct {
 	cond expr
 }
 
+type selectVal struct {
+	name string
+	cond expr
+}
+
+type rangeVal struct {
+	min  expr
+	max  expr
+	cond expr
+}
+
 type (
 	MenuKind   int
 	ConfigType int
@@ -71,6 +87,24 @@ const (
 	TypeHex
 )
 
+// String returns the keyword which declares the type in a KConfig file.
+func (ct ConfigType) String() string {
+	switch ct {
+	case TypeBool:
+		return "bool"
+	case TypeTristate:
+		return "tristate"
+	case TypeString:
+		return "string"
+	case TypeInt:
+		return "int"
+	case TypeHex:
+		return "hex"
+	}
+
+	return ""
+}
+
 // DependsOn returns all transitive configs this config depends on.
 func (m *KConfigMenu) DependsOn() map[string]bool {
 	m.depsOnce.Do(func() {
@@ -99,6 +133,16 @@ func (m *KConfigMenu) DependsOn() map[string]bool {
 	return m.deps
 }
 
+// Selects returns the names of the configs which this config selects,
+// regardless of any condition of the selection.
+func (m *KConfigMenu) Selects() []string {
+	var names []string
+	for _, sel := range m.selects {
+		names = append(names, sel.name)
+	}
+	return names
+}
+
 func (m *KConfigMenu) Prompt() string {
 	// TODO: check prompt conditions, some prompts may be not visible. If all
 	// prompts are not visible, then then menu if effectively disabled (at least
@@ -109,6 +153,11 @@ func (m *KConfigMenu) Prompt() string {
 	return ""
 }
 
+// Help returns the help text of the config, menu or choice.
+func (m *KConfigMenu) Help() string {
+	return strings.Join(m.help, "\n")
+}
+
 type kconfigParser struct {
 	*parser
 	includes  []*parser
@@ -159,6 +208,49 @@ func ParseData(data []byte, file string, extra ...*KeyValue) (*KConfigFile, erro
 	return kconf, nil
 }
 
+// Merge combines the provided trees, e.g. those of Unikraft's core and of each
+// library, into a single tree whose root is a menu with the provided prompt and
+// whose configs may depend on each other across trees.  The trees are adopted
+// by the result and should not be used afterwards.  Where several trees define
+// the same config, the first definition is retained.
+func Merge(text string, trees ...*KConfigFile) *KConfigFile {
+	root := &KConfigMenu{
+		Kind:    MenuGroup,
+		prompts: []prompt{{text: text}},
+	}
+
+	kconf := &KConfigFile{
+		Root:    root,
+		Configs: make(map[string]*KConfigMenu),
+	}
+
+	for _, tree := range trees {
+		if tree == nil || tree.Root == nil {
+			continue
+		}
+
+		tree.Root.Parent = root
+		root.Elems = append(root.Elems, tree.Root)
+
+		for name, m := range tree.Configs {
+			if _, ok := kconf.Configs[name]; !ok {
+				kconf.Configs[name] = m
+			}
+		}
+	}
+
+	kconf.adopt(root)
+	return kconf
+}
+
+// adopt sets the back-link of the menu and all its elements to the tree.
+func (kconf *KConfigFile) adopt(m *KConfigMenu) {
+	m.kconfigFile = kconf
+	for _, elem := range m.Elems {
+		kconf.adopt(elem)
+	}
+}
+
 func (kconf *KConfigFile) walk(m *KConfigMenu, dependsOn, visibleIf expr) {
 	m.kconfigFile = kconf
 	m.dependsOn = exprAnd(dependsOn, m.dependsOn)
@@ -191,7 +283,7 @@ func (kp *kconfigParser) parseLine() {
 
 	if kp.helpIdent != 0 {
 		if kp.identLevel() >= kp.helpIdent {
-			_ = kp.ConsumeLine()
+			kp.current().help = append(kp.current().help, kp.ConsumeLine())
 			return
 		}
 		kp.helpIdent = 0
@@ -221,13 +313,13 @@ func (kp *kconfigParser) parseLine() {
 
 func (kp *kconfigParser) parseMenu(cmd string) {
 	switch cmd {
-	case "source":
+	case "source", "osource":
 		file, ok := kp.TryQuotedString()
 		if !ok {
 			file = kp.ConsumeLine()
 		}
 
-		kp.includeSource(file)
+		kp.includeSource(file, cmd == "osource")
 
 	case "mainmenu":
 		kp.pushCurrent(&KConfigMenu{
@@ -335,7 +427,15 @@ func (kp *kconfigParser) parseProperty(prop string) {
 		kp.MustConsume("if")
 		cur.visibleIf = exprAnd(cur.visibleIf, kp.parseExpr())
 
-	case "select", "imply":
+	case "select":
+		sel := selectVal{name: kp.Ident()}
+		if kp.TryConsume("if") {
+			sel.cond = kp.parseExpr()
+		}
+
+		cur.selects = append(cur.selects, sel)
+
+	case "imply":
 		_ = kp.Ident()
 		if kp.TryConsume("if") {
 			_ = kp.parseExpr()
@@ -353,11 +453,13 @@ func (kp *kconfigParser) parseProperty(prop string) {
 		kp.parseDefaultValue()
 
 	case "range":
-		_, _ = kp.parseExpr(), kp.parseExpr() // from, to
+		r := rangeVal{min: kp.parseExprTerm(), max: kp.parseExprTerm()}
 		if kp.TryConsume("if") {
-			_ = kp.parseExpr()
+			r.cond = kp.parseExpr()
 		}
 
+		cur.ranges = append(cur.ranges, r)
+
 	case "help", "---help---":
 		// Help rules are tricky: end of help is identified by smaller indentation
 		// level as would be rendered on a terminal with 8-column tabs setup, minus
@@ -367,7 +469,7 @@ func (kp *kconfigParser) parseProperty(prop string) {
 				continue
 			}
 			kp.helpIdent = kp.identLevel()
-			kp.ConsumeLine()
+			cur.help = append(cur.help, kp.ConsumeLine())
 			break
 		}
 
@@ -376,12 +478,26 @@ func (kp *kconfigParser) parseProperty(prop string) {
 	}
 }
 
-func (kp *kconfigParser) includeSource(file string) {
+func (kp *kconfigParser) includeSource(file string, optional bool) {
 	kp.newCurrent(nil)
-	file = filepath.Join(kp.baseDir, file)
+
+	// Unikraft's build system generates some of the included files, e.g.
+	// `$(KCONFIG_LIB_IN)`, and passes their location as variables.  Where these
+	// are not known, the inclusion is skipped as if it were optional.
+	file, ok := kp.expand(file)
+	if !ok {
+		return
+	}
+
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(kp.baseDir, file)
+	}
+
 	data, err := os.ReadFile(file)
 	if err != nil {
-		kp.failf("%v", err)
+		if !optional || !os.IsNotExist(err) {
+			kp.failf("%v", err)
+		}
 		return
 	}
 
@@ -397,6 +513,25 @@ func (kp *kconfigParser) includeSource(file string) {
 	}
 }
 
+// expand substitutes the `$(NAME)` references of the string with the values of
+// the environment and returns whether all of them were known.
+func (kp *kconfigParser) expand(str string) (string, bool) {
+	known := true
+	expanded := reVariable.ReplaceAllStringFunc(str, func(ref string) string {
+		kv, ok := kp.env[reVariable.FindStringSubmatch(ref)[1]]
+		if !ok || kv == nil {
+			known = false
+			return ref
+		}
+
+		return kv.Value
+	})
+
+	return expanded, known
+}
+
+var reVariable = regexp.MustCompile(`\$\(([A-Za-z0-9_]+)\)`)
+
 func (kp *kconfigParser) pushCurrent(m *KConfigMenu) {
 	kp.endCurrent()
 	kp.cur = m
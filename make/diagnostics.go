@@ -0,0 +1,296 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package make
+
+import (
+	"bytes"
+	"io"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Severity is the severity of a diagnostic.
+type Severity string
+
+const (
+	SeverityError   = Severity("error")
+	SeverityWarning = Severity("warning")
+	SeverityNote    = Severity("note")
+)
+
+// Diagnostic is an error, warning or note which the compiler or the linker
+// emitted during the invocation of make.
+type Diagnostic struct {
+	// File is the source or object file the diagnostic refers to, if any.
+	File string `json:"file,omitempty"`
+
+	// Line and Column locate the diagnostic within the file, if known.
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+
+	Severity Severity `json:"severity"`
+
+	// Library is the name of the library whose sources contain the file, if
+	// any.
+	Library string `json:"library,omitempty"`
+
+	Message string `json:"message"`
+}
+
+var (
+	// E.g. `main.c:12:5: error: 'foo' undeclared` as printed by GCC and Clang
+	compilerDiagnostic = regexp.MustCompile(`^(.+?):(\d+):(?:(\d+):)? (fatal error|error|warning|note): (.*)$`)
+
+	// E.g. `ld: main.c:12: undefined reference to `foo'` as printed by the
+	// linker when the object has debugging information.  Unlike the other
+	// diagnostics of the linker, the prefix is required such that make's own
+	// messages, e.g. `Makefile:12: *** missing separator.  Stop.`, are not
+	// mistaken for the linker's.
+	linkerLineDiagnostic = regexp.MustCompile(`^(?:\S*/)?(?:\S+-)?ld(?:\.\w+)?: ([^:\s]+):(\d+): (.*)$`)
+
+	// E.g. `main.o:(.text+0x1a): undefined reference to `foo'`
+	linkerSectionDiagnostic = regexp.MustCompile(`^(?:(?:\S*/)?(?:\S+-)?ld(?:\.\w+)?: )?([^:\s]+):\([^)]*\): (.*)$`)
+
+	// E.g. `ld: cannot find -lfoo`
+	linkerDiagnostic = regexp.MustCompile(`^(?:\S*/)?(?:\S+-)?ld(?:\.\w+)?: (.*)$`)
+)
+
+// ParseDiagnostic parses a single line of output of the compiler or the linker
+// into a diagnostic.  Lines which only provide context to other diagnostics,
+// e.g. `In function 'main':`, are not diagnostics.
+func ParseDiagnostic(line string) (Diagnostic, bool) {
+	line = strings.TrimRight(line, "\r\n")
+
+	if m := compilerDiagnostic.FindStringSubmatch(line); m != nil {
+		severity := Severity(m[4])
+		if m[4] == "fatal error" {
+			severity = SeverityError
+		}
+
+		d := Diagnostic{
+			File:     m[1],
+			Severity: severity,
+			Message:  m[5],
+		}
+
+		d.Line, _ = strconv.Atoi(m[2])
+		d.Column, _ = strconv.Atoi(m[3])
+
+		return d, true
+	}
+
+	// The remaining diagnostics are the linker's, which never provides a
+	// severity other than for warnings
+	severity := func(msg string) (Severity, string) {
+		if rest, ok := strings.CutPrefix(msg, "warning: "); ok {
+			return SeverityWarning, rest
+		}
+
+		return SeverityError, msg
+	}
+
+	if m := linkerLineDiagnostic.FindStringSubmatch(line); m != nil && !isLinkerContext(m[3]) {
+		d := Diagnostic{File: m[1]}
+		d.Line, _ = strconv.Atoi(m[2])
+		d.Severity, d.Message = severity(m[3])
+
+		return d, true
+	}
+
+	if m := linkerSectionDiagnostic.FindStringSubmatch(line); m != nil && !isLinkerContext(m[2]) {
+		d := Diagnostic{File: m[1]}
+		d.Severity, d.Message = severity(m[2])
+
+		return d, true
+	}
+
+	if m := linkerDiagnostic.FindStringSubmatch(line); m != nil && !isLinkerContext(m[1]) {
+		// E.g. `ld: main.o: in function `main':`
+		if file, _, ok := strings.Cut(m[1], ": "); ok && isLinkerContext(strings.TrimPrefix(m[1], file+": ")) {
+			return Diagnostic{}, false
+		}
+
+		d := Diagnostic{}
+		d.Severity, d.Message = severity(m[1])
+
+		return d, true
+	}
+
+	return Diagnostic{}, false
+}
+
+func isLinkerContext(msg string) bool {
+	return strings.HasPrefix(msg, "in function ") ||
+		strings.HasSuffix(msg, "first defined here")
+}
+
+// Diagnostics collects the diagnostics from the output of one or more
+// invocations of make, see WithDiagnostics.  Identical diagnostics, e.g. those
+// of a header which is included by multiple sources, are only collected once.
+type Diagnostics struct {
+	mu          sync.Mutex
+	sources     map[string]string
+	writers     []*diagnosticsWriter
+	seen        map[Diagnostic]struct{}
+	diagnostics []Diagnostic
+}
+
+// NewDiagnostics returns an empty collection of diagnostics.  The provided
+// sources map the directories of libraries to their names and are used to
+// attribute each diagnostic to the library whose directory most closely
+// contains its file.
+func NewDiagnostics(sources map[string]string) *Diagnostics {
+	return &Diagnostics{
+		sources: sources,
+		seen:    map[Diagnostic]struct{}{},
+	}
+}
+
+// writer returns a writer which collects the diagnostics from the output of an
+// invocation of make in the provided directory.
+func (d *Diagnostics) writer(dir string) io.Writer {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	w := &diagnosticsWriter{diagnostics: d, dir: dir}
+	d.writers = append(d.writers, w)
+
+	return w
+}
+
+func (d *Diagnostics) add(dir, line string) {
+	diagnostic, ok := ParseDiagnostic(line)
+	if !ok {
+		return
+	}
+
+	if len(diagnostic.File) > 0 && !filepath.IsAbs(diagnostic.File) && len(dir) > 0 {
+		diagnostic.File = filepath.Join(dir, diagnostic.File)
+	}
+
+	diagnostic.Library = d.library(diagnostic.File)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.seen[diagnostic]; ok {
+		return
+	}
+
+	d.seen[diagnostic] = struct{}{}
+	d.diagnostics = append(d.diagnostics, diagnostic)
+}
+
+// library returns the name of the library whose directory most closely
+// contains the file.
+func (d *Diagnostics) library(file string) string {
+	if len(file) == 0 {
+		return ""
+	}
+
+	file = filepath.Clean(file)
+	name, longest := "", 0
+
+	for dir, lib := range d.sources {
+		dir = filepath.Clean(dir)
+		if len(dir) <= longest {
+			continue
+		}
+
+		if file == dir || strings.HasPrefix(file, dir+string(filepath.Separator)) {
+			name, longest = lib, len(dir)
+		}
+	}
+
+	return name
+}
+
+// List returns the diagnostics collected so far, sorted by severity and then
+// by the order in which they were emitted.
+func (d *Diagnostics) List() []Diagnostic {
+	d.mu.Lock()
+	writers := d.writers
+	d.mu.Unlock()
+
+	// Diagnostics on the last line of output may not be terminated by a newline
+	for _, w := range writers {
+		w.flush()
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	list := append([]Diagnostic{}, d.diagnostics...)
+	sort.SliceStable(list, func(i, j int) bool {
+		return severityOrder(list[i].Severity) < severityOrder(list[j].Severity)
+	})
+
+	return list
+}
+
+// Count returns the number of collected diagnostics of the given severity.
+func (d *Diagnostics) Count(severity Severity) int {
+	count := 0
+	for _, diagnostic := range d.List() {
+		if diagnostic.Severity == severity {
+			count++
+		}
+	}
+
+	return count
+}
+
+func severityOrder(severity Severity) int {
+	switch severity {
+	case SeverityError:
+		return 0
+	case SeverityWarning:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// diagnosticsWriter splits the output of a single stream into lines, since the
+// output is written in arbitrary chunks.
+type diagnosticsWriter struct {
+	mu          sync.Mutex
+	diagnostics *Diagnostics
+	dir         string
+	partial     []byte
+}
+
+func (w *diagnosticsWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.partial = append(w.partial, b...)
+
+	for {
+		i := bytes.IndexByte(w.partial, '\n')
+		if i < 0 {
+			break
+		}
+
+		w.diagnostics.add(w.dir, string(w.partial[:i]))
+		w.partial = w.partial[i+1:]
+	}
+
+	return len(b), nil
+}
+
+func (w *diagnosticsWriter) flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.partial) > 0 {
+		w.diagnostics.add(w.dir, string(w.partial))
+		w.partial = nil
+	}
+}
@@ -0,0 +1,130 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package make
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseDiagnostic(t *testing.T) {
+	tests := []struct {
+		line     string
+		expected *Diagnostic
+	}{
+		{
+			line: "/app/main.c:12:5: error: 'foo' undeclared (first use in this function)",
+			expected: &Diagnostic{
+				File:     "/app/main.c",
+				Line:     12,
+				Column:   5,
+				Severity: SeverityError,
+				Message:  "'foo' undeclared (first use in this function)",
+			},
+		},
+		{
+			line: "main.c:3:10: fatal error: missing.h: No such file or directory",
+			expected: &Diagnostic{
+				File:     "main.c",
+				Line:     3,
+				Column:   10,
+				Severity: SeverityError,
+				Message:  "missing.h: No such file or directory",
+			},
+		},
+		{
+			line: "/app/main.c:7: warning: implicit declaration of function 'bar'",
+			expected: &Diagnostic{
+				File:     "/app/main.c",
+				Line:     7,
+				Severity: SeverityWarning,
+				Message:  "implicit declaration of function 'bar'",
+			},
+		},
+		{
+			line: "/usr/bin/ld: /app/main.c:12: undefined reference to `foo'",
+			expected: &Diagnostic{
+				File:     "/app/main.c",
+				Line:     12,
+				Severity: SeverityError,
+				Message:  "undefined reference to `foo'",
+			},
+		},
+		{
+			line: "main.o:(.text+0x1a): undefined reference to `foo'",
+			expected: &Diagnostic{
+				File:     "main.o",
+				Severity: SeverityError,
+				Message:  "undefined reference to `foo'",
+			},
+		},
+		{
+			line: "x86_64-linux-gnu-ld: cannot find -lfoo",
+			expected: &Diagnostic{
+				Severity: SeverityError,
+				Message:  "cannot find -lfoo",
+			},
+		},
+		{line: "/usr/bin/ld: /app/build/app.o: in function `main':"},
+		{line: "/app/main.c: In function 'main':"},
+		{line: "collect2: error: ld returned 1 exit status"},
+		{line: "Makefile:12: *** missing separator.  Stop."},
+		{line: "make[1]: *** [Makefile:1170: build] Error 2"},
+		{line: "  CC      libukdebug: print.o"},
+		{line: "build: done"},
+	}
+
+	for _, tt := range tests {
+		d, ok := ParseDiagnostic(tt.line)
+		if tt.expected == nil {
+			if ok {
+				t.Errorf("expected %q not to be a diagnostic, got %+v", tt.line, d)
+			}
+
+			continue
+		}
+
+		if !ok || !reflect.DeepEqual(d, *tt.expected) {
+			t.Errorf("expected %q to be parsed into %+v, got %+v", tt.line, *tt.expected, d)
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	diagnostics := NewDiagnostics(map[string]string{
+		"/uk":                "unikraft",
+		"/uk/lib/ukdebug":    "ukdebug",
+		"/app":               "helloworld",
+		"/app/build/libmusl": "musl",
+	})
+
+	w := diagnostics.writer("/uk")
+
+	// Output arrives in arbitrary chunks and repeats diagnostics of headers
+	for _, chunk := range []string{
+		"lib/ukdebug/print.c:4:1: warning: unused variable 'x'\n/app/main",
+		".c:12:5: error: 'foo' undeclared\n",
+		"lib/ukdebug/print.c:4:1: warning: unused variable 'x'\n",
+		"/app/build/libmusl/origin/src/stdio.c:1:1: note: declared here",
+	} {
+		if _, err := w.Write([]byte(chunk)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	expected := []Diagnostic{
+		{File: "/app/main.c", Line: 12, Column: 5, Severity: SeverityError, Library: "helloworld", Message: "'foo' undeclared"},
+		{File: "/uk/lib/ukdebug/print.c", Line: 4, Column: 1, Severity: SeverityWarning, Library: "ukdebug", Message: "unused variable 'x'"},
+		{File: "/app/build/libmusl/origin/src/stdio.c", Line: 1, Column: 1, Severity: SeverityNote, Library: "musl", Message: "declared here"},
+	}
+
+	if list := diagnostics.List(); !reflect.DeepEqual(list, expected) {
+		t.Errorf("expected %+v, got %+v", expected, list)
+	}
+
+	if count := diagnostics.Count(SeverityWarning); count != 1 {
+		t.Errorf("expected 1 warning, got %d", count)
+	}
+}
@@ -149,6 +149,13 @@ func NewFromInterface(args interface{}, mopts ...MakeOption) (*Make, error) {
 		processes = append(processes, calcProgressProcess)
 	}
 
+	if make.opts.diagnostics != nil {
+		make.opts.eopts = append(make.opts.eopts,
+			exec.WithStdoutCallback(make.opts.diagnostics.writer(make.opts.directory)),
+			exec.WithStderrCallback(make.opts.diagnostics.writer(make.opts.directory)),
+		)
+	}
+
 	mainExec, err := exec.NewExecutable(make.opts.bin, *make.opts, make.opts.Vars()...)
 	if err != nil {
 		return nil, err
@@ -40,11 +40,12 @@ type MakeOptions struct {
 	version                bool     `flag:"-v"`
 	warnUndefinedVariables bool     `flag:"--warn-undefined-variables"`
 
-	bin        string
-	targets    []string
-	vars       map[string]string
-	onProgress func(float64)
-	eopts      []exec.ExecOption
+	bin         string
+	targets     []string
+	vars        map[string]string
+	onProgress  func(float64)
+	eopts       []exec.ExecOption
+	diagnostics *Diagnostics
 }
 
 type MakeOption func(mo *MakeOptions) error
@@ -425,3 +426,12 @@ func WithBinPath(path string) MakeOption {
 		return nil
 	}
 }
+
+// WithDiagnostics collects the diagnostics of the compiler and the linker from
+// the output of the invocation of make
+func WithDiagnostics(diagnostics *Diagnostics) MakeOption {
+	return func(mo *MakeOptions) error {
+		mo.diagnostics = diagnostics
+		return nil
+	}
+}
@@ -0,0 +1,143 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Masterminds/semver/v3"
+)
+
+// semverOf returns the semantic version of the ManifestVersion.  Only versions
+// which are explicitly of type semver are considered, such that untyped names
+// of branches or channels which happen to parse never satisfy a constraint.
+func semverOf(version ManifestVersion) (*semver.Version, bool) {
+	if version.Type != ManifestVersionSemver {
+		return nil, false
+	}
+
+	v, err := semver.NewVersion(version.Version)
+	if err != nil {
+		return nil, false
+	}
+
+	return v, true
+}
+
+// versionTypeOf returns the type of the version with the provided name, which
+// is semver if the name parses as a semantic version and untyped otherwise.
+func versionTypeOf(version string) ManifestVersionType {
+	if _, err := semver.NewVersion(version); err == nil {
+		return ManifestVersionSemver
+	}
+
+	return ""
+}
+
+// exactVersion returns the name of the version or channel of the Manifest which
+// exactly matches the provided string.
+func (m Manifest) exactVersion(version string) (string, bool) {
+	for _, ver := range m.Versions {
+		if ver.Version == version {
+			return ver.Version, true
+		}
+	}
+
+	for _, channel := range m.Channels {
+		if channel.Name == version {
+			return channel.Name, true
+		}
+	}
+
+	return "", false
+}
+
+// Candidates returns the versions of the Manifest which satisfy the provided
+// constraint, e.g. `^0.12`, `~1.2.3` or `>=0.11 <0.13`, ordered from the
+// highest to the lowest.  A constraint which exactly matches the name of a
+// version or channel is returned as-is so that existing Kraftfiles continue to
+// select the same version.
+func (m Manifest) Candidates(constraint string) ([]ManifestVersion, error) {
+	if name, ok := m.exactVersion(constraint); ok {
+		for _, ver := range m.Versions {
+			if ver.Version == name {
+				return []ManifestVersion{ver}, nil
+			}
+		}
+
+		// Channels are represented as a version without a type such that they are
+		// never compared
+		return []ManifestVersion{{Version: name}}, nil
+	}
+
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid version constraint %q: %v", constraint, err)
+	}
+
+	type candidate struct {
+		version ManifestVersion
+		semver  *semver.Version
+	}
+
+	var candidates []candidate
+	for _, ver := range m.Versions {
+		v, ok := semverOf(ver)
+		if !ok || !c.Check(v) {
+			continue
+		}
+
+		candidates = append(candidates, candidate{ver, v})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].semver.GreaterThan(candidates[j].semver)
+	})
+
+	versions := make([]ManifestVersion, len(candidates))
+	for i, candidate := range candidates {
+		versions[i] = candidate.version
+	}
+
+	return versions, nil
+}
+
+// ResolveVersion returns the name of the highest version of the Manifest which
+// satisfies the provided constraint.
+func (m Manifest) ResolveVersion(constraint string) (string, error) {
+	candidates, err := m.Candidates(constraint)
+	if err != nil {
+		return "", err
+	}
+
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("no version of %s satisfies %s", m.Name, constraint)
+	}
+
+	return candidates[0].Version, nil
+}
+
+// supportsUnikraft returns whether the ManifestVersion is compatible with the
+// provided version of the Unikraft core.  The Unikraft field of the version is
+// interpreted as a constraint, e.g. `0.14` or `>=0.13`, and a version without
+// it is considered compatible with any core.
+func supportsUnikraft(version ManifestVersion, core string) bool {
+	if len(version.Unikraft) == 0 || len(core) == 0 {
+		return true
+	}
+
+	v, err := semver.NewVersion(core)
+	if err != nil {
+		return version.Unikraft == core
+	}
+
+	c, err := semver.NewConstraint(version.Unikraft)
+	if err != nil {
+		return version.Unikraft == core
+	}
+
+	return c.Check(v)
+}
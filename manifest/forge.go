@@ -0,0 +1,406 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"kraftkit.sh/config"
+	"kraftkit.sh/internal/version"
+	"kraftkit.sh/log"
+	"kraftkit.sh/unikraft"
+)
+
+// forgePerPage is the number of items requested per page from the REST API of
+// a Git forge.
+const forgePerPage = 100
+
+// forgeTimeout is the maximum duration of a request to the REST API of a Git
+// forge.
+var forgeTimeout = 30 * time.Second
+
+// forgeHosts maps the hosts of well-known public instances of Git forges to
+// their kind.
+var forgeHosts = map[string]string{
+	"gitlab.com":   "gitlab",
+	"gitea.com":    "gitea",
+	"codeberg.org": "gitea",
+}
+
+// forgeHints maps each kind of Git forge to the words by which self-hosted
+// instances are commonly recognisable, e.g. gitlab.example.org.
+var forgeHints = map[string][]string{
+	"gitlab": {"gitlab"},
+	"gitea":  {"gitea", "forgejo"},
+}
+
+// forgeClient is a minimal client for the JSON REST APIs of self-hostable Git
+// forges, such as GitLab and Gitea.
+type forgeClient struct {
+	api    string
+	header http.Header
+	client *http.Client
+}
+
+// forgeRepo is the location of a repository, or of a wildcard of repositories,
+// hosted on a Git forge.
+type forgeRepo struct {
+	// base is the scheme and host of the forge, e.g. https://gitlab.com
+	base string
+
+	// host is the host of the forge, used to look up authentication
+	host string
+
+	// namespace is the group, organisation or user owning the repository
+	namespace string
+
+	// name of the repository, which may be a wildcard, e.g. lib-*
+	name string
+}
+
+// forgeRef is a branch or tag of a repository.
+type forgeRef struct {
+	name string
+	sha  string
+}
+
+// forgeProject is the information about a repository retrieved from the REST
+// API of a Git forge which is necessary to populate a Manifest.
+type forgeProject struct {
+	name          string
+	description   string
+	defaultBranch string
+	origin        string
+	branches      []forgeRef
+	tags          []forgeRef
+	releases      []string
+}
+
+// parseForgeRepo parses the provided path as the HTTP(S) location of a
+// repository on a Git forge.
+func parseForgeRepo(path string) (*forgeRepo, error) {
+	u, err := url.Parse(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("unsupported scheme: %s", u.Scheme)
+	}
+
+	// Hosts which are known to not be a self-hostable forge are skipped to avoid
+	// needless probing of their APIs.
+	if u.Host == "github.com" {
+		return nil, fmt.Errorf("not a self-hostable forge: %s", u.Host)
+	}
+
+	trimmed := strings.TrimSuffix(strings.Trim(u.Path, "/"), ".git")
+	idx := strings.LastIndex(trimmed, "/")
+	if idx <= 0 {
+		return nil, fmt.Errorf(`expected the "HOST/NAMESPACE/REPO" format, got %q`, path)
+	}
+
+	return &forgeRepo{
+		base:      u.Scheme + "://" + u.Host,
+		host:      u.Host,
+		namespace: trimmed[:idx],
+		name:      trimmed[idx+1:],
+	}, nil
+}
+
+// isForge returns whether the host of the repository is configured, i.e. has
+// authentication details, or is detected as the provided kind of forge.  Only
+// then is its REST API probed such that arbitrary Git hosts are not sent
+// requests which are bound to fail.
+func (fr forgeRepo) isForge(kind string, auths map[string]config.AuthConfig) bool {
+	if _, ok := auths[fr.host]; ok {
+		return true
+	}
+
+	hostname := strings.ToLower(fr.host)
+	if h, _, err := net.SplitHostPort(hostname); err == nil {
+		hostname = h
+	}
+
+	if known, ok := forgeHosts[hostname]; ok {
+		return known == kind
+	}
+
+	for _, hint := range forgeHints[kind] {
+		if strings.Contains(hostname, hint) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isWildcard returns whether the repository name refers to multiple
+// repositories, e.g. lib-*.
+func (fr forgeRepo) isWildcard() bool {
+	return strings.HasSuffix(fr.name, "*")
+}
+
+// newForgeClient prepares a client for the REST API at the provided location.
+// If authentication details exist for the host, setAuth is used to apply
+// them to the headers of every request.  The endpoint of the authentication
+// details, if set, overrides the location of the API.
+func newForgeClient(api string, auth *config.AuthConfig, setAuth func(http.Header, config.AuthConfig)) *forgeClient {
+	fc := &forgeClient{
+		api:    strings.TrimSuffix(api, "/"),
+		header: http.Header{},
+		client: &http.Client{Timeout: forgeTimeout},
+	}
+
+	fc.header.Set("Accept", "application/json")
+	fc.header.Set("User-Agent", version.UserAgent())
+
+	if auth != nil {
+		if !auth.VerifySSL {
+			fc.client.Transport = &http.Transport{
+				TLSClientConfig: &tls.Config{
+					InsecureSkipVerify: true,
+				},
+			}
+		}
+
+		if len(auth.Endpoint) > 0 {
+			fc.api = strings.TrimSuffix(auth.Endpoint, "/")
+		}
+
+		if len(auth.Token) > 0 {
+			setAuth(fc.header, *auth)
+		}
+	}
+
+	return fc
+}
+
+// get performs a GET request against the provided path of the API and decodes
+// the JSON response into out.
+func (fc *forgeClient) get(ctx context.Context, path string, query url.Values, out any) (*http.Response, error) {
+	endpoint := fc.api + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	if config.Offline(ctx) {
+		return nil, config.OfflineError(endpoint)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header = fc.header.Clone()
+
+	log.G(ctx).WithFields(logrus.Fields{
+		"url":    endpoint,
+		"method": "GET",
+	}).Trace("http")
+
+	res, err := fc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return res, fmt.Errorf("received HTTP status code %d from %s", res.StatusCode, endpoint)
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return res, fmt.Errorf("could not decode response from %s: %v", endpoint, err)
+	}
+
+	return res, nil
+}
+
+// forgeList retrieves all pages of a list from the API.  The next page is
+// determined by the X-Next-Page or Link headers if the API provides either,
+// otherwise pages are requested until one is not full.
+func forgeList[T any](ctx context.Context, fc *forgeClient, path string, query url.Values) ([]T, error) {
+	var all []T
+
+	if query == nil {
+		query = url.Values{}
+	}
+
+	query.Set("per_page", strconv.Itoa(forgePerPage))
+	query.Set("limit", strconv.Itoa(forgePerPage))
+
+	for page := 1; page > 0; {
+		query.Set("page", strconv.Itoa(page))
+
+		var more []T
+		res, err := fc.get(ctx, path, query, &more)
+		if err != nil {
+			return nil, err
+		}
+
+		all = append(all, more...)
+
+		if next, ok := res.Header["X-Next-Page"]; ok {
+			page, _ = strconv.Atoi(strings.Join(next, ""))
+		} else if link := res.Header.Get("Link"); len(link) > 0 {
+			if strings.Contains(link, `rel="next"`) {
+				page++
+			} else {
+				page = 0
+			}
+		} else if len(more) < forgePerPage {
+			page = 0
+		} else {
+			page++
+		}
+	}
+
+	return all, nil
+}
+
+// manifestFromForge populates a Manifest from the information about a
+// repository retrieved from a Git forge.  Branches become channels whereas
+// releases and tags become versions.  The archive function returns the
+// location of the archive of the provided reference.
+func manifestFromForge(project *forgeProject, provider Provider, archive func(string) string, mopts ...ManifestOption) (*Manifest, error) {
+	t, n, _, err := unikraft.GuessTypeNameVersion(project.name)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		Type:        t,
+		Name:        n,
+		Description: project.description,
+		Origin:      project.origin,
+		Provider:    provider,
+	}
+
+	// This is unikraft-centric ettiquette where there exists two branches:
+	// "stable" and "staging".  If these two channels exist, the "stable" channel
+	// is the default, otherwise the default branch of the repository.
+	haveStaging := false
+	haveStable := false
+	for _, branch := range project.branches {
+		if branch.name == "staging" {
+			haveStaging = true
+		} else if branch.name == "stable" {
+			haveStable = true
+		}
+	}
+
+	defaultBranch := project.defaultBranch
+	if haveStaging && haveStable {
+		defaultBranch = "stable"
+	}
+
+	for _, branch := range project.branches {
+		manifest.Channels = append(manifest.Channels, ManifestChannel{
+			Name:     branch.name,
+			Default:  branch.name == defaultBranch,
+			Resource: archive(branch.name),
+		})
+	}
+
+	shas := make(map[string]string, len(project.tags))
+	for _, tag := range project.tags {
+		shas[tag.name] = tag.sha
+	}
+
+	// Releases are listed first as they are the preferred versions, followed by
+	// the remaining tags
+	seen := map[string]bool{}
+	var tags []forgeRef
+	for _, release := range project.releases {
+		if !seen[release] {
+			seen[release] = true
+			tags = append(tags, forgeRef{name: release, sha: shas[release]})
+		}
+	}
+	for _, tag := range project.tags {
+		if !seen[tag.name] {
+			seen[tag.name] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	for _, tag := range tags {
+		version := ManifestVersion{
+			Version:  tag.name,
+			Resource: archive(tag.name),
+			Type:     versionTypeOf(tag.name),
+		}
+
+		// See GitProvider.probeVersions for the Unikraft-centric `RELEASE-`
+		// prefix convention.
+		if strings.HasPrefix(tag.name, "RELEASE-") && len(tag.sha) >= 7 {
+			version.Unikraft = strings.TrimPrefix(tag.name, "RELEASE-")
+			version.Version = tag.sha[:7]
+			version.Type = ManifestVersionGitSha
+			version.Resource = archive(tag.sha)
+		}
+
+		manifest.Versions = append(manifest.Versions, version)
+	}
+
+	for _, opt := range mopts {
+		if err := opt(manifest); err != nil {
+			return nil, fmt.Errorf("could not apply option: %v", err)
+		}
+	}
+
+	return manifest, nil
+}
+
+// manifestsFromForgeInParallel populates the Manifests of the provided
+// repositories concurrently.
+func manifestsFromForgeInParallel[T any](repos []T, fn func(T) (*Manifest, error)) ([]*Manifest, error) {
+	var manifests []*Manifest
+	var errs []error
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	wg.Add(len(repos))
+
+	for _, repo := range repos {
+		go func(repo T) {
+			defer wg.Done()
+
+			manifest, err := fn(repo)
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+
+			manifests = append(manifests, manifest)
+		}(repo)
+	}
+
+	wg.Wait()
+
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+
+	return manifests, nil
+}
@@ -0,0 +1,42 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"testing"
+
+	"kraftkit.sh/config"
+)
+
+func TestForgeRepoIsForge(t *testing.T) {
+	auths := map[string]config.AuthConfig{
+		"git.example.org:8443": {Token: "secret"},
+	}
+
+	tests := []struct {
+		host     string
+		kind     string
+		expected bool
+	}{
+		{"gitlab.com", "gitlab", true},
+		{"gitlab.com", "gitea", false},
+		{"codeberg.org", "gitea", true},
+		{"codeberg.org", "gitlab", false},
+		{"gitlab.example.org", "gitlab", true},
+		{"GitLab.example.org:8080", "gitlab", true},
+		{"forgejo.example.org", "gitea", true},
+		{"git.example.org:8443", "gitlab", true},
+		{"git.example.org:8443", "gitea", true},
+		{"git.example.org", "gitlab", false},
+		{"bitbucket.org", "gitea", false},
+	}
+
+	for _, test := range tests {
+		repo := forgeRepo{host: test.host}
+		if actual := repo.isForge(test.kind, auths); actual != test.expected {
+			t.Errorf("expected %s to be a %s forge: %t, got %t", test.host, test.kind, test.expected, actual)
+		}
+	}
+}
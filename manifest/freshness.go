@@ -0,0 +1,241 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+
+	"kraftkit.sh/internal/version"
+	"kraftkit.sh/log"
+)
+
+// sourcesStateFileName is the name of the file, within the local manifests
+// directory, which records what was observed of each manifest source when it
+// was last updated.
+const sourcesStateFileName = "sources.yaml"
+
+// probeTimeout is the maximum duration to wait for the headers of a manifest or
+// index which is served over HTTP when probing whether it has changed.
+var probeTimeout = 10 * time.Second
+
+// sourceState records what was observed of a manifest source when it was last
+// updated such that subsequent updates can skip it if it has not changed.
+type sourceState struct {
+	// Source is the path or URL of the source as it is configured.
+	Source string `yaml:"source"`
+
+	// CheckedAt is when the source was last checked for changes.
+	CheckedAt time.Time `yaml:"checked_at"`
+
+	// ExpiresAt is when the source should next be checked for changes.
+	ExpiresAt time.Time `yaml:"expires_at"`
+
+	// ETag is the entity tag of a manifest or index served over HTTP.
+	ETag string `yaml:"etag,omitempty"`
+
+	// LastModified is the modification date of a manifest or index served over
+	// HTTP.
+	LastModified string `yaml:"last_modified,omitempty"`
+
+	// Refs is a digest of the references of a Git repository.
+	Refs string `yaml:"refs,omitempty"`
+
+	// Pages are the entity tags of each page of repositories listed for a
+	// GitHub wildcard.
+	Pages []string `yaml:"pages,omitempty"`
+
+	// Repos maps the clone URL of each repository matched by a GitHub wildcard
+	// to the last time it was pushed to.
+	Repos map[string]time.Time `yaml:"repos,omitempty"`
+
+	// Manifests are the entries of the local index which were saved from the
+	// source.
+	Manifests []*Manifest `yaml:"manifests,omitempty"`
+}
+
+// sourcesState is the collection of sourceState for each manifest source,
+// keyed by the source.
+type sourcesState struct {
+	Sources map[string]*sourceState `yaml:"sources"`
+}
+
+// newSourcesStateFromFile reads the state of the manifest sources from the
+// provided path.  A missing file results in an empty state.
+func newSourcesStateFromFile(path string) (*sourcesState, error) {
+	state := &sourcesState{
+		Sources: map[string]*sourceState{},
+	}
+
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return state, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(contents, state); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", path, err)
+	}
+
+	if state.Sources == nil {
+		state.Sources = map[string]*sourceState{}
+	}
+
+	return state, nil
+}
+
+// WriteToFile saves the state of the manifest sources at the provided path.
+func (state *sourcesState) WriteToFile(path string) error {
+	contents, err := yaml.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, contents, 0o600)
+}
+
+// isHTTPDocument returns whether the source is a manifest or index which is
+// served over HTTP, as opposed to, e.g., a Git repository.
+func isHTTPDocument(source string) bool {
+	u, err := url.Parse(source)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+
+	ext := filepath.Ext(u.Path)
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// probeHTTP conditionally requests the headers of a manifest or index which
+// is served over HTTP with the validators of the previous state and returns
+// whether it has changed.  The validators of the response and, if the server
+// specifies a maximum age, the expiry are recorded in the next state.
+func probeHTTP(ctx context.Context, source string, prev, next *sourceState) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, "HEAD", source, nil)
+	if err != nil {
+		return false, err
+	}
+
+	req.Header.Set("User-Agent", version.UserAgent())
+	if len(prev.ETag) > 0 {
+		req.Header.Set("If-None-Match", prev.ETag)
+	}
+	if len(prev.LastModified) > 0 {
+		req.Header.Set("If-Modified-Since", prev.LastModified)
+	}
+
+	log.G(ctx).WithFields(logrus.Fields{
+		"url":    source,
+		"method": "HEAD",
+	}).Trace("http")
+
+	resp, err := (&http.Client{Timeout: probeTimeout}).Do(req)
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+
+	if maxAge := cacheMaxAge(resp.Header.Get("Cache-Control")); maxAge > 0 {
+		next.ExpiresAt = next.CheckedAt.Add(maxAge)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusNotModified:
+		next.ETag = prev.ETag
+		next.LastModified = prev.LastModified
+		return false, nil
+
+	case http.StatusOK:
+		next.ETag = resp.Header.Get("ETag")
+		next.LastModified = resp.Header.Get("Last-Modified")
+
+		// Not every server honours conditional requests, so compare the
+		// validators as well.
+		if len(next.ETag) > 0 {
+			return next.ETag != prev.ETag, nil
+		} else if len(next.LastModified) > 0 {
+			return next.LastModified != prev.LastModified, nil
+		}
+	}
+
+	return true, nil
+}
+
+// cacheMaxAge returns the max-age directive of a Cache-Control header or zero
+// if it is not set.
+func cacheMaxAge(header string) time.Duration {
+	for _, directive := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(directive), "=")
+		if !ok || !strings.EqualFold(key, "max-age") {
+			continue
+		}
+
+		seconds, err := strconv.Atoi(value)
+		if err != nil || seconds < 0 {
+			return 0
+		}
+
+		return time.Duration(seconds) * time.Second
+	}
+
+	return 0
+}
+
+// probeGit lists the references of a Git repository and returns whether they
+// have changed since the previous state.  The digest of the references is
+// recorded in the next state.  If the references could not be listed, the
+// source is considered to have changed.
+func probeGit(ctx context.Context, source string, prev, next *sourceState, mopts ...ManifestOption) bool {
+	provider, err := NewGitProvider(ctx, source, mopts...)
+	if err != nil {
+		log.G(ctx).WithFields(logrus.Fields{
+			"source": source,
+		}).Tracef("could not list references: %v", err)
+		return true
+	}
+
+	refs := []string{}
+	for _, ref := range provider.(GitProvider).refs {
+		refs = append(refs, ref.String())
+	}
+
+	sort.Strings(refs)
+
+	digest := sha256.Sum256([]byte(strings.Join(refs, "\n")))
+	next.Refs = hex.EncodeToString(digest[:])
+
+	return next.Refs != prev.Refs
+}
+
+// wildcardProvider returns the GitHub provider of a source whose repository
+// name is a wildcard, e.g. https://github.com/unikraft/lib-*.
+func wildcardProvider(ctx context.Context, source string, mopts ...ManifestOption) (GitHubProvider, bool) {
+	if !strings.HasSuffix(source, "*") {
+		return GitHubProvider{}, false
+	}
+
+	provider, err := NewGitHubProvider(ctx, source, mopts...)
+	if err != nil {
+		return GitHubProvider{}, false
+	}
+
+	ghp := provider.(GitHubProvider)
+	return ghp, strings.HasSuffix(ghp.repo.RepoName(), "*")
+}
@@ -0,0 +1,127 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"kraftkit.sh/unikraft"
+)
+
+func TestProbeHTTP(t *testing.T) {
+	ctx := context.Background()
+	etag := `"v1"`
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Cache-Control", "public, max-age=60")
+
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+	}))
+	defer server.Close()
+
+	source := server.URL + "/index.yaml"
+	now := time.Now()
+
+	first := &sourceState{CheckedAt: now}
+	changed, err := probeHTTP(ctx, source, &sourceState{}, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !changed || first.ETag != etag {
+		t.Fatalf("expected changed source with etag %s, got %v and %q", etag, changed, first.ETag)
+	}
+
+	if expected := now.Add(60 * time.Second); !first.ExpiresAt.Equal(expected) {
+		t.Errorf("expected expiry %s, got %s", expected, first.ExpiresAt)
+	}
+
+	second := &sourceState{CheckedAt: now}
+	changed, err = probeHTTP(ctx, source, first, second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changed || second.ETag != etag {
+		t.Errorf("expected unchanged source with etag %s, got %v and %q", etag, changed, second.ETag)
+	}
+
+	etag = `"v2"`
+
+	third := &sourceState{CheckedAt: now}
+	changed, err = probeHTTP(ctx, source, second, third)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !changed || third.ETag != etag {
+		t.Errorf("expected changed source with etag %s, got %v and %q", etag, changed, third.ETag)
+	}
+
+	if requests != 3 {
+		t.Errorf("expected 3 requests, got %d", requests)
+	}
+}
+
+func TestSourcesState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), sourcesStateFileName)
+
+	state, err := newSourcesStateFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(state.Sources) != 0 {
+		t.Fatalf("expected empty state, got %d sources", len(state.Sources))
+	}
+
+	pushedAt := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	state.Sources["https://github.com/unikraft/lib-*"] = &sourceState{
+		Source: "https://github.com/unikraft/lib-*",
+		Pages:  []string{`"page1"`},
+		Repos: map[string]time.Time{
+			"https://github.com/unikraft/lib-musl.git": pushedAt,
+		},
+		Manifests: []*Manifest{{
+			Name:     "musl",
+			Type:     unikraft.ComponentTypeLib,
+			Manifest: manifestFilename(unikraft.ComponentTypeLib, "musl"),
+		}},
+	}
+
+	if err := state.WriteToFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := newSourcesStateFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ss, ok := loaded.Sources["https://github.com/unikraft/lib-*"]
+	if !ok {
+		t.Fatal("expected source to be loaded")
+	}
+
+	if !ss.Repos["https://github.com/unikraft/lib-musl.git"].Equal(pushedAt) {
+		t.Errorf("expected pushed at %s, got %s", pushedAt, ss.Repos["https://github.com/unikraft/lib-musl.git"])
+	}
+
+	if len(ss.Manifests) != 1 || ss.Manifests[0].Manifest != "./libs/musl.yaml" {
+		t.Errorf("expected manifest ./libs/musl.yaml, got %+v", ss.Manifests)
+	}
+}
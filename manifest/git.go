@@ -17,6 +17,7 @@ import (
 	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
 	giturl "github.com/kubescape/go-git-url"
 
+	"kraftkit.sh/config"
 	"kraftkit.sh/log"
 	"kraftkit.sh/pack"
 	"kraftkit.sh/unikraft"
@@ -89,6 +90,10 @@ func NewGitProvider(ctx context.Context, path string, mopts ...ManifestOption) (
 		}
 	}
 
+	if config.Offline(ctx) {
+		return nil, config.OfflineError(path)
+	}
+
 	// If this is a valid Git repository then let's generate a Manifest based on
 	// what we can read from the remote
 	refs, err := remote.ListContext(ctx, lopts)
@@ -171,6 +176,7 @@ func (gp GitProvider) probeVersions() []ManifestVersion {
 			version := ManifestVersion{
 				Version:  ver,
 				Resource: gp.repo,
+				Type:     versionTypeOf(ver),
 			}
 
 			// This is a unikraft-centric ettiquette where the Unikraft core
@@ -270,3 +276,21 @@ func isSSHURL(path string) bool {
 
 	return false
 }
+
+// gitRemoteOf returns the URL of the remote repository at the provided
+// location, which may lack its scheme.
+func gitRemoteOf(location string) string {
+	if isSSHURL(location) {
+		if strings.HasPrefix(location, "git@") {
+			return "ssh://" + location
+		}
+
+		return location
+	}
+
+	if !strings.HasPrefix(location, "https://") {
+		return "https://" + location
+	}
+
+	return location
+}
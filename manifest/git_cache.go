@@ -0,0 +1,226 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	gitplumbing "github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/plumbing/transport"
+
+	"kraftkit.sh/config"
+	"kraftkit.sh/log"
+	"kraftkit.sh/packmanager"
+)
+
+// gitCacheDirName is the name of the directory within the sources directory
+// which contains the bare repositories shared by all projects.
+const gitCacheDirName = "git"
+
+// gitCacheDir returns the location of the bare repository which caches the
+// objects of the provided remote repository.
+func gitCacheDir(sourcesDir, remote string) string {
+	return filepath.Join(sourcesDir, gitCacheDirName, packmanager.CacheKey(remote, "")+".git")
+}
+
+// updateGitCache fetches the provided branch of the remote repository into the
+// bare repository at dir, creating it if necessary.
+func updateGitCache(ctx context.Context, dir, remote string, ref gitplumbing.ReferenceName, auth transport.AuthMethod, progress io.Writer) error {
+	repo, err := git.PlainOpen(dir)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		repo, err = git.PlainInit(dir, true)
+		if err == nil {
+			_, err = repo.CreateRemote(&gitconfig.RemoteConfig{
+				Name: git.DefaultRemoteName,
+				URLs: []string{remote},
+			})
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("could not open git cache: %w", err)
+	}
+
+	err = repo.FetchContext(ctx, &git.FetchOptions{
+		RemoteURL: remote,
+		RefSpecs: []gitconfig.RefSpec{
+			gitconfig.RefSpec(fmt.Sprintf("+%s:%s", ref, ref)),
+		},
+		Tags:     git.NoTags,
+		Auth:     auth,
+		Progress: progress,
+	})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		return fmt.Errorf("could not update git cache: %w", err)
+	}
+
+	return nil
+}
+
+// checkoutFromGitCache creates a repository at local with the provided branch
+// checked out from the bare repository at dir.  Equivalent to a shallow clone,
+// only the objects of the tip of the branch are copied.  The origin of the
+// created repository is the provided remote.
+func checkoutFromGitCache(ctx context.Context, dir, local, remote string, ref gitplumbing.ReferenceName) error {
+	cache, err := git.PlainOpen(dir)
+	if err != nil {
+		return fmt.Errorf("could not open git cache: %w", err)
+	}
+
+	head, err := cache.Reference(ref, true)
+	if err != nil {
+		return fmt.Errorf("could not find %s in git cache: %w", ref.Short(), err)
+	}
+
+	commit, err := cache.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return err
+	}
+
+	hashes := []gitplumbing.Hash{commit.Hash, tree.Hash}
+
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+
+	for {
+		_, entry, err := walker.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return err
+		}
+
+		// Submodules are retrieved from their own origin
+		if entry.Mode == filemode.Submodule {
+			continue
+		}
+
+		hashes = append(hashes, entry.Hash)
+	}
+
+	repo, err := git.PlainInit(local, false)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		obj, err := cache.Storer.EncodedObject(gitplumbing.AnyObject, hash)
+		if err != nil {
+			return err
+		}
+
+		if _, err := repo.Storer.SetEncodedObject(obj); err != nil {
+			return err
+		}
+	}
+
+	if commit.NumParents() > 0 {
+		if err := repo.Storer.SetShallow([]gitplumbing.Hash{commit.Hash}); err != nil {
+			return err
+		}
+	}
+
+	if err := repo.Storer.SetReference(gitplumbing.NewHashReference(ref, commit.Hash)); err != nil {
+		return err
+	}
+
+	if _, err := repo.CreateRemote(&gitconfig.RemoteConfig{
+		Name: git.DefaultRemoteName,
+		URLs: []string{remote},
+	}); err != nil {
+		return err
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+
+	if err := worktree.Checkout(&git.CheckoutOptions{
+		Branch: ref,
+		Force:  true,
+	}); err != nil {
+		return fmt.Errorf("could not checkout %s: %w", ref.Short(), err)
+	}
+
+	submodules, err := worktree.Submodules()
+	if err != nil {
+		return err
+	}
+
+	if err := submodules.UpdateContext(ctx, &git.SubmoduleUpdateOptions{
+		Init:              true,
+		RecurseSubmodules: git.DefaultSubmoduleRecursionDepth,
+	}); err != nil {
+		return fmt.Errorf("could not update submodules: %w", err)
+	}
+
+	return nil
+}
+
+// pullGitFromCache places the provided branch of the remote repository at
+// local via the bare repository shared by all projects within the sources
+// directory, such that the same repository is only fetched once regardless
+// of the number of projects which use it.
+func pullGitFromCache(ctx context.Context, sourcesDir, remote, local string, ref gitplumbing.ReferenceName, auth transport.AuthMethod, progress io.Writer) error {
+	if _, err := git.PlainOpen(local); err == nil {
+		return git.ErrRepositoryAlreadyExists
+	}
+
+	dir := gitCacheDir(sourcesDir, remote)
+	offline := config.Offline(ctx)
+
+	// The cache is used as-is in offline mode
+	if offline {
+		log.G(ctx).
+			WithField("cache", dir).
+			WithField("branch", ref.Short()).
+			Debug("using git cache without updating in offline mode")
+	} else if err := packmanager.SchedulerFromContext(ctx).Do(ctx, remote, dir+"@"+ref.String(), func(ctx context.Context) error {
+		unlock, err := packmanager.LockCache(ctx, dir)
+		if err != nil {
+			return err
+		}
+
+		defer unlock()
+
+		log.G(ctx).
+			WithField("from", remote).
+			WithField("cache", dir).
+			WithField("branch", ref.Short()).
+			Debug("updating git cache")
+
+		return updateGitCache(ctx, dir, remote, ref, auth, progress)
+	}); err != nil {
+		return err
+	}
+
+	if err := checkoutFromGitCache(ctx, dir, local, remote, ref); err != nil {
+		// Do not leave a partial repository behind which would otherwise be
+		// mistaken for an existing clone
+		_ = os.RemoveAll(filepath.Join(local, git.GitDirName))
+
+		if offline {
+			return fmt.Errorf("%w: %v", config.OfflineError(remote+"@"+ref.Short()), err)
+		}
+
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,231 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gobwas/glob"
+
+	"kraftkit.sh/config"
+	"kraftkit.sh/log"
+	"kraftkit.sh/pack"
+)
+
+type GiteaProvider struct {
+	path   string
+	repo   *forgeRepo
+	mopts  []ManifestOption
+	client *forgeClient
+	ctx    context.Context
+}
+
+type giteaRepository struct {
+	Name          string `json:"name"`
+	FullName      string `json:"full_name"`
+	Description   string `json:"description"`
+	DefaultBranch string `json:"default_branch"`
+	CloneURL      string `json:"clone_url"`
+	HTMLURL       string `json:"html_url"`
+}
+
+type giteaBranch struct {
+	Name   string `json:"name"`
+	Commit struct {
+		ID string `json:"id"`
+	} `json:"commit"`
+}
+
+type giteaTag struct {
+	Name   string `json:"name"`
+	Commit struct {
+		SHA string `json:"sha"`
+	} `json:"commit"`
+}
+
+type giteaRelease struct {
+	TagName string `json:"tag_name"`
+	Draft   bool   `json:"draft"`
+}
+
+// NewGiteaProvider attempts to parse the input path as a location provided on
+// a Gitea instance, including Forgejo and Codeberg.  The instance is recognised
+// by probing its REST API for the repository, or for the organisation when the
+// repository name is a wildcard, e.g. lib-*, which is only done for hosts which
+// are configured or detected as Gitea, see forgeRepo.isForge.  Authentication details are looked
+// up by the host of the instance and the token is used as an access token.
+func NewGiteaProvider(ctx context.Context, path string, mopts ...ManifestOption) (Provider, error) {
+	repo, err := parseForgeRepo(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Gitea has no nested organisations
+	if strings.Contains(repo.namespace, "/") {
+		return nil, fmt.Errorf(`expected the "HOST/OWNER/REPO" format, got %q`, path)
+	}
+
+	// Cheap hack to get authentication details
+	manifest := &Manifest{}
+	for _, o := range mopts {
+		if err := o(manifest); err != nil {
+			return nil, err
+		}
+	}
+
+	if !repo.isForge("gitea", manifest.Auths()) {
+		return nil, fmt.Errorf("not a known gitea host: %s", repo.host)
+	}
+
+	var auth *config.AuthConfig
+	if a, ok := manifest.Auths()[repo.host]; ok {
+		auth = &a
+	}
+
+	client := newForgeClient(repo.base+"/api/v1", auth, func(header http.Header, auth config.AuthConfig) {
+		header.Set("Authorization", "token "+auth.Token)
+	})
+
+	probe := "/repos/" + url.PathEscape(repo.namespace) + "/" + url.PathEscape(repo.name)
+	if repo.isWildcard() {
+		probe = "/orgs/" + url.PathEscape(repo.namespace)
+	}
+
+	var found struct {
+		ID int `json:"id"`
+	}
+	if _, err := client.get(ctx, probe, nil, &found); err != nil {
+		return nil, fmt.Errorf("not a gitea repository: %v", err)
+	} else if found.ID == 0 {
+		return nil, fmt.Errorf("not a gitea repository: %s", path)
+	}
+
+	return GiteaProvider{
+		path:   path,
+		repo:   repo,
+		mopts:  mopts,
+		client: client,
+		ctx:    ctx,
+	}, nil
+}
+
+func (gtp GiteaProvider) Manifests() ([]*Manifest, error) {
+	if gtp.repo.isWildcard() {
+		return gtp.manifestsFromWildcard()
+	}
+
+	var repo giteaRepository
+	if _, err := gtp.client.get(gtp.ctx,
+		"/repos/"+url.PathEscape(gtp.repo.namespace)+"/"+url.PathEscape(gtp.repo.name),
+		nil,
+		&repo,
+	); err != nil {
+		return nil, err
+	}
+
+	manifest, err := gtp.manifestFromRepository(repo)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Manifest{manifest}, nil
+}
+
+func (gtp GiteaProvider) PullManifest(ctx context.Context, manifest *Manifest, popts ...pack.PullOption) error {
+	if useGit {
+		return pullGit(ctx, manifest, popts...)
+	}
+
+	if err := pullArchive(ctx, manifest, popts...); err != nil {
+		log.G(ctx).Trace(err)
+		return pullGit(ctx, manifest, popts...)
+	}
+
+	return nil
+}
+
+func (gtp GiteaProvider) String() string {
+	return "gitea"
+}
+
+// manifestsFromWildcard is an internal method which is called by Manifests to
+// enumerate the repositories of a Gitea organisation whose name matches the
+// wildcard, e.g. lib-*
+func (gtp GiteaProvider) manifestsFromWildcard() ([]*Manifest, error) {
+	g := glob.MustCompile(gtp.repo.name)
+
+	repos, err := forgeList[giteaRepository](gtp.ctx, gtp.client,
+		"/orgs/"+url.PathEscape(gtp.repo.namespace)+"/repos",
+		nil,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []giteaRepository
+	for _, repo := range repos {
+		if !g.Match(repo.Name) {
+			continue
+		}
+
+		log.G(gtp.ctx).Infof("found via wildcard %s", repo.CloneURL)
+		matched = append(matched, repo)
+	}
+
+	return manifestsFromForgeInParallel(matched, gtp.manifestFromRepository)
+}
+
+// manifestFromRepository populates a Manifest with the branches, tags and
+// releases of the provided repository.
+func (gtp GiteaProvider) manifestFromRepository(repo giteaRepository) (*Manifest, error) {
+	owner, name, _ := strings.Cut(repo.FullName, "/")
+	prefix := "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(name)
+
+	branches, err := forgeList[giteaBranch](gtp.ctx, gtp.client, prefix+"/branches", nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not list branches of %s: %v", repo.FullName, err)
+	}
+
+	tags, err := forgeList[giteaTag](gtp.ctx, gtp.client, prefix+"/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not list tags of %s: %v", repo.FullName, err)
+	}
+
+	releases, err := forgeList[giteaRelease](gtp.ctx, gtp.client, prefix+"/releases", nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not list releases of %s: %v", repo.FullName, err)
+	}
+
+	fp := &forgeProject{
+		name:          repo.Name,
+		description:   repo.Description,
+		defaultBranch: repo.DefaultBranch,
+		origin:        repo.CloneURL,
+	}
+
+	for _, branch := range branches {
+		fp.branches = append(fp.branches, forgeRef{name: branch.Name, sha: branch.Commit.ID})
+	}
+
+	for _, tag := range tags {
+		fp.tags = append(fp.tags, forgeRef{name: tag.Name, sha: tag.Commit.SHA})
+	}
+
+	for _, release := range releases {
+		if release.Draft {
+			continue
+		}
+
+		fp.releases = append(fp.releases, release.TagName)
+	}
+
+	return manifestFromForge(fp, gtp, func(ref string) string {
+		return fmt.Sprintf("%s/archive/%s.tar.gz", repo.HTMLURL, ref)
+	}, gtp.mopts...)
+}
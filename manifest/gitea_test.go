@@ -0,0 +1,93 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"kraftkit.sh/config"
+)
+
+func TestGiteaProvider(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var body any
+		switch r.URL.EscapedPath() {
+		case "/api/v1/repos/unikraft/app-nginx":
+			body = map[string]any{
+				"id":             1,
+				"name":           "app-nginx",
+				"full_name":      "unikraft/app-nginx",
+				"default_branch": "main",
+				"clone_url":      srv.URL + "/unikraft/app-nginx.git",
+				"html_url":       srv.URL + "/unikraft/app-nginx",
+			}
+		case "/api/v1/repos/unikraft/app-nginx/branches":
+			// Paginate the branches to exercise the Link header
+			if r.URL.Query().Get("page") == "1" {
+				w.Header().Set("Link", `<`+srv.URL+`/api/v1/repos/unikraft/app-nginx/branches?page=2>; rel="next"`)
+				body = []any{map[string]any{"name": "main", "commit": map[string]any{"id": "1111111111"}}}
+			} else {
+				w.Header().Set("Link", `<`+srv.URL+`/api/v1/repos/unikraft/app-nginx/branches?page=1>; rel="prev"`)
+				body = []any{map[string]any{"name": "dev", "commit": map[string]any{"id": "2222222222"}}}
+			}
+		case "/api/v1/repos/unikraft/app-nginx/tags":
+			body = []any{map[string]any{"name": "v1.0.0", "commit": map[string]any{"sha": "3333333333"}}}
+		case "/api/v1/repos/unikraft/app-nginx/releases":
+			body = []any{
+				map[string]any{"tag_name": "v1.0.0"},
+				map[string]any{"tag_name": "v2.0.0", "draft": true},
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	provider, err := NewGiteaProvider(context.Background(), srv.URL+"/unikraft/app-nginx.git",
+		WithAuthConfig(map[string]config.AuthConfig{
+			u.Host: {Token: "secret", VerifySSL: true},
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifests, err := provider.Manifests()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifest := manifests[0]
+	if manifest.Name != "nginx" || manifest.Origin != srv.URL+"/unikraft/app-nginx.git" {
+		t.Errorf("unexpected manifest: %s (%s)", manifest.Name, manifest.Origin)
+	}
+
+	if len(manifest.Channels) != 2 || !manifest.Channels[0].Default {
+		t.Errorf("expected main to be the default channel: %+v", manifest.Channels)
+	}
+
+	if len(manifest.Versions) != 1 || manifest.Versions[0].Version != "v1.0.0" {
+		t.Fatalf("expected only the published release: %+v", manifest.Versions)
+	}
+
+	if resource := srv.URL + "/unikraft/app-nginx/archive/v1.0.0.tar.gz"; manifest.Versions[0].Resource != resource {
+		t.Errorf("expected resource %s but got %s", resource, manifest.Versions[0].Resource)
+	}
+}
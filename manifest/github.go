@@ -19,12 +19,17 @@ import (
 	"github.com/sirupsen/logrus"
 	"golang.org/x/oauth2"
 
+	"kraftkit.sh/config"
 	"kraftkit.sh/internal/ghrepo"
 	"kraftkit.sh/log"
 	"kraftkit.sh/pack"
 	"kraftkit.sh/unikraft"
 )
 
+// githubPerPage is the number of repositories requested per page when listing
+// the repositories of a wildcard source.
+const githubPerPage = 100
+
 type GitHubProvider struct {
 	path   string
 	repo   ghrepo.Interface
@@ -152,12 +157,65 @@ func (ghp GitHubProvider) String() string {
 // manifestsFromWildcard is an internal method which is called by Manifests to
 // parse a GitHub source with a wildcard repository name, e.g. lib-*
 func (ghp GitHubProvider) manifestsFromWildcard() ([]*Manifest, error) {
+	repos, _, _, err := ghp.listRepositories(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return ghp.appendRepositoriesToManifestInParallel(repos)
+}
+
+// listRepositories returns the repositories which match the wildcard
+// repository name of the provider as well as the entity tag of each page of
+// results.  When entity tags from a previous listing are provided, each page is
+// first requested conditionally and, if none of them has changed, no
+// repositories are returned and notModified is set.  Conditional requests which
+// are answered with 304 Not Modified do not count against GitHub's rate limit.
+func (ghp GitHubProvider) listRepositories(etags []string) (repos []*github.Repository, pages []string, notModified bool, err error) {
 	if !strings.HasSuffix(ghp.repo.RepoName(), "*") {
-		return nil, fmt.Errorf("not a wildcard")
+		return nil, nil, false, fmt.Errorf("not a wildcard")
 	}
 
-	var repos []*github.Repository
-	opts := github.ListOptions{}
+	if config.Offline(ghp.ctx) {
+		return nil, nil, false, config.OfflineError(ghp.path)
+	}
+
+	if len(etags) > 0 {
+		notModified = true
+
+		for i, etag := range etags {
+			req, err := ghp.client.NewRequest(
+				"GET",
+				fmt.Sprintf("orgs/%s/repos?per_page=%d&page=%d", ghp.repo.RepoOwner(), githubPerPage, i+1),
+				nil,
+			)
+			if err != nil {
+				return nil, nil, false, err
+			}
+
+			req.Header.Set("If-None-Match", etag)
+
+			log.G(ghp.ctx).WithFields(logrus.Fields{
+				"page": i + 1,
+			}).Trace("conditionally querying GitHub API for repositories")
+
+			resp, err := ghp.client.Do(ghp.ctx, req, nil)
+			if resp != nil && resp.StatusCode == http.StatusNotModified {
+				continue
+			} else if err != nil {
+				return nil, nil, false, err
+			}
+
+			notModified = false
+			break
+		}
+
+		if notModified {
+			return nil, etags, true, nil
+		}
+	}
+
+	opts := github.ListOptions{PerPage: githubPerPage}
 	g := glob.MustCompile(ghp.repo.RepoName())
 
 	for {
@@ -172,9 +230,11 @@ func (ghp GitHubProvider) manifestsFromWildcard() ([]*Manifest, error) {
 			},
 		)
 		if err != nil {
-			return nil, err
+			return nil, nil, false, err
 		}
 
+		pages = append(pages, resp.Header.Get("ETag"))
+
 		for _, repo := range more {
 			if !g.Match(*repo.Name) {
 				continue
@@ -191,7 +251,7 @@ func (ghp GitHubProvider) manifestsFromWildcard() ([]*Manifest, error) {
 		opts.Page = resp.NextPage
 	}
 
-	return ghp.appendRepositoriesToManifestInParallel(repos)
+	return repos, pages, false, nil
 }
 
 func (ghp GitHubProvider) appendRepositoriesToManifestInParallel(repos []*github.Repository) ([]*Manifest, error) {
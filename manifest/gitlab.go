@@ -0,0 +1,219 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/gobwas/glob"
+
+	"kraftkit.sh/config"
+	"kraftkit.sh/log"
+	"kraftkit.sh/pack"
+)
+
+type GitLabProvider struct {
+	path   string
+	repo   *forgeRepo
+	mopts  []ManifestOption
+	client *forgeClient
+	ctx    context.Context
+}
+
+type gitlabProject struct {
+	ID                int    `json:"id"`
+	Path              string `json:"path"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	Description       string `json:"description"`
+	DefaultBranch     string `json:"default_branch"`
+	HTTPURLToRepo     string `json:"http_url_to_repo"`
+	WebURL            string `json:"web_url"`
+}
+
+type gitlabRef struct {
+	Name   string `json:"name"`
+	Commit struct {
+		ID string `json:"id"`
+	} `json:"commit"`
+}
+
+type gitlabRelease struct {
+	TagName         string `json:"tag_name"`
+	UpcomingRelease bool   `json:"upcoming_release"`
+}
+
+// NewGitLabProvider attempts to parse the input path as a location provided on
+// a GitLab instance, either gitlab.com or self-hosted.  The instance is
+// recognised by probing its REST API for the project, or for the group when
+// the repository name is a wildcard, e.g. lib-*, which is only done for hosts
+// which are configured or detected as GitLab, see forgeRepo.isForge.  Authentication details are
+// looked up by the host of the instance and the token is used as a personal
+// access token.
+func NewGitLabProvider(ctx context.Context, path string, mopts ...ManifestOption) (Provider, error) {
+	repo, err := parseForgeRepo(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cheap hack to get authentication details
+	manifest := &Manifest{}
+	for _, o := range mopts {
+		if err := o(manifest); err != nil {
+			return nil, err
+		}
+	}
+
+	if !repo.isForge("gitlab", manifest.Auths()) {
+		return nil, fmt.Errorf("not a known gitlab host: %s", repo.host)
+	}
+
+	var auth *config.AuthConfig
+	if a, ok := manifest.Auths()[repo.host]; ok {
+		auth = &a
+	}
+
+	client := newForgeClient(repo.base+"/api/v4", auth, func(header http.Header, auth config.AuthConfig) {
+		header.Set("PRIVATE-TOKEN", auth.Token)
+	})
+
+	probe := "/projects/" + url.PathEscape(repo.namespace+"/"+repo.name)
+	if repo.isWildcard() {
+		probe = "/groups/" + url.PathEscape(repo.namespace)
+	}
+
+	var found struct {
+		ID int `json:"id"`
+	}
+	if _, err := client.get(ctx, probe, nil, &found); err != nil {
+		return nil, fmt.Errorf("not a gitlab repository: %v", err)
+	} else if found.ID == 0 {
+		return nil, fmt.Errorf("not a gitlab repository: %s", path)
+	}
+
+	return GitLabProvider{
+		path:   path,
+		repo:   repo,
+		mopts:  mopts,
+		client: client,
+		ctx:    ctx,
+	}, nil
+}
+
+func (glp GitLabProvider) Manifests() ([]*Manifest, error) {
+	if glp.repo.isWildcard() {
+		return glp.manifestsFromWildcard()
+	}
+
+	var project gitlabProject
+	if _, err := glp.client.get(glp.ctx,
+		"/projects/"+url.PathEscape(glp.repo.namespace+"/"+glp.repo.name),
+		nil,
+		&project,
+	); err != nil {
+		return nil, err
+	}
+
+	manifest, err := glp.manifestFromProject(project)
+	if err != nil {
+		return nil, err
+	}
+
+	return []*Manifest{manifest}, nil
+}
+
+func (glp GitLabProvider) PullManifest(ctx context.Context, manifest *Manifest, popts ...pack.PullOption) error {
+	if useGit {
+		return pullGit(ctx, manifest, popts...)
+	}
+
+	if err := pullArchive(ctx, manifest, popts...); err != nil {
+		log.G(ctx).Trace(err)
+		return pullGit(ctx, manifest, popts...)
+	}
+
+	return nil
+}
+
+func (glp GitLabProvider) String() string {
+	return "gitlab"
+}
+
+// manifestsFromWildcard is an internal method which is called by Manifests to
+// enumerate the projects of a GitLab group, including its subgroups, whose
+// name matches the wildcard, e.g. lib-*
+func (glp GitLabProvider) manifestsFromWildcard() ([]*Manifest, error) {
+	g := glob.MustCompile(glp.repo.name)
+
+	projects, err := forgeList[gitlabProject](glp.ctx, glp.client,
+		"/groups/"+url.PathEscape(glp.repo.namespace)+"/projects",
+		url.Values{"include_subgroups": []string{"true"}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var matched []gitlabProject
+	for _, project := range projects {
+		if !g.Match(project.Path) {
+			continue
+		}
+
+		log.G(glp.ctx).Infof("found via wildcard %s", project.HTTPURLToRepo)
+		matched = append(matched, project)
+	}
+
+	return manifestsFromForgeInParallel(matched, glp.manifestFromProject)
+}
+
+// manifestFromProject populates a Manifest with the branches, tags and releases
+// of the provided project.
+func (glp GitLabProvider) manifestFromProject(project gitlabProject) (*Manifest, error) {
+	prefix := fmt.Sprintf("/projects/%d", project.ID)
+
+	branches, err := forgeList[gitlabRef](glp.ctx, glp.client, prefix+"/repository/branches", nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not list branches of %s: %v", project.PathWithNamespace, err)
+	}
+
+	tags, err := forgeList[gitlabRef](glp.ctx, glp.client, prefix+"/repository/tags", nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not list tags of %s: %v", project.PathWithNamespace, err)
+	}
+
+	releases, err := forgeList[gitlabRelease](glp.ctx, glp.client, prefix+"/releases", nil)
+	if err != nil {
+		return nil, fmt.Errorf("could not list releases of %s: %v", project.PathWithNamespace, err)
+	}
+
+	fp := &forgeProject{
+		name:          project.Path,
+		description:   project.Description,
+		defaultBranch: project.DefaultBranch,
+		origin:        project.HTTPURLToRepo,
+	}
+
+	for _, branch := range branches {
+		fp.branches = append(fp.branches, forgeRef{name: branch.Name, sha: branch.Commit.ID})
+	}
+
+	for _, tag := range tags {
+		fp.tags = append(fp.tags, forgeRef{name: tag.Name, sha: tag.Commit.ID})
+	}
+
+	for _, release := range releases {
+		if release.UpcomingRelease {
+			continue
+		}
+
+		fp.releases = append(fp.releases, release.TagName)
+	}
+
+	return manifestFromForge(fp, glp, func(ref string) string {
+		return fmt.Sprintf("%s/-/archive/%s/%s-%s.tar.gz", project.WebURL, ref, project.Path, ref)
+	}, glp.mopts...)
+}
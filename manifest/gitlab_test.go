@@ -0,0 +1,124 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"kraftkit.sh/config"
+)
+
+func TestGitLabProvider(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("PRIVATE-TOKEN") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		project := map[string]any{
+			"id":                  42,
+			"path":                "lib-foo",
+			"path_with_namespace": "unikraft/libs/lib-foo",
+			"description":         "The foo library",
+			"default_branch":      "main",
+			"http_url_to_repo":    srv.URL + "/unikraft/libs/lib-foo.git",
+			"web_url":             srv.URL + "/unikraft/libs/lib-foo",
+		}
+
+		var body any
+		switch r.URL.EscapedPath() {
+		case "/api/v4/groups/unikraft%2Flibs":
+			body = map[string]any{"id": 7}
+		case "/api/v4/groups/unikraft%2Flibs/projects":
+			body = []any{project, map[string]any{"id": 43, "path": "app-bar"}}
+		case "/api/v4/projects/42/repository/branches":
+			body = []any{
+				map[string]any{"name": "staging", "commit": map[string]any{"id": "1111111111"}},
+				map[string]any{"name": "stable", "commit": map[string]any{"id": "2222222222"}},
+			}
+		case "/api/v4/projects/42/repository/tags":
+			// Paginate the tags to exercise the X-Next-Page header
+			if r.URL.Query().Get("page") == "1" {
+				w.Header().Set("X-Next-Page", "2")
+				body = []any{map[string]any{"name": "v0.1.0", "commit": map[string]any{"id": "3333333333"}}}
+			} else {
+				w.Header().Set("X-Next-Page", "")
+				body = []any{map[string]any{"name": "RELEASE-0.14.0", "commit": map[string]any{"id": "4444444444"}}}
+			}
+		case "/api/v4/projects/42/releases":
+			body = []any{
+				map[string]any{"tag_name": "v0.2.0"},
+				map[string]any{"tag_name": "v0.3.0", "upcoming_release": true},
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		_ = json.NewEncoder(w).Encode(body)
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	auths := WithAuthConfig(map[string]config.AuthConfig{
+		u.Host: {Token: "secret", VerifySSL: true},
+	})
+
+	if _, err := NewGitLabProvider(context.Background(), srv.URL+"/unikraft/libs/lib-missing"); err == nil {
+		t.Fatalf("expected unknown project to not be recognised")
+	}
+
+	provider, err := NewGitLabProvider(context.Background(), srv.URL+"/unikraft/libs/lib-*", auths)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	manifests, err := provider.Manifests()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(manifests) != 1 {
+		t.Fatalf("expected 1 manifest but got %d", len(manifests))
+	}
+
+	manifest := manifests[0]
+	if manifest.Name != "foo" || manifest.Description != "The foo library" {
+		t.Errorf("unexpected manifest: %s (%s)", manifest.Name, manifest.Description)
+	}
+
+	if manifest.Provider.String() != "gitlab" {
+		t.Errorf("expected gitlab provider but got %s", manifest.Provider)
+	}
+
+	if len(manifest.Channels) != 2 || !manifest.Channels[1].Default || manifest.Channels[0].Default {
+		t.Errorf("expected stable to be the default channel: %+v", manifest.Channels)
+	}
+
+	expected := []string{"v0.2.0", "v0.1.0", "4444444"}
+	if len(manifest.Versions) != len(expected) {
+		t.Fatalf("expected versions %v but got %+v", expected, manifest.Versions)
+	}
+
+	for i, version := range manifest.Versions {
+		if version.Version != expected[i] {
+			t.Errorf("expected version %s but got %s", expected[i], version.Version)
+		}
+	}
+
+	if manifest.Versions[2].Unikraft != "0.14.0" || manifest.Versions[2].Type != ManifestVersionGitSha {
+		t.Errorf("unexpected RELEASE- version: %+v", manifest.Versions[2])
+	}
+
+	if resource := srv.URL + "/unikraft/libs/lib-foo/-/archive/v0.2.0/lib-foo-v0.2.0.tar.gz"; manifest.Versions[0].Resource != resource {
+		t.Errorf("expected resource %s but got %s", resource, manifest.Versions[0].Resource)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 
+	"kraftkit.sh/config"
 	"kraftkit.sh/log"
 	"kraftkit.sh/pack"
 
@@ -76,7 +77,7 @@ func (mip ManifestIndexProvider) Manifests() ([]*Manifest, error) {
 }
 
 func (mip ManifestIndexProvider) PullManifest(ctx context.Context, manifest *Manifest, opts ...pack.PullOption) error {
-	return fmt.Errorf("not implemented: manifest.ManifestIndexProvider.PullManifest")
+	return pullArchive(ctx, manifest, opts...)
 }
 
 func (mip ManifestIndexProvider) String() string {
@@ -86,6 +87,15 @@ func (mip ManifestIndexProvider) String() string {
 // NewManifestIndexFromBytes parses a byte array of a YAML representing a
 // manifest index
 func NewManifestIndexFromBytes(raw []byte, mopts ...ManifestOption) (*ManifestIndex, error) {
+	return newManifestIndexFromBytes(raw, "", mopts...)
+}
+
+// newManifestIndexFromBytes parses a byte array of a YAML representing a
+// manifest index which was retrieved from the provided origin.  Resources of
+// the manifests within the index which are relative paths are resolved against
+// the location of the index, such that an index can be served alongside its
+// archives without knowing the address it is served from.
+func newManifestIndexFromBytes(raw []byte, origin string, mopts ...ManifestOption) (*ManifestIndex, error) {
 	index := &ManifestIndex{}
 
 	if err := yaml.Unmarshal(raw, index); err != nil {
@@ -97,6 +107,16 @@ func NewManifestIndexFromBytes(raw []byte, mopts ...ManifestOption) (*ManifestIn
 	}
 
 	for i, manifest := range index.Manifests {
+		if len(origin) > 0 {
+			for j, channel := range manifest.Channels {
+				manifest.Channels[j].Resource = resolveIndexResource(origin, channel.Resource)
+			}
+
+			for j, version := range manifest.Versions {
+				manifest.Versions[j].Resource = resolveIndexResource(origin, version.Resource)
+			}
+		}
+
 		for _, o := range mopts {
 			if err := o(manifest); err != nil {
 				return nil, err
@@ -122,7 +142,7 @@ func NewManifestIndexFromFile(path string, mopts ...ManifestOption) (*ManifestIn
 		return nil, err
 	}
 
-	index, err := NewManifestIndexFromBytes(contents, mopts...)
+	index, err := newManifestIndexFromBytes(contents, path, mopts...)
 	if err != nil {
 		return nil, err
 	}
@@ -140,6 +160,10 @@ func NewManifestIndexFromURL(ctx context.Context, path string, mopts ...Manifest
 		return nil, err
 	}
 
+	if config.Offline(ctx) {
+		return nil, config.OfflineError(path)
+	}
+
 	client := &http.Client{}
 
 	head, err := http.NewRequestWithContext(ctx, "HEAD", path, nil)
@@ -196,7 +220,7 @@ func NewManifestIndexFromURL(ctx context.Context, path string, mopts ...Manifest
 		return nil, err
 	}
 
-	index, err := NewManifestIndexFromBytes(contents, mopts...)
+	index, err := newManifestIndexFromBytes(contents, path, mopts...)
 	if err != nil {
 		return nil, err
 	}
@@ -206,6 +230,30 @@ func NewManifestIndexFromURL(ctx context.Context, path string, mopts ...Manifest
 	return index, nil
 }
 
+// resolveIndexResource returns the location of the provided resource of a
+// manifest within the index at origin.  Resources which are URLs or absolute
+// paths are returned as-is.
+func resolveIndexResource(origin, resource string) string {
+	if len(resource) == 0 || filepath.IsAbs(resource) {
+		return resource
+	}
+
+	if u, err := url.Parse(resource); err != nil || len(u.Scheme) > 0 {
+		return resource
+	}
+
+	if u, err := url.Parse(origin); err == nil && len(u.Scheme) > 1 && len(u.Host) > 0 {
+		ref, err := url.Parse(filepath.ToSlash(resource))
+		if err != nil {
+			return resource
+		}
+
+		return u.ResolveReference(ref).String()
+	}
+
+	return filepath.Join(filepath.Dir(origin), filepath.FromSlash(resource))
+}
+
 func (mi *ManifestIndex) WriteToFile(path string) error {
 	// Open the file (create if not present)
 	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0o600)
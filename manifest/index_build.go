@@ -0,0 +1,549 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Masterminds/semver/v3"
+	"github.com/go-git/go-git/v5"
+	gitplumbing "github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"kraftkit.sh/internal/fsutil"
+	"kraftkit.sh/log"
+	"kraftkit.sh/unikraft"
+	"kraftkit.sh/unikraft/app"
+)
+
+// DefaultIndexFileName is the name of the file which holds the generated
+// manifest index within the output directory of the IndexBuilder.
+const DefaultIndexFileName = "index.yaml"
+
+// tarballVersionRegex matches the name and version of a component from the
+// file name of a tarball without its extension, e.g. lib-musl-1.2.3.
+var tarballVersionRegex = regexp.MustCompile(`^(.+?)-(v?\d+(?:\.\d+)*(?:[-+][\w.\-+]*)?)$`)
+
+// IndexBuilder generates a ManifestIndex from component repositories and
+// tarballs which are located on the host.  Each channel and version of a
+// component is packaged as an archive within the output directory, which is
+// referenced by the resulting index relative to its location, or relative to
+// the base URL if one is provided.
+type IndexBuilder struct {
+	name    string
+	output  string
+	baseURL string
+}
+
+// IndexBuilderOption is an option which modifies the behaviour of the
+// IndexBuilder.
+type IndexBuilderOption func(*IndexBuilder) error
+
+// WithIndexName sets the name of the generated index.
+func WithIndexName(name string) IndexBuilderOption {
+	return func(ib *IndexBuilder) error {
+		ib.name = name
+		return nil
+	}
+}
+
+// WithIndexOutputDir sets the directory in which the archives of the
+// components are placed.
+func WithIndexOutputDir(dir string) IndexBuilderOption {
+	return func(ib *IndexBuilder) error {
+		if len(dir) == 0 {
+			return fmt.Errorf("cannot use empty output directory")
+		}
+
+		ib.output = dir
+		return nil
+	}
+}
+
+// WithIndexBaseURL sets the URL from which the output directory is served,
+// such that the resources of the index are absolute.
+func WithIndexBaseURL(url string) IndexBuilderOption {
+	return func(ib *IndexBuilder) error {
+		ib.baseURL = strings.TrimSuffix(url, "/")
+		return nil
+	}
+}
+
+// NewIndexBuilder returns an IndexBuilder with the provided options applied.
+func NewIndexBuilder(opts ...IndexBuilderOption) (*IndexBuilder, error) {
+	ib := &IndexBuilder{
+		output: ".",
+	}
+
+	for _, opt := range opts {
+		if err := opt(ib); err != nil {
+			return nil, err
+		}
+	}
+
+	output, err := filepath.Abs(ib.output)
+	if err != nil {
+		return nil, err
+	}
+
+	ib.output = output
+
+	return ib, nil
+}
+
+// Build scans the provided directories for component repositories and
+// tarballs and returns the index of the discovered components.  The branches
+// of a Git repository become the channels of the component, with the checked
+// out branch as the default, and its semantic version tags become its
+// versions.  Tarballs named after a component and a version, e.g.
+// lib-musl-1.2.3.tar.gz, are added to the versions of the component.
+func (ib *IndexBuilder) Build(ctx context.Context, dirs ...string) (*ManifestIndex, error) {
+	if err := os.MkdirAll(ib.output, 0o755); err != nil {
+		return nil, fmt.Errorf("could not create output directory: %v", err)
+	}
+
+	manifests := map[string]*Manifest{}
+
+	lookup := func(t unikraft.ComponentType, name string) *Manifest {
+		key := string(t) + "-" + name
+		if _, ok := manifests[key]; !ok {
+			manifests[key] = &Manifest{
+				Type: t,
+				Name: name,
+			}
+		}
+
+		return manifests[key]
+	}
+
+	for _, dir := range dirs {
+		dir, err := filepath.Abs(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			// Do not index the archives generated by a previous invocation
+			if path == ib.output && path != dir {
+				return filepath.SkipDir
+			}
+
+			if !d.IsDir() {
+				if !isTarball(path) {
+					return nil
+				}
+
+				return ib.addTarball(ctx, path, lookup)
+			}
+
+			if d.Name() == git.GitDirName {
+				return filepath.SkipDir
+			}
+
+			repo, err := git.PlainOpen(path)
+			if errors.Is(err, git.ErrRepositoryNotExists) {
+				return nil
+			} else if err != nil {
+				return fmt.Errorf("could not open repository %s: %v", path, err)
+			}
+
+			if err := ib.addRepository(ctx, path, repo, lookup); err != nil {
+				return err
+			}
+
+			return filepath.SkipDir
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	index := &ManifestIndex{
+		Name:        ib.name,
+		LastUpdated: time.Now(),
+	}
+
+	for _, manifest := range manifests {
+		sort.SliceStable(manifest.Versions, func(i, j int) bool {
+			vi, iok := semverOf(manifest.Versions[i])
+			vj, jok := semverOf(manifest.Versions[j])
+			if iok && jok {
+				return vi.GreaterThan(vj)
+			}
+
+			return manifest.Versions[i].Version > manifest.Versions[j].Version
+		})
+
+		index.Manifests = append(index.Manifests, manifest)
+	}
+
+	sort.Slice(index.Manifests, func(i, j int) bool {
+		if index.Manifests[i].Type != index.Manifests[j].Type {
+			return index.Manifests[i].Type < index.Manifests[j].Type
+		}
+
+		return index.Manifests[i].Name < index.Manifests[j].Name
+	})
+
+	return index, nil
+}
+
+// addRepository adds the branches and tags of the Git repository at path to
+// the manifest of the component which it represents.
+func (ib *IndexBuilder) addRepository(ctx context.Context, path string, repo *git.Repository, lookup func(unikraft.ComponentType, string) *Manifest) error {
+	t, name, err := guessIndexComponent(filepath.Base(path), filepath.Dir(path), path)
+	if err != nil {
+		log.G(ctx).WithField("path", path).Debugf("skipping repository: %v", err)
+		return nil
+	}
+
+	manifest := lookup(t, name)
+
+	if remote, err := repo.Remote(git.DefaultRemoteName); err == nil && len(remote.Config().URLs) > 0 {
+		manifest.Origin = remote.Config().URLs[0]
+	}
+
+	var head gitplumbing.ReferenceName
+	if ref, err := repo.Head(); err == nil {
+		head = ref.Name()
+	}
+
+	branches, err := repo.Branches()
+	if err != nil {
+		return err
+	}
+
+	if err := branches.ForEach(func(ref *gitplumbing.Reference) error {
+		channel := ref.Name().Short()
+
+		resource, checksum, err := ib.archiveCommit(ctx, repo, ref.Hash(), t, name, true, channel)
+		if err != nil {
+			return fmt.Errorf("could not archive branch %s of %s: %v", channel, path, err)
+		}
+
+		manifest.Channels = append(manifest.Channels, ManifestChannel{
+			Name:     channel,
+			Default:  ref.Name() == head,
+			Resource: resource,
+			Sha256:   checksum,
+		})
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	sort.Slice(manifest.Channels, func(i, j int) bool {
+		return manifest.Channels[i].Name < manifest.Channels[j].Name
+	})
+
+	tags, err := repo.Tags()
+	if err != nil {
+		return err
+	}
+
+	return tags.ForEach(func(ref *gitplumbing.Reference) error {
+		tag := ref.Name().Short()
+
+		if _, err := semver.NewVersion(tag); err != nil {
+			log.G(ctx).WithField("path", path).Debugf("skipping tag %s: not a semantic version", tag)
+			return nil
+		}
+
+		// Annotated tags refer to a tag object rather than to the commit
+		hash := ref.Hash()
+		if obj, err := repo.TagObject(hash); err == nil {
+			hash = obj.Target
+		}
+
+		version := strings.TrimPrefix(tag, "v")
+
+		resource, checksum, err := ib.archiveCommit(ctx, repo, hash, t, name, false, version)
+		if err != nil {
+			return fmt.Errorf("could not archive tag %s of %s: %v", tag, path, err)
+		}
+
+		manifest.Versions = append(manifest.Versions, ManifestVersion{
+			Version:  version,
+			Resource: resource,
+			Sha256:   checksum,
+			Type:     ManifestVersionSemver,
+		})
+
+		return nil
+	})
+}
+
+// addTarball adds the tarball at path to the versions of the component which
+// is identified by its file name.
+func (ib *IndexBuilder) addTarball(ctx context.Context, path string, lookup func(unikraft.ComponentType, string) *Manifest) error {
+	base := filepath.Base(path)
+	base = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(base, ".tgz"), ".gz"), ".tar")
+
+	match := tarballVersionRegex.FindStringSubmatch(base)
+	if match == nil {
+		log.G(ctx).WithField("path", path).Debug("skipping tarball: no version in file name")
+		return nil
+	}
+
+	t, name, err := guessIndexComponent(match[1], filepath.Dir(path), "")
+	if err != nil {
+		log.G(ctx).WithField("path", path).Debugf("skipping tarball: %v", err)
+		return nil
+	}
+
+	version := strings.TrimPrefix(match[2], "v")
+	dest := ib.archivePath(t, name, false, version)
+
+	if dest != path {
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+			return err
+		}
+
+		if err := fetchLocal(path, dest); err != nil {
+			return fmt.Errorf("could not copy %s: %v", path, err)
+		}
+	}
+
+	checksum, err := fsutil.Checksum(dest)
+	if err != nil {
+		return err
+	}
+
+	manifest := lookup(t, name)
+	manifest.Versions = append(manifest.Versions, ManifestVersion{
+		Version:  version,
+		Resource: ib.resource(dest),
+		Sha256:   checksum,
+		Type:     versionTypeOf(version),
+	})
+
+	return nil
+}
+
+// archivePath returns the location within the output directory of the archive
+// of the provided channel or version of a component.  Channels and versions are
+// kept apart, as a branch and a tag may share the same name.
+func (ib *IndexBuilder) archivePath(t unikraft.ComponentType, name string, channel bool, ref string) string {
+	dir := ib.output
+	if t != unikraft.ComponentTypeCore {
+		dir = filepath.Join(dir, t.Plural())
+	}
+
+	kind := "versions"
+	if channel {
+		kind = "channels"
+	}
+
+	return filepath.Join(dir, name, kind, strings.ReplaceAll(ref, "/", "-")+".tar.gz")
+}
+
+// resource returns the reference of the index to the archive at path.
+func (ib *IndexBuilder) resource(path string) string {
+	rel, err := filepath.Rel(ib.output, path)
+	if err != nil {
+		return path
+	}
+
+	rel = filepath.ToSlash(rel)
+
+	if len(ib.baseURL) > 0 {
+		return ib.baseURL + "/" + rel
+	}
+
+	return rel
+}
+
+// archiveCommit packages the tree of the provided commit as an archive within
+// the output directory and returns its resource and checksum.  Like the
+// archives generated by Git forges, all entries are placed within a single
+// top-level directory.  Submodules are not included.
+func (ib *IndexBuilder) archiveCommit(ctx context.Context, repo *git.Repository, hash gitplumbing.Hash, t unikraft.ComponentType, name string, channel bool, ref string) (string, string, error) {
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return "", "", err
+	}
+
+	tree, err := commit.Tree()
+	if err != nil {
+		return "", "", err
+	}
+
+	dest := ib.archivePath(t, name, channel, ref)
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return "", "", err
+	}
+
+	log.G(ctx).
+		WithField("commit", hash.String()).
+		WithField("dest", dest).
+		Debug("archiving")
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return "", "", err
+	}
+
+	defer f.Close()
+
+	gzw := gzip.NewWriter(f)
+	tw := tar.NewWriter(gzw)
+	prefix := name + "-" + strings.ReplaceAll(ref, "/", "-") + "/"
+	modTime := commit.Committer.When
+
+	if err := tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeDir,
+		Name:     prefix,
+		Mode:     0o755,
+		ModTime:  modTime,
+	}); err != nil {
+		return "", "", err
+	}
+
+	walker := object.NewTreeWalker(tree, true, nil)
+	defer walker.Close()
+
+	for {
+		path, entry, err := walker.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return "", "", err
+		}
+
+		header := &tar.Header{
+			Name:    prefix + path,
+			ModTime: modTime,
+		}
+
+		switch entry.Mode {
+		case filemode.Submodule:
+			continue
+
+		case filemode.Dir:
+			header.Typeflag = tar.TypeDir
+			header.Name += "/"
+			header.Mode = 0o755
+			if err := tw.WriteHeader(header); err != nil {
+				return "", "", err
+			}
+
+			continue
+		}
+
+		blob, err := repo.BlobObject(entry.Hash)
+		if err != nil {
+			return "", "", err
+		}
+
+		reader, err := blob.Reader()
+		if err != nil {
+			return "", "", err
+		}
+
+		if entry.Mode == filemode.Symlink {
+			target, err := io.ReadAll(reader)
+			reader.Close()
+			if err != nil {
+				return "", "", err
+			}
+
+			header.Typeflag = tar.TypeSymlink
+			header.Linkname = string(target)
+			header.Mode = 0o777
+			if err := tw.WriteHeader(header); err != nil {
+				return "", "", err
+			}
+
+			continue
+		}
+
+		header.Typeflag = tar.TypeReg
+		header.Size = blob.Size
+		header.Mode = 0o644
+		if entry.Mode == filemode.Executable {
+			header.Mode = 0o755
+		}
+
+		if err := tw.WriteHeader(header); err != nil {
+			reader.Close()
+			return "", "", err
+		}
+
+		_, err = io.Copy(tw, reader)
+		reader.Close()
+		if err != nil {
+			return "", "", err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return "", "", err
+	}
+
+	if err := gzw.Close(); err != nil {
+		return "", "", err
+	}
+
+	if err := f.Close(); err != nil {
+		return "", "", err
+	}
+
+	checksum, err := fsutil.Checksum(dest)
+	if err != nil {
+		return "", "", err
+	}
+
+	return ib.resource(dest), checksum, nil
+}
+
+// guessIndexComponent determines the type and name of the component with the
+// provided name, e.g. lib-musl, which is located within parent.  If the name
+// does not indicate the type, the name of the parent directory is consulted,
+// e.g. libs, followed by the contents of the component's directory dir, if
+// any, in the same way as the DirectoryProvider.
+func guessIndexComponent(name, parent, dir string) (unikraft.ComponentType, string, error) {
+	for _, candidate := range []string{name, filepath.Base(parent) + "/" + name} {
+		if t, n, _, err := unikraft.GuessTypeNameVersion(candidate); err == nil && t != unikraft.ComponentTypeUnknown {
+			return t, n, nil
+		}
+	}
+
+	if len(dir) > 0 {
+		for _, f := range app.DefaultFileNames {
+			if f, err := os.Stat(filepath.Join(dir, f)); err == nil && f.Size() > 0 {
+				return unikraft.ComponentTypeApp, name, nil
+			}
+		}
+
+		if f, err := os.Stat(filepath.Join(dir, unikraft.Config_uk)); err == nil && f.Size() > 0 {
+			return unikraft.ComponentTypeLib, name, nil
+		}
+	}
+
+	return unikraft.ComponentTypeUnknown, "", fmt.Errorf("unknown type for component: %s", name)
+}
+
+// isTarball returns whether the file at path is a gzipped tarball by its
+// extension.
+func isTarball(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
@@ -0,0 +1,139 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"kraftkit.sh/unikraft"
+)
+
+func TestIndexBuilder(t *testing.T) {
+	ctx := context.Background()
+	src := t.TempDir()
+	out := t.TempDir()
+
+	dir := filepath.Join(src, "lib-foo")
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, unikraft.Config_uk), []byte("config LIBFOO\n\tbool \"foo\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := worktree.Add(unikraft.Config_uk); err != nil {
+		t.Fatal(err)
+	}
+
+	commit, err := worktree.Commit("Initial commit", &git.CommitOptions{
+		Author: &object.Signature{Name: "KraftKit", Email: "kraftkit@unikraft.io", When: time.Now()},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateTag("v1.0.0", commit, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := repo.CreateTag("experiment", commit, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := repo.CreateBranch(&gitconfig.Branch{Name: "staging"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A tarball of another version of the same component
+	if err := os.MkdirAll(filepath.Join(src, "libs"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(src, "libs", "foo-0.9.0.tar.gz"), []byte("archive"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	builder, err := NewIndexBuilder(WithIndexOutputDir(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	index, err := builder.Build(ctx, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(index.Manifests) != 1 {
+		t.Fatalf("expected 1 manifest, got %d", len(index.Manifests))
+	}
+
+	manifest := index.Manifests[0]
+	if manifest.Type != unikraft.ComponentTypeLib || manifest.Name != "foo" {
+		t.Errorf("expected lib-foo, got %s-%s", manifest.Type, manifest.Name)
+	}
+
+	if len(manifest.Channels) != 1 || manifest.Channels[0].Name != "master" || !manifest.Channels[0].Default {
+		t.Errorf("expected the default channel master, got %+v", manifest.Channels)
+	}
+
+	if len(manifest.Versions) != 2 || manifest.Versions[0].Version != "1.0.0" || manifest.Versions[1].Version != "0.9.0" {
+		t.Fatalf("expected versions 1.0.0 and 0.9.0, got %+v", manifest.Versions)
+	}
+
+	if manifest.Versions[0].Resource != "libs/foo/versions/1.0.0.tar.gz" {
+		t.Errorf("expected relative resource, got %s", manifest.Versions[0].Resource)
+	}
+
+	for _, version := range manifest.Versions {
+		if err := verifyChecksum(filepath.Join(out, version.Resource), version.Sha256); err != nil {
+			t.Errorf("version %s: %v", version.Version, err)
+		}
+	}
+
+	path := filepath.Join(out, DefaultIndexFileName)
+	if err := index.WriteToFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := NewManifestIndexFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := filepath.Join(out, "libs", "foo", "channels", "master.tar.gz"); loaded.Manifests[0].Channels[0].Resource != expected {
+		t.Errorf("expected resource resolved to %s, got %s", expected, loaded.Manifests[0].Channels[0].Resource)
+	}
+}
+
+func TestResolveIndexResource(t *testing.T) {
+	for _, tc := range []struct {
+		origin, resource, expected string
+	}{
+		{"http://localhost:8080/index.yaml", "libs/foo/1.0.0.tar.gz", "http://localhost:8080/libs/foo/1.0.0.tar.gz"},
+		{"http://localhost:8080/catalog/index.yaml", "libs/foo.tar.gz", "http://localhost:8080/catalog/libs/foo.tar.gz"},
+		{"http://localhost:8080/index.yaml", "https://example.com/foo.tar.gz", "https://example.com/foo.tar.gz"},
+		{"/srv/catalog/index.yaml", "libs/foo.tar.gz", "/srv/catalog/libs/foo.tar.gz"},
+		{"/srv/catalog/index.yaml", "/tmp/foo.tar.gz", "/tmp/foo.tar.gz"},
+	} {
+		if actual := resolveIndexResource(tc.origin, tc.resource); actual != tc.expected {
+			t.Errorf("resolving %s against %s: expected %s, got %s", tc.resource, tc.origin, tc.expected, actual)
+		}
+	}
+}
@@ -0,0 +1,602 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
+	gitplumbing "github.com/go-git/go-git/v5/plumbing"
+	"gopkg.in/yaml.v2"
+
+	"kraftkit.sh/config"
+	"kraftkit.sh/internal/fsutil"
+	"kraftkit.sh/internal/ghrepo"
+	"kraftkit.sh/log"
+	"kraftkit.sh/pack"
+	"kraftkit.sh/packmanager"
+	"kraftkit.sh/unikraft"
+)
+
+// LockfileName is the name of the file, alongside the Kraftfile, which records
+// the exact components used by a project.
+const LockfileName = "kraft.lock"
+
+// Lockfile records the resolution of every component of a project such that
+// subsequent fetches retrieve identical sources.
+type Lockfile struct {
+	Components []LockedComponent `yaml:"components"`
+}
+
+// LockedComponent is the resolution of a single component.
+type LockedComponent struct {
+	// Type of the component
+	Type unikraft.ComponentType `yaml:"type"`
+
+	// Name of the component
+	Name string `yaml:"name"`
+
+	// Source of the component, if specified in the Kraftfile
+	Source string `yaml:"source,omitempty"`
+
+	// Constraint is the version as specified in the Kraftfile
+	Constraint string `yaml:"constraint,omitempty"`
+
+	// Version is the version or channel which the constraint resolved to
+	Version string `yaml:"version"`
+
+	// Commit is the Git SHA of the version, if known
+	Commit string `yaml:"commit,omitempty"`
+
+	// Resource is the location of the archive of the version
+	Resource string `yaml:"resource,omitempty"`
+
+	// Sha256 is the checksum of the archive of the version
+	Sha256 string `yaml:"sha256,omitempty"`
+}
+
+// String implements fmt.Stringer
+func (lc LockedComponent) String() string {
+	return Requirement{
+		Type:       lc.Type,
+		Name:       lc.Name,
+		Constraint: lc.Constraint,
+	}.String()
+}
+
+// NewLockfileFromFile reads the lockfile at the provided path.  A lockfile
+// which does not exist is returned empty.
+func NewLockfileFromFile(path string) (*Lockfile, error) {
+	lockfile := &Lockfile{}
+
+	contents, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return lockfile, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(contents, lockfile); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", path, err)
+	}
+
+	return lockfile, nil
+}
+
+// WriteToFile saves the lockfile to the provided path.
+func (l *Lockfile) WriteToFile(path string) error {
+	contents, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+
+	header := "# This file is generated by kraft.  Do not edit it manually.\n"
+
+	return os.WriteFile(path, append([]byte(header), contents...), 0o644)
+}
+
+// Lookup returns the entry of the component with the provided type, name and
+// source, if it is locked.
+func (l *Lockfile) Lookup(t unikraft.ComponentType, name, source string) (LockedComponent, bool) {
+	key := lockKey(t, name, source)
+	for _, c := range l.Components {
+		if lockKey(c.Type, c.Name, c.Source) == key {
+			return c, true
+		}
+	}
+
+	return LockedComponent{}, false
+}
+
+// lockKey identifies a component within the lockfile.
+func lockKey(t unikraft.ComponentType, name, source string) string {
+	return string(t) + "/" + name + "@" + source
+}
+
+// Locker applies a project's lockfile to the queries of its components and
+// records their resolution once they have been pulled.
+type Locker struct {
+	path   string
+	lock   *Lockfile
+	update bool
+	frozen bool
+
+	mu sync.Mutex
+
+	// constraints maps each component to its constraint in the Kraftfile
+	constraints map[string]string
+
+	// locked are the components whose resolution is honoured from the lockfile
+	locked map[string]LockedComponent
+
+	// next are the components which will be saved to the lockfile
+	next map[string]LockedComponent
+
+	// fresh maps components which were newly resolved to their local archive
+	fresh map[string]string
+}
+
+// NewLocker prepares the lockfile of the project in the provided working
+// directory.  With update set, all components are resolved anew.  With frozen
+// set, any difference between the lockfile and the Kraftfile is an error.
+func NewLocker(workdir string, update, frozen bool) (*Locker, error) {
+	if update && frozen {
+		return nil, fmt.Errorf("cannot update a frozen lockfile")
+	}
+
+	path := filepath.Join(workdir, LockfileName)
+
+	if _, err := os.Stat(path); frozen && err != nil {
+		return nil, fmt.Errorf("frozen lockfile requested but %s does not exist", path)
+	}
+
+	lock, err := NewLockfileFromFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Locker{
+		path:        path,
+		lock:        lock,
+		update:      update,
+		frozen:      frozen,
+		constraints: map[string]string{},
+		locked:      map[string]LockedComponent{},
+		next:        map[string]LockedComponent{},
+		fresh:       map[string]string{},
+	}, nil
+}
+
+// isLocalSource returns whether the source of a component is a path on the
+// host, which is never locked.
+func isLocalSource(source string) bool {
+	if len(source) == 0 {
+		return false
+	}
+
+	_, err := os.Stat(source)
+	return err == nil
+}
+
+// Resolve sets the version of each query to the version recorded in the
+// lockfile, if the component is locked with the same constraint, and resolves
+// the remaining constraints.
+func (l *Locker) Resolve(ctx context.Context, queries []packmanager.CatalogQuery) ([]packmanager.CatalogQuery, error) {
+	var missing []string
+
+	queries = append([]packmanager.CatalogQuery(nil), queries...)
+
+	for i, query := range queries {
+		if len(query.Types) != 1 || isLocalSource(query.Source) {
+			continue
+		}
+
+		key := lockKey(query.Types[0], query.Name, query.Source)
+		l.constraints[key] = query.Version
+
+		var entry *LockedComponent
+		for _, c := range l.lock.Components {
+			if lockKey(c.Type, c.Name, c.Source) == key && c.Constraint == query.Version {
+				c := c
+				entry = &c
+				break
+			}
+		}
+
+		if entry == nil || l.update {
+			missing = append(missing, Requirement{
+				Type:       query.Types[0],
+				Name:       query.Name,
+				Constraint: query.Version,
+			}.String())
+			continue
+		}
+
+		l.locked[key] = *entry
+		queries[i].Version = entry.Version
+	}
+
+	if l.frozen && len(missing) > 0 {
+		return nil, fmt.Errorf("%s is out of date, missing: %s", LockfileName, strings.Join(missing, ", "))
+	}
+
+	return ResolveQueries(ctx, queries)
+}
+
+// Retain carries the entry of the component with the provided type, name and
+// source over from the existing lockfile without resolving it, e.g. because it
+// is replaced by a local checkout.
+func (l *Locker) Retain(t unikraft.ComponentType, name, source string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	key := lockKey(t, name, source)
+	for _, c := range l.lock.Components {
+		if lockKey(c.Type, c.Name, c.Source) == key {
+			l.next[key] = c
+		}
+	}
+}
+
+// Lock returns the package which must be pulled for the provided query.  A
+// locked component is pinned to its recorded resource and checksum, whereas
+// any other is resolved and recorded.  Packages which are not manifests are
+// returned as-is.
+func (l *Locker) Lock(ctx context.Context, query packmanager.CatalogQuery, p pack.Package) (pack.Package, error) {
+	if p.Format() != ManifestFormat || len(query.Types) != 1 {
+		return p, nil
+	}
+
+	manifest, ok := p.Metadata().(*Manifest)
+	if !ok || manifest.Provider == nil || manifest.Provider.String() == "directory" {
+		return p, nil
+	}
+
+	key := lockKey(query.Types[0], query.Name, query.Source)
+
+	l.mu.Lock()
+	entry, locked := l.locked[key]
+	constraint := l.constraints[key]
+	l.mu.Unlock()
+
+	if !locked {
+		entry = LockedComponent{
+			Type:       manifest.Type,
+			Name:       manifest.Name,
+			Source:     query.Source,
+			Constraint: constraint,
+			Version:    p.Version(),
+		}
+
+		// The package has been reduced to exactly one channel or version
+		entry.Resource, _, entry.Sha256, _ = resourceCacheChecksum(manifest)
+
+		commit, err := resolveCommit(ctx, manifest, p.Version())
+		if err != nil {
+			log.G(ctx).
+				WithField("origin", manifest.Origin).
+				Warnf("could not determine commit of %s: %v", entry, err)
+		}
+
+		// Prefer the archive of the commit as the archive of a channel changes
+		// over time
+		if len(commit) > 0 {
+			entry.Commit = commit
+			if resource := commitArchive(manifest, commit); len(resource) > 0 && resource != entry.Resource {
+				entry.Resource = resource
+				entry.Sha256 = ""
+			}
+		}
+	}
+
+	pinned := *manifest
+	pinned.Channels = nil
+	pinned.Versions = []ManifestVersion{{
+		Version:  entry.Version,
+		Resource: entry.Resource,
+		Sha256:   entry.Sha256,
+	}}
+	pinned.Provider = lockedProvider{
+		Provider: manifest.Provider,
+		entry:    entry,
+		frozen:   l.frozen,
+	}
+
+	if err := WithSourcesRootDir(config.G[config.KraftKit](ctx).Paths.Sources)(&pinned); err != nil {
+		return nil, err
+	}
+
+	// Distinguish the cache of a commit from that of its channel
+	if local := pinned.Versions[0].Local; len(entry.Commit) > 0 && len(local) > 0 {
+		ext := strings.TrimPrefix(filepath.Base(local), pinned.Name+"-"+entry.Version)
+		pinned.Versions[0].Local = filepath.Join(filepath.Dir(local), pinned.Name+"-"+entry.Commit+ext)
+	}
+
+	l.mu.Lock()
+	l.next[key] = entry
+	if !locked {
+		l.fresh[key] = pinned.Versions[0].Local
+	}
+	l.mu.Unlock()
+
+	return &mpack{&pinned, entry.Version}, nil
+}
+
+// Save records the resolution of all locked components to the lockfile.  The
+// checksum of newly resolved components is calculated from their archive.
+func (l *Locker) Save(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock := &Lockfile{}
+	for key, entry := range l.next {
+		if local, ok := l.fresh[key]; ok && len(entry.Sha256) == 0 && len(local) > 0 {
+			if sum, err := fsutil.Checksum(local); err == nil {
+				entry.Sha256 = sum
+			}
+		}
+
+		lock.Components = append(lock.Components, entry)
+	}
+
+	sort.SliceStable(lock.Components, func(i, j int) bool {
+		return lockKey(lock.Components[i].Type, lock.Components[i].Name, lock.Components[i].Source) <
+			lockKey(lock.Components[j].Type, lock.Components[j].Name, lock.Components[j].Source)
+	})
+
+	if reflect.DeepEqual(lock.Components, l.lock.Components) {
+		return nil
+	}
+
+	if l.frozen {
+		return fmt.Errorf("%s is out of date with the Kraftfile", LockfileName)
+	}
+
+	log.G(ctx).
+		WithField("path", l.path).
+		Info("updating lockfile")
+
+	if err := lock.WriteToFile(l.path); err != nil {
+		return fmt.Errorf("could not save %s: %v", LockfileName, err)
+	}
+
+	l.lock = lock
+
+	return nil
+}
+
+// lockedProvider pulls a component exactly as recorded in the lockfile.
+type lockedProvider struct {
+	Provider
+	entry  LockedComponent
+	frozen bool
+}
+
+// isArchiveResource returns whether the resource is the location of an archive
+// rather than, e.g., of a Git repository.
+func isArchiveResource(resource string) bool {
+	for _, ext := range []string{".tar.gz", ".tgz", ".tar.xz", ".tar.bz2", ".tar", ".zip"} {
+		if strings.HasSuffix(resource, ext) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (lp lockedProvider) PullManifest(ctx context.Context, manifest *Manifest, popts ...pack.PullOption) error {
+	// Do not fall back to Git when the archive is known since Git does not
+	// verify the checksum
+	if len(lp.entry.Sha256) > 0 && isArchiveResource(lp.entry.Resource) {
+		return pullArchive(ctx, manifest, popts...)
+	}
+
+	if err := lp.Provider.PullManifest(ctx, manifest, popts...); err != nil {
+		return err
+	}
+
+	return checkoutCommit(ctx, manifest, lp.entry.Commit, lp.frozen, popts...)
+}
+
+// checkoutCommit checks out the provided commit in a component which was cloned
+// with Git.  The commit is fetched if the clone does not contain it, e.g.
+// because the channel which was cloned has since moved on.  A commit which
+// cannot be checked out is an error only if the lockfile is frozen.
+func checkoutCommit(ctx context.Context, manifest *Manifest, commit string, frozen bool, opts ...pack.PullOption) error {
+	popts, err := pack.NewPullOptions(opts...)
+	if err != nil {
+		return err
+	}
+
+	if len(commit) == 0 || len(popts.Workdir()) == 0 {
+		return nil
+	}
+
+	local, err := unikraft.PlaceComponent(popts.Workdir(), manifest.Type, manifest.Name)
+	if err != nil {
+		return err
+	}
+
+	// Not a Git repository, e.g. an unarchived resource
+	repo, err := git.PlainOpen(local)
+	if err != nil {
+		return nil
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		return err
+	}
+
+	if head.Hash().String() == commit {
+		return nil
+	}
+
+	hash := gitplumbing.NewHash(commit)
+
+	if _, err = repo.CommitObject(hash); err != nil {
+		err = fetchCommit(ctx, manifest, repo, commit)
+	}
+
+	if err == nil {
+		var worktree *git.Worktree
+		if worktree, err = repo.Worktree(); err == nil {
+			err = worktree.Checkout(&git.CheckoutOptions{
+				Hash:  hash,
+				Force: true,
+			})
+		}
+	}
+
+	if err != nil {
+		if frozen {
+			return fmt.Errorf("could not check out %s of %s as recorded in %s: %v",
+				commit, manifest.Name, LockfileName, err,
+			)
+		}
+
+		log.G(ctx).
+			WithField("commit", commit).
+			Warnf("could not check out %s as recorded in %s, using %s instead: %v",
+				manifest.Name, LockfileName, head.Hash().String(), err,
+			)
+	}
+
+	return nil
+}
+
+// fetchCommit fetches the provided commit from the origin of the repository.
+func fetchCommit(ctx context.Context, manifest *Manifest, repo *git.Repository, commit string) error {
+	remote, err := repo.Remote("origin")
+	if err != nil {
+		return err
+	}
+
+	if len(remote.Config().URLs) == 0 {
+		return fmt.Errorf("repository has no origin")
+	}
+
+	origin := remote.Config().URLs[0]
+
+	if config.Offline(ctx) {
+		return config.OfflineError(origin)
+	}
+
+	fopts := &git.FetchOptions{
+		RemoteName: "origin",
+		RefSpecs:   []gitconfig.RefSpec{gitconfig.RefSpec(commit + ":refs/kraft/locked")},
+		Depth:      1,
+		Tags:       git.NoTags,
+	}
+
+	if u, err := url.Parse(origin); err == nil && !isSSHURL(origin) {
+		fopts.Auth = gitHTTPAuth(manifest.Auths(), u.Host)
+	}
+
+	return packmanager.SchedulerFromContext(ctx).Do(ctx, origin, "", func(ctx context.Context) error {
+		err := repo.FetchContext(ctx, fopts)
+		if errors.Is(err, git.NoErrAlreadyUpToDate) {
+			return nil
+		}
+
+		return err
+	})
+}
+
+// resolveCommit returns the Git SHA of the provided version or channel of the
+// Manifest by listing the references of its origin.
+func resolveCommit(ctx context.Context, manifest *Manifest, version string) (string, error) {
+	origin := manifest.Origin
+	if len(origin) == 0 {
+		return "", fmt.Errorf("manifest has no origin")
+	}
+
+	if isSSHURL(origin) && strings.HasPrefix(origin, "git@") {
+		origin = "ssh://" + origin
+	}
+
+	listOpts := &git.ListOptions{}
+	if !isSSHURL(origin) {
+		if !strings.Contains(origin, "://") {
+			origin = "https://" + origin
+		}
+
+		if u, err := url.Parse(origin); err == nil {
+			listOpts.Auth = gitHTTPAuth(manifest.Auths(), u.Host)
+		}
+	}
+
+	if config.Offline(ctx) {
+		return "", config.OfflineError(origin)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, mirrorTimeout)
+	defer cancel()
+
+	remote := git.NewRemote(nil, &gitconfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{origin},
+	})
+
+	refs, err := remote.ListContext(ctx, listOpts)
+	if err != nil {
+		return "", err
+	}
+
+	// Prefer the commit of an annotated tag over the tag object itself
+	candidates := []string{
+		"refs/tags/" + version + "^{}",
+		"refs/tags/" + version,
+		"refs/heads/" + version,
+	}
+
+	for _, candidate := range candidates {
+		for _, ref := range refs {
+			if ref.Name().String() == candidate {
+				return ref.Hash().String(), nil
+			}
+		}
+	}
+
+	// Versions of type gitsha are abbreviated
+	for _, ref := range refs {
+		if strings.HasPrefix(ref.Hash().String(), version) {
+			return ref.Hash().String(), nil
+		}
+	}
+
+	return "", fmt.Errorf("could not find %s in %s", version, manifest.Origin)
+}
+
+// commitArchive returns the location of the archive of the provided commit of
+// the Manifest, if its origin is known to serve archives of commits.
+func commitArchive(manifest *Manifest, commit string) string {
+	web := strings.TrimSuffix(manifest.Origin, ".git")
+
+	switch manifest.Provider.(type) {
+	case GitLabProvider:
+		return fmt.Sprintf("%s/-/archive/%s/%s-%s.tar.gz", web, commit, filepath.Base(web), commit)
+	case GiteaProvider:
+		return fmt.Sprintf("%s/archive/%s.tar.gz", web, commit)
+	}
+
+	if u, err := url.Parse(web); err == nil && u.Host == "github.com" {
+		if repo, err := ghrepo.NewFromURL(web); err == nil {
+			return ghrepo.SHAArchive(repo, commit)
+		}
+	}
+
+	return ""
+}
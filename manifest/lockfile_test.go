@@ -0,0 +1,195 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+
+	"kraftkit.sh/pack"
+	"kraftkit.sh/packmanager"
+	"kraftkit.sh/unikraft"
+)
+
+func TestLockfileRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), LockfileName)
+
+	lock := &Lockfile{
+		Components: []LockedComponent{{
+			Type:       unikraft.ComponentTypeLib,
+			Name:       "musl",
+			Constraint: "^0.14",
+			Version:    "0.14.0",
+			Commit:     "0123456789abcdef0123456789abcdef01234567",
+			Resource:   "https://github.com/unikraft/lib-musl/archive/0123456789abcdef0123456789abcdef01234567.tar.gz",
+			Sha256:     "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855",
+		}},
+	}
+
+	if err := lock.WriteToFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := NewLockfileFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(lock, read) {
+		t.Errorf("expected %+v, got %+v", lock, read)
+	}
+}
+
+func TestLockerFrozen(t *testing.T) {
+	workdir := t.TempDir()
+
+	if _, err := NewLocker(workdir, false, true); err == nil {
+		t.Error("expected a frozen locker without a lockfile to fail")
+	}
+
+	if _, err := NewLocker(workdir, true, true); err == nil {
+		t.Error("expected an updated and frozen locker to fail")
+	}
+
+	lock := &Lockfile{
+		Components: []LockedComponent{{
+			Type:       unikraft.ComponentTypeLib,
+			Name:       "musl",
+			Constraint: "^0.14",
+			Version:    "0.14.0",
+		}},
+	}
+
+	if err := lock.WriteToFile(filepath.Join(workdir, LockfileName)); err != nil {
+		t.Fatal(err)
+	}
+
+	locker, err := NewLocker(workdir, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The constraint has changed since the lockfile was written
+	if _, err := locker.Resolve(context.Background(), []packmanager.CatalogQuery{{
+		Name:    "musl",
+		Version: "^0.15",
+		Types:   []unikraft.ComponentType{unikraft.ComponentTypeLib},
+	}}); err == nil {
+		t.Error("expected a changed constraint to fail with a frozen lockfile")
+	}
+
+	// The component is no longer used
+	if err := locker.Save(context.Background()); err == nil {
+		t.Error("expected a stale lockfile to fail when frozen")
+	}
+}
+
+func TestLockerRetain(t *testing.T) {
+	workdir := t.TempDir()
+
+	lock := &Lockfile{
+		Components: []LockedComponent{{
+			Type:       unikraft.ComponentTypeLib,
+			Name:       "musl",
+			Constraint: "stable",
+			Version:    "stable",
+		}},
+	}
+
+	if err := lock.WriteToFile(filepath.Join(workdir, LockfileName)); err != nil {
+		t.Fatal(err)
+	}
+
+	locker, err := NewLocker(workdir, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A linked component is neither resolved nor locked but must not be
+	// removed from a frozen lockfile
+	locker.Retain(unikraft.ComponentTypeLib, "musl", "")
+
+	if err := locker.Save(context.Background()); err != nil {
+		t.Errorf("expected the retained lockfile to be unchanged: %v", err)
+	}
+}
+
+func TestCheckoutCommit(t *testing.T) {
+	ctx := context.Background()
+	upstream := t.TempDir()
+	workdir := t.TempDir()
+
+	repo, err := git.PlainInit(upstream, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var commits []string
+	for _, version := range []string{"0.14.0", "0.15.0"} {
+		if err := os.WriteFile(filepath.Join(upstream, "VERSION"), []byte(version), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := worktree.Add("VERSION"); err != nil {
+			t.Fatal(err)
+		}
+
+		commit, err := worktree.Commit(version, &git.CommitOptions{
+			Author: &object.Signature{Name: "KraftKit", Email: "kraftkit@unikraft.io", When: time.Now()},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		commits = append(commits, commit.String())
+	}
+
+	manifest := &Manifest{Type: unikraft.ComponentTypeLib, Name: "musl"}
+
+	local, err := unikraft.PlaceComponent(workdir, manifest.Type, manifest.Name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The channel has moved on since the lockfile was written
+	if _, err := git.PlainClone(local, false, &git.CloneOptions{URL: upstream}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := checkoutCommit(ctx, manifest, commits[0], true, pack.WithPullWorkdir(workdir)); err != nil {
+		t.Fatal(err)
+	}
+
+	contents, err := os.ReadFile(filepath.Join(local, "VERSION"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(contents) != "0.14.0" {
+		t.Errorf("expected the locked commit to be checked out, got version %s", contents)
+	}
+
+	unknown := "0123456789abcdef0123456789abcdef01234567"
+
+	if err := checkoutCommit(ctx, manifest, unknown, true, pack.WithPullWorkdir(workdir)); err == nil {
+		t.Error("expected an unknown commit to fail with a frozen lockfile")
+	}
+
+	if err := checkoutCommit(ctx, manifest, unknown, false, pack.WithPullWorkdir(workdir)); err != nil {
+		t.Errorf("expected an unknown commit to only warn: %v", err)
+	}
+}
@@ -5,15 +5,18 @@
 package manifest
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"os"
 	"path/filepath"
 	"sort"
+	"strings"
 	"time"
 	"unicode"
 
 	"github.com/gobwas/glob"
+	"github.com/google/go-github/v32/github"
 	"github.com/sirupsen/logrus"
 
 	"kraftkit.sh/cmdfactory"
@@ -54,71 +57,301 @@ func NewManifestManager(ctx context.Context) (packmanager.PackageManager, error)
 	return manager{}, nil
 }
 
-// update retrieves and returns a cache of the upstream manifest registry
-func (m manager) update(ctx context.Context) (*ManifestIndex, error) {
-	if len(config.G[config.KraftKit](ctx).Unikraft.Manifests) == 0 {
-		return nil, fmt.Errorf("no manifests specified in config")
+// Update implements packmanager.PackageManager.  Each source is only
+// retrieved again if it is no longer fresh and has changed upstream, and only
+// the manifests whose contents have changed are rewritten.
+func (m manager) Update(ctx context.Context, opts ...packmanager.UpdateOption) error {
+	uopts := packmanager.NewUpdateOptions(opts...)
+	sources := config.G[config.KraftKit](ctx).Unikraft.Manifests
+
+	if len(sources) == 0 {
+		return fmt.Errorf("no manifests specified in config")
 	}
 
-	localIndex := &ManifestIndex{
-		LastUpdated: time.Now(),
+	if len(uopts.Source()) > 0 {
+		found := false
+		for _, source := range sources {
+			if source == uopts.Source() {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("unknown manifest source: %s", uopts.Source())
+		}
+
+		sources = []string{uopts.Source()}
+	}
+
+	if config.Offline(ctx) {
+		return config.OfflineError(strings.Join(sources, ", "))
+	}
+
+	var ttl time.Duration
+	if s := config.G[config.KraftKit](ctx).Unikraft.ManifestsTTL; len(s) > 0 {
+		var err error
+		ttl, err = time.ParseDuration(s)
+		if err != nil {
+			return fmt.Errorf("could not parse manifests TTL: %v", err)
+		}
+	}
+
+	// Create parent directories if not present
+	if err := os.MkdirAll(filepath.Dir(m.LocalManifestIndex(ctx)), 0o771); err != nil {
+		return err
+	}
+
+	state, err := newSourcesStateFromFile(m.localSourcesState(ctx))
+	if err != nil {
+		return err
 	}
 
 	mopts := []ManifestOption{
 		WithAuthConfig(config.G[config.KraftKit](ctx).Auth),
+		WithMirrors(config.G[config.KraftKit](ctx).Unikraft.Mirrors),
 		WithSourcesRootDir(config.G[config.KraftKit](ctx).Paths.Sources),
 	}
 
-	for _, manipath := range config.G[config.KraftKit](ctx).Unikraft.Manifests {
-		// If the path of the manipath is the same as the current manifest or it
-		// resides in the same directory as KraftKit's configured path for manifests
-		// then we can skip this since we don't want to update ourselves.
-		// if manipath == m.LocalManifestIndex() || filepath.Dir(manipath) == m.LocalManifestsDir() {
-		// 	m.opts.Log.Debugf("skipping: %s", manipath)
-		// 	continue
-		// }
+	next := &sourcesState{
+		Sources: map[string]*sourceState{},
+	}
+
+	for _, source := range sources {
+		prev := state.Sources[source]
+
+		if prev != nil && !uopts.Force() && time.Now().Before(prev.ExpiresAt) && m.haveManifests(ctx, prev.Manifests) {
+			log.G(ctx).WithFields(logrus.Fields{
+				"manifest": source,
+				"expires":  prev.ExpiresAt,
+			}).Debug("skipping fresh")
+			next.Sources[source] = prev
+			continue
+		}
 
 		log.G(ctx).WithFields(logrus.Fields{
-			"manifest": manipath,
+			"manifest": source,
 		}).Debug("fetching")
 
-		manifests, err := FindManifestsFromSource(ctx, manipath, mopts...)
+		updated, err := m.updateSource(ctx, source, prev, ttl, uopts.Force(), mopts...)
 		if err != nil {
 			log.G(ctx).Warnf("%s", err)
+
+			if prev != nil {
+				next.Sources[source] = prev
+			}
+
+			continue
 		}
 
-		localIndex.Origin = manipath
-		localIndex.Manifests = append(localIndex.Manifests, manifests...)
+		next.Sources[source] = updated
 	}
 
-	return localIndex, nil
-}
+	// Sources which were not considered by this update remain as they are,
+	// whereas sources which are no longer configured are dropped.
+	for _, source := range config.G[config.KraftKit](ctx).Unikraft.Manifests {
+		if _, ok := next.Sources[source]; !ok && state.Sources[source] != nil {
+			next.Sources[source] = state.Sources[source]
+		}
+	}
 
-func (m manager) Update(ctx context.Context) error {
-	// Create parent directories if not present
-	if err := os.MkdirAll(filepath.Dir(m.LocalManifestIndex(ctx)), 0o771); err != nil {
-		return err
+	// Remove manifests which are no longer provided by any source
+	saved := map[string]bool{}
+	for _, ss := range next.Sources {
+		for _, manifest := range ss.Manifests {
+			saved[manifest.Manifest] = true
+		}
 	}
 
-	localIndex, err := m.update(ctx)
-	if err != nil {
+	for _, ss := range state.Sources {
+		for _, manifest := range ss.Manifests {
+			if saved[manifest.Manifest] {
+				continue
+			}
+
+			saved[manifest.Manifest] = true
+			fileloc := filepath.Join(m.LocalManifestsDir(ctx), manifest.Manifest)
+
+			log.G(ctx).WithFields(logrus.Fields{
+				"path": fileloc,
+			}).Debugf("removing manifest")
+
+			if err := os.Remove(fileloc); err != nil && !os.IsNotExist(err) {
+				log.G(ctx).Errorf("could not remove manifest: %s", err)
+			}
+		}
+	}
+
+	if err := next.WriteToFile(m.localSourcesState(ctx)); err != nil {
 		return err
 	}
 
-	// TODO: Partition directories when there is a large number of manifests
 	// TODO: Merge manifests of same name and type?
 
-	// Create a file for each manifest
-	for i, manifest := range localIndex.Manifests {
-		filename := manifest.Name + ".yaml"
+	localIndex := &ManifestIndex{
+		LastUpdated: time.Now(),
+	}
+
+	for _, source := range config.G[config.KraftKit](ctx).Unikraft.Manifests {
+		if ss, ok := next.Sources[source]; ok {
+			localIndex.Origin = source
+			localIndex.Manifests = append(localIndex.Manifests, ss.Manifests...)
+		}
+	}
+
+	return localIndex.WriteToFile(m.LocalManifestIndex(ctx))
+}
+
+// updateSource checks whether the source has changed since its previous state
+// and, if so, retrieves and saves its manifests.  The returned state records
+// the validators of the source as well as the manifests which were saved from
+// it.
+func (m manager) updateSource(ctx context.Context, source string, prev *sourceState, ttl time.Duration, force bool, mopts ...ManifestOption) (*sourceState, error) {
+	next := &sourceState{
+		Source:    source,
+		CheckedAt: time.Now(),
+	}
+	next.ExpiresAt = next.CheckedAt.Add(ttl)
+
+	// Without a usable previous state every source is considered changed.
+	if prev == nil || force || !m.haveManifests(ctx, prev.Manifests) {
+		prev = &sourceState{}
+	}
+
+	if ghp, ok := wildcardProvider(ctx, source, mopts...); ok {
+		return m.updateWildcard(ctx, ghp, prev, next)
+	}
+
+	changed := true
+	if isLocalSource(source) {
+		// Local sources are cheap to read again.
+	} else if isHTTPDocument(source) {
+		var err error
+		changed, err = probeHTTP(ctx, source, prev, next)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		changed = probeGit(ctx, source, prev, next, mopts...)
+	}
+
+	if !changed {
+		log.G(ctx).WithFields(logrus.Fields{
+			"manifest": source,
+		}).Debug("unchanged")
+		next.Manifests = prev.Manifests
+		return next, nil
+	}
+
+	manifests, err := FindManifestsFromSource(ctx, source, mopts...)
+	if err != nil {
+		return nil, err
+	}
+
+	next.Manifests, err = m.saveManifests(ctx, manifests)
+	if err != nil {
+		return nil, err
+	}
+
+	return next, nil
+}
+
+// updateWildcard lists the repositories matched by a GitHub wildcard and only
+// retrieves the manifests of those which have been pushed to since the
+// previous state.
+func (m manager) updateWildcard(ctx context.Context, ghp GitHubProvider, prev, next *sourceState) (*sourceState, error) {
+	repos, pages, notModified, err := ghp.listRepositories(prev.Pages)
+	if err != nil {
+		return nil, err
+	}
+
+	next.Pages = pages
+
+	if notModified {
+		log.G(ctx).WithFields(logrus.Fields{
+			"manifest": ghp.path,
+		}).Debug("unchanged")
+		next.Repos = prev.Repos
+		next.Manifests = prev.Manifests
+		return next, nil
+	}
+
+	previous := map[string]*Manifest{}
+	for _, manifest := range prev.Manifests {
+		previous[manifest.Manifest] = manifest
+	}
+
+	var changed []*github.Repository
+	next.Repos = map[string]time.Time{}
+
+	for _, repo := range repos {
+		next.Repos[repo.GetCloneURL()] = repo.GetPushedAt().Time
+
+		t, n, _, err := unikraft.GuessTypeNameVersion(repo.GetName())
+		if err != nil {
+			changed = append(changed, repo)
+			continue
+		}
 
-		if manifest.Type != unikraft.ComponentTypeCore {
-			filename = manifest.Type.Plural() + "/" + filename
+		pushedAt, ok := prev.Repos[repo.GetCloneURL()]
+		if manifest := previous[manifestFilename(t, n)]; ok && manifest != nil && pushedAt.Equal(repo.GetPushedAt().Time) {
+			next.Manifests = append(next.Manifests, manifest)
+			continue
 		}
 
+		changed = append(changed, repo)
+	}
+
+	log.G(ctx).WithFields(logrus.Fields{
+		"manifest": ghp.path,
+		"changed":  len(changed),
+		"total":    len(repos),
+	}).Debug("wildcard")
+
+	manifests, err := ghp.appendRepositoriesToManifestInParallel(changed)
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := m.saveManifests(ctx, manifests)
+	if err != nil {
+		return nil, err
+	}
+
+	next.Manifests = append(next.Manifests, saved...)
+
+	return next, nil
+}
+
+// saveManifests writes each manifest to its file within the local manifests
+// directory, unless the file already has the same contents, and returns the
+// entries which reference them from the local index.
+func (m manager) saveManifests(ctx context.Context, manifests []*Manifest) ([]*Manifest, error) {
+	// TODO: Partition directories when there is a large number of manifests
+	var entries []*Manifest
+
+	for _, manifest := range manifests {
+		filename := manifestFilename(manifest.Type, manifest.Name)
 		fileloc := filepath.Join(m.LocalManifestsDir(ctx), filename)
+
+		// Replace manifest with relative path
+		entries = append(entries, &Manifest{
+			Name:     manifest.Name,
+			Type:     manifest.Type,
+			Manifest: filename,
+		})
+
+		contents, err := manifest.marshal()
+		if err != nil {
+			return nil, err
+		}
+
+		if existing, err := os.ReadFile(fileloc); err == nil && bytes.Equal(existing, contents) {
+			continue
+		}
+
 		if err := os.MkdirAll(filepath.Dir(fileloc), 0o771); err != nil {
-			return err
+			return nil, err
 		}
 
 		log.G(ctx).WithFields(logrus.Fields{
@@ -128,16 +361,31 @@ func (m manager) Update(ctx context.Context) error {
 		if err := manifest.WriteToFile(fileloc); err != nil {
 			log.G(ctx).Errorf("could not save manifest: %s", err)
 		}
+	}
 
-		// Replace manifest with relative path
-		localIndex.Manifests[i] = &Manifest{
-			Name:     manifest.Name,
-			Type:     manifest.Type,
-			Manifest: "./" + filename,
+	return entries, nil
+}
+
+// haveManifests returns whether each of the provided index entries references
+// an existing file within the local manifests directory.
+func (m manager) haveManifests(ctx context.Context, entries []*Manifest) bool {
+	for _, entry := range entries {
+		if _, err := os.Stat(filepath.Join(m.LocalManifestsDir(ctx), entry.Manifest)); err != nil {
+			return false
 		}
 	}
 
-	return localIndex.WriteToFile(m.LocalManifestIndex(ctx))
+	return true
+}
+
+// manifestFilename returns the path of the manifest of the component with the
+// provided type and name, relative to the local manifests directory.
+func manifestFilename(t unikraft.ComponentType, name string) string {
+	if t == unikraft.ComponentTypeCore {
+		return "./" + name + ".yaml"
+	}
+
+	return "./" + t.Plural() + "/" + name + ".yaml"
 }
 
 func (m manager) AddSource(ctx context.Context, source string) error {
@@ -182,62 +430,36 @@ func (m manager) From(sub pack.PackageFormat) (packmanager.PackageManager, error
 	return nil, fmt.Errorf("method not applicable to manifest manager")
 }
 
-func (m manager) Catalog(ctx context.Context, query packmanager.CatalogQuery) ([]pack.Package, error) {
-	var err error
-	var index *ManifestIndex
-	var allManifests []*Manifest
-
-	mopts := []ManifestOption{
-		WithAuthConfig(config.G[config.KraftKit](ctx).Auth),
-		WithSourcesRootDir(config.G[config.KraftKit](ctx).Paths.Sources),
-	}
-
-	log.G(ctx).WithFields(logrus.Fields{
-		"name":    query.Name,
-		"version": query.Version,
-		"source":  query.Source,
-		"types":   query.Types,
-		"cache":   !query.NoCache,
-	}).Debug("querying manifest catalog")
-
+// manifests returns all manifests which are available to the query, either
+// from its source or the local index, which is first updated if the query does
+// not use the cache.
+func (m manager) manifests(ctx context.Context, query packmanager.CatalogQuery, mopts ...ManifestOption) ([]*Manifest, error) {
 	if len(query.Source) > 0 {
 		provider, err := NewProvider(ctx, query.Source, mopts...)
 		if err != nil {
 			return nil, err
 		}
 
-		manifests, err := provider.Manifests()
-		if err != nil {
-			return nil, err
-		}
-
-		allManifests = append(allManifests, manifests...)
+		return provider.Manifests()
 	} else if query.NoCache {
-		index, err = m.update(ctx)
-		if err != nil {
-			return nil, err
-		}
-
-		allManifests = append(allManifests, index.Manifests...)
-	} else {
-		index, err = NewManifestIndexFromFile(m.LocalManifestIndex(ctx))
-		if err != nil {
+		// Only the sources which have changed upstream are retrieved again
+		if err := m.Update(ctx); err != nil {
 			return nil, err
 		}
-
-		manifests, err := FindManifestsFromSource(ctx, index.Origin, mopts...)
-		if err != nil {
-			return nil, err
-		}
-
-		allManifests = append(allManifests, manifests...)
 	}
 
-	log.G(ctx).Debugf("found %d manifests in catalog", len(allManifests))
+	index, err := NewManifestIndexFromFile(m.LocalManifestIndex(ctx))
+	if err != nil {
+		return nil, err
+	}
 
-	var packages []pack.Package
-	var g glob.Glob
+	return FindManifestsFromSource(ctx, index.Origin, mopts...)
+}
 
+// filterManifests returns the manifests which match the types, source and
+// name of the provided query, as well as the query with its type and version
+// completed by those which are encoded in its name, e.g. lib/musl:stable.
+func filterManifests(manifests []*Manifest, query packmanager.CatalogQuery) ([]*Manifest, packmanager.CatalogQuery) {
 	if len(query.Name) > 0 {
 		t, n, v, err := unikraft.GuessTypeNameVersion(query.Name)
 
@@ -254,9 +476,11 @@ func (m manager) Catalog(ctx context.Context, query packmanager.CatalogQuery) ([
 		}
 	}
 
-	g = glob.MustCompile(query.Name)
+	g := glob.MustCompile(query.Name)
+
+	var filtered []*Manifest
 
-	for _, manifest := range allManifests {
+	for _, manifest := range manifests {
 		if len(query.Types) > 0 {
 			found := false
 			for _, t := range query.Types {
@@ -278,30 +502,81 @@ func (m manager) Catalog(ctx context.Context, query packmanager.CatalogQuery) ([
 			continue
 		}
 
+		filtered = append(filtered, manifest)
+	}
+
+	return filtered, query
+}
+
+// Manifests returns the manifests of the catalog which match the provided
+// query.  Unlike Catalog, which returns a package for a single channel or
+// version of each component, the manifests retain all of their channels and
+// versions.  The version of the query is ignored.
+func Manifests(ctx context.Context, query packmanager.CatalogQuery) ([]*Manifest, error) {
+	all, err := manager{}.manifests(ctx, query,
+		WithAuthConfig(config.G[config.KraftKit](ctx).Auth),
+		WithMirrors(config.G[config.KraftKit](ctx).Unikraft.Mirrors),
+		WithSourcesRootDir(config.G[config.KraftKit](ctx).Paths.Sources),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	manifests, _ := filterManifests(all, query)
+
+	sort.SliceStable(manifests, func(i, j int) bool {
+		if manifests[i].Type != manifests[j].Type {
+			return manifests[i].Type < manifests[j].Type
+		}
+
+		return manifests[i].Name < manifests[j].Name
+	})
+
+	return manifests, nil
+}
+
+func (m manager) Catalog(ctx context.Context, query packmanager.CatalogQuery) ([]pack.Package, error) {
+	mopts := []ManifestOption{
+		WithAuthConfig(config.G[config.KraftKit](ctx).Auth),
+		WithMirrors(config.G[config.KraftKit](ctx).Unikraft.Mirrors),
+		WithSourcesRootDir(config.G[config.KraftKit](ctx).Paths.Sources),
+	}
+
+	log.G(ctx).WithFields(logrus.Fields{
+		"name":    query.Name,
+		"version": query.Version,
+		"source":  query.Source,
+		"types":   query.Types,
+		"cache":   !query.NoCache,
+	}).Debug("querying manifest catalog")
+
+	allManifests, err := m.manifests(ctx, query, mopts...)
+	if err != nil {
+		return nil, err
+	}
+
+	log.G(ctx).Debugf("found %d manifests in catalog", len(allManifests))
+
+	var packages []pack.Package
+
+	manifests, query := filterManifests(allManifests, query)
+
+	for _, manifest := range manifests {
 		var versions []string
 		if len(query.Version) > 0 {
 			if len(manifest.Versions) == 1 && len(manifest.Versions[0].Version) == 0 {
 				log.G(ctx).Warn("manifest does not supply version")
 			}
 
-			for _, version := range manifest.Versions {
-				if version.Version == query.Version {
-					versions = append(versions, version.Version)
-					break
-				}
-			}
-			if len(versions) == 0 {
-				for _, channel := range manifest.Channels {
-					if channel.Name == query.Version {
-						versions = append(versions, channel.Name)
-						break
-					}
-				}
+			// The version is either the exact name of a version or channel, or a
+			// constraint which selects the highest satisfying version
+			version, err := manifest.ResolveVersion(query.Version)
+			if err != nil {
+				log.G(ctx).Debug(err)
+				continue
 			}
 
-			if len(versions) == 0 {
-				break
-			}
+			versions = append(versions, version)
 		}
 
 		if len(versions) > 0 {
@@ -394,6 +669,12 @@ func (m manager) LocalManifestIndex(ctx context.Context) string {
 	return filepath.Join(m.LocalManifestsDir(ctx), "index.yaml")
 }
 
+// localSourcesState returns the path to the file which records what was
+// observed of each manifest source when it was last updated.
+func (m manager) localSourcesState(ctx context.Context) string {
+	return filepath.Join(m.LocalManifestsDir(ctx), sourcesStateFileName)
+}
+
 func (m manager) Format() pack.PackageFormat {
 	return ManifestFormat
 }
@@ -58,6 +58,14 @@ type Manifest struct {
 	// Manifest to access information a bout itself aswell as downloading a given
 	// resource
 	auths map[string]config.AuthConfig
+
+	// mirrors is an internal property set by a ManifestOption which lists
+	// alternative locations which are tried in order before the resource itself
+	mirrors []string
+
+	// sourcesDir is an internal property set by a ManifestOption which is the
+	// root of the cache of resources shared by all projects
+	sourcesDir string
 }
 
 type ManifestProvider struct {
@@ -202,6 +210,10 @@ func NewManifestFromURL(ctx context.Context, path string, mopts ...ManifestOptio
 		return nil, fmt.Errorf("provided path was not a valid URL")
 	}
 
+	if config.Offline(ctx) {
+		return nil, config.OfflineError(path)
+	}
+
 	var contents []byte
 	client := &http.Client{}
 
@@ -364,11 +376,31 @@ func (m Manifest) WriteToFile(path string) error {
 
 	defer f.Close()
 
-	contents, err := yaml.Marshal(m)
+	contents, err := m.marshal()
+	if err != nil {
+		return err
+	}
+
+	if err := f.Truncate(0); err != nil {
+		return err
+	}
+
+	_, err = f.Write(contents)
 	if err != nil {
 		return err
 	}
 
+	return nil
+}
+
+// marshal returns the YAML representation of the manifest as it is saved by
+// WriteToFile.
+func (m Manifest) marshal() ([]byte, error) {
+	contents, err := yaml.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+
 	// TODO: This serialization mechanism is used to encode the provider into the
 	// resulting manifest file and feels a bit of a hack since we are running
 	// `yaml.Marshal` twice.  The library exposes `yaml.Marshler` and
@@ -379,7 +411,7 @@ func (m Manifest) WriteToFile(path string) error {
 	// implemented, this code is duplicated also inside of index.go
 	var iface map[string]interface{}
 	if err := yaml.Unmarshal(contents, &iface); err != nil {
-		return err
+		return nil, err
 	}
 
 	if m.Provider != nil {
@@ -388,21 +420,7 @@ func (m Manifest) WriteToFile(path string) error {
 		delete(iface, "provider")
 	}
 
-	contents, err = yaml.Marshal(iface)
-	if err != nil {
-		return err
-	}
-
-	if err := f.Truncate(0); err != nil {
-		return err
-	}
-
-	_, err = f.Write(contents)
-	if err != nil {
-		return err
-	}
-
-	return nil
+	return yaml.Marshal(iface)
 }
 
 // DefaultChannel returns the default channel of the Manifest
@@ -432,3 +450,8 @@ func (m Manifest) Auths() map[string]config.AuthConfig {
 
 	return m.auths
 }
+
+// Mirrors returns the list of mirrors passed as an option to the Manifest
+func (m Manifest) Mirrors() []string {
+	return m.mirrors
+}
@@ -0,0 +1,342 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"kraftkit.sh/config"
+	"kraftkit.sh/internal/fsutil"
+	"kraftkit.sh/internal/version"
+	"kraftkit.sh/log"
+)
+
+// mirrorTimeout is the maximum duration to wait for a mirror to connect and
+// respond, or to send more of a resource, before falling back to the next one.
+var mirrorTimeout = 10 * time.Second
+
+// mirrorsOf returns the ordered list of locations from which the resource can
+// be retrieved: each of the provided mirrors followed by the resource itself.
+// A mirror replicates the host and path of the upstream resource, such that
+// the resource https://github.com/unikraft/unikraft/archive/stable.tar.gz is
+// looked up at <mirror>/github.com/unikraft/unikraft/archive/stable.tar.gz.
+// Mirrors can either be URLs or paths to local directories.
+func mirrorsOf(resource string, mirrors []string) []string {
+	u, err := url.Parse(resource)
+	if err != nil || u.Host == "" {
+		return []string{resource}
+	}
+
+	var candidates []string
+
+	for _, mirror := range mirrors {
+		if len(mirror) == 0 {
+			continue
+		}
+
+		if isLocal(mirror) {
+			dir := strings.TrimPrefix(mirror, "file://")
+			candidates = append(candidates,
+				filepath.Join(dir, u.Host, filepath.FromSlash(u.Path)),
+			)
+		} else {
+			candidates = append(candidates,
+				strings.TrimSuffix(mirror, "/")+"/"+u.Host+u.Path,
+			)
+		}
+	}
+
+	return append(candidates, resource)
+}
+
+// isLocal returns whether the location is a path on the host rather than a
+// remote URL.
+func isLocal(location string) bool {
+	u, err := url.Parse(location)
+	if err != nil {
+		return true
+	}
+
+	return u.Scheme == "" || u.Scheme == "file"
+}
+
+// fetchArchive downloads a resource to the destination path by trying each of
+// the candidate locations in order until one succeeds.  The download is first
+// written to a partial file, see partialPath, which is resumed on subsequent
+// attempts against the same location only.  The partial file of a location
+// which fails is removed before moving on to the next.  If a checksum is
+// provided, the download is validated against it and, on mismatch, discarded
+// in favour of the next location.
+func fetchArchive(ctx context.Context, candidates []string, dest, checksum string, auths map[string]config.AuthConfig, pp *pullProgressArchive) error {
+	if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
+		return fmt.Errorf("could not create parent directorires: %v", err)
+	}
+
+	var errs []string
+	offline := config.Offline(ctx)
+
+	for _, candidate := range candidates {
+		tmp := partialPath(dest, candidate)
+
+		var err error
+		if isLocal(candidate) {
+			err = fetchLocal(candidate, tmp)
+		} else if offline {
+			// Only local mirrors can be used in offline mode
+			continue
+		} else {
+			err = fetchHTTP(ctx, candidate, tmp, auths, pp)
+		}
+		if err != nil {
+			// An interrupted download is resumed from the same location
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			_ = os.Remove(tmp)
+
+			log.G(ctx).WithFields(logrus.Fields{
+				"url": candidate,
+			}).Warnf("could not fetch resource, trying next location: %v", err)
+			errs = append(errs, fmt.Sprintf("%s: %v", candidate, err))
+			continue
+		}
+
+		if len(checksum) > 0 {
+			if err := verifyChecksum(tmp, checksum); err != nil {
+				// The partial file cannot be trusted to be resumed from
+				_ = os.Remove(tmp)
+
+				log.G(ctx).WithFields(logrus.Fields{
+					"url": candidate,
+				}).Warnf("could not validate resource, trying next location: %v", err)
+				errs = append(errs, fmt.Sprintf("%s: %v", candidate, err))
+				continue
+			}
+
+			log.G(ctx).WithFields(logrus.Fields{
+				"url":      candidate,
+				"checksum": checksum,
+			}).Debug("checksum OK")
+		}
+
+		// Copy the completed download to the local cache path
+		if err := os.Rename(tmp, dest); err != nil {
+			return fmt.Errorf("could not move downloaded package '%s' to destination '%s': %v", tmp, dest, err)
+		}
+
+		return nil
+	}
+
+	if offline && len(candidates) > 0 {
+		return config.OfflineError(candidates[len(candidates)-1])
+	}
+
+	return fmt.Errorf("could not download package from any location: %s", strings.Join(errs, "; "))
+}
+
+// partialPath returns the path of the partial download of the resource at the
+// destination from the provided location.  Each location has its own partial
+// file since mirrors are not guaranteed to serve identical bytes, so that a
+// download started from one must not be resumed from another.
+func partialPath(dest, location string) string {
+	sum := sha256.Sum256([]byte(location))
+
+	return dest + "." + hex.EncodeToString(sum[:6]) + ".part"
+}
+
+// fetchLocal copies a resource from a local mirror to the destination path.
+func fetchLocal(path, dest string) error {
+	src, err := os.Open(strings.TrimPrefix(path, "file://"))
+	if err != nil {
+		return err
+	}
+
+	defer src.Close()
+
+	f, err := os.OpenFile(dest, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return fmt.Errorf("could not create cache file: %v", err)
+	}
+
+	defer f.Close()
+
+	_, err = io.Copy(f, src)
+	return err
+}
+
+// fetchHTTP downloads a resource over HTTP to the destination path.  If the
+// destination already exists, the download is resumed from its current size
+// using a HTTP Range request.
+func fetchHTTP(ctx context.Context, resource, dest string, auths map[string]config.AuthConfig, pp *pullProgressArchive) error {
+	u, err := url.Parse(resource)
+	if err != nil {
+		return err
+	}
+
+	var offset int64
+	if fi, err := os.Stat(dest); err == nil {
+		offset = fi.Size()
+	}
+
+	// The request is cancelled by the transfer stalling as well as by the
+	// caller
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: mirrorTimeout}).DialContext
+	transport.TLSHandshakeTimeout = mirrorTimeout
+	transport.ResponseHeaderTimeout = mirrorTimeout
+	client := &http.Client{Transport: transport}
+
+	get, err := http.NewRequestWithContext(ctx, "GET", resource, nil)
+	if err != nil {
+		return err
+	}
+
+	get.Header.Set("User-Agent", version.UserAgent())
+
+	authenticated := false
+	if auth, ok := auths[u.Host]; ok {
+		if len(auth.User) > 0 {
+			authenticated = true
+			get.Header.Set("Authorization", "Basic "+base64.StdEncoding.
+				EncodeToString([]byte(auth.User+":"+auth.Token)))
+		} else if len(auth.Token) > 0 {
+			authenticated = true
+			get.Header.Set("Authorization", "Bearer "+auth.Token)
+		}
+	}
+
+	if offset > 0 {
+		get.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	log.G(ctx).WithFields(logrus.Fields{
+		"url":           resource,
+		"method":        "GET",
+		"offset":        offset,
+		"authenticated": authenticated,
+	}).Trace("http")
+
+	res, err := client.Do(get)
+	if err != nil {
+		return fmt.Errorf("could not initialize GET request to download package: %v", err)
+	}
+
+	defer res.Body.Close()
+
+	flags := os.O_RDWR | os.O_CREATE
+
+	switch res.StatusCode {
+	case http.StatusPartialContent:
+		log.G(ctx).WithFields(logrus.Fields{
+			"url":    resource,
+			"offset": offset,
+		}).Debug("resuming download")
+		flags |= os.O_APPEND
+
+	case http.StatusOK:
+		// The server does not support ranges, start from the beginning
+		offset = 0
+		flags |= os.O_TRUNC
+
+	case http.StatusRequestedRangeNotSatisfiable:
+		if offset == 0 {
+			return fmt.Errorf("received HTTP status code %d without requesting a range", res.StatusCode)
+		}
+
+		// The partial download is larger than the resource and therefore cannot
+		// be resumed
+		if err := os.Remove(dest); err != nil {
+			return err
+		}
+
+		return fetchHTTP(ctx, resource, dest, auths, pp)
+
+	default:
+		return fmt.Errorf("received HTTP status code %d when attempting to download package", res.StatusCode)
+	}
+
+	if res.ContentLength <= 0 {
+		log.G(ctx).Warnf("could not determine package size before pulling")
+		pp.total = 0
+	} else {
+		pp.total = int(offset + res.ContentLength)
+	}
+
+	pp.downloaded = int(offset)
+
+	f, err := os.OpenFile(dest, flags, 0o644)
+	if err != nil {
+		return fmt.Errorf("could not create cache file: %v", err)
+	}
+
+	defer f.Close()
+
+	idle := time.AfterFunc(mirrorTimeout, cancel)
+	defer idle.Stop()
+
+	// With io.TeeReader we are able to pass in the implementing io.Writer such
+	// that we are able to call the onProgress method
+	_, err = io.Copy(f, io.TeeReader(&idleReader{
+		Reader:  res.Body,
+		timer:   idle,
+		timeout: mirrorTimeout,
+	}, pp))
+	if err != nil && !idle.Stop() {
+		return fmt.Errorf("download stalled for more than %s", mirrorTimeout)
+	}
+
+	return err
+}
+
+// idleReader resets the timer with every read which returns data, such that
+// the timer only fires once the reader has stalled for the timeout.
+type idleReader struct {
+	io.Reader
+	timer   *time.Timer
+	timeout time.Duration
+}
+
+// Read implements io.Reader
+func (r *idleReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	if n > 0 {
+		r.timer.Reset(r.timeout)
+	}
+
+	return n, err
+}
+
+// verifyChecksum compares the SHA-256 digest of the file at the provided path
+// against the expected hex-encoded digest.
+func verifyChecksum(path, expected string) error {
+	actual, err := fsutil.Checksum(path)
+	if err != nil {
+		return fmt.Errorf("could not perform checksum: %v", err)
+	}
+
+	if !strings.EqualFold(actual, strings.TrimSpace(expected)) {
+		return fmt.Errorf("checksum of package does not match: expected %s but got %s", expected, actual)
+	}
+
+	return nil
+}
@@ -0,0 +1,266 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"kraftkit.sh/config"
+)
+
+func TestMirrorsOf(t *testing.T) {
+	resource := "https://github.com/unikraft/unikraft/archive/stable.tar.gz"
+
+	got := mirrorsOf(resource, []string{
+		"https://mirror.example.com/unikraft/",
+		"/srv/mirror",
+	})
+	expected := []string{
+		"https://mirror.example.com/unikraft/github.com/unikraft/unikraft/archive/stable.tar.gz",
+		filepath.Join("/srv/mirror", "github.com", "unikraft", "unikraft", "archive", "stable.tar.gz"),
+		resource,
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v but got %v", expected, got)
+	}
+}
+
+func TestFetchArchiveFallbackAndResume(t *testing.T) {
+	content := bytes.Repeat([]byte("unikraft"), 4096)
+	sum := sha256.Sum256(content)
+
+	broken := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer broken.Close()
+
+	var ranged string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranged = r.Header.Get("Range")
+		http.ServeContent(w, r, "archive.tar.gz", time.Time{}, bytes.NewReader(content))
+	}))
+	defer upstream.Close()
+
+	dest := filepath.Join(t.TempDir(), "archive.tar.gz")
+
+	// Simulate interrupted downloads from both locations, of which the broken
+	// one differs
+	if err := os.WriteFile(partialPath(dest, broken.URL+"/archive.tar.gz"), []byte("broken"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(partialPath(dest, upstream.URL+"/archive.tar.gz"), content[:1000], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	err := fetchArchive(context.Background(),
+		[]string{broken.URL + "/archive.tar.gz", upstream.URL + "/archive.tar.gz"},
+		dest,
+		hex.EncodeToString(sum[:]),
+		nil,
+		&pullProgressArchive{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ranged != "bytes=1000-" {
+		t.Errorf("expected download to be resumed but got range %q", ranged)
+	}
+
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, content) {
+		t.Errorf("downloaded content does not match")
+	}
+
+	if parts, _ := filepath.Glob(dest + ".*.part"); len(parts) > 0 {
+		t.Errorf("expected partial downloads to be removed, got %v", parts)
+	}
+}
+
+func TestFetchArchiveResumeSameLocation(t *testing.T) {
+	content := bytes.Repeat([]byte("unikraft"), 4096)
+
+	var ranges []string
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		http.ServeContent(w, r, "archive.tar.gz", time.Time{}, bytes.NewReader(content))
+	}))
+	defer mirror.Close()
+
+	upstream := mirror.URL + "/upstream/archive.tar.gz"
+	dest := filepath.Join(t.TempDir(), "archive.tar.gz")
+
+	// A download which was started from another location is not resumed
+	if err := os.WriteFile(partialPath(dest, mirror.URL+"/mirror/archive.tar.gz"), content[:1000], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fetchArchive(context.Background(), []string{upstream}, dest, "", nil, &pullProgressArchive{}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(ranges, []string{""}) {
+		t.Errorf("expected the download not to be resumed, got ranges %q", ranges)
+	}
+}
+
+func TestFetchArchiveChecksumMismatch(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("tampered"))
+	}))
+	defer upstream.Close()
+
+	dest := filepath.Join(t.TempDir(), "archive.tar.gz")
+
+	err := fetchArchive(context.Background(),
+		[]string{upstream.URL + "/archive.tar.gz"},
+		dest,
+		"0000000000000000000000000000000000000000000000000000000000000000",
+		nil,
+		&pullProgressArchive{},
+	)
+	if err == nil {
+		t.Fatal("expected checksum mismatch to fail")
+	}
+
+	if _, err := os.Stat(dest); err == nil {
+		t.Errorf("expected destination to not exist")
+	}
+
+	if _, err := os.Stat(partialPath(dest, upstream.URL+"/archive.tar.gz")); err == nil {
+		t.Errorf("expected partial download to be removed")
+	}
+}
+
+func TestFetchArchiveChecksumMismatchFallback(t *testing.T) {
+	content := []byte("unikraft")
+	sum := sha256.Sum256(content)
+
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("tampered"))
+	}))
+	defer mirror.Close()
+
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content)
+	}))
+	defer upstream.Close()
+
+	dest := filepath.Join(t.TempDir(), "archive.tar.gz")
+
+	// A mirror which serves different contents must not block the upstream
+	if err := fetchArchive(context.Background(),
+		[]string{mirror.URL + "/archive.tar.gz", upstream.URL + "/archive.tar.gz"},
+		dest,
+		hex.EncodeToString(sum[:]),
+		nil,
+		&pullProgressArchive{},
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := os.ReadFile(dest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !bytes.Equal(got, content) {
+		t.Errorf("expected the contents of the upstream but got %q", got)
+	}
+}
+
+func TestFetchHTTPStalled(t *testing.T) {
+	timeout := mirrorTimeout
+	mirrorTimeout = 100 * time.Millisecond
+	defer func() { mirrorTimeout = timeout }()
+
+	release := make(chan struct{})
+	defer close(release)
+
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "1024")
+		_, _ = w.Write([]byte("unikraft"))
+		w.(http.Flusher).Flush()
+
+		// Stall in the middle of the body
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer mirror.Close()
+
+	dest := filepath.Join(t.TempDir(), "archive.tar.gz")
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- fetchHTTP(context.Background(), mirror.URL+"/archive.tar.gz", dest, nil, &pullProgressArchive{})
+	}()
+
+	select {
+	case err := <-errs:
+		if err == nil || !strings.Contains(err.Error(), "stalled") {
+			t.Errorf("expected the stalled download to fail, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the stalled download to be aborted")
+	}
+}
+
+func TestFetchArchiveOffline(t *testing.T) {
+	var requested bool
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer upstream.Close()
+
+	ctx := config.WithOffline(context.Background(), true)
+	resource := upstream.URL + "/unikraft/archive/stable.tar.gz"
+	dest := filepath.Join(t.TempDir(), "archive.tar.gz")
+
+	err := fetchArchive(ctx, []string{resource}, dest, "", nil, &pullProgressArchive{})
+	if !errors.Is(err, config.ErrOffline) || !strings.Contains(err.Error(), resource) {
+		t.Errorf("expected offline error naming %s but got %v", resource, err)
+	}
+
+	if requested {
+		t.Error("expected no request in offline mode")
+	}
+
+	// Local mirrors remain usable
+	mirror := t.TempDir()
+	u, _ := url.Parse(resource)
+	local := filepath.Join(mirror, u.Host, filepath.FromSlash(u.Path))
+	if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(local, []byte("unikraft"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fetchArchive(ctx, mirrorsOf(resource, []string{mirror}), dest, "", nil, &pullProgressArchive{}); err != nil {
+		t.Fatal(err)
+	}
+}
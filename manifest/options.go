@@ -5,9 +5,12 @@
 package manifest
 
 import (
+	"os"
 	"path/filepath"
+	"strings"
 
 	"kraftkit.sh/config"
+	"kraftkit.sh/packmanager"
 	"kraftkit.sh/unikraft"
 )
 
@@ -20,6 +23,15 @@ func WithAuthConfig(auths map[string]config.AuthConfig) ManifestOption {
 	}
 }
 
+// WithMirrors sets the list of mirrors which are tried in order before the
+// upstream location of a resource when pulling the Manifest.
+func WithMirrors(mirrors []string) ManifestOption {
+	return func(m *Manifest) error {
+		m.mirrors = mirrors
+		return nil
+	}
+}
+
 // WithSourcesRootDir is an option which helps find cached Manifest Channel or
 // Version resources.  When set to a directory, the fixed structure of this
 // directory should allow us to look up (and also store) resources here for
@@ -29,18 +41,29 @@ func WithSourcesRootDir(dir string) ManifestOption {
 		// See: https://github.com/golang/go/wiki/CommonMistakes#using-reference-to-loop-iterator-variable
 		dir := dir
 
+		m.sourcesDir = dir
+
 		if m.Type != unikraft.ComponentTypeCore {
 			dir = filepath.Join(dir, m.Type.Plural())
 		}
 
+		// Resources are cached by their origin and version such that identically
+		// named components from different origins do not share an entry whilst the
+		// same component used by different projects does
+		origin := m.Origin
+
 		for i, channel := range m.Channels {
 			ext := filepath.Ext(channel.Resource)
 			if ext == ".gz" {
 				ext = ".tar.gz"
 			}
 
+			if len(m.Origin) == 0 {
+				origin = channel.Resource
+			}
+
 			m.Channels[i].Local = filepath.Join(
-				dir, m.Name+"-"+channel.Name+ext,
+				dir, m.Name+"-"+channel.Name+"-"+packmanager.CacheKey(origin, channel.Name)[:12]+ext,
 			)
 		}
 
@@ -50,11 +73,57 @@ func WithSourcesRootDir(dir string) ManifestOption {
 				ext = ".tar.gz"
 			}
 
+			if len(m.Origin) == 0 {
+				origin = version.Resource
+			}
+
 			m.Versions[i].Local = filepath.Join(
-				dir, m.Name+"-"+version.Version+ext,
+				dir, m.Name+"-"+version.Version+"-"+packmanager.CacheKey(origin, version.Version)[:12]+ext,
 			)
 		}
 
 		return nil
 	}
 }
+
+// legacyLocal returns the location at which the resource cached at the
+// provided location was cached before cached resources were distinguished by
+// their origin, i.e. `<name>-<version><ext>`.  It is empty if the location does
+// not carry the key of an origin.
+func legacyLocal(local string) string {
+	base := filepath.Base(local)
+
+	for i := strings.LastIndex(base, "-"); i > 0; i = strings.LastIndex(base[:i], "-") {
+		key, ext := base[i+1:], ""
+		if dot := strings.Index(key, "."); dot >= 0 {
+			key, ext = key[:dot], key[dot:]
+		}
+
+		if len(key) != 12 || strings.Trim(key, "0123456789abcdef") != "" {
+			continue
+		}
+
+		return filepath.Join(filepath.Dir(local), base[:i]+ext)
+	}
+
+	return ""
+}
+
+// migrateLocal moves a resource which was cached under its legacy name, see
+// legacyLocal, to the provided location such that it is not fetched again.
+func migrateLocal(local string) error {
+	legacy := legacyLocal(local)
+	if len(legacy) == 0 {
+		return nil
+	}
+
+	if _, err := os.Stat(local); err == nil {
+		return nil
+	}
+
+	if _, err := os.Stat(legacy); err != nil {
+		return nil
+	}
+
+	return os.Rename(legacy, local)
+}
@@ -0,0 +1,66 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLegacyLocal(t *testing.T) {
+	tests := []struct {
+		local  string
+		legacy string
+	}{
+		{"/sources/libs/musl-stable-0123456789ab.tar.gz", "/sources/libs/musl-stable.tar.gz"},
+		{"/sources/libs/lwip-2.1.2-0123456789ab.zip", "/sources/libs/lwip-2.1.2.zip"},
+		{"/sources/unikraft-staging-abcdefabcdef", "/sources/unikraft-staging"},
+		{"/sources/libs/musl-stable.tar.gz", ""},
+		{"/sources/libs/musl-stable-0123456789AB.tar.gz", ""},
+		{"/sources/libs/musl-stable-0123456789a.tar.gz", ""},
+	}
+
+	for _, test := range tests {
+		if legacy := legacyLocal(test.local); legacy != test.legacy {
+			t.Errorf("legacyLocal(%q) = %q, expected %q", test.local, legacy, test.legacy)
+		}
+	}
+}
+
+func TestMigrateLocal(t *testing.T) {
+	dir := t.TempDir()
+	local := filepath.Join(dir, "musl-stable-0123456789ab.tar.gz")
+	legacy := filepath.Join(dir, "musl-stable.tar.gz")
+
+	if err := os.WriteFile(legacy, []byte("archive"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrateLocal(local); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(legacy); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be moved", legacy)
+	}
+
+	if contents, err := os.ReadFile(local); err != nil || string(contents) != "archive" {
+		t.Errorf("expected %s to be migrated: %v", local, err)
+	}
+
+	// A resource which is already cached under its new name is kept
+	if err := os.WriteFile(legacy, []byte("stale"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrateLocal(local); err != nil {
+		t.Fatal(err)
+	}
+
+	if contents, _ := os.ReadFile(local); string(contents) != "archive" {
+		t.Errorf("expected %s to be kept, got %s", local, contents)
+	}
+}
@@ -6,21 +6,16 @@ package manifest
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/base64"
 	"fmt"
-	"io"
-	"net/http"
-	"net/url"
 	"os"
-	"path/filepath"
 
 	"github.com/sirupsen/logrus"
 
 	"kraftkit.sh/archive"
-	"kraftkit.sh/internal/version"
+	"kraftkit.sh/config"
 	"kraftkit.sh/log"
 	"kraftkit.sh/pack"
+	"kraftkit.sh/packmanager"
 	"kraftkit.sh/unikraft"
 )
 
@@ -59,142 +54,66 @@ func pullArchive(ctx context.Context, manifest *Manifest, opts ...pack.PullOptio
 		downloaded: 0,
 	}
 
-	if f, err := os.Stat(cache); !popts.UseCache() || err != nil || f.Size() == 0 {
-		u, err := url.Parse(resource)
-		if err != nil {
-			return err
-		}
-
-		authHeader := ""
-		authenticated := false
-
-		if auth, ok := manifest.Auths()[u.Host]; ok {
-			if len(auth.User) > 0 {
-				authenticated = true
-				authHeader = "Basic " + base64.StdEncoding.
-					EncodeToString([]byte(auth.User+":"+auth.Token))
-			} else if len(auth.Token) > 0 {
-				authenticated = true
-				authHeader = "Bearer " + auth.Token
-			}
-		}
-
-		client := &http.Client{}
-
-		head, err := http.NewRequestWithContext(ctx, "HEAD", resource, nil)
-		if err != nil {
-			return err
-		}
-
-		head.Header.Set("User-Agent", version.UserAgent())
-		if authenticated {
-			head.Header.Set("Authorization", authHeader)
-		}
-
-		log.G(ctx).WithFields(logrus.Fields{
-			"url":           resource,
-			"method":        "HEAD",
-			"authenticated": authenticated,
-		}).Trace("http")
-
-		// Get the total size of the remote resource.  Note: this fails for GitHub
-		// archives as Content-Length is, for some reason, always set to 0.
-		res, err := client.Do(head)
-		if err != nil {
-			return fmt.Errorf("could not perform HEAD request on resource: %v", err)
-		} else if res.StatusCode != http.StatusOK {
-			return fmt.Errorf("received HTTP error code %d on resource", res.StatusCode)
-		} else if res.ContentLength <= 0 {
-			log.G(ctx).Warnf("could not determine package size before pulling")
-			pp.total = 0
-		} else {
-			pp.total = int(res.ContentLength)
-		}
-
-		// Create a temporary partial of the destination path of the resource
-		tmpCache := cache + ".part"
-		if err := os.MkdirAll(filepath.Dir(tmpCache), 0o755); err != nil {
-			return fmt.Errorf("could not create parent directorires: %v", err)
-		}
-
-		f, err := os.OpenFile(tmpCache, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0o755)
-		if err != nil {
-			return fmt.Errorf("could not create cache file: %v", err)
-		}
-
-		defer f.Close()
-
-		get, err := http.NewRequestWithContext(ctx, "GET", resource, nil)
-		if err != nil {
-			return err
-		}
-
-		get.Header.Set("User-Agent", version.UserAgent())
-		if authenticated {
-			head.Header.Set("Authorization", authHeader)
-		}
+	// The cache is the only source of resources in offline mode
+	useCache := popts.UseCache() || config.Offline(ctx)
 
-		log.G(ctx).WithFields(logrus.Fields{
-			"url":           resource,
-			"method":        "GET",
-			"authenticated": authenticated,
-		}).Trace("http")
+	if len(checksum) == 0 && popts.CalculateChecksum() {
+		log.G(ctx).Warnf("manifest does not specify checksum!")
+	}
 
-		// Perform the request to actually retrieve the file
-		res, err = client.Do(get)
-		if err != nil {
-			return fmt.Errorf("could not initialize GET request to download package: %v", err)
-		} else if res.StatusCode != http.StatusOK {
-			return fmt.Errorf("received non-200 HTTP status code when attempting to download package: %v", err)
-		}
+	// Fetches of the same resource are deduplicated, both within this process by
+	// the scheduler and between processes by locking the cache entry
+	if err := packmanager.SchedulerFromContext(ctx).Do(ctx, resource, cache, func(ctx context.Context) error {
+		if len(cache) > 0 {
+			unlock, err := packmanager.LockCache(ctx, cache)
+			if err != nil {
+				return err
+			}
 
-		defer res.Body.Close()
+			defer unlock()
 
-		// With io.TeeReader we are able to pass in the implementing io.Writer such
-		// that we are able to call the onProgress method
-		_, err = io.Copy(f, io.TeeReader(res.Body, pp))
-		if err != nil {
-			return err
+			if err := migrateLocal(cache); err != nil {
+				log.G(ctx).WithFields(logrus.Fields{
+					"local": cache,
+				}).Debugf("could not migrate cache: %v", err)
+			}
 		}
 
-		if popts.CalculateChecksum() {
-			log.G(ctx).Debugf("calculating checksum for manifest package...")
-
-			if len(checksum) == 0 {
-				log.G(ctx).Warnf("manifest does not specify checksum!")
-			} else {
-
-				f, err := os.Open(tmpCache)
-				if err != nil {
-					return fmt.Errorf("could not perform checksum: %v", err)
-				}
-				defer f.Close()
-
-				h := sha256.New()
-				if _, err := io.Copy(h, f); err != nil {
-					return fmt.Errorf("could not perform checksum: %v", err)
-				}
+		// Discard a cached resource which does not match the expected checksum,
+		// e.g. as a result of corruption or of the upstream resource being
+		// replaced.
+		if f, err := os.Stat(cache); useCache && err == nil && f.Size() > 0 && len(checksum) > 0 {
+			if err := verifyChecksum(cache, checksum); err != nil {
+				log.G(ctx).WithFields(logrus.Fields{
+					"local": cache,
+				}).Warnf("discarding cache: %v", err)
 
-				if checksum != string(h.Sum(nil)) {
-					return fmt.Errorf("checksum of package does not match")
+				if err := os.Remove(cache); err != nil {
+					return err
 				}
-
-				log.G(ctx).WithFields(logrus.Fields{
-					"url":      resource,
-					"checksum": checksum,
-				}).Debug("checksum OK")
 			}
 		}
 
-		// Copy the completed download to the local cache path
-		if err := os.Rename(tmpCache, cache); err != nil {
-			return fmt.Errorf("could not move downloaded package '%s' to destination '%s': %v", tmpCache, cache, err)
+		if f, err := os.Stat(cache); !useCache || err != nil || f.Size() == 0 {
+			if err := fetchArchive(ctx,
+				mirrorsOf(resource, manifest.Mirrors()),
+				cache,
+				checksum,
+				manifest.Auths(),
+				pp,
+			); err != nil {
+				return err
+			}
+		} else {
+			log.G(ctx).WithFields(logrus.Fields{
+				"local":  cache,
+				"remote": resource,
+			}).Debug("using cache")
 		}
-	} else {
-		log.G(ctx).WithFields(logrus.Fields{
-			"local":  cache,
-			"remote": resource,
-		}).Debug("using cache")
+
+		return nil
+	}); err != nil {
+		return err
 	}
 
 	local := cache
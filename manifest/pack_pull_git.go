@@ -16,12 +16,16 @@ import (
 	"time"
 
 	"github.com/go-git/go-git/v5"
+	gitconfig "github.com/go-git/go-git/v5/config"
 	gitplumbing "github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/transport"
 	githttp "github.com/go-git/go-git/v5/plumbing/transport/http"
 	gitssh "github.com/go-git/go-git/v5/plumbing/transport/ssh"
 
+	"kraftkit.sh/config"
 	"kraftkit.sh/log"
 	"kraftkit.sh/pack"
+	"kraftkit.sh/packmanager"
 	"kraftkit.sh/unikraft"
 )
 
@@ -98,6 +102,59 @@ func (p cloneProgress) Write(b []byte) (int, error) {
 	return len(b), nil
 }
 
+// wait blocks until the simulated progress of the clone has completed.  If the
+// simulation was never started, it is prevented from starting.
+func (p cloneProgress) wait() {
+	started := true
+	p.once.Do(func() {
+		started = false
+	})
+
+	if started {
+		p.completeWorker <- struct{}{}
+		<-p.completeParent
+	}
+}
+
+// gitHTTPAuth returns the authentication method for the provided host based on
+// the provided configuration, if any.
+func gitHTTPAuth(auths map[string]config.AuthConfig, host string) transport.AuthMethod {
+	cfg, ok := auths[host]
+	if !ok {
+		return nil
+	}
+
+	if cfg.User != "" && cfg.Token != "" {
+		return &githttp.BasicAuth{
+			Username: cfg.User,
+			Password: cfg.Token,
+		}
+	} else if cfg.Token != "" {
+		return &githttp.TokenAuth{
+			Token: cfg.Token,
+		}
+	}
+
+	return nil
+}
+
+// probeGitRemote checks whether the Git repository at the provided location is
+// reachable within the mirror timeout.
+func probeGitRemote(ctx context.Context, location string, auth transport.AuthMethod) error {
+	ctx, cancel := context.WithTimeout(ctx, mirrorTimeout)
+	defer cancel()
+
+	remote := git.NewRemote(nil, &gitconfig.RemoteConfig{
+		Name: "origin",
+		URLs: []string{location},
+	})
+
+	_, err := remote.ListContext(ctx, &git.ListOptions{
+		Auth: auth,
+	})
+	return err
+}
+
 // pullGit is used internally to pull a specific Manifest resource using if the
 // Manifest has the repo defined within.
 func pullGit(ctx context.Context, manifest *Manifest, opts ...pack.PullOption) error {
@@ -132,40 +189,44 @@ func pullGit(ctx context.Context, manifest *Manifest, opts ...pack.PullOption) e
 			once:           &sync.Once{},
 		},
 	}
-	path := manifest.Origin
+	path := gitRemoteOf(manifest.Origin)
 
 	// Is this an SSH URL?
 	if isSSHURL(path) {
-		if strings.HasPrefix(path, "git@") {
-			path = "ssh://" + path
-		}
 		copts.Auth, err = gitssh.NewSSHAgentAuth("git")
 		if err != nil {
 			return fmt.Errorf("could not create SSH agent auth: %w", err)
 		}
 	} else {
-		if !strings.HasPrefix(path, "https://") {
-			path = "https://" + path
-		}
 		u, err := url.Parse(path)
 		if err != nil {
 			return fmt.Errorf("could not parse URL: %w", err)
 		}
-		endpoint := u.Host
-
-		if manifest.Auths() != nil {
-			if cfg, ok := manifest.Auths()[endpoint]; ok {
-				if cfg.User != "" && cfg.Token != "" {
-					copts.Auth = &githttp.BasicAuth{
-						Username: cfg.User,
-						Password: cfg.Token,
-					}
-				} else if cfg.Token != "" {
-					copts.Auth = &githttp.TokenAuth{
-						Token: cfg.Token,
-					}
-				}
+
+		copts.Auth = gitHTTPAuth(manifest.Auths(), u.Host)
+
+		// Use the first reachable mirror, otherwise fall back to the upstream
+		// repository
+		for _, candidate := range mirrorsOf(path, manifest.Mirrors()) {
+			if candidate == path || config.Offline(ctx) {
+				break
 			}
+
+			var auth transport.AuthMethod
+			if mu, err := url.Parse(candidate); err == nil && len(mu.Host) > 0 {
+				auth = gitHTTPAuth(manifest.Auths(), mu.Host)
+			}
+
+			if err := probeGitRemote(ctx, candidate, auth); err != nil {
+				log.G(ctx).
+					WithField("url", candidate).
+					Warnf("could not reach mirror, trying next location: %v", err)
+				continue
+			}
+
+			path = candidate
+			copts.Auth = auth
+			break
 		}
 	}
 	copts.URL = path
@@ -183,13 +244,49 @@ func pullGit(ctx context.Context, manifest *Manifest, opts ...pack.PullOption) e
 		return fmt.Errorf("could not place component package: %w", err)
 	}
 
+	progress := copts.Progress.(cloneProgress)
+
+	// Prefer the shared cache of repositories such that the same repository is
+	// only fetched once for all projects
+	if len(manifest.sourcesDir) > 0 && len(copts.ReferenceName) > 0 {
+		err := pullGitFromCache(ctx, manifest.sourcesDir, path, local, copts.ReferenceName, copts.Auth, progress)
+		if err == nil {
+			progress.wait()
+			popts.OnProgress(1.0)
+
+			log.G(ctx).Infof("successfully cloned %s into %s", path, local)
+
+			return nil
+		} else if errors.Is(err, config.ErrOffline) {
+			return err
+		} else if !errors.Is(err, git.ErrRepositoryAlreadyExists) {
+			log.G(ctx).
+				WithField("from", path).
+				Debugf("could not use git cache, cloning directly: %v", err)
+		}
+	}
+
+	if config.Offline(ctx) {
+		if _, err := git.PlainOpen(local); err == nil {
+			log.G(ctx).
+				WithField("local", local).
+				Debug("using existing repository in offline mode")
+			return nil
+		}
+
+		return config.OfflineError(path)
+	}
+
 	log.G(ctx).
 		WithField("from", path).
 		WithField("to", local).
 		WithField("branch", popts.Version()).
 		Infof("git clone")
 
-	_, err = git.PlainCloneContext(ctx, local, false, copts)
+	err = packmanager.SchedulerFromContext(ctx).Do(ctx, path, "", func(ctx context.Context) error {
+		_, err := git.PlainCloneContext(ctx, local, false, copts)
+		return err
+	})
 	switch {
 	case errors.Is(err, git.ErrRepositoryAlreadyExists):
 		reps, err := git.PlainOpen(local)
@@ -215,8 +312,7 @@ func pullGit(ctx context.Context, manifest *Manifest, opts ...pack.PullOption) e
 	}
 
 	// Wait for the go routine to finish
-	completeWorker <- struct{}{}
-	<-completeParent
+	progress.wait()
 	popts.OnProgress(1.0)
 
 	log.G(ctx).Infof("successfully cloned %s into %s", path, local)
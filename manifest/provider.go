@@ -10,6 +10,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"kraftkit.sh/config"
 	"kraftkit.sh/log"
 	"kraftkit.sh/pack"
 )
@@ -65,6 +66,34 @@ func NewProvider(ctx context.Context, path string, mopts ...ManifestOption) (Pro
 		return provider, nil
 	}
 
+	// Every remaining provider retrieves information about the path from the
+	// network
+	if config.Offline(ctx) {
+		return nil, config.OfflineError(path)
+	}
+
+	log.G(ctx).WithFields(logrus.Fields{
+		"path": path,
+	}).Trace("trying gitlab provider")
+	provider, err = NewGitLabProvider(ctx, path, mopts...)
+	if err == nil {
+		log.G(ctx).WithFields(logrus.Fields{
+			"path": path,
+		}).Trace("using gitlab provider")
+		return provider, nil
+	}
+
+	log.G(ctx).WithFields(logrus.Fields{
+		"path": path,
+	}).Trace("trying gitea provider")
+	provider, err = NewGiteaProvider(ctx, path, mopts...)
+	if err == nil {
+		log.G(ctx).WithFields(logrus.Fields{
+			"path": path,
+		}).Trace("using gitea provider")
+		return provider, nil
+	}
+
 	log.G(ctx).WithFields(logrus.Fields{
 		"path": path,
 	}).Trace("trying github provider")
@@ -108,6 +137,10 @@ func NewProviderFromString(ctx context.Context, provider, path string, entity an
 		}, nil
 	case "github":
 		return NewGitHubProvider(ctx, path, mopts...)
+	case "gitlab":
+		return NewGitLabProvider(ctx, path, mopts...)
+	case "gitea":
+		return NewGiteaProvider(ctx, path, mopts...)
 	case "git":
 		return NewGitProvider(ctx, path, mopts...)
 	case "directory":
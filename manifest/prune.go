@@ -0,0 +1,429 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gobwas/glob"
+	"github.com/sirupsen/logrus"
+
+	"kraftkit.sh/config"
+	"kraftkit.sh/log"
+	"kraftkit.sh/packmanager"
+	"kraftkit.sh/unikraft"
+)
+
+// localResource represents a channel or version of a manifest which has been
+// cached within the sources directory.
+type localResource struct {
+	ctype   unikraft.ComponentType
+	name    string
+	version string
+}
+
+func (lr localResource) Type() unikraft.ComponentType {
+	return lr.ctype
+}
+
+func (lr localResource) Name() string {
+	return lr.name
+}
+
+func (lr localResource) Version() string {
+	return lr.version
+}
+
+// localResources returns a map of paths within the sources directory to the
+// manifest channel or version which would be cached at that location, as well
+// as a map of the bare repositories of the shared git cache to the channels and
+// versions of the manifests whose repositories they may cache.
+func (m manager) localResources(ctx context.Context) (map[string]localResource, map[string][]localResource, error) {
+	resources := map[string]localResource{}
+	gitCaches := map[string][]localResource{}
+
+	if _, err := os.Stat(m.LocalManifestIndex(ctx)); err != nil {
+		return resources, gitCaches, nil
+	}
+
+	manifests, err := FindManifestsFromSource(ctx, m.LocalManifestIndex(ctx),
+		WithSourcesRootDir(config.G[config.KraftKit](ctx).Paths.Sources),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sources := config.G[config.KraftKit](ctx).Paths.Sources
+	mirrors := config.G[config.KraftKit](ctx).Unikraft.Mirrors
+
+	// Resources which are cached under their legacy name are known until they
+	// are migrated the next time they are pulled
+	add := func(local string, resource localResource) {
+		resources[local] = resource
+		if legacy := legacyLocal(local); len(legacy) > 0 {
+			if _, ok := resources[legacy]; !ok {
+				resources[legacy] = resource
+			}
+		}
+	}
+
+	for _, manifest := range manifests {
+		var all []localResource
+
+		for _, channel := range manifest.Channels {
+			resource := localResource{
+				ctype:   manifest.Type,
+				name:    manifest.Name,
+				version: channel.Name,
+			}
+
+			add(channel.Local, resource)
+			all = append(all, resource)
+		}
+
+		for _, version := range manifest.Versions {
+			resource := localResource{
+				ctype:   manifest.Type,
+				name:    manifest.Name,
+				version: version.Version,
+			}
+
+			add(version.Local, resource)
+			all = append(all, resource)
+		}
+
+		for _, dir := range gitCachesOf(sources, mirrors, manifest) {
+			gitCaches[dir] = append(gitCaches[dir], all...)
+		}
+	}
+
+	return resources, gitCaches, nil
+}
+
+// gitCachesOf returns the locations of the bare repositories within the
+// sources directory which may cache the repository of the manifest, or one of
+// its mirrors.
+func gitCachesOf(sources string, mirrors []string, manifest *Manifest) []string {
+	locations := []string{manifest.Origin}
+	for _, channel := range manifest.Channels {
+		locations = append(locations, channel.Resource)
+	}
+	for _, version := range manifest.Versions {
+		locations = append(locations, version.Resource)
+	}
+
+	var dirs []string
+	for _, location := range locations {
+		if len(location) == 0 {
+			continue
+		}
+
+		for _, candidate := range mirrorsOf(gitRemoteOf(location), mirrors) {
+			dirs = append(dirs, gitCacheDir(sources, candidate))
+		}
+	}
+
+	return dirs
+}
+
+// parentsOf returns the directories within the sources directory which contain
+// the known resources or the shared git cache.
+func parentsOf(sources string, resources map[string]localResource) map[string]bool {
+	dirs := map[string]bool{
+		filepath.Join(sources, gitCacheDirName): true,
+	}
+
+	for path := range resources {
+		for dir := filepath.Dir(path); dir != sources && strings.HasPrefix(dir, sources+string(filepath.Separator)); dir = filepath.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+
+	return dirs
+}
+
+// matchesQuery returns whether the resource is matched by the provided query.
+func matchesQuery(lr localResource, query packmanager.CatalogQuery) bool {
+	types := query.Types
+	name := query.Name
+	version := query.Version
+
+	if len(name) > 0 {
+		if t, n, v, err := unikraft.GuessTypeNameVersion(name); err == nil {
+			name = n
+			if t != unikraft.ComponentTypeUnknown {
+				types = append(types, t)
+			}
+			if len(v) > 0 {
+				version = v
+			}
+		}
+	}
+
+	if len(types) > 0 {
+		found := false
+		for _, t := range types {
+			if lr.ctype == t {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(name) > 0 {
+		g, err := glob.Compile(name)
+		if err != nil || !g.Match(lr.name) {
+			return false
+		}
+	}
+
+	if len(version) > 0 && lr.version != version {
+		return false
+	}
+
+	return true
+}
+
+// Prune removes cached resources from the sources directory and manifests
+// which are no longer part of the local index.  Without a query, partial
+// downloads are always removed, whereas resources which are unknown to the local
+// index, stale manifests, unreferenced resources and the shared repositories
+// which no referenced component may use are only removed when all unreferenced
+// packages should be pruned.  With a query, only the resources
+// which match it are removed.
+func (m manager) Prune(ctx context.Context, opts ...packmanager.PruneOption) ([]packmanager.Reclaimed, error) {
+	popts := packmanager.NewPruneOptions(opts...)
+
+	resources, gitCaches, err := m.localResources(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reclaimed []packmanager.Reclaimed
+
+	remove := func(path, name string, size int64) error {
+		log.G(ctx).WithFields(logrus.Fields{
+			"path":   path,
+			"dryrun": popts.DryRun(),
+		}).Debug("pruning")
+
+		if !popts.DryRun() {
+			if err := os.RemoveAll(path); err != nil {
+				return err
+			}
+		}
+
+		reclaimed = append(reclaimed, packmanager.Reclaimed{
+			Format: ManifestFormat,
+			Name:   name,
+			Size:   size,
+		})
+
+		return nil
+	}
+
+	sources := config.G[config.KraftKit](ctx).Paths.Sources
+	if len(sources) > 0 {
+		dirs := parentsOf(sources, resources)
+
+		err = filepath.WalkDir(sources, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+
+			// The shared repositories are not individual resources and are only
+			// removed as a whole, once no referenced component may use them
+			if d.IsDir() && filepath.Dir(path) == filepath.Join(sources, gitCacheDirName) && strings.HasSuffix(path, ".git") {
+				if popts.Query() != nil || !popts.All() {
+					return filepath.SkipDir
+				}
+
+				for _, resource := range gitCaches[path] {
+					if popts.IsReferenced(resource) {
+						return filepath.SkipDir
+					}
+				}
+
+				fi, err := d.Info()
+				if err != nil {
+					return err
+				}
+
+				if !popts.IsOldEnough(fi.ModTime()) {
+					return filepath.SkipDir
+				}
+
+				size, err := dirSize(path)
+				if err != nil {
+					return err
+				}
+
+				if err := remove(path, path, size); err != nil {
+					return err
+				}
+
+				return filepath.SkipDir
+			}
+
+			// Directories which cannot contain any known resource are removed as a
+			// whole, whereas they are still walked otherwise to remove the partial
+			// downloads within them
+			if d.IsDir() && path != sources && !dirs[path] {
+				if popts.Query() != nil || !popts.All() {
+					return nil
+				}
+
+				fi, err := d.Info()
+				if err != nil {
+					return err
+				}
+
+				if !popts.IsOldEnough(fi.ModTime()) {
+					return nil
+				}
+
+				size, err := dirSize(path)
+				if err != nil {
+					return err
+				}
+
+				if err := remove(path, path, size); err != nil {
+					return err
+				}
+
+				return filepath.SkipDir
+			}
+
+			if d.IsDir() || !d.Type().IsRegular() || strings.HasSuffix(path, ".lock") {
+				return nil
+			}
+
+			fi, err := d.Info()
+			if err != nil {
+				return err
+			}
+
+			if !popts.IsOldEnough(fi.ModTime()) {
+				return nil
+			}
+
+			resource, known := resources[path]
+
+			switch {
+			case strings.HasSuffix(path, ".part"):
+				if popts.Query() != nil {
+					return nil
+				}
+
+				return remove(path, path, fi.Size())
+
+			case !known:
+				if popts.Query() != nil || !popts.All() {
+					return nil
+				}
+
+				return remove(path, path, fi.Size())
+
+			case popts.IsReferenced(resource):
+				return nil
+
+			case popts.Query() != nil:
+				if !matchesQuery(resource, *popts.Query()) {
+					return nil
+				}
+
+			case !popts.All():
+				return nil
+			}
+
+			return remove(path, unikraft.TypeNameVersion(resource), fi.Size())
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if popts.Query() != nil || !popts.All() {
+		return reclaimed, nil
+	}
+
+	// Remove manifests which are no longer referenced by the local index, e.g.
+	// those which were removed upstream since the last update.
+	index, err := NewManifestIndexFromFile(m.LocalManifestIndex(ctx))
+	if err != nil {
+		return reclaimed, nil
+	}
+
+	indexed := map[string]bool{
+		m.LocalManifestIndex(ctx): true,
+		m.localSourcesState(ctx):  true,
+	}
+	for _, manifest := range index.Manifests {
+		if len(manifest.Manifest) > 0 {
+			indexed[filepath.Join(m.LocalManifestsDir(ctx), manifest.Manifest)] = true
+		}
+	}
+
+	err = filepath.WalkDir(m.LocalManifestsDir(ctx), func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() || filepath.Ext(path) != ".yaml" || indexed[path] {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		if !popts.IsOldEnough(fi.ModTime()) {
+			return nil
+		}
+
+		return remove(path, path, fi.Size())
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return reclaimed, nil
+}
+
+// dirSize returns the total size of the regular files within the directory.
+func dirSize(dir string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if !d.Type().IsRegular() {
+			return nil
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		size += fi.Size()
+		return nil
+	})
+
+	return size, err
+}
@@ -0,0 +1,99 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"testing"
+
+	"kraftkit.sh/packmanager"
+	"kraftkit.sh/unikraft"
+)
+
+func TestMatchesQuery(t *testing.T) {
+	musl := localResource{
+		ctype:   unikraft.ComponentTypeLib,
+		name:    "musl",
+		version: "stable",
+	}
+
+	tests := []struct {
+		name     string
+		query    packmanager.CatalogQuery
+		expected bool
+	}{
+		{"empty", packmanager.CatalogQuery{}, true},
+		{"name", packmanager.CatalogQuery{Name: "musl"}, true},
+		{"other name", packmanager.CatalogQuery{Name: "lwip"}, false},
+		{"glob", packmanager.CatalogQuery{Name: "mu*"}, true},
+		{"other glob", packmanager.CatalogQuery{Name: "lw*"}, false},
+		{"name and version", packmanager.CatalogQuery{Name: "musl:stable"}, true},
+		{"name and other version", packmanager.CatalogQuery{Name: "musl:staging"}, false},
+		{"type and name", packmanager.CatalogQuery{Name: "lib/musl"}, true},
+		{"other type and name", packmanager.CatalogQuery{Name: "app/musl"}, false},
+		{"type, name and version", packmanager.CatalogQuery{Name: "lib/musl:stable"}, true},
+		{"version", packmanager.CatalogQuery{Version: "stable"}, true},
+		{"other version", packmanager.CatalogQuery{Version: "0.14.0"}, false},
+		{"types", packmanager.CatalogQuery{Types: []unikraft.ComponentType{unikraft.ComponentTypeLib}}, true},
+		{"other types", packmanager.CatalogQuery{Types: []unikraft.ComponentType{unikraft.ComponentTypeApp}}, false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := matchesQuery(musl, test.query); actual != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGitCachesOf(t *testing.T) {
+	sources := "/tmp/sources"
+	manifest := &Manifest{
+		Origin: "github.com/unikraft/lib-musl.git",
+		Channels: []ManifestChannel{
+			{Name: "stable", Resource: "https://github.com/unikraft/lib-musl/archive/refs/heads/stable.tar.gz"},
+		},
+	}
+
+	dirs := gitCachesOf(sources, []string{"https://mirror.example.com"}, manifest)
+
+	for _, expected := range []string{
+		gitCacheDir(sources, "https://github.com/unikraft/lib-musl.git"),
+		gitCacheDir(sources, "https://mirror.example.com/github.com/unikraft/lib-musl.git"),
+	} {
+		found := false
+		for _, dir := range dirs {
+			if dir == expected {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			t.Errorf("expected %s among %v", expected, dirs)
+		}
+	}
+}
+
+func TestParentsOf(t *testing.T) {
+	sources := "/tmp/sources"
+	dirs := parentsOf(sources, map[string]localResource{
+		"/tmp/sources/libs/musl-stable-0123456789ab.tar.gz": {},
+		"/tmp/sources/unikraft-stable-0123456789ab.tar.gz":  {},
+		"/tmp/elsewhere/musl/musl.tar.gz":                   {},
+	})
+
+	for dir, expected := range map[string]bool{
+		"/tmp/sources/libs":               true,
+		"/tmp/sources/" + gitCacheDirName: true,
+		"/tmp/sources":                    false,
+		"/tmp/sources/apps":               false,
+		"/tmp/elsewhere/musl":             false,
+	} {
+		if dirs[dir] != expected {
+			t.Errorf("expected %s to be known: %t", dir, expected)
+		}
+	}
+}
@@ -0,0 +1,307 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"kraftkit.sh/config"
+	"kraftkit.sh/log"
+	"kraftkit.sh/packmanager"
+	"kraftkit.sh/unikraft"
+)
+
+// Requirement is a component which is required at a version satisfying the
+// constraint, e.g. `^0.12`, `~1.2.3`, `>=0.11 <0.13` or the exact name of a
+// version or channel.
+type Requirement struct {
+	Type       unikraft.ComponentType
+	Name       string
+	Constraint string
+}
+
+// String implements fmt.Stringer
+func (r Requirement) String() string {
+	s := r.Name
+	if r.Type != unikraft.ComponentTypeCore && r.Type != unikraft.ComponentTypeUnknown {
+		s = string(r.Type) + "-" + s
+	}
+
+	if len(r.Constraint) > 0 {
+		s += ":" + r.Constraint
+	}
+
+	return s
+}
+
+// Conflict describes why a requirement could not be satisfied.
+type Conflict struct {
+	Requirement Requirement
+	Reason      string
+}
+
+// ConflictError is returned when the versions of a set of requirements cannot
+// be resolved.  It reports every requirement which could not be satisfied.
+type ConflictError struct {
+	// Unikraft is the version of the Unikraft core against which the conflicts
+	// were determined, if any.
+	Unikraft string
+
+	Conflicts []Conflict
+}
+
+// Error implements error
+func (e *ConflictError) Error() string {
+	var b strings.Builder
+
+	b.WriteString("could not resolve component versions")
+	if len(e.Unikraft) > 0 {
+		b.WriteString(" compatible with unikraft ")
+		b.WriteString(e.Unikraft)
+	}
+	b.WriteString(":")
+
+	for _, conflict := range e.Conflicts {
+		b.WriteString("\n  - ")
+		b.WriteString(conflict.Requirement.String())
+		b.WriteString(": ")
+		b.WriteString(conflict.Reason)
+	}
+
+	return b.String()
+}
+
+// unikraftVersionOf returns the version of the Unikraft core represented by
+// the ManifestVersion of the core, if known.
+func unikraftVersionOf(version ManifestVersion) string {
+	if len(version.Unikraft) > 0 {
+		return version.Unikraft
+	}
+
+	if _, ok := semverOf(version); ok {
+		return version.Version
+	}
+
+	return ""
+}
+
+// describeVersions lists the provided versions for use within a conflict
+// report.  When withUnikraft is set, the version of the core supported by
+// each version is included.
+func describeVersions(versions []ManifestVersion, withUnikraft bool) string {
+	const max = 5
+
+	var described []string
+	for i, version := range versions {
+		if i == max {
+			described = append(described, fmt.Sprintf("and %d more", len(versions)-max))
+			break
+		}
+
+		if withUnikraft {
+			described = append(described, fmt.Sprintf("%s requires unikraft %s", version.Version, version.Unikraft))
+		} else {
+			described = append(described, version.Version)
+		}
+	}
+
+	return strings.Join(described, ", ")
+}
+
+// ResolveRequirements selects a version for each of the requirements from the
+// provided manifests.  The highest version satisfying each constraint is
+// preferred, whilst ensuring that every library supports the selected version
+// of the Unikraft core as recorded by ManifestVersion.Unikraft.  Requirements
+// without a constraint or without a corresponding manifest are left
+// unresolved, i.e. their resolved version is empty.  A *ConflictError is
+// returned if the requirements cannot be satisfied together.
+func ResolveRequirements(manifests []*Manifest, reqs []Requirement) ([]string, error) {
+	resolved := make([]string, len(reqs))
+	candidates := make([][]ManifestVersion, len(reqs))
+	core := -1
+
+	var conflicts []Conflict
+
+	for i, req := range reqs {
+		if len(req.Constraint) == 0 {
+			continue
+		}
+
+		var manifest *Manifest
+		for _, m := range manifests {
+			if m.Type == req.Type && m.Name == req.Name {
+				manifest = m
+				break
+			}
+		}
+
+		if manifest == nil {
+			continue
+		}
+
+		var err error
+		candidates[i], err = manifest.Candidates(req.Constraint)
+		if err != nil {
+			conflicts = append(conflicts, Conflict{req, err.Error()})
+			continue
+		}
+
+		if len(candidates[i]) == 0 {
+			var available []ManifestVersion
+			for _, version := range manifest.Versions {
+				if _, ok := semverOf(version); ok {
+					available = append(available, version)
+				}
+			}
+
+			reason := "no version satisfies the constraint"
+			if len(available) > 0 {
+				reason += " (available: " + describeVersions(available, false) + ")"
+			}
+
+			conflicts = append(conflicts, Conflict{req, reason})
+			continue
+		}
+
+		if req.Type == unikraft.ComponentTypeCore {
+			core = i
+		}
+	}
+
+	if len(conflicts) > 0 {
+		return nil, &ConflictError{Conflicts: conflicts}
+	}
+
+	// Without a constrained core there is nothing to check compatibility against
+	cores := []ManifestVersion{{}}
+	if core >= 0 {
+		cores = candidates[core]
+	}
+
+	var first *ConflictError
+
+	// Try each version of the core, from the highest, until one is supported by
+	// all libraries
+	for _, coreVersion := range cores {
+		uk := unikraftVersionOf(coreVersion)
+		attempt := &ConflictError{Unikraft: uk}
+
+		for i, req := range reqs {
+			if len(candidates[i]) == 0 {
+				continue
+			}
+
+			if i == core {
+				resolved[i] = coreVersion.Version
+				continue
+			}
+
+			resolved[i] = ""
+			for _, candidate := range candidates[i] {
+				if supportsUnikraft(candidate, uk) {
+					resolved[i] = candidate.Version
+					break
+				}
+			}
+
+			if len(resolved[i]) == 0 {
+				attempt.Conflicts = append(attempt.Conflicts, Conflict{
+					Requirement: req,
+					Reason: fmt.Sprintf("no version supports unikraft %s (%s)",
+						uk, describeVersions(candidates[i], true),
+					),
+				})
+			}
+		}
+
+		if len(attempt.Conflicts) == 0 {
+			return resolved, nil
+		}
+
+		if first == nil {
+			first = attempt
+		}
+	}
+
+	return nil, first
+}
+
+// ResolveQueries resolves the version constraints of the provided queries
+// together against the manifest catalog such that each query refers to an
+// exact version.  Queries which do not refer to a single component type, or to
+// a component which is not known to the catalog, are returned unchanged.
+func ResolveQueries(ctx context.Context, queries []packmanager.CatalogQuery) ([]packmanager.CatalogQuery, error) {
+	m := manager{}
+	mopts := []ManifestOption{
+		WithAuthConfig(config.G[config.KraftKit](ctx).Auth),
+		WithMirrors(config.G[config.KraftKit](ctx).Unikraft.Mirrors),
+		WithSourcesRootDir(config.G[config.KraftKit](ctx).Paths.Sources),
+	}
+
+	// Manifests are retrieved once per source
+	cache := map[string][]*Manifest{}
+
+	var manifests []*Manifest
+	reqs := make([]Requirement, len(queries))
+
+	for i, query := range queries {
+		if len(query.Types) != 1 || len(query.Version) == 0 {
+			continue
+		}
+
+		reqs[i] = Requirement{
+			Type:       query.Types[0],
+			Name:       query.Name,
+			Constraint: query.Version,
+		}
+
+		found, ok := cache[query.Source]
+		if !ok {
+			// The index is not updated here since the catalog queries which follow
+			// the resolution already do so when the cache is not used
+			lookup := query
+			lookup.NoCache = false
+
+			var err error
+			found, err = m.manifests(ctx, lookup, mopts...)
+			if err != nil {
+				log.G(ctx).
+					WithField("query", query.String()).
+					Debugf("could not retrieve manifests: %v", err)
+			}
+
+			cache[query.Source] = found
+		}
+
+		// Only consider the manifest from the query's own source
+		for _, manifest := range found {
+			if manifest.Type == reqs[i].Type && manifest.Name == reqs[i].Name {
+				manifests = append(manifests, manifest)
+				break
+			}
+		}
+	}
+
+	resolved, err := ResolveRequirements(manifests, reqs)
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make([]packmanager.CatalogQuery, len(queries))
+	for i, query := range queries {
+		if len(resolved[i]) > 0 && resolved[i] != query.Version {
+			log.G(ctx).
+				WithField("constraint", query.Version).
+				Debugf("resolved %s to %s", reqs[i], resolved[i])
+			query.Version = resolved[i]
+		}
+
+		ret[i] = query
+	}
+
+	return ret, nil
+}
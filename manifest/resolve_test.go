@@ -0,0 +1,140 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package manifest
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"kraftkit.sh/packmanager"
+	"kraftkit.sh/unikraft"
+)
+
+func testManifests() []*Manifest {
+	return []*Manifest{
+		{
+			Type:     unikraft.ComponentTypeCore,
+			Name:     "unikraft",
+			Channels: []ManifestChannel{{Name: "stable", Default: true}},
+			Versions: []ManifestVersion{
+				{Version: "0.11.0", Type: ManifestVersionSemver},
+				{Version: "0.12.0", Type: ManifestVersionSemver},
+				{Version: "0.12.3", Type: ManifestVersionSemver},
+				{Version: "0.13.0", Type: ManifestVersionSemver},
+				{Version: "abcdef1", Type: ManifestVersionGitSha, Unikraft: "0.14.0"},
+			},
+		},
+		{
+			Type: unikraft.ComponentTypeLib,
+			Name: "musl",
+			Versions: []ManifestVersion{
+				{Version: "1.0.0", Type: ManifestVersionSemver, Unikraft: "0.12"},
+				{Version: "1.1.0", Type: ManifestVersionSemver, Unikraft: ">=0.11 <0.13"},
+				{Version: "2.0.0", Type: ManifestVersionSemver, Unikraft: "0.13"},
+			},
+		},
+		{
+			Type: unikraft.ComponentTypeLib,
+			Name: "lwip",
+			Versions: []ManifestVersion{
+				{Version: "0.12.0", Type: ManifestVersionSemver, Unikraft: "0.12"},
+				{Version: "0.13.0", Type: ManifestVersionSemver, Unikraft: "0.13"},
+			},
+		},
+	}
+}
+
+func TestManifestResolveVersion(t *testing.T) {
+	core := testManifests()[0]
+
+	for constraint, expected := range map[string]string{
+		"^0.12":        "0.12.3",
+		"~0.12.0":      "0.12.3",
+		">=0.11 <0.12": "0.11.0",
+		"0.12.0":       "0.12.0",
+		"stable":       "stable",
+		"abcdef1":      "abcdef1",
+	} {
+		version, err := core.ResolveVersion(constraint)
+		if err != nil {
+			t.Errorf("%s: %v", constraint, err)
+		} else if version != expected {
+			t.Errorf("%s: expected %s but got %s", constraint, expected, version)
+		}
+	}
+
+	if _, err := core.ResolveVersion("^1.0"); err == nil {
+		t.Errorf("expected unsatisfiable constraint to fail")
+	}
+
+	// Untyped versions, e.g. branches, never satisfy a constraint
+	core.Versions = append(core.Versions, ManifestVersion{Version: "1.0"})
+	if _, err := core.ResolveVersion("^1.0"); err == nil {
+		t.Errorf("expected untyped version not to satisfy the constraint")
+	}
+}
+
+func TestResolveRequirements(t *testing.T) {
+	// The highest core, 0.13.0, is not supported by any version of lwip below
+	// 0.13 and therefore 0.12.3 is selected
+	resolved, err := ResolveRequirements(testManifests(), []Requirement{
+		{Type: unikraft.ComponentTypeCore, Name: "unikraft", Constraint: ">=0.12"},
+		{Type: unikraft.ComponentTypeLib, Name: "musl", Constraint: "^1"},
+		{Type: unikraft.ComponentTypeLib, Name: "lwip", Constraint: "<0.13"},
+		{Type: unikraft.ComponentTypeLib, Name: "unknown", Constraint: "^1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"0.12.3", "1.1.0", "0.12.0", ""}
+	for i := range expected {
+		if resolved[i] != expected[i] {
+			t.Errorf("expected %v but got %v", expected, resolved)
+			break
+		}
+	}
+}
+
+func TestResolveRequirementsConflict(t *testing.T) {
+	_, err := ResolveRequirements(testManifests(), []Requirement{
+		{Type: unikraft.ComponentTypeCore, Name: "unikraft", Constraint: "^0.13"},
+		{Type: unikraft.ComponentTypeLib, Name: "musl", Constraint: "^1"},
+		{Type: unikraft.ComponentTypeLib, Name: "lwip", Constraint: "^0.13"},
+	})
+
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) {
+		t.Fatalf("expected conflict but got: %v", err)
+	}
+
+	if conflict.Unikraft != "0.13.0" || len(conflict.Conflicts) != 1 || conflict.Conflicts[0].Requirement.Name != "musl" {
+		t.Fatalf("unexpected conflicts: %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "lib-musl:^1: no version supports unikraft 0.13.0") {
+		t.Errorf("unexpected report: %v", err)
+	}
+}
+
+func TestFilterManifests(t *testing.T) {
+	manifests, query := filterManifests(testManifests(), packmanager.CatalogQuery{
+		Name: "lib/mus*:1.1.0",
+	})
+
+	if len(manifests) != 1 || manifests[0].Name != "musl" {
+		t.Fatalf("expected only musl, got %v", manifests)
+	}
+
+	// The complete manifest is returned rather than one reduced to the version
+	if len(manifests[0].Versions) != 3 {
+		t.Errorf("expected all versions of musl, got %d", len(manifests[0].Versions))
+	}
+
+	if query.Version != "1.1.0" {
+		t.Errorf("expected the version to be taken from the name, got %q", query.Version)
+	}
+}
@@ -17,6 +17,7 @@ const (
 	AnnotationKernelKConfig        = "org.unikraft.kernel.kconfig."
 	AnnotationKernelArch           = "org.unikraft.kernel.arch"
 	AnnotationKernelPlat           = "org.unikraft.kernel.plat"
+	AnnotationSizeReportPath       = "org.unikraft.kernel.size-report"
 	AnnotationFilesystemPath       = "org.unikraft.filesystem"
 	AnnotationDiskIndexPathPattern = "org.unikraft.disk-%d"
 	AnnotationKraftKitVersion      = "sh.kraftkit.version"
@@ -133,6 +133,28 @@ func (handle *ContainerdHandler) ListManifests(ctx context.Context) (manifests [
 	return manifests, nil
 }
 
+// FetchDigest implements DigestFetcher.
+func (handle *ContainerdHandler) FetchDigest(ctx context.Context, desc ocispec.Descriptor) (io.ReadCloser, error) {
+	// Reading existing content does not require a lease, only the namespace.
+	ctx = namespaces.WithNamespace(ctx, handle.namespace)
+
+	ra, err := handle.client.ContentStore().ReaderAt(ctx, desc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &readCloser{
+		Reader: content.NewReader(ra),
+		Closer: ra,
+	}, nil
+}
+
+// readCloser combines an io.Reader with the io.Closer of its origin.
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
 // PushDigest implements DigestPusher.
 func (handle *ContainerdHandler) PushDigest(ctx context.Context, ref string, desc ocispec.Descriptor, reader io.Reader, onProgress func(float64)) (err error) {
 	ctx, done, err := handle.lease(ctx)
@@ -539,6 +561,22 @@ func (handle *ContainerdHandler) UnpackImage(ctx context.Context, ref string, de
 	return nil
 }
 
+// DeleteImage implements ImageDeleter.
+func (handle *ContainerdHandler) DeleteImage(ctx context.Context, ref string) (err error) {
+	ctx, done, err := handle.lease(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		err = combineErrors(err, done(ctx))
+	}()
+
+	// Delete synchronously such that the garbage collector has released the
+	// unreferenced content of the image by the time the method returns.
+	return handle.client.ImageService().Delete(ctx, ref, images.SynchronousDelete())
+}
+
 // FinalizeImage implements ImageFinalizer.
 func (handle *ContainerdHandler) FinalizeImage(ctx context.Context, image ocispec.Image) error {
 	return fmt.Errorf("not implemented: oci.handler.ContainerdHandler.FinalizeImage")
@@ -16,6 +16,10 @@ type DigestResolver interface {
 	DigestExists(context.Context, digest.Digest) (bool, error)
 }
 
+type DigestFetcher interface {
+	FetchDigest(context.Context, ocispec.Descriptor) (io.ReadCloser, error)
+}
+
 type DigestPusher interface {
 	PushDigest(context.Context, string, ocispec.Descriptor, io.Reader, func(float64)) error
 }
@@ -40,11 +44,17 @@ type ImageUnpacker interface {
 	UnpackImage(context.Context, string, string) error
 }
 
+type ImageDeleter interface {
+	DeleteImage(context.Context, string) error
+}
+
 type Handler interface {
 	DigestResolver
+	DigestFetcher
 	DigestPusher
 	ManifestLister
 	ImageResolver
 	ImageFetcher
 	ImageUnpacker
+	ImageDeleter
 }
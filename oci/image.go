@@ -78,6 +78,18 @@ func NewImageFromManifestSpec(ctx context.Context, handle handler.Handler, manif
 
 	image.manifest = manifest
 
+	// The descriptor of the manifest identifies the image as the subject of its
+	// referrers.
+	manifestJson, err := json.Marshal(manifest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	image.manifestDesc = content.NewDescriptorFromBytes(
+		ocispec.MediaTypeImageManifest,
+		manifestJson,
+	)
+
 	// TODO(nderjung): This method could better populate the Image structure based
 	// on parsing the of the manifest itself and probing for any embedded
 	// configuration.
@@ -346,3 +358,74 @@ func (image *Image) Save(ctx context.Context, source string, onProgress func(flo
 
 	return image.manifestDesc, nil
 }
+
+// AddReferrer attaches the blob to the saved image as an artifact of the
+// provided type.  The artifact has its own manifest, saved under the provided
+// reference, which refers to the image as its subject such that the image
+// itself, including its root filesystem and digest, is left untouched.
+func (image *Image) AddReferrer(ctx context.Context, ref, artifactType string, blob *Blob) (ocispec.Descriptor, error) {
+	if image.manifestDesc.Digest == "" {
+		return ocispec.Descriptor{}, fmt.Errorf("cannot add referrer to unsaved image")
+	}
+
+	if _, err := image.AddBlob(ctx, blob); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	// Artifacts without a configuration of their own use the scratch config.
+	configBlob, err := NewBlob(ctx, ocispec.MediaTypeScratch, []byte("{}"))
+	if err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	if _, err := image.AddBlob(ctx, configBlob); err != nil {
+		return ocispec.Descriptor{}, err
+	}
+
+	manifest := ocispec.Manifest{
+		Versioned: specs.Versioned{
+			SchemaVersion: 2,
+		},
+		MediaType:    ocispec.MediaTypeImageManifest,
+		ArtifactType: artifactType,
+		Config:       configBlob.desc,
+		Layers:       []ocispec.Descriptor{blob.desc},
+		Subject: &ocispec.Descriptor{
+			MediaType: image.manifestDesc.MediaType,
+			Digest:    image.manifestDesc.Digest,
+			Size:      image.manifestDesc.Size,
+		},
+		Annotations: map[string]string{
+			ocispec.AnnotationCreated: time.Now().UTC().Format(time.RFC3339),
+		},
+	}
+
+	manifestJson, err := json.Marshal(manifest)
+	if err != nil {
+		return ocispec.Descriptor{}, fmt.Errorf("failed to marshal manifest: %w", err)
+	}
+
+	desc := content.NewDescriptorFromBytes(
+		ocispec.MediaTypeImageManifest,
+		manifestJson,
+	)
+	desc.ArtifactType = artifactType
+
+	log.G(ctx).WithFields(logrus.Fields{
+		"ref":          ref,
+		"artifactType": artifactType,
+		"subject":      image.manifestDesc.Digest.String(),
+	}).Trace("oci: saving referrer")
+
+	if err := image.handle.PushDigest(
+		ctx,
+		ref,
+		desc,
+		bytes.NewReader(manifestJson),
+		nil,
+	); err != nil && !errors.Is(err, errdefs.ErrAlreadyExists) {
+		return ocispec.Descriptor{}, fmt.Errorf("failed to push referrer manifest: %w", err)
+	}
+
+	return desc, nil
+}
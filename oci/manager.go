@@ -79,7 +79,7 @@ func (manager ociManager) handle(ctx context.Context) (context.Context, handler.
 }
 
 // Update implements packmanager.PackageManager
-func (manager ociManager) Update(ctx context.Context) error {
+func (manager ociManager) Update(ctx context.Context, opts ...packmanager.UpdateOption) error {
 	return nil
 }
 
@@ -177,7 +177,9 @@ func (manager ociManager) Catalog(ctx context.Context, query packmanager.Catalog
 		return nil, err
 	}
 
-	if query.NoCache {
+	if query.NoCache && config.Offline(ctx) {
+		return nil, config.OfflineError("the catalog of the remote registries")
+	} else if query.NoCache {
 		// If a direct reference can be made, attempt to generate a package from it
 		if refErr == nil {
 			pack, err := NewPackageFromRemoteOCIRef(ctx, handle, ref.String())
@@ -388,6 +390,11 @@ func (manager ociManager) IsCompatible(ctx context.Context, source string) (pack
 
 	log.G(ctx).Debugf("could not resolve local image: %v", err)
 
+	// Every remaining check queries a remote registry
+	if config.Offline(ctx) {
+		return nil, false, config.OfflineError(source)
+	}
+
 	// 2. Check if the source is a remote registry
 
 	if uri, err := url.Parse(source); err == nil && uri.Host == source {
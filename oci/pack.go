@@ -5,14 +5,19 @@
 package oci
 
 import (
+	"archive/tar"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/opencontainers/go-digest"
 	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/sirupsen/logrus"
 
@@ -24,6 +29,8 @@ import (
 	"kraftkit.sh/oci/handler"
 	"kraftkit.sh/pack"
 	"kraftkit.sh/packmanager"
+	"kraftkit.sh/sbom"
+	"kraftkit.sh/sizereport"
 	"kraftkit.sh/unikraft"
 	"kraftkit.sh/unikraft/arch"
 	"kraftkit.sh/unikraft/plat"
@@ -47,8 +54,10 @@ type ociPackage struct {
 }
 
 var (
-	_ pack.Package  = (*ociPackage)(nil)
-	_ target.Target = (*ociPackage)(nil)
+	_ pack.Package        = (*ociPackage)(nil)
+	_ target.Target       = (*ociPackage)(nil)
+	_ sbom.Provider       = (*ociPackage)(nil)
+	_ sizereport.Provider = (*ociPackage)(nil)
 )
 
 // NewPackageFromTarget generates an OCI implementation of the pack.Package
@@ -184,6 +193,29 @@ func NewPackageFromTarget(ctx context.Context, targ target.Target, opts ...packm
 		}
 	}
 
+	if popts.SizeReport() != "" {
+		log.G(ctx).WithFields(logrus.Fields{
+			"src":  popts.SizeReport(),
+			"dest": WellKnownSizeReportPath,
+		}).Debug("oci: including size report")
+
+		layer, err := NewLayerFromFile(ctx,
+			ocispec.MediaTypeImageLayer,
+			popts.SizeReport(),
+			WellKnownSizeReportPath,
+			WithLayerAnnotation(AnnotationSizeReportPath, WellKnownSizeReportPath),
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := image.AddLayer(ctx, layer); err != nil {
+			return nil, err
+		}
+
+		image.SetAnnotation(ctx, AnnotationSizeReportPath, WellKnownSizeReportPath)
+	}
+
 	// TODO(nderjung): See below.
 
 	// if popts.PackKernelLibraryObjects() {
@@ -222,6 +254,7 @@ func NewPackageFromTarget(ctx context.Context, targ target.Target, opts ...packm
 			}
 
 			image.SetOSFeature(ctx, k.String())
+			image.SetAnnotation(ctx, AnnotationKernelKConfig+k.Key, k.Value)
 		}
 	}
 
@@ -233,11 +266,39 @@ func NewPackageFromTarget(ctx context.Context, targ target.Target, opts ...packm
 		"tag": ocipack.Name(),
 	}).Debug("oci: saving image")
 
-	_, err = image.Save(ctx, ocipack.imageRef(), nil)
+	desc, err := image.Save(ctx, ocipack.imageRef(), nil)
 	if err != nil {
 		return nil, err
 	}
 
+	if popts.SBOM() != "" {
+		data, err := os.ReadFile(popts.SBOM())
+		if err != nil {
+			return nil, fmt.Errorf("could not read sbom: %v", err)
+		}
+
+		format, err := sbom.DetectFormat(data)
+		if err != nil {
+			return nil, err
+		}
+
+		ref := sbomRef(ocipack.Name(), desc.Digest)
+
+		log.G(ctx).WithFields(logrus.Fields{
+			"ref":    ref,
+			"format": format,
+		}).Debug("oci: attaching sbom")
+
+		blob, err := NewBlobFromFile(ctx, format.MediaType(), popts.SBOM())
+		if err != nil {
+			return nil, err
+		}
+
+		if _, err := image.AddReferrer(ctx, ref, format.MediaType(), blob); err != nil {
+			return nil, fmt.Errorf("could not attach sbom: %v", err)
+		}
+	}
+
 	ocipack.image = image
 
 	return &ocipack, nil
@@ -283,6 +344,7 @@ func NewPackageFromOCIManifestSpec(ctx context.Context, handle handler.Handler,
 	}
 
 	ocipack.plat = platform.(plat.Platform)
+	ocipack.kconfig = kconfigFromAnnotations(manifest.Annotations)
 
 	return &ocipack, nil
 }
@@ -303,6 +365,10 @@ func NewPackageFromRemoteOCIRef(ctx context.Context, handle handler.Handler, ref
 		return nil, fmt.Errorf("cannot parse OCI image name reference: %v", err)
 	}
 
+	if config.Offline(ctx) {
+		return nil, config.OfflineError(ocipack.ref.String())
+	}
+
 	raw, err := crane.Manifest(ref)
 	if err != nil {
 		return nil, fmt.Errorf("could not get manifest: %v", err)
@@ -353,9 +419,25 @@ func NewPackageFromRemoteOCIRef(ctx context.Context, handle handler.Handler, ref
 		return nil, fmt.Errorf("could not convert platform string")
 	}
 
+	ocipack.kconfig = kconfigFromAnnotations(manifest.Annotations)
+
 	return &ocipack, nil
 }
 
+// kconfigFromAnnotations returns the KConfig options which the package was
+// built with from the annotations of its manifest.
+func kconfigFromAnnotations(annotations map[string]string) kconfig.KeyValueMap {
+	values := kconfig.KeyValueMap{}
+
+	for key, value := range annotations {
+		if strings.HasPrefix(key, AnnotationKernelKConfig) {
+			values.Set(strings.TrimPrefix(key, AnnotationKernelKConfig), value)
+		}
+	}
+
+	return values
+}
+
 // Type implements unikraft.Nameable
 func (ocipack *ociPackage) Type() unikraft.ComponentType {
 	return unikraft.ComponentTypeApp
@@ -400,6 +482,10 @@ func (ocipack *ociPackage) Pull(ctx context.Context, opts ...pack.PullOption) er
 		goto unpack
 	}
 
+	if config.Offline(ctx) {
+		return config.OfflineError(ocipack.imageRef())
+	}
+
 	if err := ocipack.image.handle.FetchImage(
 		ctx,
 		ocipack.imageRef(),
@@ -426,6 +512,99 @@ unpack:
 	return nil
 }
 
+// sbomRef returns the reference under which the SBOM of the image with the
+// provided manifest digest is saved, following the `<digest>.sbom` tag
+// convention of cosign.
+func sbomRef(name string, dgst digest.Digest) string {
+	return fmt.Sprintf("%s:%s-%s.sbom", name, dgst.Algorithm(), dgst.Encoded())
+}
+
+// isSBOMMediaType returns whether the media type is that of an SBOM document.
+func isSBOMMediaType(mediaType string) bool {
+	return mediaType == sbom.FormatSPDX.MediaType() ||
+		mediaType == sbom.FormatCycloneDX.MediaType()
+}
+
+// SBOM implements sbom.Provider
+func (ocipack *ociPackage) SBOM(ctx context.Context) ([]byte, error) {
+	if ocipack.image == nil {
+		return nil, fmt.Errorf("package has no image")
+	}
+
+	manifests, err := ocipack.handle.ListManifests(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, manifest := range manifests {
+		if manifest.Subject == nil ||
+			manifest.Subject.Digest != ocipack.image.manifestDesc.Digest ||
+			!isSBOMMediaType(manifest.ArtifactType) ||
+			len(manifest.Layers) != 1 {
+			continue
+		}
+
+		reader, err := ocipack.handle.FetchDigest(ctx, manifest.Layers[0])
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch sbom: %v", err)
+		}
+
+		defer reader.Close()
+
+		return io.ReadAll(reader)
+	}
+
+	return nil, fmt.Errorf("package %s has no sbom attached", ocipack.imageRef())
+}
+
+// SizeReport implements sizereport.Provider
+func (ocipack *ociPackage) SizeReport(ctx context.Context) ([]byte, error) {
+	return ocipack.readLayerFile(ctx, AnnotationSizeReportPath, "size report")
+}
+
+// readLayerFile returns the contents of the file which is attached to the
+// package in the layer with the provided annotation, whose value is the path
+// of the file within the layer.
+func (ocipack *ociPackage) readLayerFile(ctx context.Context, annotation, what string) ([]byte, error) {
+	if ocipack.image == nil {
+		return nil, fmt.Errorf("package has no image")
+	}
+
+	for _, desc := range ocipack.image.manifest.Layers {
+		dest, ok := desc.Annotations[annotation]
+		if !ok {
+			continue
+		}
+
+		reader, err := ocipack.handle.FetchDigest(ctx, desc)
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch %s layer (has the package been pulled?): %v", what, err)
+		}
+
+		defer reader.Close()
+
+		tr := tar.NewReader(reader)
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("could not read %s layer: %v", what, err)
+			}
+
+			if hdr.Typeflag != tar.TypeReg || "/"+filepath.Clean(hdr.Name) != dest {
+				continue
+			}
+
+			return io.ReadAll(tr)
+		}
+
+		return nil, fmt.Errorf("%s layer does not contain %s", what, dest)
+	}
+
+	return nil, fmt.Errorf("package %s has no %s attached", ocipack.imageRef(), what)
+}
+
 // Pull implements pack.Package
 func (ocipack *ociPackage) Format() pack.PackageFormat {
 	return OCIFormat
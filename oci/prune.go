@@ -0,0 +1,160 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/containerd/containerd/errdefs"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/opencontainers/go-digest"
+	ocispec "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sirupsen/logrus"
+
+	"kraftkit.sh/log"
+	"kraftkit.sh/packmanager"
+)
+
+// normalizeRef returns the fully qualified repository and tag of the provided
+// reference and whether the reference explicitly contained a tag.
+func normalizeRef(ref string) (string, string, bool) {
+	parsed, err := name.ParseReference(ref,
+		name.WithDefaultRegistry(defaultRegistry),
+	)
+	if err != nil {
+		return ref, "", false
+	}
+
+	tagged := strings.ContainsAny(path.Base(ref), ":@")
+
+	return parsed.Context().String(), parsed.Identifier(), tagged
+}
+
+// matchesRef returns whether the image identified by its repository and tag is
+// matched by the provided reference.  A reference without a tag matches all
+// tags of the repository.
+func matchesRef(repo, tag, ref string) bool {
+	refRepo, refTag, tagged := normalizeRef(ref)
+	if refRepo != repo {
+		return false
+	}
+
+	return !tagged || refTag == tag
+}
+
+// Prune implements packmanager.PackageManager.  Only unikernel images, i.e.
+// those which have been created or pulled by KraftKit, are considered.
+func (manager ociManager) Prune(ctx context.Context, opts ...packmanager.PruneOption) ([]packmanager.Reclaimed, error) {
+	popts := packmanager.NewPruneOptions(opts...)
+
+	// Dangling content is garbage collected by containerd itself, so there is
+	// only something to do when removing entire images.
+	if !popts.All() && popts.Query() == nil {
+		return nil, nil
+	}
+
+	ctx, handle, err := manager.handle(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	manifests, err := handle.ListManifests(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var reclaimed []packmanager.Reclaimed
+
+	for _, manifest := range manifests {
+		if _, ok := manifest.Annotations[AnnotationKernelVersion]; !ok {
+			continue
+		}
+
+		refname, ok := manifest.Annotations[ocispec.AnnotationRefName]
+		if !ok {
+			continue
+		}
+
+		revision, ok := manifest.Annotations[ocispec.AnnotationRevision]
+		if !ok {
+			continue
+		}
+
+		fullref := fmt.Sprintf("%s:%s", refname, revision)
+		repo, tag, _ := normalizeRef(fullref)
+
+		if query := popts.Query(); query != nil {
+			queryRef := query.Name
+			if len(query.Version) > 0 {
+				queryRef += ":" + query.Version
+			}
+
+			if !matchesRef(repo, tag, queryRef) {
+				continue
+			}
+		}
+
+		referenced := false
+		for _, keep := range popts.Keep() {
+			if matchesRef(repo, tag, keep) {
+				referenced = true
+				break
+			}
+		}
+		if referenced {
+			log.G(ctx).WithField("ref", fullref).Debug("skipping referenced image")
+			continue
+		}
+
+		// Images without a known creation date are never old enough
+		if popts.OlderThan() > 0 {
+			created, err := time.Parse(time.RFC3339, manifest.Annotations[ocispec.AnnotationCreated])
+			if err != nil || !popts.IsOldEnough(created) {
+				continue
+			}
+		}
+
+		size := manifest.Config.Size
+		for _, layer := range manifest.Layers {
+			size += layer.Size
+		}
+
+		log.G(ctx).WithFields(logrus.Fields{
+			"ref":    fullref,
+			"dryrun": popts.DryRun(),
+		}).Debug("pruning")
+
+		if !popts.DryRun() {
+			if err := handle.DeleteImage(ctx, fullref); err != nil {
+				return nil, fmt.Errorf("could not delete image %s: %v", fullref, err)
+			}
+
+			// The SBOM attached to the image would otherwise be left behind
+			raw, err := json.Marshal(manifest)
+			if err != nil {
+				return nil, err
+			}
+
+			ref := sbomRef(refname, digest.FromBytes(raw))
+			if err := handle.DeleteImage(ctx, ref); err != nil && !errors.Is(err, errdefs.ErrNotFound) {
+				return nil, fmt.Errorf("could not delete sbom %s: %v", ref, err)
+			}
+		}
+
+		reclaimed = append(reclaimed, packmanager.Reclaimed{
+			Format: OCIFormat,
+			Name:   fullref,
+			Size:   size,
+		})
+	}
+
+	return reclaimed, nil
+}
@@ -0,0 +1,60 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package oci
+
+import "testing"
+
+func TestNormalizeRef(t *testing.T) {
+	tests := []struct {
+		ref    string
+		repo   string
+		tag    string
+		tagged bool
+	}{
+		{"nginx", "unikraft.org/nginx", "latest", false},
+		{"nginx:1.25", "unikraft.org/nginx", "1.25", true},
+		{"unikraft.org/nginx:1.25", "unikraft.org/nginx", "1.25", true},
+		{"localhost:5000/nginx", "localhost:5000/nginx", "latest", false},
+		{"localhost:5000/nginx:1.25", "localhost:5000/nginx", "1.25", true},
+		{"ghcr.io/org/app:v1", "ghcr.io/org/app", "v1", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.ref, func(t *testing.T) {
+			repo, tag, tagged := normalizeRef(test.ref)
+			if repo != test.repo || tag != test.tag || tagged != test.tagged {
+				t.Errorf("expected (%s, %s, %t), got (%s, %s, %t)",
+					test.repo, test.tag, test.tagged, repo, tag, tagged,
+				)
+			}
+		})
+	}
+}
+
+func TestMatchesRef(t *testing.T) {
+	tests := []struct {
+		name     string
+		repo     string
+		tag      string
+		ref      string
+		expected bool
+	}{
+		{"untagged", "unikraft.org/nginx", "1.25", "nginx", true},
+		{"tagged", "unikraft.org/nginx", "1.25", "nginx:1.25", true},
+		{"other tag", "unikraft.org/nginx", "1.25", "nginx:1.24", false},
+		{"qualified", "unikraft.org/nginx", "1.25", "unikraft.org/nginx", true},
+		{"other repository", "unikraft.org/nginx", "1.25", "redis", false},
+		{"other registry", "unikraft.org/nginx", "1.25", "ghcr.io/nginx", false},
+		{"registry port", "localhost:5000/nginx", "latest", "localhost:5000/nginx", true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if actual := matchesRef(test.repo, test.tag, test.ref); actual != test.expected {
+				t.Errorf("expected %t, got %t", test.expected, actual)
+			}
+		})
+	}
+}
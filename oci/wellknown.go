@@ -8,6 +8,7 @@ const (
 	WellKnownKernelPath      = "/unikraft/bin/kernel"
 	WellKnownInitrdPath      = "/unikraft/bin/initrd"
 	WellKnownConfigPath      = "/unikraft/bin/config"
+	WellKnownSizeReportPath  = "/unikraft/bin/kernel.size.json"
 	WellKnownKernelSourceDir = "/unikraft/src"
 	WellKnownAppSourceDir    = "/unikraft/app"
 )
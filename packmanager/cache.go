@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package packmanager
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// cacheLockPoll is the interval at which a held cache lock is re-attempted.
+	cacheLockPoll = 100 * time.Millisecond
+
+	// cacheLockRefresh is the interval at which a held cache lock is touched to
+	// signal that its holder is still alive.
+	cacheLockRefresh = 10 * time.Second
+
+	// cacheLockStale is the age after which a cache lock which has not been
+	// touched is considered to have been abandoned, e.g. by a process which was
+	// killed.
+	cacheLockStale = 6 * cacheLockRefresh
+)
+
+// CacheKey returns the key under which the resource of a component from the
+// provided origin at the provided version is cached.  Identical origins and
+// versions, for example the same library used by different projects, share
+// the same key whereas identically named components from different origins
+// do not.
+func CacheKey(origin, version string) string {
+	origin = strings.TrimSuffix(strings.TrimSuffix(origin, "/"), ".git")
+	sum := sha256.Sum256([]byte(origin + "@" + version))
+	return hex.EncodeToString(sum[:])
+}
+
+// LockCache acquires an exclusive lock on the cache entry at the provided path
+// which is shared between processes, such that concurrent invocations of
+// KraftKit do not populate the same entry at once.  The lock is kept fresh
+// for as long as it is held, however long populating the entry takes, and is
+// only taken over once its holder has stopped refreshing it.  The returned
+// function releases the lock.
+func LockCache(ctx context.Context, path string) (func(), error) {
+	lock := path + ".lock"
+
+	if err := os.MkdirAll(filepath.Dir(lock), 0o755); err != nil {
+		return nil, fmt.Errorf("could not create cache directory: %v", err)
+	}
+
+	for {
+		f, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			fmt.Fprintf(f, "%d\n", os.Getpid())
+			f.Close()
+
+			done := make(chan struct{})
+			stopped := make(chan struct{})
+
+			go func() {
+				defer close(stopped)
+
+				ticker := time.NewTicker(cacheLockRefresh)
+				defer ticker.Stop()
+
+				for {
+					select {
+					case <-done:
+						return
+					case now := <-ticker.C:
+						_ = os.Chtimes(lock, now, now)
+					}
+				}
+			}()
+
+			return func() {
+				close(done)
+				<-stopped
+				_ = os.Remove(lock)
+			}, nil
+		}
+
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("could not lock %s: %v", path, err)
+		}
+
+		if fi, err := os.Stat(lock); err == nil && time.Since(fi.ModTime()) > cacheLockStale {
+			_ = os.Remove(lock)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(cacheLockPoll):
+		}
+	}
+}
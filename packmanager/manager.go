@@ -20,7 +20,7 @@ type NewManagerConstructor func(context.Context) (PackageManager, error)
 
 type PackageManager interface {
 	// Update retrieves and stores locally a cache of the upstream registry.
-	Update(context.Context) error
+	Update(context.Context, ...UpdateOption) error
 
 	// Pack turns the provided component into the distributable package.  Since
 	// components can comprise of other components, it is possible to return more
@@ -36,6 +36,10 @@ type PackageManager interface {
 	// Catalog returns all packages known to the manager via given query
 	Catalog(context.Context, CatalogQuery) ([]pack.Package, error)
 
+	// Prune removes packages and data from the local package store and returns
+	// the list of reclaimed entries.
+	Prune(context.Context, ...PruneOption) ([]Reclaimed, error)
+
 	// Add a source to the package manager
 	AddSource(context.Context, string) error
 
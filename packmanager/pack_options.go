@@ -16,6 +16,8 @@ type PackOptions struct {
 	kernelSourceFiles                bool
 	kernelVersion                    string
 	output                           string
+	sbom                             string
+	sizeReport                       string
 }
 
 // PackAppSourceFiles returns whether the application source files should be
@@ -65,6 +67,18 @@ func (popts *PackOptions) Output() string {
 	return popts.output
 }
 
+// SBOM returns the path of the SBOM document that should be attached to the
+// package.
+func (popts *PackOptions) SBOM() string {
+	return popts.sbom
+}
+
+// SizeReport returns the path of the size report of the kernel that should be
+// attached to the package.
+func (popts *PackOptions) SizeReport() string {
+	return popts.sizeReport
+}
+
 // PackOption is an option function which is used to modify PackOptions.
 type PackOption func(*PackOptions)
 
@@ -133,3 +147,18 @@ func PackOutput(output string) PackOption {
 		popts.output = output
 	}
 }
+
+// PackSBOM attaches the SBOM document at the provided path to the package.
+func PackSBOM(sbom string) PackOption {
+	return func(popts *PackOptions) {
+		popts.sbom = sbom
+	}
+}
+
+// PackSizeReport attaches the size report of the kernel at the provided path
+// to the package.
+func PackSizeReport(sizeReport string) PackOption {
+	return func(popts *PackOptions) {
+		popts.sizeReport = sizeReport
+	}
+}
@@ -0,0 +1,144 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package packmanager
+
+import (
+	"time"
+
+	"kraftkit.sh/pack"
+	"kraftkit.sh/unikraft"
+)
+
+// Reclaimed represents an entry which has been (or, in the case of a dry-run,
+// would be) removed from the local package store.
+type Reclaimed struct {
+	// Format is the package manager which owned the entry.
+	Format pack.PackageFormat
+
+	// Name is a human-readable representation of the entry, e.g. the canonical
+	// name of the package or a path.
+	Name string
+
+	// Size is the number of bytes freed by removing the entry.
+	Size int64
+}
+
+// PruneOptions contains the list of options which can be set when removing
+// packages and data from the local package store.
+type PruneOptions struct {
+	all       bool
+	dryRun    bool
+	olderThan time.Duration
+	query     *CatalogQuery
+	keep      []string
+}
+
+// All returns whether all unreferenced packages should be removed as opposed
+// to only partial downloads.
+func (popts *PruneOptions) All() bool {
+	return popts.all
+}
+
+// DryRun returns whether the removal should only be reported and not
+// performed.
+func (popts *PruneOptions) DryRun() bool {
+	return popts.dryRun
+}
+
+// OlderThan returns the minimum age of an entry for it to be removed.
+func (popts *PruneOptions) OlderThan() time.Duration {
+	return popts.olderThan
+}
+
+// Query returns the query which restricts the removal to matching packages, or
+// nil if the removal is unrestricted.
+func (popts *PruneOptions) Query() *CatalogQuery {
+	return popts.query
+}
+
+// Keep returns the list of references which must not be removed.
+func (popts *PruneOptions) Keep() []string {
+	return popts.keep
+}
+
+// IsOldEnough returns whether an entry last modified at the provided time
+// satisfies the minimum age requirement.
+func (popts *PruneOptions) IsOldEnough(modTime time.Time) bool {
+	if popts.olderThan <= 0 {
+		return true
+	}
+
+	return time.Since(modTime) >= popts.olderThan
+}
+
+// IsReferenced returns whether the provided entity is referenced by any of the
+// references which must be kept.  A reference without a version keeps all
+// versions of the named entity.
+func (popts *PruneOptions) IsReferenced(entity unikraft.Nameable) bool {
+	candidates := map[string]bool{
+		unikraft.TypeNameVersion(entity):            true,
+		string(entity.Type()) + "/" + entity.Name(): true,
+		entity.Name() + ":" + entity.Version():      true,
+		entity.Name():                               true,
+	}
+
+	for _, ref := range popts.keep {
+		if candidates[ref] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// PruneOption is an option function which is used to modify PruneOptions.
+type PruneOption func(*PruneOptions)
+
+// NewPruneOptions creates PruneOptions from the provided options.
+func NewPruneOptions(opts ...PruneOption) *PruneOptions {
+	popts := &PruneOptions{}
+	for _, opt := range opts {
+		opt(popts)
+	}
+
+	return popts
+}
+
+// PruneAll marks that all unreferenced packages should be removed.
+func PruneAll(all bool) PruneOption {
+	return func(popts *PruneOptions) {
+		popts.all = all
+	}
+}
+
+// PruneDryRun marks that entries should only be reported and not removed.
+func PruneDryRun(dryRun bool) PruneOption {
+	return func(popts *PruneOptions) {
+		popts.dryRun = dryRun
+	}
+}
+
+// PruneOlderThan only removes entries which have not been modified within the
+// provided duration.
+func PruneOlderThan(olderThan time.Duration) PruneOption {
+	return func(popts *PruneOptions) {
+		popts.olderThan = olderThan
+	}
+}
+
+// PruneQuery restricts the removal to packages matching the provided query.
+func PruneQuery(query CatalogQuery) PruneOption {
+	return func(popts *PruneOptions) {
+		popts.query = &query
+	}
+}
+
+// PruneKeep adds references, in the format `[TYPE/]NAME[:VERSION]`, which must
+// not be removed.
+func PruneKeep(refs ...string) PruneOption {
+	return func(popts *PruneOptions) {
+		popts.keep = append(popts.keep, refs...)
+	}
+}
@@ -0,0 +1,258 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package packmanager
+
+import (
+	"context"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/semaphore"
+
+	"kraftkit.sh/config"
+)
+
+const (
+	// DefaultMaxPerHost is the default number of fetches which are performed
+	// concurrently against the same host.
+	DefaultMaxPerHost = 4
+)
+
+// Scheduler bounds the number of fetches which are performed concurrently,
+// both in total and against any single host, and deduplicates concurrent
+// fetches of the same resource such that, for example, pulling the components
+// of many projects at once does not download the same library more than once.
+//
+// Every fetch performed by the pull of a component goes through the scheduler
+// of its context, such that commands which pull in parallel need not bound
+// their pulls themselves.  The process-wide scheduler is sized by the `fetch`
+// section of the configuration and performs a single fetch at a time when
+// parallelism is disabled with `--no-parallel`.
+type Scheduler struct {
+	workers *semaphore.Weighted
+	perHost int64
+	hosts   map[string]*semaphore.Weighted
+	calls   map[string]*call
+	mu      sync.Mutex
+}
+
+// call is a fetch which is shared by every caller of Do with the same key.
+type call struct {
+	done    chan struct{}
+	err     error
+	cancel  context.CancelFunc
+	waiters int
+}
+
+// detachedContext carries the values of its parent, e.g. the logger and the
+// configuration, but neither its deadline nor its cancellation.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// SchedulerOption is an option which modifies the behaviour of the Scheduler.
+type SchedulerOption func(*Scheduler)
+
+// WithMaxWorkers sets the total number of fetches which are performed
+// concurrently.  A non-positive number results in the default, which is the
+// number of CPUs.
+func WithMaxWorkers(n int) SchedulerOption {
+	return func(s *Scheduler) {
+		if n <= 0 {
+			n = runtime.NumCPU()
+		}
+
+		s.workers = semaphore.NewWeighted(int64(n))
+	}
+}
+
+// WithMaxPerHost sets the number of fetches which are performed concurrently
+// against the same host.  A non-positive number results in the default.
+func WithMaxPerHost(n int) SchedulerOption {
+	return func(s *Scheduler) {
+		if n <= 0 {
+			n = DefaultMaxPerHost
+		}
+
+		s.perHost = int64(n)
+	}
+}
+
+// NewScheduler returns a Scheduler with the provided options applied.
+func NewScheduler(opts ...SchedulerOption) *Scheduler {
+	s := &Scheduler{
+		workers: semaphore.NewWeighted(int64(runtime.NumCPU())),
+		perHost: DefaultMaxPerHost,
+		hosts:   map[string]*semaphore.Weighted{},
+		calls:   map[string]*call{},
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// host returns the semaphore limiting fetches against the provided host.
+func (s *Scheduler) host(host string) *semaphore.Weighted {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sem, ok := s.hosts[host]
+	if !ok {
+		sem = semaphore.NewWeighted(s.perHost)
+		s.hosts[host] = sem
+	}
+
+	return sem
+}
+
+// Do performs the fetch fn of the resource at the provided location once both
+// a worker and a slot for the host of the location are available.  Concurrent
+// calls with the same non-empty key are deduplicated: fn is only invoked once
+// and every caller receives its result.  The key should therefore identify
+// the result of the fetch, e.g. its destination, rather than the caller.
+//
+// A deduplicated fetch is detached from the context of the caller which
+// started it.  Each caller instead stops waiting as soon as its own context is
+// cancelled, and the fetch itself is only cancelled once every caller has.
+func (s *Scheduler) Do(ctx context.Context, location, key string, fn func(context.Context) error) error {
+	if len(key) == 0 {
+		return s.run(ctx, location, fn)
+	}
+
+	s.mu.Lock()
+	c, ok := s.calls[key]
+	if !ok {
+		var callCtx context.Context
+		c = &call{done: make(chan struct{})}
+		callCtx, c.cancel = context.WithCancel(detachedContext{ctx})
+		s.calls[key] = c
+
+		go func() {
+			c.err = s.run(callCtx, location, fn)
+
+			s.mu.Lock()
+			if s.calls[key] == c {
+				delete(s.calls, key)
+			}
+			s.mu.Unlock()
+
+			c.cancel()
+			close(c.done)
+		}()
+	}
+
+	c.waiters++
+	s.mu.Unlock()
+
+	select {
+	case <-c.done:
+		return c.err
+
+	case <-ctx.Done():
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		// Callers which arrive after the fetch has been abandoned start anew
+		c.waiters--
+		if c.waiters == 0 {
+			c.cancel()
+			if s.calls[key] == c {
+				delete(s.calls, key)
+			}
+		}
+
+		return ctx.Err()
+	}
+}
+
+// run performs fn once both a worker and a slot for the host of the location
+// are available.
+func (s *Scheduler) run(ctx context.Context, location string, fn func(context.Context) error) error {
+	hsem := s.host(HostOf(location))
+	if err := hsem.Acquire(ctx, 1); err != nil {
+		return err
+	}
+
+	defer hsem.Release(1)
+
+	if err := s.workers.Acquire(ctx, 1); err != nil {
+		return err
+	}
+
+	defer s.workers.Release(1)
+
+	return fn(ctx)
+}
+
+// HostOf returns the host of the provided location, which may be a URL, an
+// SCP-like SSH location, e.g. git@github.com:unikraft/unikraft.git, or a host
+// and path without a scheme.  Local paths have an empty host.
+func HostOf(location string) string {
+	if u, err := url.Parse(location); err == nil && len(u.Scheme) > 1 {
+		return u.Host
+	}
+
+	if at := strings.Index(location, "@"); at >= 0 {
+		location = location[at+1:]
+		if colon := strings.Index(location, ":"); colon >= 0 {
+			return location[:colon]
+		}
+	}
+
+	if strings.HasPrefix(location, "/") || strings.HasPrefix(location, ".") {
+		return ""
+	}
+
+	host, _, _ := strings.Cut(location, "/")
+	return host
+}
+
+// schedulerContextKey is used to retrieve the scheduler from the context.
+type schedulerContextKey struct{}
+
+var (
+	defaultScheduler     *Scheduler
+	defaultSchedulerOnce sync.Once
+)
+
+// WithScheduler returns a new context with the provided scheduler.
+func WithScheduler(ctx context.Context, s *Scheduler) context.Context {
+	return context.WithValue(ctx, schedulerContextKey{}, s)
+}
+
+// SchedulerFromContext returns the scheduler in the context, or the process
+// wide scheduler which is configured by the KraftKit configuration in the
+// context at the time of its first use.
+func SchedulerFromContext(ctx context.Context) *Scheduler {
+	if s, ok := ctx.Value(schedulerContextKey{}).(*Scheduler); ok && s != nil {
+		return s
+	}
+
+	defaultSchedulerOnce.Do(func() {
+		cfg := config.G[config.KraftKit](ctx)
+
+		workers := cfg.Fetch.Workers
+		if cfg.NoParallel {
+			workers = 1
+		}
+
+		defaultScheduler = NewScheduler(
+			WithMaxWorkers(workers),
+			WithMaxPerHost(cfg.Fetch.PerHost),
+		)
+	})
+
+	return defaultScheduler
+}
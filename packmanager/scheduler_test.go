@@ -0,0 +1,164 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package packmanager
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSchedulerDeduplicates(t *testing.T) {
+	s := NewScheduler(WithMaxWorkers(4))
+	ctx := context.Background()
+
+	var calls int32
+	var wg sync.WaitGroup
+	release := make(chan struct{})
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := s.Do(ctx, "https://github.com/unikraft/lib-musl.git", "lib-musl@stable", func(context.Context) error {
+				atomic.AddInt32(&calls, 1)
+				<-release
+				return nil
+			}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	// Allow every caller to join the in-flight fetch
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("expected a single fetch, got %d", calls)
+	}
+}
+
+func TestSchedulerLimitsPerHost(t *testing.T) {
+	s := NewScheduler(WithMaxWorkers(8), WithMaxPerHost(2))
+	ctx := context.Background()
+
+	var running, peak int32
+	var wg sync.WaitGroup
+
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			if err := s.Do(ctx, "https://github.com/unikraft/unikraft.git", "", func(context.Context) error {
+				now := atomic.AddInt32(&running, 1)
+				for {
+					old := atomic.LoadInt32(&peak)
+					if now <= old || atomic.CompareAndSwapInt32(&peak, old, now) {
+						break
+					}
+				}
+
+				time.Sleep(20 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				return nil
+			}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if peak > 2 {
+		t.Errorf("expected at most 2 concurrent fetches, got %d", peak)
+	}
+}
+
+func TestSchedulerCancelsCallersIndividually(t *testing.T) {
+	s := NewScheduler()
+	location := "https://github.com/unikraft/lib-musl.git"
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	first, cancel := context.WithCancel(context.Background())
+	errs := make(chan error, 1)
+
+	go func() {
+		errs <- s.Do(first, location, "lib-musl@stable", func(ctx context.Context) error {
+			close(started)
+
+			select {
+			case <-release:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	<-started
+
+	second := make(chan error, 1)
+	go func() {
+		second <- s.Do(context.Background(), location, "lib-musl@stable", func(context.Context) error {
+			t.Error("expected the in-flight fetch to be shared")
+			return nil
+		})
+	}()
+
+	// Allow the second caller to join the in-flight fetch
+	time.Sleep(100 * time.Millisecond)
+
+	// Cancelling the caller which started the fetch must not affect the other
+	cancel()
+	if err := <-errs; err != context.Canceled {
+		t.Errorf("expected the first caller to be cancelled, got %v", err)
+	}
+
+	close(release)
+	if err := <-second; err != nil {
+		t.Errorf("expected the second caller to receive the result, got %v", err)
+	}
+}
+
+func TestSchedulerCancelsAbandonedFetch(t *testing.T) {
+	s := NewScheduler()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancelled := make(chan struct{})
+
+	go func() {
+		_ = s.Do(ctx, "https://github.com/unikraft/lib-musl.git", "lib-musl@stable", func(ctx context.Context) error {
+			cancel()
+			<-ctx.Done()
+			close(cancelled)
+			return ctx.Err()
+		})
+	}()
+
+	select {
+	case <-cancelled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the fetch to be cancelled once every caller is")
+	}
+}
+
+func TestHostOf(t *testing.T) {
+	for location, expected := range map[string]string{
+		"https://github.com/unikraft/unikraft.git": "github.com",
+		"git@github.com:unikraft/unikraft.git":     "github.com",
+		"github.com/unikraft/unikraft":             "github.com",
+		"/tmp/mirror/unikraft.tar.gz":              "",
+	} {
+		if host := HostOf(location); host != expected {
+			t.Errorf("expected host of %s to be %q, got %q", location, expected, host)
+		}
+	}
+}
@@ -6,10 +6,12 @@ package packmanager
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/sirupsen/logrus"
 
+	"kraftkit.sh/config"
 	"kraftkit.sh/log"
 	"kraftkit.sh/pack"
 	"kraftkit.sh/unikraft/component"
@@ -75,12 +77,12 @@ func (u umbrella) From(sub pack.PackageFormat) (PackageManager, error) {
 	return nil, fmt.Errorf("unknown package manager: %s", sub)
 }
 
-func (u umbrella) Update(ctx context.Context) error {
+func (u umbrella) Update(ctx context.Context, opts ...UpdateOption) error {
 	for _, manager := range packageManagers {
 		log.G(ctx).WithFields(logrus.Fields{
 			"format": manager.Format(),
 		}).Tracef("updating")
-		err := manager.Update(ctx)
+		err := manager.Update(ctx, opts...)
 		if err != nil {
 			return err
 		}
@@ -161,7 +163,12 @@ func (u umbrella) Catalog(ctx context.Context, query CatalogQuery) ([]pack.Packa
 	var packages []pack.Package
 	for _, manager := range packageManagers {
 		pack, err := manager.Catalog(ctx, query)
-		if err != nil {
+		if errors.Is(err, config.ErrOffline) {
+			log.G(ctx).
+				WithField("format", manager.Format()).
+				Warnf("could not query catalog: %v", err)
+			continue
+		} else if err != nil {
 			log.G(ctx).
 				WithField("format", manager.Format()).
 				Debugf("could not query catalog: %v", err)
@@ -174,6 +181,23 @@ func (u umbrella) Catalog(ctx context.Context, query CatalogQuery) ([]pack.Packa
 	return packages, nil
 }
 
+func (u umbrella) Prune(ctx context.Context, opts ...PruneOption) ([]Reclaimed, error) {
+	var reclaimed []Reclaimed
+	for _, manager := range packageManagers {
+		log.G(ctx).WithFields(logrus.Fields{
+			"format": manager.Format(),
+		}).Tracef("pruning")
+		more, err := manager.Prune(ctx, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		reclaimed = append(reclaimed, more...)
+	}
+
+	return reclaimed, nil
+}
+
 func (u umbrella) IsCompatible(ctx context.Context, source string) (PackageManager, bool, error) {
 	if source == "" {
 		return nil, false, fmt.Errorf("cannot determine compatibility of empty source")
@@ -0,0 +1,51 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package packmanager
+
+// UpdateOptions contains the list of options which can be set when updating
+// the local cache of the upstream registries.
+type UpdateOptions struct {
+	source string
+	force  bool
+}
+
+// Source returns the only source which should be refreshed, or an empty string
+// if all sources should be considered.
+func (uopts *UpdateOptions) Source() string {
+	return uopts.source
+}
+
+// Force returns whether sources should be refreshed regardless of whether they
+// are still considered fresh or have not changed upstream.
+func (uopts *UpdateOptions) Force() bool {
+	return uopts.force
+}
+
+// UpdateOption is an option function which is used to modify UpdateOptions.
+type UpdateOption func(*UpdateOptions)
+
+// NewUpdateOptions creates UpdateOptions from the provided options.
+func NewUpdateOptions(opts ...UpdateOption) *UpdateOptions {
+	uopts := &UpdateOptions{}
+	for _, opt := range opts {
+		opt(uopts)
+	}
+
+	return uopts
+}
+
+// UpdateSource restricts the update to the provided source.
+func UpdateSource(source string) UpdateOption {
+	return func(uopts *UpdateOptions) {
+		uopts.source = source
+	}
+}
+
+// UpdateForce refreshes sources even if they are still fresh or unchanged.
+func UpdateForce(force bool) UpdateOption {
+	return func(uopts *UpdateOptions) {
+		uopts.force = force
+	}
+}
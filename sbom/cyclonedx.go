@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package sbom
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"kraftkit.sh/internal/version"
+	"kraftkit.sh/unikraft"
+)
+
+const cyclonedxSpecVersion = "1.4"
+
+type cyclonedxDocument struct {
+	BOMFormat    string                `json:"bomFormat"`
+	SpecVersion  string                `json:"specVersion"`
+	SerialNumber string                `json:"serialNumber"`
+	Version      int                   `json:"version"`
+	Metadata     cyclonedxMetadata     `json:"metadata"`
+	Components   []cyclonedxComponent  `json:"components"`
+	Dependencies []cyclonedxDependency `json:"dependencies,omitempty"`
+}
+
+type cyclonedxMetadata struct {
+	Timestamp string             `json:"timestamp"`
+	Tools     []cyclonedxTool    `json:"tools"`
+	Component cyclonedxComponent `json:"component"`
+}
+
+type cyclonedxTool struct {
+	Vendor  string `json:"vendor"`
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type cyclonedxComponent struct {
+	BOMRef             string                       `json:"bom-ref"`
+	Type               string                       `json:"type"`
+	Name               string                       `json:"name"`
+	Version            string                       `json:"version,omitempty"`
+	Hashes             []cyclonedxHash              `json:"hashes,omitempty"`
+	ExternalReferences []cyclonedxExternalReference `json:"externalReferences,omitempty"`
+	Properties         []cyclonedxProperty          `json:"properties,omitempty"`
+}
+
+type cyclonedxHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cyclonedxExternalReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+type cyclonedxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type cyclonedxDependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// cyclonedxRef returns the bom-ref of a component of the given type and name.
+func cyclonedxRef(t unikraft.ComponentType, name, version string) string {
+	ref := fmt.Sprintf("%s/%s", t, name)
+	if version != "" {
+		ref += "@" + version
+	}
+
+	return ref
+}
+
+// cyclonedx converts the SBOM into a CycloneDX 1.4 document.
+func (bom *SBOM) cyclonedx() cyclonedxDocument {
+	doc := cyclonedxDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  cyclonedxSpecVersion,
+		SerialNumber: "urn:uuid:" + bom.uuid(),
+		Version:      1,
+		Metadata: cyclonedxMetadata{
+			Timestamp: bom.Created.Format(time.RFC3339),
+			Tools: []cyclonedxTool{{
+				Vendor:  "Unikraft",
+				Name:    "kraftkit",
+				Version: version.Version(),
+			}},
+			Component: cyclonedxComponent{
+				BOMRef:  cyclonedxRef(unikraft.ComponentTypeApp, bom.Name, bom.Version),
+				Type:    "application",
+				Name:    bom.Name,
+				Version: bom.Version,
+			},
+		},
+		Components: []cyclonedxComponent{},
+	}
+
+	if bom.Checksum != "" {
+		doc.Metadata.Component.Hashes = []cyclonedxHash{{
+			Alg:     "SHA-256",
+			Content: bom.Checksum,
+		}}
+	}
+
+	if bom.Target != "" {
+		doc.Metadata.Component.Properties = append(doc.Metadata.Component.Properties, cyclonedxProperty{
+			Name:  "unikraft:target",
+			Value: bom.Target,
+		})
+	}
+
+	for _, kv := range bom.Properties {
+		doc.Metadata.Component.Properties = append(doc.Metadata.Component.Properties, cyclonedxProperty{
+			Name:  "unikraft:kconfig:" + kv.Key,
+			Value: kv.Value,
+		})
+	}
+
+	root := cyclonedxDependency{
+		Ref: doc.Metadata.Component.BOMRef,
+	}
+
+	for _, comp := range bom.Components {
+		entry := cyclonedxComponent{
+			BOMRef:  cyclonedxRef(comp.Type, comp.Name, comp.Version),
+			Type:    "library",
+			Name:    comp.Name,
+			Version: comp.Version,
+		}
+
+		switch comp.Type {
+		case unikraft.ComponentTypeApp:
+			entry.Type = "application"
+		case unikraft.ComponentTypeCore:
+			entry.Type = "operating-system"
+		}
+
+		if comp.Checksum != "" {
+			entry.Hashes = []cyclonedxHash{{
+				Alg:     "SHA-256",
+				Content: comp.Checksum,
+			}}
+		}
+
+		if comp.Origin != "" {
+			refType := "distribution"
+			if strings.HasSuffix(comp.Origin, ".git") {
+				refType = "vcs"
+			}
+
+			entry.ExternalReferences = []cyclonedxExternalReference{{
+				Type: refType,
+				URL:  comp.Origin,
+			}}
+		}
+
+		doc.Components = append(doc.Components, entry)
+		root.DependsOn = append(root.DependsOn, entry.BOMRef)
+	}
+
+	doc.Dependencies = []cyclonedxDependency{root}
+
+	return doc
+}
@@ -0,0 +1,330 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package sbom generates Software Bill of Materials (SBOM) documents for
+// Unikraft unikernels.  A unikernel is the sum of the Unikraft core, its
+// libraries and the application itself, all of which are known at build time.
+package sbom
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/filemode"
+
+	"kraftkit.sh/internal/fsutil"
+	"kraftkit.sh/kconfig"
+	"kraftkit.sh/manifest"
+	"kraftkit.sh/unikraft"
+	"kraftkit.sh/unikraft/app"
+	"kraftkit.sh/unikraft/component"
+	"kraftkit.sh/unikraft/target"
+)
+
+// Format is the serialization format of an SBOM document.
+type Format string
+
+const (
+	FormatSPDX      Format = "spdx"
+	FormatCycloneDX Format = "cyclonedx"
+)
+
+// String implements fmt.Stringer
+func (f Format) String() string {
+	return string(f)
+}
+
+// MediaType returns the IANA registered media type of the format.
+func (f Format) MediaType() string {
+	switch f {
+	case FormatSPDX:
+		return "application/spdx+json"
+	case FormatCycloneDX:
+		return "application/vnd.cyclonedx+json"
+	}
+
+	return ""
+}
+
+// Extension returns the conventional file extension of the format.
+func (f Format) Extension() string {
+	switch f {
+	case FormatSPDX:
+		return ".spdx.json"
+	case FormatCycloneDX:
+		return ".cdx.json"
+	}
+
+	return ".json"
+}
+
+// Formats returns the list of supported SBOM formats.
+func Formats() []string {
+	return []string{
+		FormatSPDX.String(),
+		FormatCycloneDX.String(),
+	}
+}
+
+// FormatFromString returns the Format matching the provided name.
+func FormatFromString(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "spdx":
+		return FormatSPDX, nil
+	case "cyclonedx", "cdx":
+		return FormatCycloneDX, nil
+	}
+
+	return "", fmt.Errorf("unknown sbom format: %s (expected one of: %s)", name, strings.Join(Formats(), ", "))
+}
+
+// DetectFormat determines the format of a serialized SBOM document.
+func DetectFormat(data []byte) (Format, error) {
+	var probe struct {
+		SPDXVersion string `json:"spdxVersion"`
+		BOMFormat   string `json:"bomFormat"`
+	}
+
+	if err := json.Unmarshal(data, &probe); err != nil {
+		return "", fmt.Errorf("could not parse sbom: %v", err)
+	}
+
+	if probe.SPDXVersion != "" {
+		return FormatSPDX, nil
+	} else if probe.BOMFormat == "CycloneDX" {
+		return FormatCycloneDX, nil
+	}
+
+	return "", fmt.Errorf("could not determine sbom format")
+}
+
+// Provider is implemented by packages which are able to return an attached
+// SBOM document in its serialized form.
+type Provider interface {
+	SBOM(context.Context) ([]byte, error)
+}
+
+// Component represents a single entry in the bill of materials.
+type Component struct {
+	Type     unikraft.ComponentType
+	Name     string
+	Version  string
+	Origin   string
+	Checksum string
+}
+
+// SBOM is the format-agnostic representation of a unikernel's bill of
+// materials.
+type SBOM struct {
+	Name       string
+	Version    string
+	Target     string
+	Checksum   string
+	Created    time.Time
+	Components []Component
+	Properties []*kconfig.KeyValue
+}
+
+// NewFromTarget generates an SBOM for the provided target of the project.  The
+// target's kernel image is checksummed if it has been built.
+func NewFromTarget(ctx context.Context, project app.Application, targ target.Target) (*SBOM, error) {
+	components, err := project.Components(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	bom := &SBOM{
+		Name:    project.Name(),
+		Version: project.Version(),
+		Target:  target.TargetPlatArchName(targ),
+		Created: time.Now().UTC(),
+	}
+
+	if len(targ.Kernel()) > 0 {
+		if checksum, err := Checksum(targ.Kernel()); err == nil {
+			bom.Checksum = checksum
+		}
+	}
+
+	lockfile, err := manifest.NewLockfileFromFile(filepath.Join(project.WorkingDir(), manifest.LockfileName))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, comp := range components {
+		entry, err := NewComponent(comp, lockfile)
+		if err != nil {
+			return nil, err
+		}
+
+		bom.Components = append(bom.Components, *entry)
+	}
+
+	sort.SliceStable(bom.Components, func(i, j int) bool {
+		if bom.Components[i].Type != bom.Components[j].Type {
+			return bom.Components[i].Type < bom.Components[j].Type
+		}
+
+		return bom.Components[i].Name < bom.Components[j].Name
+	})
+
+	bom.Properties = EnabledKConfig(targ.KConfig())
+
+	return bom, nil
+}
+
+// NewComponent converts a Unikraft component into an SBOM entry.  Its checksum
+// is that of the pristine archive of its version, as pinned by the lockfile,
+// otherwise that of the files tracked by its Git checkout.  The remaining
+// contents of its directory, e.g. build artifacts, are never checksummed.
+func NewComponent(comp component.Component, lockfile *manifest.Lockfile) (*Component, error) {
+	entry := Component{
+		Type:    comp.Type(),
+		Name:    comp.Name(),
+		Version: comp.Version(),
+		Origin:  comp.Source(),
+	}
+
+	if lockfile != nil {
+		if locked, ok := lockfile.Lookup(comp.Type(), comp.Name(), comp.Source()); ok && len(locked.Sha256) > 0 {
+			entry.Checksum = locked.Sha256
+			return &entry, nil
+		}
+	}
+
+	if path := comp.Path(); len(path) > 0 {
+		if _, err := git.PlainOpen(path); err == nil {
+			checksum, err := Checksum(path)
+			if err != nil {
+				return nil, fmt.Errorf("could not checksum %s: %v", comp.Name(), err)
+			}
+
+			entry.Checksum = checksum
+		}
+	}
+
+	return &entry, nil
+}
+
+// EnabledKConfig returns the sorted list of enabled KConfig options, omitting
+// host-specific options such as paths.
+func EnabledKConfig(kvmap kconfig.KeyValueMap) []*kconfig.KeyValue {
+	var enabled []*kconfig.KeyValue
+
+	for _, kv := range kvmap {
+		if kv == nil || kv.Value == "" || kv.Value == "n" || kv.Value == kconfig.No {
+			continue
+		}
+
+		// Filter out host-specific KConfig options
+		switch kv.Key {
+		case unikraft.UK_BASE, unikraft.UK_APP:
+			continue
+		}
+
+		enabled = append(enabled, kv)
+	}
+
+	sort.Slice(enabled, func(i, j int) bool {
+		return enabled[i].Key < enabled[j].Key
+	})
+
+	return enabled
+}
+
+// Checksum returns the hex-encoded SHA-256 digest of the provided path.  If
+// the path is a directory, it must be a Git checkout and the digest is computed
+// over the sorted list of the paths and contents of the files which it tracks.
+func Checksum(path string) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+
+	if !fi.IsDir() {
+		return fsutil.Checksum(path)
+	}
+
+	repo, err := git.PlainOpen(path)
+	if err != nil {
+		return "", fmt.Errorf("could not open git checkout: %v", err)
+	}
+
+	index, err := repo.Storer.Index()
+	if err != nil {
+		return "", fmt.Errorf("could not read git index: %v", err)
+	}
+
+	h := sha256.New()
+
+	// Index entries are sorted by their path
+	for _, e := range index.Entries {
+		if !e.Mode.IsRegular() && e.Mode != filemode.Executable {
+			continue
+		}
+
+		sum, err := fsutil.Checksum(filepath.Join(path, filepath.FromSlash(e.Name)))
+		if os.IsNotExist(err) {
+			continue
+		} else if err != nil {
+			return "", err
+		}
+
+		fmt.Fprintf(h, "%s  %s\n", sum, e.Name)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Marshal serializes the SBOM in the requested format.
+func (bom *SBOM) Marshal(format Format) ([]byte, error) {
+	switch format {
+	case FormatSPDX:
+		return json.MarshalIndent(bom.spdx(), "", "  ")
+	case FormatCycloneDX:
+		return json.MarshalIndent(bom.cyclonedx(), "", "  ")
+	}
+
+	return nil, fmt.Errorf("unknown sbom format: %s", format)
+}
+
+// WriteFile serializes the SBOM in the requested format to the provided path.
+func (bom *SBOM) WriteFile(path string, format Format) error {
+	data, err := bom.Marshal(format)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, data, 0o644)
+}
+
+// uuid returns a deterministic RFC 4122 (version 5-style) identifier derived
+// from the contents of the SBOM such that identical builds produce identical
+// document identifiers.
+func (bom *SBOM) uuid() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s:%s:%s:%s\n", bom.Name, bom.Version, bom.Target, bom.Checksum)
+	for _, comp := range bom.Components {
+		fmt.Fprintf(h, "%s:%s:%s:%s\n", comp.Type, comp.Name, comp.Version, comp.Checksum)
+	}
+	for _, kv := range bom.Properties {
+		fmt.Fprintf(h, "%s\n", kv.String())
+	}
+
+	b := h.Sum(nil)[:16]
+	b[6] = (b[6] & 0x0f) | 0x50
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
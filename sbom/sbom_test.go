@@ -0,0 +1,170 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package sbom
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+
+	"kraftkit.sh/kconfig"
+	"kraftkit.sh/manifest"
+	"kraftkit.sh/unikraft"
+	"kraftkit.sh/unikraft/lib"
+)
+
+func testSBOM() *SBOM {
+	return &SBOM{
+		Name:     "helloworld",
+		Version:  "0.1.0",
+		Target:   "qemu/x86_64",
+		Checksum: "deadbeef",
+		Created:  time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		Components: []Component{
+			{
+				Type:    unikraft.ComponentTypeCore,
+				Name:    "unikraft",
+				Version: "0.12.0",
+				Origin:  "https://github.com/unikraft/unikraft.git",
+			},
+			{
+				Type:     unikraft.ComponentTypeLib,
+				Name:     "musl",
+				Version:  "stable",
+				Origin:   "https://github.com/unikraft/lib-musl.git",
+				Checksum: "cafebabe",
+			},
+		},
+		Properties: EnabledKConfig(kconfig.KeyValueMap{}.
+			Set("CONFIG_LIBMUSL", "y").
+			Set("CONFIG_LIBVFSCORE", "n").
+			Set(unikraft.UK_BASE, "/home/user/unikraft")),
+	}
+}
+
+func TestMarshalDetectFormat(t *testing.T) {
+	bom := testSBOM()
+
+	for _, format := range []Format{FormatSPDX, FormatCycloneDX} {
+		data, err := bom.Marshal(format)
+		if err != nil {
+			t.Fatalf("marshal %s: %v", format, err)
+		}
+
+		detected, err := DetectFormat(data)
+		if err != nil {
+			t.Fatalf("detect %s: %v", format, err)
+		}
+
+		if detected != format {
+			t.Errorf("expected format %s, got %s", format, detected)
+		}
+
+		// Identical inputs must result in identical documents
+		again, _ := bom.Marshal(format)
+		if string(data) != string(again) {
+			t.Errorf("%s output is not deterministic", format)
+		}
+	}
+}
+
+func TestEnabledKConfig(t *testing.T) {
+	props := testSBOM().Properties
+	if len(props) != 1 || props[0].Key != "CONFIG_LIBMUSL" {
+		t.Errorf("expected only CONFIG_LIBMUSL to be enabled, got %v", props)
+	}
+}
+
+func TestChecksumDirectory(t *testing.T) {
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "a.c"), []byte("int a;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Checksum(dir); err == nil {
+		t.Errorf("expected directory which is not a git checkout to fail")
+	}
+
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := worktree.Add("a.c"); err != nil {
+		t.Fatal(err)
+	}
+
+	before, err := Checksum(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Untracked files, e.g. build artifacts, must not influence the checksum
+	if err := os.WriteFile(filepath.Join(dir, "a.o"), []byte("\x7fELF"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	after, err := Checksum(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if before != after {
+		t.Errorf("checksum changed after adding an untracked file")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "a.c"), []byte("int b;"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	changed, err := Checksum(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changed == before {
+		t.Errorf("checksum did not change after modifying a tracked file")
+	}
+}
+
+func TestNewComponentLocked(t *testing.T) {
+	musl := &lib.LibraryConfig{}
+	for _, opt := range []lib.LibraryOption{
+		lib.WithName("musl"),
+		lib.WithVersion("stable"),
+		lib.WithPath(t.TempDir()),
+	} {
+		if err := opt(musl); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	lockfile := &manifest.Lockfile{
+		Components: []manifest.LockedComponent{{
+			Type:    unikraft.ComponentTypeLib,
+			Name:    "musl",
+			Version: "stable",
+			Sha256:  "cafebabe",
+		}},
+	}
+
+	entry, err := NewComponent(musl, lockfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if entry.Checksum != "cafebabe" {
+		t.Errorf("expected checksum of the locked archive but got %q", entry.Checksum)
+	}
+}
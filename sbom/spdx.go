@@ -0,0 +1,168 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package sbom
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"kraftkit.sh/internal/version"
+	"kraftkit.sh/unikraft"
+)
+
+const (
+	spdxVersion      = "SPDX-2.3"
+	spdxDataLicense  = "CC0-1.0"
+	spdxNoAssertion  = "NOASSERTION"
+	spdxDocumentID   = "SPDXRef-DOCUMENT"
+	spdxNamespaceURL = "https://kraftkit.sh/spdx"
+)
+
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	Name                  string           `json:"name"`
+	SPDXID                string           `json:"SPDXID"`
+	VersionInfo           string           `json:"versionInfo,omitempty"`
+	DownloadLocation      string           `json:"downloadLocation"`
+	FilesAnalyzed         bool             `json:"filesAnalyzed"`
+	PrimaryPackagePurpose string           `json:"primaryPackagePurpose,omitempty"`
+	Checksums             []spdxChecksum   `json:"checksums,omitempty"`
+	Annotations           []spdxAnnotation `json:"annotations,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxAnnotation struct {
+	AnnotationDate string `json:"annotationDate"`
+	AnnotationType string `json:"annotationType"`
+	Annotator      string `json:"annotator"`
+	Comment        string `json:"comment"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+var spdxInvalidIDChars = regexp.MustCompile(`[^a-zA-Z0-9.\-]+`)
+
+// spdxID returns a valid SPDX element identifier for the provided parts.
+func spdxID(parts ...string) string {
+	id := "SPDXRef"
+	for _, part := range parts {
+		id += "-" + spdxInvalidIDChars.ReplaceAllString(part, "-")
+	}
+
+	return id
+}
+
+// spdx converts the SBOM into an SPDX 2.3 document.
+func (bom *SBOM) spdx() spdxDocument {
+	created := bom.Created.Format(time.RFC3339)
+	tool := fmt.Sprintf("Tool: kraftkit-%s", version.Version())
+
+	doc := spdxDocument{
+		SPDXVersion:       spdxVersion,
+		DataLicense:       spdxDataLicense,
+		SPDXID:            spdxDocumentID,
+		Name:              bom.Name,
+		DocumentNamespace: fmt.Sprintf("%s/%s-%s", spdxNamespaceURL, bom.Name, bom.uuid()),
+		CreationInfo: spdxCreationInfo{
+			Created:  created,
+			Creators: []string{tool},
+		},
+	}
+
+	root := spdxPackage{
+		Name:                  bom.Name,
+		SPDXID:                spdxID("Package", string(unikraft.ComponentTypeApp), bom.Name),
+		VersionInfo:           bom.Version,
+		DownloadLocation:      spdxNoAssertion,
+		PrimaryPackagePurpose: "APPLICATION",
+	}
+
+	if bom.Checksum != "" {
+		root.Checksums = []spdxChecksum{{
+			Algorithm:     "SHA256",
+			ChecksumValue: bom.Checksum,
+		}}
+	}
+
+	// SPDX has no notion of arbitrary key-value properties on a package, so the
+	// enabled KConfig options are recorded as annotations.
+	for _, kv := range bom.Properties {
+		root.Annotations = append(root.Annotations, spdxAnnotation{
+			AnnotationDate: created,
+			AnnotationType: "OTHER",
+			Annotator:      tool,
+			Comment:        kv.String(),
+		})
+	}
+
+	doc.Packages = append(doc.Packages, root)
+	doc.Relationships = append(doc.Relationships, spdxRelationship{
+		SPDXElementID:      spdxDocumentID,
+		RelationshipType:   "DESCRIBES",
+		RelatedSPDXElement: root.SPDXID,
+	})
+
+	for _, comp := range bom.Components {
+		pkg := spdxPackage{
+			Name:             comp.Name,
+			SPDXID:           spdxID("Package", string(comp.Type), comp.Name),
+			VersionInfo:      comp.Version,
+			DownloadLocation: spdxNoAssertion,
+		}
+
+		if comp.Origin != "" {
+			pkg.DownloadLocation = comp.Origin
+		}
+
+		switch comp.Type {
+		case unikraft.ComponentTypeApp:
+			pkg.PrimaryPackagePurpose = "APPLICATION"
+		case unikraft.ComponentTypeCore:
+			pkg.PrimaryPackagePurpose = "OPERATING-SYSTEM"
+		default:
+			pkg.PrimaryPackagePurpose = "LIBRARY"
+		}
+
+		if comp.Checksum != "" {
+			pkg.Checksums = []spdxChecksum{{
+				Algorithm:     "SHA256",
+				ChecksumValue: comp.Checksum,
+			}}
+		}
+
+		doc.Packages = append(doc.Packages, pkg)
+		doc.Relationships = append(doc.Relationships, spdxRelationship{
+			SPDXElementID:      root.SPDXID,
+			RelationshipType:   "CONTAINS",
+			RelatedSPDXElement: pkg.SPDXID,
+		})
+	}
+
+	return doc
+}
@@ -0,0 +1,115 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package sizereport
+
+import "sort"
+
+// TotalName is the name of the change of the size of the entire kernel.
+const TotalName = "total"
+
+// Change is the change of the total size of the kernel or of a library between
+// two reports.
+type Change struct {
+	Name string `json:"name"`
+	Old  uint64 `json:"old"`
+	New  uint64 `json:"new"`
+
+	// Regression is set if the size grew by more than the threshold.
+	Regression bool `json:"regression"`
+}
+
+// Delta returns the number of bytes by which the size changed.
+func (c Change) Delta() int64 {
+	return int64(c.New) - int64(c.Old)
+}
+
+// Percent returns the change relative to the old size.  A library which was
+// added grows by 100 percent.
+func (c Change) Percent() float64 {
+	if c.Old == 0 {
+		if c.New == 0 {
+			return 0
+		}
+
+		return 100
+	}
+
+	return float64(c.Delta()) / float64(c.Old) * 100
+}
+
+// Compare returns the changes of the total size of the kernel and of each of
+// its libraries from the previous report to this one, largest first, and sets
+// them on the report.  Sizes which grew by more than the threshold, in percent,
+// are regressions.
+func (r *Report) Compare(previous *Report, threshold float64) []Change {
+	changes := []Change{}
+
+	if change, ok := compare(TotalName, previous.Total(), r.Total(), threshold); ok {
+		changes = append(changes, change)
+	}
+
+	names := map[string]struct{}{}
+	for _, lib := range previous.Libraries {
+		names[lib.Name] = struct{}{}
+	}
+
+	for _, lib := range r.Libraries {
+		names[lib.Name] = struct{}{}
+	}
+
+	var libs []Change
+	for name := range names {
+		old, _ := previous.Library(name)
+		new, _ := r.Library(name)
+
+		if change, ok := compare(name, old.Total(), new.Total(), threshold); ok {
+			libs = append(libs, change)
+		}
+	}
+
+	sort.Slice(libs, func(i, j int) bool {
+		a, b := abs(libs[i].Delta()), abs(libs[j].Delta())
+		if a != b {
+			return a > b
+		}
+
+		return libs[i].Name < libs[j].Name
+	})
+
+	r.Changes = append(changes, libs...)
+
+	return r.Changes
+}
+
+func compare(name string, old, new uint64, threshold float64) (Change, bool) {
+	if old == new {
+		return Change{}, false
+	}
+
+	change := Change{Name: name, Old: old, New: new}
+	change.Regression = new > old && change.Percent() > threshold
+
+	return change, true
+}
+
+// Regressions returns the changes of the report which are regressions.
+func (r *Report) Regressions() []Change {
+	var regressions []Change
+	for _, change := range r.Changes {
+		if change.Regression {
+			regressions = append(regressions, change)
+		}
+	}
+
+	return regressions
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
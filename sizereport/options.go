@@ -0,0 +1,40 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package sizereport
+
+// ReportOptions are the options of a report.
+type ReportOptions struct {
+	buildDir string
+	symbols  int
+	target   string
+	platform string
+}
+
+// ReportOption is an option function which is used to modify ReportOptions.
+type ReportOption func(*ReportOptions)
+
+// WithBuildDir sets the build directory whose library objects the symbols of
+// the kernel are attributed with.
+func WithBuildDir(dir string) ReportOption {
+	return func(ropts *ReportOptions) {
+		ropts.buildDir = dir
+	}
+}
+
+// WithSymbols sets the number of the largest symbols to report, 10 by default.
+func WithSymbols(n int) ReportOption {
+	return func(ropts *ReportOptions) {
+		ropts.symbols = n
+	}
+}
+
+// WithTarget records the name and the platform and architecture of the target
+// the kernel was built for.
+func WithTarget(name, platform string) ReportOption {
+	return func(ropts *ReportOptions) {
+		ropts.target = name
+		ropts.platform = platform
+	}
+}
@@ -0,0 +1,406 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package sizereport breaks the size of a built unikernel down into the text,
+// data and bss of each Unikraft library it consists of and its largest
+// symbols, such that the size of kernels can be tracked and compared.
+package sizereport
+
+import (
+	"context"
+	"debug/elf"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FileExtension is appended to the path of a kernel to derive the path of its
+// report.
+const FileExtension = ".size.json"
+
+// OtherLibrary is the name under which symbols which could not be attributed
+// to a library, and the size of sections which is not covered by symbols,
+// e.g. alignment, are accounted.
+const OtherLibrary = "other"
+
+// Section is the kind of section a symbol is placed in.
+type Section string
+
+const (
+	SectionText = Section("text")
+	SectionData = Section("data")
+	SectionBSS  = Section("bss")
+)
+
+// Sizes are the sizes in bytes of the text, which includes read-only data as
+// with the Berkeley format of `size`, the data and the bss of a kernel or a
+// library.
+type Sizes struct {
+	Text uint64 `json:"text"`
+	Data uint64 `json:"data"`
+	BSS  uint64 `json:"bss"`
+}
+
+// Total returns the sum of the text, data and bss.
+func (s Sizes) Total() uint64 {
+	return s.Text + s.Data + s.BSS
+}
+
+func (s *Sizes) add(section Section, size uint64) {
+	switch section {
+	case SectionText:
+		s.Text += size
+	case SectionData:
+		s.Data += size
+	case SectionBSS:
+		s.BSS += size
+	}
+}
+
+// Library is the size of a single library within the kernel.
+type Library struct {
+	Name string `json:"name"`
+	Sizes
+}
+
+// Symbol is a function or object within the kernel.
+type Symbol struct {
+	Name    string  `json:"name"`
+	Library string  `json:"library"`
+	Section Section `json:"section"`
+	Size    uint64  `json:"size"`
+}
+
+// Report is the size breakdown of a kernel.
+type Report struct {
+	// Target and Platform identify the target the kernel was built for, e.g.
+	// `helloworld` and `qemu-x86_64`.
+	Target   string `json:"target,omitempty"`
+	Platform string `json:"platform,omitempty"`
+
+	Sizes
+
+	// Libraries are sorted by their total size, largest first.
+	Libraries []Library `json:"libraries"`
+
+	// Symbols are the largest symbols, largest first.
+	Symbols []Symbol `json:"symbols"`
+
+	// Changes are set when the report was compared to a previous report, see
+	// Compare.
+	Changes []Change `json:"changes,omitempty"`
+}
+
+// Provider is implemented by packages which are able to return an attached
+// size report in its serialized form.
+type Provider interface {
+	SizeReport(context.Context) ([]byte, error)
+}
+
+// New returns the report of the kernel at the provided path.  The symbols of
+// the kernel are attributed to the libraries whose objects within the build
+// directory, i.e. `<build>/lib<name>/*.o` and `<build>/app<name>/*.o`, define
+// them, which requires the kernel to have a symbol table, e.g. its `.dbg`
+// variant.
+func New(kernel string, opts ...ReportOption) (*Report, error) {
+	ropts := ReportOptions{symbols: 10}
+	for _, opt := range opts {
+		opt(&ropts)
+	}
+
+	f, err := elf.Open(kernel)
+	if err != nil {
+		return nil, fmt.Errorf("could not open kernel: %v", err)
+	}
+
+	defer f.Close()
+
+	report := &Report{
+		Target:    ropts.target,
+		Platform:  ropts.platform,
+		Libraries: []Library{},
+		Symbols:   []Symbol{},
+	}
+
+	for _, section := range f.Sections {
+		if kind, ok := classify(section); ok {
+			report.add(kind, section.Size)
+		}
+	}
+
+	owners := &owners{globals: map[string]string{}, locals: map[string]string{}}
+	if len(ropts.buildDir) > 0 {
+		if err := owners.scan(ropts.buildDir); err != nil {
+			return nil, err
+		}
+	}
+
+	symbols, err := f.Symbols()
+	if err != nil && err != elf.ErrNoSymbols {
+		return nil, fmt.Errorf("could not read symbols: %v", err)
+	}
+
+	libraries := map[string]*Library{}
+	var all []Symbol
+
+	// Aliases of a symbol, e.g. weak ones, share its address and are only
+	// accounted once
+	type location struct {
+		section elf.SectionIndex
+		value   uint64
+	}
+	seen := map[location]struct{}{}
+
+	file := ""
+	attributed := Sizes{}
+
+	for _, sym := range symbols {
+		typ := elf.ST_TYPE(sym.Info)
+		if typ == elf.STT_FILE {
+			file = sym.Name
+			continue
+		}
+
+		if (typ != elf.STT_FUNC && typ != elf.STT_OBJECT) || sym.Size == 0 {
+			continue
+		}
+
+		if sym.Section == elf.SHN_UNDEF || int(sym.Section) >= len(f.Sections) {
+			continue
+		}
+
+		kind, ok := classify(f.Sections[sym.Section])
+		if !ok {
+			continue
+		}
+
+		loc := location{sym.Section, sym.Value}
+		if _, ok := seen[loc]; ok {
+			continue
+		}
+
+		seen[loc] = struct{}{}
+
+		name := owners.lookup(file, sym)
+
+		lib, ok := libraries[name]
+		if !ok {
+			lib = &Library{Name: name}
+			libraries[name] = lib
+		}
+
+		lib.add(kind, sym.Size)
+		attributed.add(kind, sym.Size)
+
+		all = append(all, Symbol{
+			Name:    sym.Name,
+			Library: name,
+			Section: kind,
+			Size:    sym.Size,
+		})
+	}
+
+	// Account the remainder of the sections, e.g. padding, to no library
+	remainder := Sizes{
+		Text: sub(report.Text, attributed.Text),
+		Data: sub(report.Data, attributed.Data),
+		BSS:  sub(report.BSS, attributed.BSS),
+	}
+
+	if remainder.Total() > 0 {
+		lib, ok := libraries[OtherLibrary]
+		if !ok {
+			lib = &Library{Name: OtherLibrary}
+			libraries[OtherLibrary] = lib
+		}
+
+		lib.Text += remainder.Text
+		lib.Data += remainder.Data
+		lib.BSS += remainder.BSS
+	}
+
+	for _, lib := range libraries {
+		report.Libraries = append(report.Libraries, *lib)
+	}
+
+	sort.Slice(report.Libraries, func(i, j int) bool {
+		if a, b := report.Libraries[i].Total(), report.Libraries[j].Total(); a != b {
+			return a > b
+		}
+
+		return report.Libraries[i].Name < report.Libraries[j].Name
+	})
+
+	sort.SliceStable(all, func(i, j int) bool {
+		return all[i].Size > all[j].Size
+	})
+
+	if len(all) > ropts.symbols {
+		all = all[:ropts.symbols]
+	}
+
+	report.Symbols = append(report.Symbols, all...)
+
+	return report, nil
+}
+
+// Load reads a report which was previously saved with WriteFile.
+func Load(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Parse(data)
+}
+
+// Parse parses a serialized report.
+func Parse(data []byte) (*Report, error) {
+	var report Report
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("could not parse size report: %v", err)
+	}
+
+	return &report, nil
+}
+
+// WriteFile saves the report as JSON to the provided path.
+func (r *Report) WriteFile(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// Library returns the sizes of the library with the provided name.
+func (r *Report) Library(name string) (Library, bool) {
+	for _, lib := range r.Libraries {
+		if lib.Name == name {
+			return lib, true
+		}
+	}
+
+	return Library{}, false
+}
+
+// classify returns the kind of an allocated section, i.e. whether its contents
+// are accounted as text, data or bss.
+func classify(section *elf.Section) (Section, bool) {
+	switch {
+	case section.Flags&elf.SHF_ALLOC == 0:
+		return "", false
+	case section.Type == elf.SHT_NOBITS:
+		return SectionBSS, true
+	case section.Flags&elf.SHF_WRITE != 0:
+		return SectionData, true
+	}
+
+	return SectionText, true
+}
+
+// owners maps the names of symbols to the libraries whose objects define them.
+// Local symbols are qualified by the name of the source file, as recorded by
+// the compiler, since different libraries may use the same names.
+type owners struct {
+	globals map[string]string
+	locals  map[string]string
+}
+
+func (o *owners) lookup(file string, sym elf.Symbol) string {
+	if elf.ST_BIND(sym.Info) == elf.STB_LOCAL {
+		if name, ok := o.locals[file+"\x00"+sym.Name]; ok {
+			return name
+		}
+	}
+
+	if name, ok := o.globals[sym.Name]; ok {
+		return name
+	}
+
+	return OtherLibrary
+}
+
+// scan reads the symbols defined by the objects of each library in the build
+// directory.
+func (o *owners) scan(buildDir string) error {
+	dirs, err := os.ReadDir(buildDir)
+	if err != nil {
+		return fmt.Errorf("could not read build directory: %v", err)
+	}
+
+	for _, dir := range dirs {
+		name := dir.Name()
+		if !dir.IsDir() || !(strings.HasPrefix(name, "lib") || strings.HasPrefix(name, "app")) {
+			continue
+		}
+
+		err := filepath.WalkDir(filepath.Join(buildDir, name), func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() || filepath.Ext(path) != ".o" {
+				return nil
+			}
+
+			o.scanObject(name, path)
+
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (o *owners) scanObject(library, path string) {
+	f, err := elf.Open(path)
+	if err != nil {
+		// Not every file with the extension is an ELF object
+		return
+	}
+
+	defer f.Close()
+
+	symbols, err := f.Symbols()
+	if err != nil {
+		return
+	}
+
+	file := ""
+	for _, sym := range symbols {
+		typ := elf.ST_TYPE(sym.Info)
+		if typ == elf.STT_FILE {
+			file = sym.Name
+			continue
+		}
+
+		if (typ != elf.STT_FUNC && typ != elf.STT_OBJECT) || sym.Section == elf.SHN_UNDEF {
+			continue
+		}
+
+		if elf.ST_BIND(sym.Info) == elf.STB_LOCAL {
+			o.locals[file+"\x00"+sym.Name] = library
+		} else {
+			o.globals[sym.Name] = library
+		}
+	}
+}
+
+func sub(a, b uint64) uint64 {
+	if b > a {
+		return 0
+	}
+
+	return a - b
+}
@@ -0,0 +1,138 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package sizereport
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+// build compiles the sources, given by their path relative to the build
+// directory, into objects next to them and links them into a kernel.
+func build(t *testing.T, dir string, sources map[string]string) string {
+	t.Helper()
+
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc is not available")
+	}
+
+	var objects []string
+	for rel, source := range sources {
+		path := filepath.Join(dir, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+			t.Fatal(err)
+		}
+
+		object := path[:len(path)-len(filepath.Ext(path))] + ".o"
+		if out, err := exec.Command("gcc", "-c", "-O0", "-fno-pic", "-o", object, path).CombinedOutput(); err != nil {
+			t.Fatalf("could not compile %s: %v: %s", rel, err, out)
+		}
+
+		objects = append(objects, object)
+	}
+
+	kernel := filepath.Join(dir, "app_qemu-x86_64.dbg")
+	args := append([]string{"-nostdlib", "-static", "-no-pie", "-Wl,-e,main", "-o", kernel}, objects...)
+	if out, err := exec.Command("gcc", args...).CombinedOutput(); err != nil {
+		t.Skipf("could not link: %v: %s", err, out)
+	}
+
+	return kernel
+}
+
+func TestReport(t *testing.T) {
+	dir := t.TempDir()
+
+	kernel := build(t, dir, map[string]string{
+		"libukalloc/alloc.c": `
+static char heap[4096];
+int counter = 1;
+static int helper(void) { return 1; }
+char *alloc(int n) { return heap + n + helper(); }
+`,
+		"apphelloworld/main.c": `
+static int helper(void) { return 2; }
+char table[100] = { 1 };
+char *alloc(int n);
+int main(void) { return *alloc(helper()) + table[0]; }
+`,
+	})
+
+	report, err := New(kernel,
+		WithBuildDir(dir),
+		WithSymbols(2),
+		WithTarget("app", "qemu-x86_64"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alloc, ok := report.Library("libukalloc")
+	if !ok {
+		t.Fatalf("expected libukalloc in %+v", report.Libraries)
+	}
+
+	if alloc.BSS != 4096 {
+		t.Errorf("expected 4096 bytes of bss in libukalloc, got %d", alloc.BSS)
+	}
+
+	if alloc.Data != 4 {
+		t.Errorf("expected 4 bytes of data in libukalloc, got %d", alloc.Data)
+	}
+
+	app, ok := report.Library("apphelloworld")
+	if !ok || app.Data != 100 || app.Text == 0 {
+		t.Errorf("expected 100 bytes of data and some text in apphelloworld, got %+v", app)
+	}
+
+	if len(report.Symbols) != 2 || report.Symbols[0].Name != "heap" || report.Symbols[0].Library != "libukalloc" {
+		t.Errorf("expected heap to be the largest symbol, got %+v", report.Symbols)
+	}
+
+	if report.Libraries[0].Name != "libukalloc" {
+		t.Errorf("expected libukalloc to be the largest library, got %+v", report.Libraries)
+	}
+
+	path := filepath.Join(dir, "report.json")
+	if err := report.WriteFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	previous, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if changes := report.Compare(previous, 0); len(changes) != 0 {
+		t.Errorf("expected no changes, got %+v", changes)
+	}
+
+	// A library which grew by more than the threshold is a regression
+	previous.Libraries = append(previous.Libraries[:0:0], previous.Libraries...)
+	for i := range previous.Libraries {
+		if previous.Libraries[i].Name == "libukalloc" {
+			previous.Libraries[i].BSS = 2048
+		}
+	}
+
+	previous.BSS -= 2048
+
+	report.Compare(previous, 10)
+
+	regressions := report.Regressions()
+	if len(regressions) != 2 || regressions[0].Name != TotalName || regressions[1].Name != "libukalloc" || regressions[1].Delta() != 2048 {
+		t.Errorf("expected the total and libukalloc to regress, got %+v", regressions)
+	}
+
+	if report.Compare(previous, 100); len(report.Regressions()) != 0 {
+		t.Errorf("expected no regressions within the threshold, got %+v", report.Regressions())
+	}
+}
@@ -0,0 +1,240 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package syscalls
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"kraftkit.sh/kconfig"
+	"kraftkit.sh/unikraft/lib"
+)
+
+// Status is whether a syscall used by the application is provided.
+type Status string
+
+const (
+	// StatusAvailable is the status of syscalls which an enabled library
+	// implements.
+	StatusAvailable = Status("available")
+
+	// StatusStubbed is the status of syscalls which an enabled library provides,
+	// but only as a stub which fails, e.g. with `ENOSYS`.
+	StatusStubbed = Status("stubbed")
+
+	// StatusMissing is the status of syscalls which no enabled library provides.
+	StatusMissing = Status("missing")
+)
+
+// Result is the status of a single syscall used by the application.
+type Result struct {
+	Syscall string `json:"syscall"`
+	Status  Status `json:"status"`
+
+	// Library is the name of the library which provides the syscall, unless it
+	// is missing.
+	Library string `json:"library,omitempty"`
+
+	// Sources are the symbols through which the application uses the syscall,
+	// see Usage.
+	Sources []string `json:"sources"`
+}
+
+// Check returns the status of each syscall in the usage, sorted by name, given
+// the libraries of the unikernel.  Only libraries which are enabled in the
+// provided configuration are considered and the syscalls they provide only
+// under a condition are considered if it holds.  When the configuration is
+// nil, all libraries and syscalls are considered.
+func Check(usage *Usage, libraries lib.Libraries, config kconfig.KeyValueMap) []Result {
+	names := []string{}
+	for name := range libraries {
+		names = append(names, name)
+	}
+
+	// Prefer libraries deterministically if several provide the same syscall
+	sort.Strings(names)
+
+	providers := map[string][]*lib.LibraryConfig{}
+	for _, name := range names {
+		library := libraries[name]
+		if config != nil && !enabled(config, library.KConfigName()) {
+			continue
+		}
+
+		for _, syscall := range library.Syscalls() {
+			if syscall.DependsOn != nil && config != nil && !holds(config, *syscall.DependsOn) {
+				continue
+			}
+
+			providers[syscall.Name] = append(providers[syscall.Name], library)
+		}
+	}
+
+	stubs := map[string]map[string]bool{}
+
+	results := []Result{}
+	for _, name := range usage.Names() {
+		result := Result{
+			Syscall: name,
+			Status:  StatusMissing,
+			Sources: usage.Syscalls[name],
+		}
+
+		for _, library := range providers[name] {
+			if _, ok := stubs[library.Name()]; !ok {
+				stubs[library.Name()] = stubbed(library.Path())
+			}
+
+			if stubs[library.Name()][name] {
+				if result.Status == StatusMissing {
+					result.Status = StatusStubbed
+					result.Library = library.Name()
+				}
+
+				continue
+			}
+
+			result.Status = StatusAvailable
+			result.Library = library.Name()
+
+			break
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// enabled returns whether the KConfig option is set.
+func enabled(config kconfig.KeyValueMap, name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+
+	value, ok := config.Get(name)
+
+	return ok && value.Value == kconfig.Yes
+}
+
+// holds returns whether the condition of a Makefile.uk addition, e.g.
+// `$(CONFIG_LIBPOSIX_PROCESS_CLONE)`, holds with the configuration.
+// Conditions which are not a single option are assumed to hold.
+func holds(config kconfig.KeyValueMap, condition string) bool {
+	if condition == kconfig.Yes {
+		return true
+	}
+
+	if !strings.HasPrefix(condition, "$(") || !strings.HasSuffix(condition, ")") {
+		return true
+	}
+
+	return enabled(config, condition[2:len(condition)-1])
+}
+
+var (
+	// syscallDefine matches the definitions of syscalls by Unikraft's
+	// `uk/syscall.h`, e.g. `UK_SYSCALL_R_DEFINE(int, getpid)`.
+	syscallDefine = regexp.MustCompile(`UK_(?:LL)?SYSCALL(?:_R)?(?:_E)?_DEFINE\s*\(\s*[^,]+?,\s*(\w+)`)
+
+	// stubBodies are the bodies of syscalls, without whitespace, which do
+	// nothing but fail.
+	stubBodies = map[string]bool{
+		"{return-ENOSYS;}":             true,
+		"{errno=ENOSYS;return-1;}":     true,
+		"{return-EOPNOTSUPP;}":         true,
+		"{errno=EOPNOTSUPP;return-1;}": true,
+	}
+)
+
+// stubbed returns the names of the syscalls which the C sources of the library
+// at the provided path define as stubs, i.e. whose bodies either warn with
+// `UK_WARN_STUBBED` or only fail.
+func stubbed(path string) map[string]bool {
+	ret := map[string]bool{}
+	if len(path) == 0 {
+		return ret
+	}
+
+	_ = filepath.WalkDir(path, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+
+		if d.IsDir() {
+			if file != path && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+
+			return nil
+		}
+
+		if filepath.Ext(file) != ".c" {
+			return nil
+		}
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil
+		}
+
+		for name := range stubbedIn(string(data)) {
+			ret[name] = true
+		}
+
+		return nil
+	})
+
+	return ret
+}
+
+// stubbedIn returns the names of the syscalls which the C source defines as
+// stubs.
+func stubbedIn(source string) map[string]bool {
+	ret := map[string]bool{}
+
+	for _, match := range syscallDefine.FindAllStringSubmatchIndex(source, -1) {
+		name := source[match[2]:match[3]]
+
+		block, ok := body(source[match[1]:])
+		if !ok {
+			continue
+		}
+
+		if strings.Contains(block, "UK_WARN_STUBBED") || stubBodies[strings.Join(strings.Fields(block), "")] {
+			ret[name] = true
+		}
+	}
+
+	return ret
+}
+
+// body returns the first block of the source, from its opening to its
+// matching closing brace.
+func body(source string) (string, bool) {
+	start := strings.IndexByte(source, '{')
+	if start < 0 {
+		return "", false
+	}
+
+	depth := 0
+	for i := start; i < len(source); i++ {
+		switch source[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return source[start : i+1], true
+			}
+		}
+	}
+
+	return "", false
+}
@@ -0,0 +1,111 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package syscalls
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"kraftkit.sh/kconfig"
+	"kraftkit.sh/unikraft/lib"
+)
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"Makefile.uk": `
+$(eval $(call addlib_s,libposix_process,$(CONFIG_LIBPOSIX_PROCESS)))
+
+LIBPOSIX_PROCESS_SRCS-y += $(LIBPOSIX_PROCESS_BASE)/process.c
+
+UK_PROVIDED_SYSCALLS-$(CONFIG_LIBPOSIX_PROCESS) += getpid-0 kill-2
+UK_PROVIDED_SYSCALLS-$(CONFIG_LIBPOSIX_PROCESS_CLONE) += clone-5
+`,
+		"process.c": `
+UK_SYSCALL_R_DEFINE(int, getpid)
+{
+	return pid;
+}
+
+UK_SYSCALL_R_DEFINE(int, kill, int, pid, int, sig)
+{
+	UK_WARN_STUBBED();
+	return 0;
+}
+
+UK_LLSYSCALL_R_DEFINE(int, clone, unsigned long, flags, void *, sp,
+		      int *, parent_tid, int *, child_tid, unsigned long, tlsp)
+{
+	return -ENOSYS;
+}
+`,
+	}
+
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	libraries, err := lib.NewFromDir(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	usage := &Usage{
+		Arch: ArchX86_64,
+		Syscalls: map[string][]string{
+			"clone":  {"pthread_create"},
+			"getpid": {"getpid"},
+			"kill":   {"kill"},
+			"open":   {"open"},
+		},
+	}
+
+	status := func(config kconfig.KeyValueMap) map[string]Status {
+		ret := map[string]Status{}
+		for _, result := range Check(usage, libraries, config) {
+			ret[result.Syscall] = result.Status
+		}
+
+		return ret
+	}
+
+	expected := map[string]Status{
+		"clone":  StatusStubbed,
+		"getpid": StatusAvailable,
+		"kill":   StatusStubbed,
+		"open":   StatusMissing,
+	}
+
+	if got := status(nil); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	// Syscalls which are provided under a condition which does not hold
+	expected["clone"] = StatusMissing
+
+	config := kconfig.KeyValueMap{}
+	config.Set("CONFIG_LIBPOSIX_PROCESS", kconfig.Yes)
+
+	if got := status(config); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	// Libraries which are not enabled
+	for name := range expected {
+		if name != "open" {
+			expected[name] = StatusMissing
+		}
+	}
+
+	if got := status(kconfig.KeyValueMap{}); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,251 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+// Package syscalls statically determines which Linux syscalls an application
+// uses and checks whether the libraries of a Unikraft unikernel provide them.
+package syscalls
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"fmt"
+	"sort"
+)
+
+// Architectures whose syscalls can be determined.
+const (
+	ArchX86_64 = "x86_64"
+	ArchArm64  = "arm64"
+)
+
+// SourceInstruction is the source of syscalls which are invoked directly by
+// an instruction, rather than through a libc wrapper.
+const SourceInstruction = "<instruction>"
+
+// Usage is the set of syscalls used by an application.
+type Usage struct {
+	// Arch is the architecture of the application.
+	Arch string `json:"arch"`
+
+	// Syscalls maps the names of the used syscalls to their sources, i.e. the
+	// symbols of the libc wrappers through which they are invoked or
+	// SourceInstruction.
+	Syscalls map[string][]string `json:"syscalls"`
+}
+
+// Names returns the sorted names of the used syscalls.
+func (u *Usage) Names() []string {
+	names := []string{}
+	for name := range u.Syscalls {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+func (u *Usage) add(syscall, source string) {
+	for _, s := range u.Syscalls[syscall] {
+		if s == source {
+			return
+		}
+	}
+
+	u.Syscalls[syscall] = append(u.Syscalls[syscall], source)
+}
+
+// Scan returns the syscalls used by the ELF executables, shared objects or
+// relocatable objects at the provided paths, which must all be of the same
+// architecture.  Syscalls are found by the symbols of the libc wrappers which
+// are referenced, or defined in the case of statically linked executables, and
+// by syscall instructions whose number is loaded immediately before.
+func Scan(paths ...string) (*Usage, error) {
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no files to scan")
+	}
+
+	var usage *Usage
+
+	for _, path := range paths {
+		f, err := elf.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not open %s: %v", path, err)
+		}
+
+		arch, numbers, err := architecture(f)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("could not scan %s: %v", path, err)
+		}
+
+		if usage == nil {
+			usage = &Usage{Arch: arch, Syscalls: map[string][]string{}}
+		} else if usage.Arch != arch {
+			f.Close()
+			return nil, fmt.Errorf("could not scan %s: expected %s but is %s", path, usage.Arch, arch)
+		}
+
+		err = scanFile(f, numbers, usage)
+		f.Close()
+		if err != nil {
+			return nil, fmt.Errorf("could not scan %s: %v", path, err)
+		}
+	}
+
+	return usage, nil
+}
+
+// architecture returns the name of the architecture of the ELF file and the
+// table of its syscall numbers.
+func architecture(f *elf.File) (string, map[int]string, error) {
+	switch f.Machine {
+	case elf.EM_X86_64:
+		return ArchX86_64, numbersX86_64, nil
+	case elf.EM_AARCH64:
+		return ArchArm64, numbersArm64, nil
+	}
+
+	return "", nil, fmt.Errorf("unsupported machine: %s", f.Machine)
+}
+
+// isStatic returns whether the ELF file is linked statically, i.e. neither
+// requests an interpreter nor depends on shared libraries.  Unlike checking the
+// type of the file, this also holds for static position independent
+// executables.
+func isStatic(f *elf.File) bool {
+	for _, prog := range f.Progs {
+		if prog.Type == elf.PT_INTERP {
+			return false
+		}
+	}
+
+	needed, err := f.DynString(elf.DT_NEEDED)
+	return err == nil && len(needed) == 0
+}
+
+func scanFile(f *elf.File, numbers map[int]string, usage *Usage) error {
+	known := map[string]bool{}
+	for _, name := range numbers {
+		known[name] = true
+	}
+
+	symbols, err := f.Symbols()
+	if err != nil && err != elf.ErrNoSymbols {
+		return fmt.Errorf("could not read symbols: %v", err)
+	}
+
+	// Only functions which are defined within statically linked executables
+	// are part of the application, those of objects are not
+	static := (f.Type == elf.ET_EXEC || f.Type == elf.ET_DYN) && isStatic(f)
+
+	for _, sym := range symbols {
+		if elf.ST_BIND(sym.Info) == elf.STB_LOCAL {
+			continue
+		}
+
+		undefined := sym.Section == elf.SHN_UNDEF
+		if !undefined && !(static && elf.ST_TYPE(sym.Info) == elf.STT_FUNC) {
+			continue
+		}
+
+		for _, syscall := range fromSymbol(sym.Name, known) {
+			usage.add(syscall, sym.Name)
+		}
+	}
+
+	if imported, err := f.ImportedSymbols(); err == nil {
+		for _, sym := range imported {
+			for _, syscall := range fromSymbol(sym.Name, known) {
+				usage.add(syscall, sym.Name)
+			}
+		}
+	}
+
+	for _, section := range f.Sections {
+		if section.Type != elf.SHT_PROGBITS || section.Flags&elf.SHF_EXECINSTR == 0 {
+			continue
+		}
+
+		data, err := section.Data()
+		if err != nil {
+			return fmt.Errorf("could not read section %s: %v", section.Name, err)
+		}
+
+		var found []int
+		switch f.Machine {
+		case elf.EM_X86_64:
+			found = scanX86_64(data)
+		case elf.EM_AARCH64:
+			found = scanArm64(data, f.ByteOrder)
+		}
+
+		for _, nr := range found {
+			if name, ok := numbers[nr]; ok {
+				usage.add(name, SourceInstruction)
+			}
+		}
+	}
+
+	return nil
+}
+
+// scanX86_64 returns the numbers of the syscalls invoked with the `syscall`
+// instruction, i.e. `0f 05`, whose number was loaded into `eax` shortly
+// before with `mov $nr, %eax`, i.e. `b8 <imm32>`.
+func scanX86_64(data []byte) []int {
+	const window = 16
+
+	var ret []int
+	for i := 0; i+1 < len(data); i++ {
+		if data[i] != 0x0f || data[i+1] != 0x05 {
+			continue
+		}
+
+		// Look for the closest load, which is not itself part of another
+		// instruction's immediate in most compiled code
+		for j := i - 5; j >= 0 && j >= i-window; j-- {
+			if data[j] != 0xb8 {
+				continue
+			}
+
+			ret = append(ret, int(binary.LittleEndian.Uint32(data[j+1:j+5])))
+			break
+		}
+	}
+
+	return ret
+}
+
+// scanArm64 returns the numbers of the syscalls invoked with the `svc #0`
+// instruction whose number was loaded into `x8` shortly before with
+// `mov x8, #nr` or `mov w8, #nr`, i.e. `movz`.
+func scanArm64(data []byte, order binary.ByteOrder) []int {
+	const (
+		svc    = 0xd4000001
+		window = 8
+	)
+
+	var ret []int
+	for i := 0; i+4 <= len(data); i += 4 {
+		if order.Uint32(data[i:]) != svc {
+			continue
+		}
+
+		for j := i - 4; j >= 0 && j >= i-window*4; j -= 4 {
+			word := order.Uint32(data[j:])
+
+			// movz w8/x8, #imm16 without shift
+			if word&0x7fe0001f != 0x52800008 {
+				continue
+			}
+
+			ret = append(ret, int((word>>5)&0xffff))
+			break
+		}
+	}
+
+	return ret
+}
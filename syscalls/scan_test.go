@@ -0,0 +1,148 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package syscalls
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		t.Skip("the test object is built for x86_64")
+	}
+
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc is not available")
+	}
+
+	dir := t.TempDir()
+	source := filepath.Join(dir, "main.c")
+	object := filepath.Join(dir, "main.o")
+
+	if err := os.WriteFile(source, []byte(`
+int open(const char *path, int flags, ...);
+long read(int fd, void *buf, unsigned long count);
+int __libc_fork(void);
+int sleep(unsigned int seconds);
+
+int main(void)
+{
+	char buf[1];
+	int fd = open("/dev/zero", 0);
+
+	read(fd, buf, sizeof(buf));
+	sleep(__libc_fork());
+
+	/* getpid(2) */
+	__asm__ volatile("mov $39, %%eax; syscall" ::: "rax", "rcx", "r11", "memory");
+
+	return 0;
+}
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, err := exec.Command("gcc", "-c", "-O0", "-o", object, source).CombinedOutput(); err != nil {
+		t.Fatalf("could not compile: %v: %s", err, out)
+	}
+
+	usage, err := Scan(object)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if usage.Arch != ArchX86_64 {
+		t.Errorf("expected %s, got %s", ArchX86_64, usage.Arch)
+	}
+
+	expected := map[string][]string{
+		"fork":      {"__libc_fork"},
+		"getpid":    {SourceInstruction},
+		"nanosleep": {"sleep"},
+		"open":      {"open"},
+		"read":      {"read"},
+	}
+
+	if !reflect.DeepEqual(usage.Syscalls, expected) {
+		t.Errorf("expected %v, got %v", expected, usage.Syscalls)
+	}
+}
+
+func TestFromSymbol(t *testing.T) {
+	x86_64, arm64 := map[string]bool{}, map[string]bool{}
+	for _, name := range numbersX86_64 {
+		x86_64[name] = true
+	}
+
+	for _, name := range numbersArm64 {
+		arm64[name] = true
+	}
+
+	tests := []struct {
+		symbol string
+		known  map[string]bool
+		expect []string
+	}{
+		{"read", x86_64, []string{"read"}},
+		{"read@GLIBC_2.2.5", x86_64, []string{"read"}},
+		{"__read_nocancel", x86_64, []string{"read"}},
+		{"open", x86_64, []string{"open"}},
+		{"open64", arm64, []string{"openat"}},
+		{"pread64", x86_64, []string{"pread64"}},
+		{"remove", arm64, []string{"unlinkat"}},
+		{"pthread_create", arm64, []string{"clone", "mmap", "mprotect"}},
+		{"printf", x86_64, nil},
+	}
+
+	for _, test := range tests {
+		if got := fromSymbol(test.symbol, test.known); !reflect.DeepEqual(got, test.expect) {
+			t.Errorf("%s: expected %v, got %v", test.symbol, test.expect, got)
+		}
+	}
+}
+
+func TestScanStaticPIE(t *testing.T) {
+	if runtime.GOARCH != "amd64" {
+		t.Skip("the test executable is built for x86_64")
+	}
+
+	if _, err := exec.LookPath("gcc"); err != nil {
+		t.Skip("gcc is not available")
+	}
+
+	dir := t.TempDir()
+	source := filepath.Join(dir, "main.c")
+	executable := filepath.Join(dir, "main")
+
+	if err := os.WriteFile(source, []byte(`
+int getpid(void) { return 0; }
+
+void _start(void)
+{
+	getpid();
+	for (;;);
+}
+`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if out, err := exec.Command("gcc", "-O0", "-fPIE", "-static-pie", "-nostdlib", "-o", executable, source).CombinedOutput(); err != nil {
+		t.Skipf("could not link a static-pie executable: %v: %s", err, out)
+	}
+
+	usage, err := Scan(executable)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sources := usage.Syscalls["getpid"]; !reflect.DeepEqual(sources, []string{"getpid"}) {
+		t.Errorf("expected the defined wrapper of getpid, got %v", usage.Syscalls)
+	}
+}
@@ -0,0 +1,149 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package syscalls
+
+import "strings"
+
+// wrappers maps libc functions to the syscalls they invoke, where these differ
+// from the name of the function or do not exist on all architectures.  Of
+// alternatives separated by `|`, the first which exists on the architecture is
+// invoked, e.g. `open` is implemented with `openat` on arm64.  Functions which
+// share the name of their syscall, e.g. `read`, need not be listed.
+var wrappers = map[string][]string{
+	"access":          {"access|faccessat"},
+	"alarm":           {"alarm|setitimer"},
+	"chmod":           {"chmod|fchmodat"},
+	"chown":           {"chown|fchownat"},
+	"clock":           {"clock_gettime"},
+	"creat":           {"creat|openat"},
+	"daemon":          {"fork|clone", "setsid"},
+	"dup2":            {"dup2|dup3"},
+	"epoll_create":    {"epoll_create|epoll_create1"},
+	"epoll_wait":      {"epoll_wait|epoll_pwait"},
+	"fdopendir":       {"fstat"},
+	"fopen":           {"open|openat"},
+	"fork":            {"fork|clone"},
+	"fstatat":         {"newfstatat"},
+	"getaddrinfo":     {"socket", "connect", "sendto", "recvfrom"},
+	"getdents":        {"getdents|getdents64"},
+	"getentropy":      {"getrandom"},
+	"gethostbyname":   {"socket", "connect", "sendto", "recvfrom"},
+	"getrlimit":       {"getrlimit|prlimit64"},
+	"inotify_init":    {"inotify_init|inotify_init1"},
+	"isatty":          {"ioctl"},
+	"link":            {"link|linkat"},
+	"lstat":           {"lstat|newfstatat"},
+	"malloc":          {"brk", "mmap"},
+	"mkdir":           {"mkdir|mkdirat"},
+	"mkfifo":          {"mknod|mknodat"},
+	"nanosleep":       {"nanosleep|clock_nanosleep"},
+	"open":            {"open|openat"},
+	"opendir":         {"open|openat"},
+	"pause":           {"pause|ppoll"},
+	"pipe":            {"pipe|pipe2"},
+	"poll":            {"poll|ppoll"},
+	"posix_fadvise":   {"fadvise64"},
+	"posix_spawn":     {"clone", "execve"},
+	"pread":           {"pread64"},
+	"pselect":         {"pselect6"},
+	"pthread_create":  {"clone", "mmap", "mprotect"},
+	"pthread_kill":    {"tgkill"},
+	"pthread_sigmask": {"rt_sigprocmask"},
+	"pwrite":          {"pwrite64"},
+	"raise":           {"gettid", "tgkill"},
+	"readdir":         {"getdents64"},
+	"readlink":        {"readlink|readlinkat"},
+	"remove":          {"unlink|unlinkat", "rmdir|unlinkat"},
+	"rename":          {"rename|renameat|renameat2"},
+	"rmdir":           {"rmdir|unlinkat"},
+	"select":          {"select|pselect6"},
+	"setrlimit":       {"setrlimit|prlimit64"},
+	"sigaction":       {"rt_sigaction"},
+	"signal":          {"rt_sigaction"},
+	"sigpending":      {"rt_sigpending"},
+	"sigprocmask":     {"rt_sigprocmask"},
+	"sigsuspend":      {"rt_sigsuspend"},
+	"sigtimedwait":    {"rt_sigtimedwait"},
+	"sigwait":         {"rt_sigtimedwait"},
+	"sleep":           {"nanosleep|clock_nanosleep"},
+	"stat":            {"stat|newfstatat"},
+	"symlink":         {"symlink|symlinkat"},
+	"sysconf":         {"sched_getaffinity", "prlimit64"},
+	"system":          {"clone", "execve", "wait4"},
+	"tcgetattr":       {"ioctl"},
+	"tcsetattr":       {"ioctl"},
+	"time":            {"time|clock_gettime"},
+	"ttyname":         {"readlink|readlinkat", "ioctl"},
+	"unlink":          {"unlink|unlinkat"},
+	"usleep":          {"nanosleep|clock_nanosleep"},
+	"utime":           {"utime|utimensat"},
+	"utimes":          {"utimes|utimensat"},
+	"vfork":           {"vfork|clone"},
+	"wait":            {"wait4"},
+	"waitpid":         {"wait4"},
+}
+
+// fromSymbol returns the syscalls which the libc function with the provided
+// name invokes, if it is a wrapper of any, for the architecture whose
+// syscalls are known by the provided names.  Variants of the function, e.g.
+// `__libc_read`, `read64` or `__read_nocancel`, are recognized.
+func fromSymbol(symbol string, known map[string]bool) []string {
+	name := symbol
+
+	// Versioned symbols of dynamically linked binaries, e.g. `read@GLIBC_2.2.5`
+	name, _, _ = strings.Cut(name, "@")
+
+	for _, prefix := range []string{"__libc_", "__sys_", "__"} {
+		name = strings.TrimPrefix(name, prefix)
+	}
+
+	name = strings.TrimSuffix(name, "_nocancel")
+	name = strings.TrimSuffix(name, "_chk")
+
+	if syscalls, ok := wrappers[name]; ok {
+		return filterKnown(syscalls, known)
+	}
+
+	if known[name] {
+		return []string{name}
+	}
+
+	// Large file support variants, e.g. `open64` or `fstat64`, unless the
+	// syscall itself has the suffix, e.g. `pread64`
+	if base := strings.TrimSuffix(name, "64"); base != name {
+		if syscalls, ok := wrappers[base]; ok {
+			return filterKnown(syscalls, known)
+		}
+
+		if known[base] {
+			return []string{base}
+		}
+	}
+
+	return nil
+}
+
+// filterKnown returns the syscalls, of each the first alternative, which exist
+// on the architecture.
+func filterKnown(syscalls []string, known map[string]bool) []string {
+	var ret []string
+	seen := map[string]bool{}
+	for _, alternatives := range syscalls {
+		for _, syscall := range strings.Split(alternatives, "|") {
+			if !known[syscall] {
+				continue
+			}
+
+			if !seen[syscall] {
+				seen[syscall] = true
+				ret = append(ret, syscall)
+			}
+
+			break
+		}
+	}
+
+	return ret
+}
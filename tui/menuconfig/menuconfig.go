@@ -0,0 +1,447 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package menuconfig
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"golang.org/x/term"
+
+	"kraftkit.sh/kconfig"
+)
+
+type mode uint
+
+const (
+	modeBrowse mode = iota
+	modeEdit
+	modeSearch
+	modeHelp
+	modeConfirm
+)
+
+// level is a menu which the user has navigated into.
+type level struct {
+	menu   *kconfig.KConfigMenu
+	cursor int
+	offset int
+}
+
+// MenuConfig is an interactive editor of the .config of a KConfig tree which
+// evaluates the visibility and dependencies of its configs as they change.
+type MenuConfig struct {
+	tree     *kconfig.KConfigFile
+	config   *kconfig.DotConfigFile
+	output   string
+	title    string
+	eval     *kconfig.Evaluator
+	stack    []*level
+	entries  []*kconfig.KConfigMenu
+	results  []*kconfig.KConfigMenu
+	cursor   int
+	offset   int
+	input    textinput.Model
+	mode     mode
+	message  string
+	width    int
+	height   int
+	modified bool
+	saved    bool
+	quitting bool
+	err      error
+}
+
+// NewMenuConfig prepares an editor of the provided configuration of the tree
+// which, when saved, is written to the output path.
+func NewMenuConfig(tree *kconfig.KConfigFile, config *kconfig.DotConfigFile, output string, opts ...MenuConfigOption) (*MenuConfig, error) {
+	if tree == nil || tree.Root == nil {
+		return nil, fmt.Errorf("cannot configure an empty KConfig tree")
+	}
+
+	if config == nil {
+		config, _ = kconfig.ParseConfigData(nil)
+	}
+
+	mc := &MenuConfig{
+		tree:   tree,
+		config: config,
+		output: output,
+		title:  tree.Root.Prompt(),
+		eval:   tree.NewEvaluator(config),
+		stack:  []*level{{menu: tree.Root}},
+		input:  textinput.New(),
+	}
+
+	for _, opt := range opts {
+		if err := opt(mc); err != nil {
+			return nil, err
+		}
+	}
+
+	mc.refresh()
+
+	return mc, nil
+}
+
+// Start runs the editor until the user quits it.
+func (mc *MenuConfig) Start() error {
+	// Set this before bubbletea does such that the first frame is rendered at
+	// the right size.
+	mc.width, mc.height, _ = term.GetSize(int(os.Stdout.Fd()))
+
+	if _, err := tea.NewProgram(mc, tea.WithAltScreen()).Run(); err != nil {
+		return err
+	}
+
+	return mc.err
+}
+
+// Saved returns whether the configuration was written to the output path.
+func (mc *MenuConfig) Saved() bool {
+	return mc.saved
+}
+
+func (mc *MenuConfig) Init() tea.Cmd {
+	return nil
+}
+
+// top returns the menu which is currently shown.
+func (mc *MenuConfig) top() *level {
+	return mc.stack[len(mc.stack)-1]
+}
+
+// current returns the entry under the cursor, if any.
+func (mc *MenuConfig) current() *kconfig.KConfigMenu {
+	list, cursor := mc.entries, mc.top().cursor
+	if mc.mode == modeSearch {
+		list, cursor = mc.results, mc.cursor
+	}
+
+	if cursor < 0 || cursor >= len(list) {
+		return nil
+	}
+
+	return list[cursor]
+}
+
+// refresh re-evaluates the configuration and the entries of the current menu.
+func (mc *MenuConfig) refresh() {
+	mc.eval = mc.tree.NewEvaluator(mc.config)
+	mc.entries = mc.list(mc.top().menu)
+
+	if top := mc.top(); top.cursor >= len(mc.entries) {
+		top.cursor = len(mc.entries) - 1
+	}
+	if mc.top().cursor < 0 {
+		mc.top().cursor = 0
+	}
+
+	if mc.mode == modeSearch {
+		mc.search()
+	}
+}
+
+// list returns the visible entries of the menu.  Entries which only group
+// others without being shown themselves, e.g. `if` blocks or the invisible
+// config of an application, are replaced by their own entries.
+func (mc *MenuConfig) list(menu *kconfig.KConfigMenu) []*kconfig.KConfigMenu {
+	var entries []*kconfig.KConfigMenu
+	for _, elem := range menu.Elems {
+		if mc.isFlattened(elem) {
+			entries = append(entries, mc.list(elem)...)
+		} else if mc.eval.IsVisible(elem) {
+			entries = append(entries, elem)
+		}
+	}
+
+	return entries
+}
+
+// isFlattened returns whether the entries of the menu are shown in place of
+// the menu itself, which is the case for `if` blocks, the main menu of a tree
+// which was merged with others and configs without a prompt.
+func (mc *MenuConfig) isFlattened(m *kconfig.KConfigMenu) bool {
+	switch m.Kind {
+	case kconfig.MenuGroup:
+		return m.Prompt() == ""
+	case kconfig.MenuConfig:
+		return len(m.Name) == 0 || (len(m.Elems) > 0 && !mc.eval.IsVisible(m))
+	}
+
+	return false
+}
+
+// isMenu returns whether the entry can be navigated into.
+func (mc *MenuConfig) isMenu(m *kconfig.KConfigMenu) bool {
+	switch m.Kind {
+	case kconfig.MenuGroup, kconfig.MenuChoice:
+		return true
+	case kconfig.MenuConfig:
+		// A `menuconfig` with visible entries.
+		return len(mc.list(m)) > 0
+	}
+
+	return false
+}
+
+// isChoiceMember returns whether the config is one of the options of a choice.
+func isChoiceMember(m *kconfig.KConfigMenu) bool {
+	return m.Parent != nil && m.Parent.Kind == kconfig.MenuChoice
+}
+
+// enter navigates into the menu.
+func (mc *MenuConfig) enter(m *kconfig.KConfigMenu) {
+	mc.stack = append(mc.stack, &level{menu: m})
+	mc.refresh()
+
+	// Place the cursor on the option of a choice which is selected.
+	if m.Kind == kconfig.MenuChoice {
+		selected := mc.eval.Choice(m)
+		for i, entry := range mc.entries {
+			if entry == selected {
+				mc.top().cursor = i
+			}
+		}
+	}
+}
+
+// back navigates out of the current menu and returns whether it could.
+func (mc *MenuConfig) back() bool {
+	if len(mc.stack) == 1 {
+		return false
+	}
+
+	mc.stack = mc.stack[:len(mc.stack)-1]
+	mc.refresh()
+	return true
+}
+
+// jump navigates to the menu which shows the config and places the cursor on
+// it.
+func (mc *MenuConfig) jump(m *kconfig.KConfigMenu) {
+	var ancestors []*kconfig.KConfigMenu
+	for parent := m.Parent; parent != nil && parent != mc.tree.Root; parent = parent.Parent {
+		ancestors = append([]*kconfig.KConfigMenu{parent}, ancestors...)
+	}
+
+	mc.stack = mc.stack[:1]
+	mc.refresh()
+
+	for _, ancestor := range ancestors {
+		if !mc.isFlattened(ancestor) && mc.isMenu(ancestor) {
+			mc.enter(ancestor)
+		}
+	}
+
+	for i, entry := range mc.entries {
+		if entry == m {
+			mc.top().cursor = i
+			return
+		}
+	}
+
+	mc.message = fmt.Sprintf("%s is not visible", m.Name)
+}
+
+// set changes the value of the config and re-evaluates the configuration.
+func (mc *MenuConfig) set(m *kconfig.KConfigMenu, value kconfig.Tristate) {
+	if value < mc.eval.Tristate(m.Name) {
+		if by := mc.eval.SelectedBy(m.Name); len(by) > 0 {
+			mc.message = fmt.Sprintf("%s is selected by %s", m.Name, strings.Join(by, ", "))
+			return
+		}
+	}
+
+	if m.Type == kconfig.TypeBool && value == kconfig.TristateMod {
+		value = kconfig.TristateYes
+	}
+
+	if isChoiceMember(m) {
+		if value == kconfig.TristateNo {
+			return
+		}
+
+		for _, elem := range m.Parent.Elems {
+			if elem.Kind == kconfig.MenuConfig && elem != m {
+				mc.config.Set(elem.Name, kconfig.No)
+			}
+		}
+	}
+
+	if value == kconfig.TristateNo {
+		mc.config.Set(m.Name, kconfig.No)
+	} else {
+		mc.config.Set(m.Name, value.String())
+	}
+
+	mc.modified = true
+	mc.refresh()
+}
+
+// toggle changes the value of a bool or tristate config to the next one.
+func (mc *MenuConfig) toggle(m *kconfig.KConfigMenu) {
+	switch mc.eval.Tristate(m.Name) {
+	case kconfig.TristateYes:
+		mc.set(m, kconfig.TristateNo)
+	case kconfig.TristateMod:
+		mc.set(m, kconfig.TristateYes)
+	default:
+		if m.Type == kconfig.TypeTristate {
+			mc.set(m, kconfig.TristateMod)
+		} else {
+			mc.set(m, kconfig.TristateYes)
+		}
+	}
+}
+
+// edit prompts for the value of a string, int or hex config.
+func (mc *MenuConfig) edit(m *kconfig.KConfigMenu) tea.Cmd {
+	mc.mode = modeEdit
+	mc.input.Prompt = m.Name + ": "
+	mc.input.SetValue(unquote(mc.eval.Value(m.Name)))
+	mc.input.CursorEnd()
+	return mc.input.Focus()
+}
+
+// commit sets the value of the config which is being edited.
+func (mc *MenuConfig) commit(m *kconfig.KConfigMenu, value string) error {
+	value = strings.TrimSpace(value)
+
+	switch m.Type {
+	case kconfig.TypeInt:
+		if _, err := strconv.ParseInt(value, 10, 64); err != nil {
+			return fmt.Errorf("%s is not an integer", value)
+		}
+
+	case kconfig.TypeHex:
+		if !strings.HasPrefix(strings.ToLower(value), "0x") {
+			value = "0x" + value
+		}
+		if _, err := strconv.ParseUint(value[2:], 16, 64); err != nil {
+			return fmt.Errorf("%s is not a hexadecimal number", value)
+		}
+
+	default:
+		value = strconv.Quote(value)
+	}
+
+	if lo, hi, ok := mc.eval.Range(m); ok && !inRange(value, lo, hi) {
+		return fmt.Errorf("%s is not within the range of %s to %s", value, lo, hi)
+	}
+
+	mc.config.Set(m.Name, value)
+	mc.modified = true
+	mc.refresh()
+
+	return nil
+}
+
+// search lists the configs whose name or prompt contain the query.
+func (mc *MenuConfig) search() {
+	query := strings.ToLower(strings.TrimSpace(mc.input.Value()))
+	mc.results = nil
+
+	if len(query) > 0 {
+		var walk func(*kconfig.KConfigMenu)
+		walk = func(m *kconfig.KConfigMenu) {
+			if m.Kind == kconfig.MenuConfig && len(m.Name) > 0 && m.Type > 0 &&
+				(strings.Contains(strings.ToLower(m.Name), query) ||
+					strings.Contains(strings.ToLower(m.Prompt()), query)) {
+				mc.results = append(mc.results, m)
+			}
+
+			for _, elem := range m.Elems {
+				walk(elem)
+			}
+		}
+
+		walk(mc.tree.Root)
+	}
+
+	if mc.cursor >= len(mc.results) {
+		mc.cursor = len(mc.results) - 1
+	}
+	if mc.cursor < 0 {
+		mc.cursor = 0
+	}
+}
+
+// save writes the effective value of every config whose dependencies are met
+// to the output path, as a .config generated by Unikraft's own menuconfig
+// would.
+func (mc *MenuConfig) save() error {
+	resolved := mc.config.Clone()
+
+	var walk func(*kconfig.KConfigMenu)
+	walk = func(m *kconfig.KConfigMenu) {
+		if m.Kind == kconfig.MenuConfig && len(m.Name) > 0 && m.Type > 0 {
+			switch value := mc.eval.Value(m.Name); value {
+			case "":
+			case kconfig.TristateNo.String():
+				resolved.Set(m.Name, kconfig.No)
+			default:
+				resolved.Set(m.Name, value)
+			}
+		}
+
+		for _, elem := range m.Elems {
+			walk(elem)
+		}
+	}
+
+	walk(mc.tree.Root)
+
+	if err := os.WriteFile(mc.output, resolved.Serialize(), 0o644); err != nil {
+		return fmt.Errorf("could not save configuration: %v", err)
+	}
+
+	mc.modified = false
+	mc.saved = true
+	mc.message = fmt.Sprintf("saved to %s", mc.output)
+
+	return nil
+}
+
+// inRange returns whether the int or hex value is within the bounds, which are
+// ignored if they are not numbers themselves.
+func inRange(value, lo, hi string) bool {
+	v, err := parseNumber(value)
+	if err != nil {
+		return false
+	}
+
+	if l, err := parseNumber(lo); err == nil && v < l {
+		return false
+	}
+
+	if h, err := parseNumber(hi); err == nil && v > h {
+		return false
+	}
+
+	return true
+}
+
+// parseNumber parses the value of an int or hex config, where only the latter
+// is prefixed with `0x` and leading zeros do not denote an octal number.
+func parseNumber(value string) (int64, error) {
+	if len(value) > 2 && strings.EqualFold(value[:2], "0x") {
+		return strconv.ParseInt(value[2:], 16, 64)
+	}
+
+	return strconv.ParseInt(value, 10, 64)
+}
+
+func unquote(value string) string {
+	if s, err := strconv.Unquote(value); err == nil {
+		return s
+	}
+
+	return value
+}
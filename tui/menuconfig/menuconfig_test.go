@@ -0,0 +1,259 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package menuconfig
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"kraftkit.sh/kconfig"
+)
+
+func newTestMenuConfig(t *testing.T, opts ...MenuConfigOption) *MenuConfig {
+	t.Helper()
+
+	tree, err := kconfig.ParseData([]byte(`
+mainmenu "Test"
+
+config LIBFOO
+	bool "foo"
+	default y
+
+if LIBFOO
+config LIBFOO_STACK
+	int "Stack size"
+	range 1024 65536
+	default 4096
+
+config LIBFOO_ADDR
+	hex "Address"
+	default 0x1000
+
+config LIBFOO_NAME
+	string "Name"
+	default "foo"
+endif
+
+config LIBBAR
+	bool "bar"
+`), "Config.uk")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mc, err := NewMenuConfig(tree, nil, filepath.Join(t.TempDir(), ".config"), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return mc
+}
+
+// press sends the keys to the editor, where keys which are not named are typed
+// as text.
+func press(mc *MenuConfig, keys ...string) {
+	named := map[string]tea.KeyType{
+		"enter":  tea.KeyEnter,
+		"esc":    tea.KeyEsc,
+		"up":     tea.KeyUp,
+		"down":   tea.KeyDown,
+		"ctrl+u": tea.KeyCtrlU,
+	}
+
+	for _, k := range keys {
+		msg := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+		if typ, ok := named[k]; ok {
+			msg = tea.KeyMsg{Type: typ}
+		}
+
+		mc.Update(msg)
+	}
+}
+
+// cursorTo moves the cursor of the current menu onto the config.
+func cursorTo(t *testing.T, mc *MenuConfig, name string) {
+	t.Helper()
+
+	press(mc, "home")
+
+	for i := 0; i < len(mc.entries); i++ {
+		if entry := mc.current(); entry != nil && entry.Name == name {
+			return
+		}
+
+		press(mc, "down")
+	}
+
+	t.Fatalf("%s is not visible", name)
+}
+
+func TestMenuConfigToggle(t *testing.T) {
+	mc := newTestMenuConfig(t)
+
+	cursorTo(t, mc, "LIBFOO")
+	press(mc, " ")
+
+	if value := mc.eval.Value("LIBFOO"); value != "n" {
+		t.Errorf("expected LIBFOO to be disabled but is %q", value)
+	}
+
+	if !mc.modified {
+		t.Errorf("expected the configuration to be modified")
+	}
+
+	for _, entry := range mc.entries {
+		if entry.Name == "LIBFOO_STACK" {
+			t.Errorf("expected LIBFOO_STACK to be hidden once LIBFOO is disabled")
+		}
+	}
+
+	press(mc, "y")
+
+	if value := mc.eval.Value("LIBFOO"); value != "y" {
+		t.Errorf("expected LIBFOO to be enabled but is %q", value)
+	}
+
+	cursorTo(t, mc, "LIBFOO_STACK")
+}
+
+func TestMenuConfigEdit(t *testing.T) {
+	mc := newTestMenuConfig(t)
+
+	cursorTo(t, mc, "LIBFOO_STACK")
+	press(mc, "enter")
+
+	if mc.mode != modeEdit {
+		t.Fatalf("expected to edit LIBFOO_STACK")
+	}
+
+	if value := mc.input.Value(); value != "4096" {
+		t.Errorf("expected to edit the current value but got %q", value)
+	}
+
+	for _, tc := range []struct {
+		input   string
+		message string
+	}{
+		{"big", "big is not an integer"},
+		{"512", "512 is not within the range of 1024 to 65536"},
+		{"131072", "131072 is not within the range of 1024 to 65536"},
+		{"0100000", "0100000 is not within the range of 1024 to 65536"},
+	} {
+		press(mc, "ctrl+u", tc.input, "enter")
+
+		if mc.mode != modeEdit {
+			t.Errorf("%s: expected to keep editing", tc.input)
+		}
+
+		if mc.message != tc.message {
+			t.Errorf("%s: expected message %q but got %q", tc.input, tc.message, mc.message)
+		}
+
+		if value := mc.eval.Value("LIBFOO_STACK"); value != "4096" {
+			t.Errorf("%s: expected value to be unchanged but is %q", tc.input, value)
+		}
+	}
+
+	press(mc, "ctrl+u", "8192", "enter")
+
+	if mc.mode != modeBrowse {
+		t.Errorf("expected to stop editing once committed")
+	}
+
+	if value := mc.eval.Value("LIBFOO_STACK"); value != "8192" {
+		t.Errorf("expected LIBFOO_STACK to be 8192 but is %q", value)
+	}
+
+	cursorTo(t, mc, "LIBFOO_ADDR")
+	press(mc, "enter", "ctrl+u", "2000", "enter")
+
+	if value := mc.eval.Value("LIBFOO_ADDR"); value != "0x2000" {
+		t.Errorf("expected LIBFOO_ADDR to be 0x2000 but is %q", value)
+	}
+
+	cursorTo(t, mc, "LIBFOO_NAME")
+	press(mc, "enter", "ctrl+u", "bar", "esc")
+
+	if value := mc.eval.Value("LIBFOO_NAME"); value != `"foo"` {
+		t.Errorf("expected canceled edit to keep LIBFOO_NAME but is %q", value)
+	}
+}
+
+func TestMenuConfigSearch(t *testing.T) {
+	mc := newTestMenuConfig(t)
+
+	press(mc, "/", "stack")
+
+	if mc.mode != modeSearch {
+		t.Fatalf("expected to search")
+	}
+
+	if len(mc.results) != 1 || mc.results[0].Name != "LIBFOO_STACK" {
+		t.Fatalf("expected to find LIBFOO_STACK but got %v", mc.results)
+	}
+
+	press(mc, "enter")
+
+	if mc.mode != modeBrowse {
+		t.Errorf("expected to browse once a result is chosen")
+	}
+
+	if entry := mc.current(); entry == nil || entry.Name != "LIBFOO_STACK" {
+		t.Errorf("expected the cursor to be on LIBFOO_STACK but is on %v", entry)
+	}
+
+	mc = newTestMenuConfig(t, WithSearch("LIB"))
+
+	if mc.mode != modeSearch || len(mc.results) != 5 {
+		t.Errorf("expected to start searching with 5 results but got %d", len(mc.results))
+	}
+
+	press(mc, "esc")
+
+	if mc.mode != modeBrowse {
+		t.Errorf("expected to stop searching")
+	}
+}
+
+func TestMenuConfigSave(t *testing.T) {
+	mc := newTestMenuConfig(t)
+
+	cursorTo(t, mc, "LIBFOO_STACK")
+	press(mc, "enter", "ctrl+u", "8192", "enter")
+
+	// Quitting with changes asks whether to save them first
+	press(mc, "q")
+
+	if mc.mode != modeConfirm {
+		t.Fatalf("expected to confirm saving the changes")
+	}
+
+	press(mc, "y")
+
+	if !mc.Saved() || !mc.quitting {
+		t.Fatalf("expected to save and quit: %s", mc.message)
+	}
+
+	data, err := os.ReadFile(mc.output)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range []string{
+		"CONFIG_LIBFOO=y\n",
+		"CONFIG_LIBFOO_STACK=8192\n",
+		"CONFIG_LIBFOO_ADDR=0x1000\n",
+		"CONFIG_LIBFOO_NAME=\"foo\"\n",
+		"# CONFIG_LIBBAR is not set\n",
+	} {
+		if !strings.Contains(string(data), line) {
+			t.Errorf("expected %q in:\n%s", line, data)
+		}
+	}
+}
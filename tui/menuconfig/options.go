@@ -0,0 +1,32 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package menuconfig
+
+type MenuConfigOption func(mc *MenuConfig) error
+
+// WithTitle replaces the title of the editor, which is otherwise the prompt of
+// the root of the tree.
+func WithTitle(title string) MenuConfigOption {
+	return func(mc *MenuConfig) error {
+		mc.title = title
+		return nil
+	}
+}
+
+// WithSearch starts the editor searching for the provided query.
+func WithSearch(query string) MenuConfigOption {
+	return func(mc *MenuConfig) error {
+		if len(query) == 0 {
+			return nil
+		}
+
+		mc.mode = modeSearch
+		mc.input.Prompt = "/"
+		mc.input.SetValue(query)
+		mc.input.CursorEnd()
+		mc.input.Focus()
+		return nil
+	}
+}
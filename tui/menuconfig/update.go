@@ -0,0 +1,248 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package menuconfig
+
+import (
+	"fmt"
+
+	tea "github.com/charmbracelet/bubbletea"
+
+	"kraftkit.sh/kconfig"
+)
+
+func (mc *MenuConfig) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		mc.width = msg.Width
+		mc.height = msg.Height
+		return mc, nil
+
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			mc.quitting = true
+			mc.err = fmt.Errorf("force quit")
+			return mc, tea.Quit
+		}
+
+		mc.message = ""
+
+		switch mc.mode {
+		case modeEdit:
+			return mc.updateEdit(msg)
+		case modeSearch:
+			return mc.updateSearch(msg)
+		case modeHelp:
+			mc.mode = modeBrowse
+			return mc, nil
+		case modeConfirm:
+			return mc.updateConfirm(msg)
+		}
+
+		return mc.updateBrowse(msg)
+	}
+
+	return mc, nil
+}
+
+func (mc *MenuConfig) updateBrowse(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	top := mc.top()
+	entry := mc.current()
+
+	switch msg.String() {
+	case "up", "k":
+		top.cursor--
+	case "down", "j":
+		top.cursor++
+	case "pgup":
+		top.cursor -= mc.pageSize()
+	case "pgdown":
+		top.cursor += mc.pageSize()
+	case "home", "g":
+		top.cursor = 0
+	case "end", "G":
+		top.cursor = len(mc.entries) - 1
+
+	case "left", "h", "esc", "backspace":
+		if !mc.back() && msg.String() == "esc" {
+			return mc.quit()
+		}
+
+	case "right", "l", "enter":
+		if entry == nil {
+			break
+		}
+
+		if mc.isMenu(entry) {
+			mc.enter(entry)
+		} else if entry.Kind == kconfig.MenuConfig {
+			switch entry.Type {
+			case kconfig.TypeBool, kconfig.TypeTristate:
+				if isChoiceMember(entry) {
+					mc.set(entry, kconfig.TristateYes)
+					mc.back()
+				} else {
+					mc.toggle(entry)
+				}
+			default:
+				return mc, mc.edit(entry)
+			}
+		}
+
+	case " ":
+		if entry != nil && entry.Kind == kconfig.MenuConfig {
+			switch entry.Type {
+			case kconfig.TypeBool, kconfig.TypeTristate:
+				if isChoiceMember(entry) {
+					mc.set(entry, kconfig.TristateYes)
+				} else {
+					mc.toggle(entry)
+				}
+			case kconfig.TypeString, kconfig.TypeInt, kconfig.TypeHex:
+				return mc, mc.edit(entry)
+			}
+		}
+
+	case "y", "m", "n":
+		if entry != nil && entry.Kind == kconfig.MenuConfig &&
+			(entry.Type == kconfig.TypeBool || entry.Type == kconfig.TypeTristate) {
+			values := map[string]kconfig.Tristate{
+				"y": kconfig.TristateYes,
+				"m": kconfig.TristateMod,
+				"n": kconfig.TristateNo,
+			}
+
+			mc.set(entry, values[msg.String()])
+		}
+
+	case "/":
+		mc.mode = modeSearch
+		mc.cursor = 0
+		mc.input.Prompt = "/"
+		mc.input.SetValue("")
+		mc.search()
+		return mc, mc.input.Focus()
+
+	case "?":
+		if entry != nil {
+			mc.mode = modeHelp
+		}
+
+	case "s":
+		if err := mc.save(); err != nil {
+			mc.message = err.Error()
+		}
+
+	case "q":
+		return mc.quit()
+	}
+
+	mc.clamp()
+	return mc, nil
+}
+
+func (mc *MenuConfig) updateEdit(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		mc.mode = modeBrowse
+		mc.input.Blur()
+		return mc, nil
+
+	case "enter":
+		if err := mc.commit(mc.current(), mc.input.Value()); err != nil {
+			mc.message = err.Error()
+			return mc, nil
+		}
+
+		mc.mode = modeBrowse
+		mc.input.Blur()
+		return mc, nil
+	}
+
+	var cmd tea.Cmd
+	mc.input, cmd = mc.input.Update(msg)
+	return mc, cmd
+}
+
+func (mc *MenuConfig) updateSearch(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		mc.mode = modeBrowse
+		mc.input.Blur()
+		return mc, nil
+
+	case "up":
+		mc.cursor--
+		mc.clamp()
+		return mc, nil
+
+	case "down":
+		mc.cursor++
+		mc.clamp()
+		return mc, nil
+
+	case "enter":
+		entry := mc.current()
+		mc.mode = modeBrowse
+		mc.input.Blur()
+
+		if entry != nil {
+			mc.jump(entry)
+		}
+
+		return mc, nil
+	}
+
+	var cmd tea.Cmd
+	mc.input, cmd = mc.input.Update(msg)
+	mc.search()
+	return mc, cmd
+}
+
+func (mc *MenuConfig) updateConfirm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "y", "enter":
+		if err := mc.save(); err != nil {
+			mc.mode = modeBrowse
+			mc.message = err.Error()
+			return mc, nil
+		}
+
+		mc.quitting = true
+		return mc, tea.Quit
+
+	case "n":
+		mc.quitting = true
+		return mc, tea.Quit
+	}
+
+	mc.mode = modeBrowse
+	return mc, nil
+}
+
+// quit exits the editor, asking whether to save any changes first.
+func (mc *MenuConfig) quit() (tea.Model, tea.Cmd) {
+	if mc.modified {
+		mc.mode = modeConfirm
+		return mc, nil
+	}
+
+	mc.quitting = true
+	return mc, tea.Quit
+}
+
+// clamp keeps the cursor within the list which is shown.
+func (mc *MenuConfig) clamp() {
+	cursor, length := &mc.top().cursor, len(mc.entries)
+	if mc.mode == modeSearch {
+		cursor, length = &mc.cursor, len(mc.results)
+	}
+
+	if *cursor >= length {
+		*cursor = length - 1
+	}
+	if *cursor < 0 {
+		*cursor = 0
+	}
+}
@@ -0,0 +1,278 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package menuconfig
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
+	"github.com/muesli/reflow/wordwrap"
+
+	"kraftkit.sh/kconfig"
+	"kraftkit.sh/tui"
+)
+
+// chrome is the number of lines around the list of entries: the title and
+// breadcrumb, a blank line, the message or input and the key hints.
+const chrome = 5
+
+func (mc *MenuConfig) View() string {
+	if mc.quitting {
+		return ""
+	}
+
+	var s strings.Builder
+
+	s.WriteString(tui.TextTitle(mc.title) + "\n")
+	s.WriteString(tui.TextLightGray(mc.breadcrumb()) + "\n\n")
+
+	switch mc.mode {
+	case modeHelp:
+		s.WriteString(mc.viewHelp(mc.current()))
+
+	case modeSearch:
+		mc.viewList(&s, mc.results, mc.cursor, &mc.offset, mc.viewResult)
+
+	default:
+		mc.viewList(&s, mc.entries, mc.top().cursor, &mc.top().offset, mc.viewEntry)
+	}
+
+	s.WriteString("\n")
+
+	switch mc.mode {
+	case modeEdit, modeSearch:
+		s.WriteString(mc.input.View() + "\n")
+	case modeConfirm:
+		s.WriteString(tui.TextTitle("Save changes before quitting? (y/n)") + "\n")
+	default:
+		s.WriteString(mc.message + "\n")
+	}
+
+	s.WriteString(tui.TextLightGray(mc.hints()))
+
+	return s.String()
+}
+
+// pageSize returns the number of entries which fit on the screen.
+func (mc *MenuConfig) pageSize() int {
+	if size := mc.height - chrome; size > 0 {
+		return size
+	}
+
+	return 1
+}
+
+// viewList renders the entries which fit on the screen, scrolling such that
+// the one under the cursor is always shown.
+func (mc *MenuConfig) viewList(s *strings.Builder, entries []*kconfig.KConfigMenu, cursor int, offset *int, render func(*kconfig.KConfigMenu) string) {
+	size := mc.pageSize()
+
+	if cursor < *offset {
+		*offset = cursor
+	} else if cursor >= *offset+size {
+		*offset = cursor - size + 1
+	}
+	if *offset > len(entries)-size {
+		*offset = len(entries) - size
+	}
+	if *offset < 0 {
+		*offset = 0
+	}
+
+	lines := 0
+	for i := *offset; i < len(entries) && lines < size; i++ {
+		line := " " + render(entries[i])
+		if mc.width > 0 {
+			line = truncate.String(line, uint(mc.width))
+		}
+
+		if i == cursor {
+			if pad := mc.width - lipgloss.Width(line); pad > 0 {
+				line += strings.Repeat(" ", pad)
+			}
+
+			line = tui.TextBlue(line)
+		}
+
+		s.WriteString(line + "\n")
+		lines++
+	}
+
+	for ; lines < size; lines++ {
+		s.WriteString("\n")
+	}
+}
+
+// viewEntry renders an entry in the style of Unikraft's own menuconfig, e.g.
+// `[*] Enable foo`, `(4096) Stack size` or `Allocator (tlsf)  --->`.
+func (mc *MenuConfig) viewEntry(m *kconfig.KConfigMenu) string {
+	prompt := mc.eval.Prompt(m)
+
+	switch m.Kind {
+	case kconfig.MenuComment:
+		return "    *** " + prompt + " ***"
+
+	case kconfig.MenuGroup:
+		return "    " + prompt + "  --->"
+
+	case kconfig.MenuChoice:
+		if selected := mc.eval.Choice(m); selected != nil {
+			prompt += " (" + mc.eval.Prompt(selected) + ")"
+		}
+
+		return "    " + prompt + "  --->"
+	}
+
+	line := mc.viewValue(m) + " " + prompt + " " + tui.TextLightGray("("+m.Name+")")
+	if mc.isMenu(m) {
+		line += "  --->"
+	}
+
+	return line
+}
+
+// viewValue renders the value of the config as a checkbox, radio button or
+// text.
+func (mc *MenuConfig) viewValue(m *kconfig.KConfigMenu) string {
+	value := mc.eval.Value(m.Name)
+	selected := len(mc.eval.SelectedBy(m.Name)) > 0
+
+	switch m.Type {
+	case kconfig.TypeBool:
+		if isChoiceMember(m) {
+			if value == kconfig.Yes {
+				return "(X)"
+			}
+
+			return "( )"
+		}
+
+		switch {
+		case selected:
+			return "-*-"
+		case value == kconfig.Yes:
+			return "[*]"
+		}
+
+		return "[ ]"
+
+	case kconfig.TypeTristate:
+		switch {
+		case selected && value == kconfig.Yes:
+			return "-*-"
+		case selected:
+			return "-M-"
+		case value == kconfig.Yes:
+			return "<*>"
+		case value == kconfig.Mod:
+			return "<M>"
+		}
+
+		return "< >"
+	}
+
+	return "(" + unquote(value) + ")"
+}
+
+// viewResult renders a config which matches the search query.
+func (mc *MenuConfig) viewResult(m *kconfig.KConfigMenu) string {
+	line := m.Name
+	if prompt := m.Prompt(); len(prompt) > 0 {
+		line += " " + tui.TextLightGray(prompt)
+	}
+
+	if value := mc.eval.Value(m.Name); len(value) > 0 {
+		line += " = " + value
+	}
+
+	if !mc.eval.IsVisible(m) {
+		line += tui.TextLightGray(" (hidden)")
+	}
+
+	return line
+}
+
+// viewHelp renders the details of the entry.
+func (mc *MenuConfig) viewHelp(m *kconfig.KConfigMenu) string {
+	var s strings.Builder
+
+	if len(m.Name) > 0 {
+		fmt.Fprintf(&s, "%s %s\n", tui.TextTitle("Symbol:"), kconfig.Prefix+m.Name)
+	}
+	if prompt := m.Prompt(); len(prompt) > 0 {
+		fmt.Fprintf(&s, "%s %s\n", tui.TextTitle("Prompt:"), prompt)
+	}
+	if m.Type > 0 {
+		fmt.Fprintf(&s, "%s %s\n", tui.TextTitle("Type:"), m.Type)
+		fmt.Fprintf(&s, "%s %s\n", tui.TextTitle("Value:"), mc.eval.Value(m.Name))
+	}
+
+	if deps := m.DependsOn(); len(deps) > 0 {
+		var names []string
+		for name := range deps {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+		fmt.Fprintf(&s, "%s %s\n", tui.TextTitle("Depends on:"), strings.Join(names, ", "))
+	}
+
+	if by := mc.eval.SelectedBy(m.Name); len(m.Name) > 0 && len(by) > 0 {
+		sort.Strings(by)
+		fmt.Fprintf(&s, "%s %s\n", tui.TextTitle("Selected by:"), strings.Join(by, ", "))
+	}
+
+	if selects := m.Selects(); len(selects) > 0 {
+		fmt.Fprintf(&s, "%s %s\n", tui.TextTitle("Selects:"), strings.Join(selects, ", "))
+	}
+
+	help := m.Help()
+	if len(help) == 0 {
+		help = "There is no help available for this option."
+	}
+
+	width := mc.width
+	if width <= 0 {
+		width = 80
+	}
+
+	s.WriteString("\n" + wordwrap.String(help, width) + "\n")
+
+	return s.String()
+}
+
+// breadcrumb returns the prompts of the menus which the user has navigated
+// into.
+func (mc *MenuConfig) breadcrumb() string {
+	var crumbs []string
+	for _, l := range mc.stack[1:] {
+		crumbs = append(crumbs, l.menu.Prompt())
+	}
+
+	if mc.modified {
+		crumbs = append(crumbs, "(modified)")
+	}
+
+	return strings.Join(crumbs, " > ")
+}
+
+// hints returns the keys which are available in the current mode.
+func (mc *MenuConfig) hints() string {
+	switch mc.mode {
+	case modeEdit:
+		return "enter to confirm • esc to cancel • ctrl+c to quit"
+	case modeSearch:
+		return "↑/↓ to move • enter to jump • esc to cancel • ctrl+c to quit"
+	case modeHelp:
+		return "press any key to return"
+	case modeConfirm:
+		return "esc to cancel"
+	}
+
+	return "↑/↓ to move • enter to select • space to toggle • / to search • ? for help • s to save • q to quit"
+}
@@ -50,6 +50,10 @@ type Application interface {
 	// Extensions returns the application's extensions
 	Extensions() component.Extensions
 
+	// Links returns the local checkouts which replace the sources of the
+	// application's components
+	Links() *Links
+
 	// Kraftfiles returns the application's kraft configuration files
 	Kraftfiles() []string
 
@@ -117,6 +121,22 @@ type Application interface {
 	// WithTarget is a reducer that returns the application with only the provided
 	// target.
 	WithTarget(target.Target) (Application, error)
+
+	// WithOutDir is a reducer that returns the application with the provided
+	// output directory, such that targets can be built in isolation from one
+	// another.
+	WithOutDir(string) (Application, error)
+
+	// MissingLibraries returns the KConfig symbols of libraries, e.g. LIBMUSL,
+	// which the enabled configuration of the application for the provided
+	// target depends on or selects but which are provided neither by the
+	// Unikraft core nor by any of the application's libraries.
+	MissingLibraries(context.Context, target.Target) ([]string, error)
+
+	// KConfigTrees returns the KConfig trees of the Unikraft core, its internal
+	// libraries, the application's libraries and the application itself which
+	// could be parsed.
+	KConfigTrees(context.Context) []*kconfig.KConfigFile
 }
 
 type application struct {
@@ -134,6 +154,7 @@ type application struct {
 	kraftfiles    []string
 	configuration kconfig.KeyValueMap
 	extensions    component.Extensions
+	links         *Links
 }
 
 func (app application) Name() string {
@@ -191,6 +212,10 @@ func (app application) Extensions() component.Extensions {
 	return app.extensions
 }
 
+func (app application) Links() *Links {
+	return app.links
+}
+
 func (app application) Kraftfiles() []string {
 	return app.kraftfiles
 }
@@ -228,6 +253,11 @@ func (app application) MergeTemplate(ctx context.Context, merge Application) (Ap
 	uk.KConfig().OverrideBy(app.unikraft.KConfig())
 	app.unikraft = uk.(core.UnikraftConfig)
 
+	app.links = merge.Links()
+	if err := app.links.apply(&app); err != nil {
+		return nil, err
+	}
+
 	return app, nil
 }
 
@@ -642,3 +672,9 @@ func (app application) WithTarget(targ target.Target) (Application, error) {
 	ret.targets = target.Targets{targ.(*target.TargetConfig)}
 	return ret, nil
 }
+
+func (app application) WithOutDir(outDir string) (Application, error) {
+	ret := app
+	ret.outDir = outDir
+	return ret, nil
+}
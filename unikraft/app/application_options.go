@@ -51,6 +51,10 @@ func NewApplicationFromOptions(aopts ...ApplicationOption) (Application, error)
 		ac.outDir = filepath.Join(ac.workingDir, unikraft.BuildDir)
 	}
 
+	if err := ac.links.apply(ac); err != nil {
+		return nil, fmt.Errorf("could not apply links: %v", err)
+	}
+
 	if len(ac.unikraft.Source()) > 0 {
 		if p, err := os.Stat(ac.unikraft.Source()); err == nil && p.IsDir() {
 			ac.configuration.Set(unikraft.UK_BASE, ac.unikraft.Source())
@@ -149,6 +153,15 @@ func WithExtensions(extensions component.Extensions) ApplicationOption {
 	}
 }
 
+// WithLinks sets the local checkouts which replace the sources of the
+// application's components
+func WithLinks(links *Links) ApplicationOption {
+	return func(ac *application) error {
+		ac.links = links
+		return nil
+	}
+}
+
 // WithKraftfiles sets the application's kraft yaml files
 func WithKraftfiles(kraftfiles []string) ApplicationOption {
 	return func(ac *application) error {
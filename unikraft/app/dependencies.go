@@ -0,0 +1,212 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package app
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"kraftkit.sh/kconfig"
+	"kraftkit.sh/log"
+	"kraftkit.sh/unikraft"
+	"kraftkit.sh/unikraft/lib"
+	"kraftkit.sh/unikraft/target"
+)
+
+func (app application) MissingLibraries(ctx context.Context, tc target.Target) ([]string, error) {
+	values := kconfig.KeyValueMap{}
+	values.OverrideBy(app.KConfig())
+
+	if tc != nil {
+		values.OverrideBy(tc.KConfig())
+
+		// A previous configuration of the target takes precedence as it reflects
+		// any changes made via menuconfig.
+		if app.IsConfigured(tc) {
+			dotconfig, err := kconfig.ParseConfig(filepath.Join(app.workingDir, tc.ConfigFilename()))
+			if err != nil {
+				return nil, fmt.Errorf("could not parse configuration of %s: %v", tc.Name(), err)
+			}
+
+			values.OverrideBy(dotconfig.Map)
+		}
+	}
+
+	trees := app.KConfigTrees(ctx)
+
+	// Only the symbols which, by convention, enable a library are of interest
+	// since all other symbols cannot be provided by adding a library.
+	var missing []string
+	for _, symbol := range kconfig.MissingSymbols(values, trees...) {
+		if len(lib.NamesFromKConfigSymbol(symbol)) > 0 {
+			missing = append(missing, symbol)
+		}
+	}
+
+	return missing, nil
+}
+
+func (app application) KConfigTrees(ctx context.Context) []*kconfig.KConfigFile {
+	var trees []*kconfig.KConfigFile
+
+	addTree := func(name string, tree *kconfig.KConfigFile, err error) {
+		if err != nil {
+			log.G(ctx).
+				WithField("component", name).
+				Debugf("could not parse KConfig: %v", err)
+			return
+		} else if tree == nil {
+			return
+		}
+
+		trees = append(trees, tree)
+	}
+
+	// Unikraft's core includes some of its own files relative to its base.
+	uk := app.Unikraft(ctx)
+	tree, err := uk.KConfigTree(
+		&kconfig.KeyValue{Key: unikraft.UK_BASE, Value: uk.Path()},
+		&kconfig.KeyValue{Key: strings.TrimPrefix(unikraft.UK_BASE, kconfig.Prefix), Value: uk.Path()},
+	)
+	addTree(uk.Name(), tree, err)
+
+	// The internal libraries are listed separately from the application's own
+	// since Libraries adds the former to the latter.
+	uklibs, err := uk.Libraries(ctx)
+	if err != nil {
+		log.G(ctx).Debugf("could not list internal libraries: %v", err)
+	}
+
+	for _, libs := range []lib.Libraries{uklibs, app.libraries} {
+		names := make([]string, 0, len(libs))
+		for name := range libs {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		for _, name := range names {
+			tree, err := libs[name].KConfigTree()
+			addTree(name, tree, err)
+		}
+	}
+
+	tree, err = app.KConfigTree()
+	addTree(app.name, tree, err)
+
+	return trees
+}
+
+// AddLibrariesToKraftfile adds the provided libraries, a map of library names
+// to versions, to the `libraries` section of the Kraftfile at the provided
+// path.  Libraries which are already listed are left untouched and the
+// remaining contents of the Kraftfile, including comments, are preserved.
+func AddLibrariesToKraftfile(path string, libraries map[string]string) error {
+	doc, err := decodeKraftfile(path)
+	if err != nil {
+		return err
+	}
+
+	section := kraftfileSection(doc, "libraries")
+	if section.Kind == 0 {
+		*section = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	} else if section.Kind != yaml.MappingNode {
+		return fmt.Errorf("could not update %s: expected the libraries to be a mapping", path)
+	}
+
+	existing := map[string]bool{}
+	for i := 0; i < len(section.Content); i += 2 {
+		existing[section.Content[i].Value] = true
+	}
+
+	var names []string
+	for name := range libraries {
+		if !existing[name] {
+			names = append(names, name)
+		}
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		section.Content = append(section.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: name},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: libraries[name]},
+		)
+	}
+
+	return encodeKraftfile(path, doc)
+}
+
+// decodeKraftfile parses the Kraftfile at the provided path into a YAML node
+// such that it can be edited without losing its comments.
+func decodeKraftfile(path string) (*yaml.Node, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", path, err)
+	}
+
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("could not parse %s: expected a mapping", path)
+	}
+
+	return &doc, nil
+}
+
+// kraftfileSection returns the top-level section of the decoded Kraftfile with
+// the provided name.  A section which is missing is added and, like a section
+// which is empty, has a zero kind.
+func kraftfileSection(doc *yaml.Node, name string) *yaml.Node {
+	root := doc.Content[0]
+
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		if root.Content[i].Value != name {
+			continue
+		}
+
+		section := root.Content[i+1]
+		if section.Kind == yaml.ScalarNode && section.Tag == "!!null" {
+			*section = yaml.Node{}
+		}
+
+		return section
+	}
+
+	section := &yaml.Node{}
+	root.Content = append(root.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: name},
+		section,
+	)
+
+	return section
+}
+
+// encodeKraftfile writes the edited Kraftfile back to the provided path.
+func encodeKraftfile(path string, doc *yaml.Node) error {
+	var b strings.Builder
+	enc := yaml.NewEncoder(&b)
+	enc.SetIndent(2)
+
+	if err := enc.Encode(doc); err != nil {
+		return fmt.Errorf("could not encode %s: %v", path, err)
+	}
+
+	if err := enc.Close(); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, []byte(b.String()), 0o644)
+}
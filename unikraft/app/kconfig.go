@@ -0,0 +1,133 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+
+package app
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"kraftkit.sh/kconfig"
+)
+
+// UpdateKConfigInKraftfile sets the provided values in, and removes the
+// provided keys from, the top-level `kconfig` section of the Kraftfile at the
+// provided path.  The section may either be a mapping or a list of `KEY=VALUE`
+// entries; existing entries, with or without the CONFIG_ prefix, are updated
+// in place and the remaining contents of the Kraftfile, including comments, are
+// preserved.  Quoted values, i.e. those of string options, are written as YAML
+// strings.
+func UpdateKConfigInKraftfile(path string, set kconfig.KeyValueMap, remove []string) error {
+	doc, err := decodeKraftfile(path)
+	if err != nil {
+		return err
+	}
+
+	section := kraftfileSection(doc, "kconfig")
+	if section.Kind == 0 {
+		*section = yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	}
+
+	var keys []string
+	for key, kv := range set {
+		if kv != nil {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	removed := map[string]bool{}
+	for _, key := range remove {
+		removed[strings.TrimPrefix(key, kconfig.Prefix)] = true
+	}
+
+	switch section.Kind {
+	case yaml.MappingNode:
+		var content []*yaml.Node
+		existing := map[string]*yaml.Node{}
+
+		for i := 0; i+1 < len(section.Content); i += 2 {
+			key := strings.TrimPrefix(section.Content[i].Value, kconfig.Prefix)
+			if removed[key] {
+				continue
+			}
+
+			existing[key] = section.Content[i+1]
+			content = append(content, section.Content[i], section.Content[i+1])
+		}
+
+		for _, key := range keys {
+			if node, ok := existing[strings.TrimPrefix(key, kconfig.Prefix)]; ok {
+				setKConfigValue(node, set[key].Value)
+				continue
+			}
+
+			node := &yaml.Node{}
+			setKConfigValue(node, set[key].Value)
+			content = append(content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, node)
+		}
+
+		section.Content = content
+
+	case yaml.SequenceNode:
+		var content []*yaml.Node
+		existing := map[string]*yaml.Node{}
+
+		for _, entry := range section.Content {
+			key, _, _ := strings.Cut(entry.Value, "=")
+			key = strings.TrimPrefix(key, kconfig.Prefix)
+			if removed[key] {
+				continue
+			}
+
+			existing[key] = entry
+			content = append(content, entry)
+		}
+
+		for _, key := range keys {
+			value := set[key].Value
+
+			entry, ok := existing[strings.TrimPrefix(key, kconfig.Prefix)]
+			if ok {
+				// Keep the key as it is written.
+				key, _, _ = strings.Cut(entry.Value, "=")
+			} else {
+				entry = &yaml.Node{}
+				content = append(content, entry)
+			}
+
+			entry.Kind = yaml.ScalarNode
+			entry.Tag = ""
+			entry.Style = 0
+			entry.Value = key + "=" + value
+		}
+
+		section.Content = content
+
+	default:
+		return fmt.Errorf("could not update %s: expected kconfig to be a mapping or a list", path)
+	}
+
+	return encodeKraftfile(path, doc)
+}
+
+// setKConfigValue sets the value of an option in a `kconfig` mapping.
+func setKConfigValue(node *yaml.Node, value string) {
+	node.Kind = yaml.ScalarNode
+	node.Tag = ""
+	node.Style = 0
+
+	if s, err := strconv.Unquote(value); err == nil && strings.HasPrefix(value, `"`) {
+		node.Style = yaml.DoubleQuotedStyle
+		value = s
+	}
+
+	node.Value = value
+}
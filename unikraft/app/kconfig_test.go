@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kraftkit.sh/kconfig"
+)
+
+func TestUpdateKConfigInKraftfile(t *testing.T) {
+	for _, test := range []struct {
+		name     string
+		before   string
+		expected string
+	}{
+		{
+			name: "mapping",
+			before: `specification: v0.5
+# The options of the application
+kconfig:
+  # Enable networking
+  LIBLWIP: y
+  CONFIG_LWIP_TCP_SND_BUF: 4096
+`,
+			expected: `specification: v0.5
+# The options of the application
+kconfig:
+  # Enable networking
+  LIBLWIP: y
+  CONFIG_LIBPOSIX_SOCKET: y
+  CONFIG_LWIP_HOSTNAME: "unikraft"
+`,
+		},
+		{
+			name: "list",
+			before: `specification: v0.5
+kconfig:
+  - LIBLWIP=n # disabled for now
+  - CONFIG_LWIP_TCP_SND_BUF=4096
+`,
+			expected: `specification: v0.5
+kconfig:
+  - LIBLWIP=y # disabled for now
+  - CONFIG_LIBPOSIX_SOCKET=y
+  - CONFIG_LWIP_HOSTNAME="unikraft"
+`,
+		},
+		{
+			name:   "missing",
+			before: "specification: v0.5\n",
+			expected: `specification: v0.5
+kconfig:
+  CONFIG_LIBLWIP: y
+  CONFIG_LIBPOSIX_SOCKET: y
+  CONFIG_LWIP_HOSTNAME: "unikraft"
+`,
+		},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "Kraftfile")
+			if err := os.WriteFile(path, []byte(test.before), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			set := kconfig.NewKeyValueMapFromSlice(
+				"CONFIG_LIBLWIP=y",
+				"CONFIG_LIBPOSIX_SOCKET=y",
+				`CONFIG_LWIP_HOSTNAME="unikraft"`,
+			)
+
+			if err := UpdateKConfigInKraftfile(path, set, []string{"CONFIG_LWIP_TCP_SND_BUF"}); err != nil {
+				t.Fatal(err)
+			}
+
+			after, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if string(after) != test.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", test.expected, after)
+			}
+		})
+	}
+}
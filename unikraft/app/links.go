@@ -0,0 +1,163 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package app
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+
+	"kraftkit.sh/unikraft"
+	"kraftkit.sh/unikraft/core"
+	"kraftkit.sh/unikraft/lib"
+)
+
+// LinksFileName is the name of the file, alongside the Kraftfile, which records
+// the local checkouts which replace the sources of a project's components.  It
+// is specific to a developer's machine and is not meant to be committed.
+const LinksFileName = ".kraft.links"
+
+// Links records the local checkouts which replace the sources of a project's
+// components during development, e.g. a fork of a library, without the need
+// to modify the Kraftfile.
+type Links struct {
+	Components []LinkedComponent `yaml:"components"`
+}
+
+// LinkedComponent is the local checkout of a single component.
+type LinkedComponent struct {
+	// Type of the component
+	Type unikraft.ComponentType `yaml:"type"`
+
+	// Name of the component
+	Name string `yaml:"name"`
+
+	// Path to the local checkout of the component
+	Path string `yaml:"path"`
+}
+
+// NewLinksFromFile reads the links at the provided path.  Relative paths of
+// linked components are resolved against the directory of the file.  A file
+// which does not exist results in no links.
+func NewLinksFromFile(path string) (*Links, error) {
+	links := &Links{}
+
+	contents, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return links, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := yaml.Unmarshal(contents, links); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", path, err)
+	}
+
+	for i, component := range links.Components {
+		if !filepath.IsAbs(component.Path) {
+			links.Components[i].Path = filepath.Join(filepath.Dir(path), component.Path)
+		}
+	}
+
+	return links, nil
+}
+
+// WriteToFile saves the links to the provided path.  If there are no links,
+// the file is removed instead.
+func (l *Links) WriteToFile(path string) error {
+	if len(l.Components) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+
+		return nil
+	}
+
+	sort.Slice(l.Components, func(i, j int) bool {
+		if l.Components[i].Type != l.Components[j].Type {
+			return l.Components[i].Type < l.Components[j].Type
+		}
+
+		return l.Components[i].Name < l.Components[j].Name
+	})
+
+	contents, err := yaml.Marshal(l)
+	if err != nil {
+		return err
+	}
+
+	header := "# This file is generated by `kraft pkg link`.  Do not commit it.\n"
+
+	return os.WriteFile(path, append([]byte(header), contents...), 0o644)
+}
+
+// Lookup returns the path of the local checkout which replaces the component
+// with the provided type and name, if it is linked.
+func (l *Links) Lookup(t unikraft.ComponentType, name string) (string, bool) {
+	if l == nil {
+		return "", false
+	}
+
+	for _, component := range l.Components {
+		if component.Type == t && component.Name == name {
+			return component.Path, true
+		}
+	}
+
+	return "", false
+}
+
+// Link replaces the sources of the component with the provided type and name
+// with the local checkout at the provided path.
+func (l *Links) Link(t unikraft.ComponentType, name, path string) {
+	for i, component := range l.Components {
+		if component.Type == t && component.Name == name {
+			l.Components[i].Path = path
+			return
+		}
+	}
+
+	l.Components = append(l.Components, LinkedComponent{
+		Type: t,
+		Name: name,
+		Path: path,
+	})
+}
+
+// Unlink restores the sources of the component with the provided type and
+// name and returns whether it was linked.
+func (l *Links) Unlink(t unikraft.ComponentType, name string) bool {
+	for i, component := range l.Components {
+		if component.Type == t && component.Name == name {
+			l.Components = append(l.Components[:i], l.Components[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+// apply replaces the paths of the application's components with those of the
+// local checkouts which they are linked to.
+func (l *Links) apply(app *application) error {
+	if path, ok := l.Lookup(unikraft.ComponentTypeCore, app.unikraft.Name()); ok {
+		if err := core.WithPath(path)(&app.unikraft); err != nil {
+			return err
+		}
+	}
+
+	for _, library := range app.libraries {
+		if path, ok := l.Lookup(unikraft.ComponentTypeLib, library.Name()); ok {
+			if err := lib.WithPath(path)(library); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
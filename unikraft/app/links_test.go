@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package app
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"kraftkit.sh/unikraft"
+	"kraftkit.sh/unikraft/core"
+	"kraftkit.sh/unikraft/lib"
+)
+
+func TestLinks(t *testing.T) {
+	workdir := t.TempDir()
+	path := filepath.Join(workdir, LinksFileName)
+
+	links, err := NewLinksFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	links.Link(unikraft.ComponentTypeLib, "musl", "/src/lib-musl")
+	links.Link(unikraft.ComponentTypeCore, "unikraft", "/src/unikraft")
+	links.Link(unikraft.ComponentTypeLib, "musl", "/src/fork/lib-musl")
+
+	if err := links.WriteToFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	read, err := NewLinksFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(read.Components) != 2 {
+		t.Fatalf("expected 2 links, got %+v", read.Components)
+	}
+
+	if p, ok := read.Lookup(unikraft.ComponentTypeLib, "musl"); !ok || p != "/src/fork/lib-musl" {
+		t.Errorf("expected musl to be linked to /src/fork/lib-musl, got %q", p)
+	}
+
+	musl := &lib.LibraryConfig{}
+	if err := lib.WithName("musl")(musl); err != nil {
+		t.Fatal(err)
+	}
+
+	uk, err := core.NewUnikraftFromOptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	project := &application{
+		unikraft:  *uk.(*core.UnikraftConfig),
+		libraries: lib.Libraries{"musl": musl},
+	}
+
+	if err := read.apply(project); err != nil {
+		t.Fatal(err)
+	}
+
+	if project.unikraft.Path() != "/src/unikraft" || musl.Path() != "/src/fork/lib-musl" {
+		t.Errorf("expected linked paths, got %s and %s", project.unikraft.Path(), musl.Path())
+	}
+
+	read.Unlink(unikraft.ComponentTypeLib, "musl")
+	read.Unlink(unikraft.ComponentTypeCore, "unikraft")
+
+	if err := read.WriteToFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed without links", path)
+	}
+}
@@ -158,6 +158,11 @@ func NewProjectFromOptions(ctx context.Context, opts ...ProjectOption) (Applicat
 		target.KConfig().OverrideBy(kvmap)
 	}
 
+	links, err := NewLinksFromFile(filepath.Join(popts.workdir, LinksFileName))
+	if err != nil {
+		return nil, err
+	}
+
 	project, err := NewApplicationFromOptions(
 		WithName(projectName),
 		WithWorkingDir(popts.workdir),
@@ -169,6 +174,7 @@ func NewProjectFromOptions(ctx context.Context, opts ...ProjectOption) (Applicat
 		WithTargets(app.targets),
 		WithConfiguration(popts.kconfig.Slice()...),
 		WithExtensions(app.extensions),
+		WithLinks(links),
 	)
 	if err != nil {
 		return nil, err
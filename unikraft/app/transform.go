@@ -137,21 +137,8 @@ var transformMapStringString TransformerFunc = func(_ context.Context, data inte
 	}
 }
 
-var transformInitrd TransformerFunc = func(_ context.Context, data interface{}) (interface{}, error) {
-	switch value := data.(type) {
-	case map[string]interface{}:
-		if format, ok := value["format"]; ok {
-			if typ, ok := initrd.NameToType[format.(string)]; ok {
-				value["format"] = typ
-			} else {
-				return value, errors.Errorf("invalid option for initrd type: %s", format)
-			}
-		}
-
-		return value, nil
-	default:
-		return data, errors.Errorf("invalid type %T for platform", value)
-	}
+var transformInitrd TransformerFunc = func(ctx context.Context, data interface{}) (interface{}, error) {
+	return initrd.TransformFromSchema(ctx, data)
 }
 
 func transformMappingOrList(mappingOrList interface{}, sep string, allowNil bool) (interface{}, error) {
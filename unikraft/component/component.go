@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"net/url"
 	"os"
+	"strings"
 
 	"kraftkit.sh/kconfig"
 	"kraftkit.sh/unikraft"
@@ -102,6 +103,9 @@ func parseStringProp(entry string) map[string]interface{} {
 
 	if f, err := os.Stat(entry); err == nil && f.IsDir() {
 		component["source"] = entry
+	} else if len(entry) > 0 && strings.ContainsAny(entry[:1], "^~<>=!") {
+		// Version constraints, e.g. ^0.12 or >=0.11 <0.13, are never sources
+		component["version"] = entry
 	} else if u, err := url.Parse(entry); err == nil && u.Host != "" {
 		component["source"] = entry
 
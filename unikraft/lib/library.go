@@ -31,6 +31,10 @@ type Library interface {
 	// Syscalls contains the list of provided syscalls by the library.
 	Syscalls() []*unikraft.ProvidedSyscall
 
+	// KConfigName is the name of the KConfig option which enables the library,
+	// e.g. `CONFIG_LIBPOSIX_PROCESS`.
+	KConfigName() string
+
 	// IsInternal dictates whether the library comes from the Unikraft core
 	// repository.
 	IsInternal() bool
@@ -178,6 +182,10 @@ func (lc LibraryConfig) Path() string {
 	return lc.path
 }
 
+func (lc LibraryConfig) KConfigName() string {
+	return lc.kname
+}
+
 func (lc LibraryConfig) KConfigTree(env ...*kconfig.KeyValue) (*kconfig.KConfigFile, error) {
 	config_uk := filepath.Join(lc.Path(), unikraft.Config_uk)
 	if _, err := os.Stat(config_uk); err != nil {
@@ -196,7 +204,7 @@ func (lc LibraryConfig) KConfig() kconfig.KeyValueMap {
 	if menu == nil {
 		// Naively set the KConfig option for this library based on Unikraft
 		// convention.
-		values.Set(kconfig.Prefix+"LIB"+strings.ToUpper(lc.name), kconfig.Yes)
+		values.Set(kconfig.Prefix+KConfigSymbol(lc.name), kconfig.Yes)
 
 		return values
 	}
@@ -363,12 +371,17 @@ func NewFromDir(ctx context.Context, dir string, opts ...LibraryOption) (Librari
 
 		// Match exported syscalls
 		for _, syscalls := range additions[unikraft.UK_PROVIDED_SYSCALLS] {
+			condition := syscalls.condition
 			for _, syscall := range syscalls.matches {
 				provided := unikraft.NewProvidedSyscall(syscall)
 				if provided == nil {
 					continue // TODO: Warn, error?
 				}
 
+				if condition != "y" {
+					provided.DependsOn = &condition
+				}
+
 				libs[onlylib].syscalls = append(libs[onlylib].syscalls, provided)
 			}
 		}
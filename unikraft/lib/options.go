@@ -38,3 +38,11 @@ func WithVersion(version string) LibraryOption {
 		return nil
 	}
 }
+
+// WithPath sets the location of the library's sources.
+func WithPath(path string) LibraryOption {
+	return func(lc *LibraryConfig) error {
+		lc.path = path
+		return nil
+	}
+}
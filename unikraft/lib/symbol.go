@@ -0,0 +1,49 @@
+// SPDX-License-Identifier: BSD-3-Clause
+// Copyright (c) 2022, Unikraft GmbH and The KraftKit Authors.
+// Licensed under the BSD-3-Clause License (the "License").
+// You may not use this file except in compliance with the License.
+package lib
+
+import (
+	"strings"
+)
+
+// KConfigSymbolPrefix is the prefix of the KConfig symbol which enables a
+// library by Unikraft convention, e.g. LIBMUSL.
+const KConfigSymbolPrefix = "LIB"
+
+// KConfigSymbol returns the KConfig symbol which enables the library with the
+// provided name by Unikraft convention, e.g. LIBCOMPILER_RT for compiler-rt.
+func KConfigSymbol(name string) string {
+	return KConfigSymbolPrefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+}
+
+// NamesFromKConfigSymbol returns the possible names of the library which
+// provides the KConfig symbol, from the most to the least specific.  Since a
+// library may define symbols for its own options, e.g. LIBMUSL_LOCALE, and
+// since both dashes and underscores are rendered as underscores, every prefix
+// of the symbol is considered.  Symbols which do not follow the convention
+// return no names.
+func NamesFromKConfigSymbol(symbol string) []string {
+	if !strings.HasPrefix(symbol, KConfigSymbolPrefix) || len(symbol) == len(KConfigSymbolPrefix) {
+		return nil
+	}
+
+	parts := strings.Split(strings.ToLower(strings.TrimPrefix(symbol, KConfigSymbolPrefix)), "_")
+
+	var names []string
+	for i := len(parts); i > 0; i-- {
+		if len(parts[i-1]) == 0 {
+			continue
+		}
+
+		dashed := strings.Join(parts[:i], "-")
+		names = append(names, dashed)
+
+		if i > 1 {
+			names = append(names, strings.Join(parts[:i], "_"))
+		}
+	}
+
+	return names
+}
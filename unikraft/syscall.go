@@ -14,6 +14,10 @@ import (
 type ProvidedSyscall struct {
 	Name  string
 	Nargs uint
+
+	// DependsOn is the condition, e.g. `$(CONFIG_LIBPOSIX_PROCESS_CLONE)`, under
+	// which the syscall is provided.  When nil, it is always provided.
+	DependsOn *string
 }
 
 // NewProvidedSyscall converts an exported `UK_PROVIDED_SYSCALL` entry into a
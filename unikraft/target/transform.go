@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"path/filepath"
 
+	"kraftkit.sh/initrd"
 	"kraftkit.sh/kconfig"
 	"kraftkit.sh/unikraft"
 	"kraftkit.sh/unikraft/arch"
@@ -49,6 +50,15 @@ func TransformFromSchema(ctx context.Context, data interface{}) (interface{}, er
 			case "kernel":
 				t.name = prop.(string)
 
+			case "initrd":
+				ramfs, err := initrd.TransformFromSchema(ctx, prop)
+				if err != nil {
+					return nil, err
+				}
+
+				config := ramfs.(initrd.InitrdConfig)
+				t.initrd = &config
+
 			case "kconfig":
 				switch tprop := prop.(type) {
 				case map[string]interface{}: